@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/service"
+)
+
+// startBulkJobs starts the workers of the bulk jobs and queues the jobs left
+// unfinished by the previous run. Every resumeInterval the unfinished jobs are
+// queued again, so jobs of instances that stopped are taken over once their
+// lock expires.
+func startBulkJobs(jobs *service.BulkJobService, workers int, resumeInterval time.Duration) {
+	ctx := context.Background()
+	results := jobs.Start(ctx, workers)
+	go func() {
+		for result := range results {
+			// Cancelled jobs stop with their context error
+			if result.Err != nil && !errors.Is(result.Err, context.Canceled) {
+				log.Printf("Bulk job %s failed: %v", result.Job.ID, result.Err)
+			}
+		}
+	}()
+
+	resumeBulkJobs(ctx, jobs)
+	if resumeInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(resumeInterval)
+			defer ticker.Stop()
+
+			for range ticker.C {
+				resumeBulkJobs(ctx, jobs)
+			}
+		}()
+	}
+}
+
+// resumeBulkJobs queues the unfinished jobs that no worker of this instance has
+func resumeBulkJobs(ctx context.Context, jobs *service.BulkJobService) {
+	resumed, err := jobs.Resume(ctx)
+	if err != nil {
+		log.Printf("Failed to resume bulk jobs: %v", err)
+		return
+	}
+	if resumed > 0 {
+		log.Printf("Resumed %d unfinished bulk jobs", resumed)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/dwipurnomo515/yard-planning/config"
+	"github.com/dwipurnomo515/yard-planning/pkg/cache"
+)
+
+// newSuggestionCache builds the cache selected by CACHE_MODE. It also returns
+// the in-process tier and the Redis client when they are used. The redis and
+// tiered modes fall back to the in-process cache when Redis is unreachable.
+func newSuggestionCache(cfg *config.Config) (cache.Cache, *cache.LocalCache, *cache.RedisClient, error) {
+	local := cache.NewLocalCache(cfg.CacheLocalSize, cfg.CacheLocalTTL)
+
+	switch cfg.CacheMode {
+	case "local":
+		return local, local, nil, nil
+	case "redis", "tiered":
+	default:
+		return nil, nil, nil, fmt.Errorf("unknown cache mode %q", cfg.CacheMode)
+	}
+
+	redisClient, err := cache.NewRedisClient(cache.RedisConfig{
+		Host:     cfg.RedisHost,
+		Port:     cfg.RedisPort,
+		Password: cfg.RedisPass,
+		DB:       cfg.RedisDB,
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to connect to Redis: %v. Using local cache only.", err)
+		return local, local, nil, nil
+	}
+
+	if cfg.CacheMode == "tiered" {
+		return cache.NewTieredCache(local, redisClient), local, redisClient, nil
+	}
+	return redisClient, nil, redisClient, nil
+}
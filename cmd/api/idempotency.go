@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+)
+
+// idempotencyPurgeInterval is how often expired idempotency keys are deleted
+const idempotencyPurgeInterval = time.Hour
+
+// purgeIdempotencyKeys deletes the stored responses of requests older than
+// ttl. Expired keys are already ignored by the middleware; this only keeps
+// the table small.
+func purgeIdempotencyKeys(store repository.IdempotencyStore, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	ctx := context.Background()
+	ticker := time.NewTicker(idempotencyPurgeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deleted, err := store.DeleteBefore(ctx, time.Now().Add(-ttl))
+		if err != nil {
+			log.Printf("Failed to purge idempotency keys: %v", err)
+			continue
+		}
+		if deleted > 0 {
+			log.Printf("Purged %d expired idempotency keys", deleted)
+		}
+	}
+}
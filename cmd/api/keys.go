@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/config"
+	"github.com/dwipurnomo515/yard-planning/internal/auth"
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
+)
+
+const keysUsage = "usage: api keys [-tenant TENANT] create NAME [TTL]|list|revoke ID|rotate ID [GRACE]"
+
+// defaultRotationGrace is how long a rotated key keeps working so clients can
+// switch to the new key
+const defaultRotationGrace = 24 * time.Hour
+
+// newAuthenticator sets up API key and, when a JWKS file is configured, JWT
+// authentication
+func newAuthenticator(cfg *config.Config, keys repository.APIKeyStore) (*auth.Authenticator, error) {
+	var verifier *auth.JWTVerifier
+	if cfg.JWTJWKSFile != "" {
+		var err error
+		verifier, err = auth.LoadJWKS(cfg.JWTJWKSFile, cfg.JWTIssuer, cfg.JWTAudience)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("JWT bearer tokens accepted (keys from %s)", cfg.JWTJWKSFile)
+	}
+
+	for _, origin := range cfg.CORSAllowedOrigins {
+		if origin == "*" {
+			log.Println("Warning: CORS allows any origin. Set CORS_ALLOWED_ORIGINS to the front-end origins.")
+		}
+	}
+
+	return auth.NewAuthenticator(keys, verifier), nil
+}
+
+// runKeys implements the keys subcommand. The plain key is printed once on
+// creation and rotation; only its hash is stored. New keys belong to the
+// tenant given with -tenant.
+func runKeys(cfg *config.Config, args []string) error {
+	keyTenant, args, err := parseTenant("keys", args)
+	if err != nil {
+		return fmt.Errorf(keysUsage)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf(keysUsage)
+	}
+
+	db, _, _, err := openSQLDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	keys := repository.NewAPIKeyRepository(db)
+
+	ctx := context.Background()
+	switch args[0] {
+	case "create":
+		if len(args) < 2 {
+			return fmt.Errorf(keysUsage)
+		}
+		var expiresAt *time.Time
+		if len(args) > 2 {
+			ttl, err := time.ParseDuration(args[2])
+			if err != nil {
+				return fmt.Errorf("invalid TTL %q", args[2])
+			}
+			at := time.Now().Add(ttl)
+			expiresAt = &at
+		}
+		return createKey(ctx, keys, args[1], keyTenant, expiresAt)
+	case "list":
+		list, err := keys.GetAll(ctx)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, k := range list {
+			state := "active"
+			switch {
+			case !k.IsActive(now):
+				state = "expired " + k.ExpiresAt.Format("2006-01-02 15:04:05")
+			case k.ExpiresAt != nil:
+				state = "expires " + k.ExpiresAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%4d  %-24s %-16s %s...  %s\n", k.ID, k.Name, k.Tenant, k.Prefix, state)
+		}
+	case "revoke":
+		if len(args) < 2 {
+			return fmt.Errorf(keysUsage)
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid key id %q", args[1])
+		}
+		if err := keys.Expire(ctx, id, time.Now()); err != nil {
+			return err
+		}
+		log.Printf("Revoked API key %d", id)
+	case "rotate":
+		if len(args) < 2 {
+			return fmt.Errorf(keysUsage)
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid key id %q", args[1])
+		}
+		grace := defaultRotationGrace
+		if len(args) > 2 {
+			if grace, err = time.ParseDuration(args[2]); err != nil {
+				return fmt.Errorf("invalid grace period %q", args[2])
+			}
+		}
+		return rotateKey(ctx, keys, id, grace)
+	default:
+		return fmt.Errorf(keysUsage)
+	}
+
+	return nil
+}
+
+func createKey(ctx context.Context, keys repository.APIKeyStore, name, keyTenant string, expiresAt *time.Time) error {
+	plain, key, err := auth.NewAPIKey(name, expiresAt)
+	if err != nil {
+		return err
+	}
+	key.Tenant = keyTenant
+	if err := keys.Create(ctx, key); err != nil {
+		return err
+	}
+	log.Printf("Created API key %d (%s, tenant %s). Store it now, it cannot be shown again:", key.ID, name, key.Tenant)
+	fmt.Println(plain)
+	return nil
+}
+
+// rotateKey issues a new key with the same name and tenant and lets the old
+// one expire after the grace period
+func rotateKey(ctx context.Context, keys repository.APIKeyStore, id int, grace time.Duration) error {
+	list, err := keys.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	var old *model.APIKey
+	for i := range list {
+		if list[i].ID == id {
+			old = &list[i]
+		}
+	}
+	if old == nil {
+		return fmt.Errorf("api key %d not found", id)
+	}
+
+	if err := createKey(ctx, keys, old.Name, old.Tenant, nil); err != nil {
+		return err
+	}
+	expiry := time.Now().Add(grace)
+	if old.ExpiresAt != nil && old.ExpiresAt.Before(expiry) {
+		expiry = *old.ExpiresAt
+	}
+	if err := keys.Expire(ctx, id, expiry); err != nil {
+		return err
+	}
+	log.Printf("API key %d stays valid until %s", id, expiry.Format("2006-01-02 15:04:05"))
+	return nil
+}
+
+// parseTenant reads the -tenant option that precedes the arguments of the
+// keys and roles subcommands
+func parseTenant(name string, args []string) (string, []string, error) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	t := flags.String("tenant", tenant.Default, "tenant (terminal operator)")
+	if err := flags.Parse(args); err != nil {
+		return "", nil, err
+	}
+	return *t, flags.Args(), nil
+}
@@ -3,102 +3,267 @@ package main
 import (
 	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/dwipurnomo515/yard-planning/config"
+	"github.com/dwipurnomo515/yard-planning/internal/auth"
 	"github.com/dwipurnomo515/yard-planning/internal/handler"
 	"github.com/dwipurnomo515/yard-planning/internal/middleware"
+	"github.com/dwipurnomo515/yard-planning/internal/occupancy"
 	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/repository/memory"
 	"github.com/dwipurnomo515/yard-planning/internal/service"
 	"github.com/dwipurnomo515/yard-planning/pkg/cache"
-	"github.com/dwipurnomo515/yard-planning/pkg/database"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.LoadConfig()
 
-	// Initialize database
-	db, err := database.NewPostgresDB(database.DBConfig{
-		Host:     cfg.DBHost,
-		Port:     cfg.DBPort,
-		User:     cfg.DBUser,
-		Password: cfg.DBPassword,
-		DBName:   cfg.DBName,
-	})
-	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+	// Schema management: api migrate status|up|down|to N|seed
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
-	defer db.Close()
 
-	// Initialize repositories
-	yardRepo := repository.NewYardRepository(db)
-	blockRepo := repository.NewBlockRepository(db)
-	planRepo := repository.NewYardPlanRepository(db)
-	containerRepo := repository.NewContainerRepository(db)
+	// API key management: api keys create|list|revoke|rotate
+	if len(os.Args) > 1 && os.Args[1] == "keys" {
+		if err := runKeys(cfg, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	// Initialize services
-	var containerHandler *handler.ContainerHandler
-	var bulkHandler *handler.BulkHandler
+	// Role bindings: api roles grant|list|revoke
+	if len(os.Args) > 1 && os.Args[1] == "roles" {
+		if err := runRoles(cfg, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	if cfg.EnableCache {
-		// Initialize Redis client
-		redisClient, err := cache.NewRedisClient(cache.RedisConfig{
-			Host:     cfg.RedisHost,
-			Port:     cfg.RedisPort,
-			Password: cfg.RedisPass,
-			DB:       cfg.RedisDB,
-		})
+	// An open API must be asked for explicitly
+	if !cfg.AuthEnabled && !cfg.AllowInsecure {
+		log.Fatal("Authentication is disabled: set AUTH_ENABLED=true, or ALLOW_INSECURE=true for local development")
+	}
+
+	// Initialize repositories. Writes lock the yards they change: replicas
+	// sharing a PostgreSQL database lock through it, memory and SQLite storage
+	// serve one instance and lock inside the process.
+	var stores repository.Stores
+	var locker cache.Locker
+	switch cfg.Storage {
+	case "memory":
+		store := memory.NewStore()
+		if err := store.Seed(); err != nil {
+			log.Fatal("Failed to seed in-memory storage:", err)
+		}
+		stores = store.Stores()
+		locker = cache.NewLocalLocker()
+		log.Println("Using in-memory storage, data is lost on exit")
+	case "postgres", "sqlite":
+		db, migrator, seed, err := openSQLDatabase(cfg)
 		if err != nil {
-			log.Printf("Warning: Failed to connect to Redis: %v. Running without cache.", err)
-			// Fall back to non-cached service
-			containerService := service.NewContainerService(
-				yardRepo,
-				blockRepo,
-				planRepo,
-				containerRepo,
-			)
-			containerHandler = handler.NewContainerHandler(containerService)
-			bulkHandler = handler.NewBulkHandler(containerService)
+			log.Fatal("Failed to connect to database:", err)
+		}
+		defer db.Close()
+		if err := prepareDatabase(cfg, migrator, seed); err != nil {
+			log.Fatal("Failed to prepare database:", err)
+		}
+		stores = repository.NewSQLStores(db)
+		if cfg.Storage == "postgres" {
+			locker = cache.NewPostgresLocker(db)
 		} else {
+			locker = cache.NewLocalLocker()
+		}
+		log.Printf("Using %s storage", cfg.Storage)
+	default:
+		log.Fatalf("Unknown storage backend %q", cfg.Storage)
+	}
+
+	// Keep occupied cells in memory for suggestions
+	var index *occupancy.Index
+	if cfg.OccupancyIndex {
+		var err error
+		stores, index, err = startOccupancyIndex(stores, cfg.OccupancyCheckInterval)
+		if err != nil {
+			log.Fatal("Failed to load occupancy index:", err)
+		}
+		log.Println("Occupancy index loaded")
+	}
+
+	// Caches are set up before the services, so every write goes through the
+	// stores that tell the other replicas about it
+	var suggestionCache cache.Cache
+	if cfg.EnableCache {
+		var localCache *cache.LocalCache
+		var redisClient *cache.RedisClient
+		var err error
+		suggestionCache, localCache, redisClient, err = newSuggestionCache(cfg)
+		if err != nil {
+			log.Fatal("Failed to set up cache:", err)
+		}
+		if redisClient != nil {
 			defer redisClient.Close()
-			log.Println("Redis cache enabled")
-
-			// Use cached service
-			cachedService := service.NewCachedContainerService(
-				yardRepo,
-				blockRepo,
-				planRepo,
-				containerRepo,
-				redisClient,
-			)
-			// Convert to base service for handler
-			baseService := &cachedService.ContainerService
-			containerHandler = handler.NewContainerHandler(baseService)
-			bulkHandler = handler.NewBulkHandler(baseService)
+
+			// Replicas reload what was changed by each other
+			stores = shareYardEvents(redisClient, stores, index, localCache)
+
+			// and lock through Redis rather than holding database connections
+			if cfg.Storage == "postgres" {
+				locker = cache.NewRedisLocker(redisClient)
+			}
 		}
+		log.Printf("Cache enabled (%s)", cfg.CacheMode)
 	} else {
 		log.Println("Cache disabled")
-		containerService := service.NewContainerService(
+	}
+
+	yardRepo := stores.Yards
+	blockRepo := stores.Blocks
+	planRepo := stores.Plans
+	containerRepo := stores.Containers
+	closureRepo := stores.Closures
+	equipmentRepo := stores.Equipment
+	workOrderRepo := stores.WorkOrders
+
+	// Initialize services
+	weights := service.DefaultSuggestionWeights()
+	weights.WorkloadPenalty = cfg.WorkloadPenalty
+	weights.DistanceWeight = cfg.DistanceWeight
+
+	containerService := service.NewContainerService(
+		yardRepo,
+		blockRepo,
+		planRepo,
+		containerRepo,
+		closureRepo,
+		equipmentRepo,
+		workOrderRepo,
+	)
+	containerService.SetSuggestionWeights(weights)
+	containerService.SetWorkOrderConfirmation(cfg.WorkOrderConfirmation)
+	containerService.SetOccupancyIndex(index)
+	containerService.SetTransactions(stores.Atomic)
+
+	// Handlers serve the cached service when caching is enabled
+	var containerOps service.ContainerOperations = containerService
+	if cfg.EnableCache {
+		cachedService := service.NewCachedContainerService(
 			yardRepo,
 			blockRepo,
 			planRepo,
 			containerRepo,
+			closureRepo,
+			equipmentRepo,
+			workOrderRepo,
+			suggestionCache,
 		)
-		containerHandler = handler.NewContainerHandler(containerService)
-		bulkHandler = handler.NewBulkHandler(containerService)
+		cachedService.SetSuggestionWeights(weights)
+		cachedService.SetWorkOrderConfirmation(cfg.WorkOrderConfirmation)
+		cachedService.SetOccupancyIndex(index)
+		cachedService.SetTransactions(stores.Atomic)
+		cachedService.SetLocker(locker)
+		containerOps = cachedService
 	}
 
-	// Setup routes
+	handler.SetStrictJSON(cfg.StrictJSON)
+	containerHandler := handler.NewContainerHandler(containerOps)
+	bulkHandler := handler.NewBulkHandler(containerOps)
+
+	// Confirmed jobs lock their yard like the requests that created them
+	containerService.SetLocker(locker)
+
+	bulkJobService := service.NewBulkJobService(stores.BulkJobs, stores.Roles, containerOps)
+	bulkJobService.SetLocker(locker, stores.Atomic)
+	startBulkJobs(bulkJobService, cfg.BulkJobWorkers, cfg.BulkJobResumeInterval)
+	bulkJobHandler := handler.NewBulkJobHandler(bulkJobService)
+
+	closureHandler := handler.NewClosureHandler(
+		service.NewClosureService(yardRepo, blockRepo, closureRepo),
+	)
+	holdHandler := handler.NewHoldHandler(service.NewHoldService(yardRepo, containerRepo))
+	equipmentHandler := handler.NewEquipmentHandler(
+		service.NewEquipmentService(yardRepo, blockRepo, equipmentRepo),
+	)
+	yardHandler := handler.NewYardHandler(service.NewYardService(yardRepo))
+	layoutHandler := handler.NewLayoutHandler(
+		service.NewLayoutService(yardRepo, blockRepo),
+	)
+	planningService := service.NewPlanningService(yardRepo, blockRepo, planRepo, containerRepo)
+	planningService.SetOccupancyIndex(index)
+	planningService.SetTransactions(stores.Atomic)
+	planningService.SetLocker(locker)
+	planningHandler := handler.NewPlanningHandler(planningService)
+	workOrderHandler := handler.NewWorkOrderHandler(
+		service.NewWorkOrderService(yardRepo, equipmentRepo, workOrderRepo, containerService),
+	)
+
+	adminHandler := handler.NewAdminHandler(
+		service.NewRoleBindingService(yardRepo, stores.Roles),
+	)
+
+	// Setup routes. Every route cancels its work after the configured timeout
+	// and needs a permission in at least one yard; the services check the
+	// permission for the yard of each request.
 	mux := http.NewServeMux()
+	single := func(read, write auth.Permission, h http.HandlerFunc) http.Handler {
+		return middleware.Require(read, write, middleware.Timeout(cfg.RequestTimeout, h))
+	}
+	bulk := func(perm auth.Permission, h http.HandlerFunc) http.Handler {
+		return middleware.Require(perm, perm, middleware.Timeout(cfg.BulkTimeout, h))
+	}
+	// Retries with the same Idempotency-Key get the response of the first request
+	once := func(h http.HandlerFunc) http.HandlerFunc {
+		return middleware.Idempotency(stores.Idempotency, cfg.IdempotencyTTL, h).ServeHTTP
+	}
+	go purgeIdempotencyKeys(stores.Idempotency, cfg.IdempotencyTTL)
 
 	// Single operation endpoints
-	mux.HandleFunc("/suggestion", containerHandler.HandleSuggestion)
-	mux.HandleFunc("/placement", containerHandler.HandlePlacement)
-	mux.HandleFunc("/pickup", containerHandler.HandlePickup)
+	mux.Handle("/suggestion", single(auth.PermSuggest, auth.PermSuggest, containerHandler.HandleSuggestion))
+	mux.Handle("/placement", single(auth.PermPlace, auth.PermPlace, once(containerHandler.HandlePlacement)))
+	mux.Handle("/pickup", single(auth.PermPickup, auth.PermPickup, once(containerHandler.HandlePickup)))
+	mux.Handle("/move", single(auth.PermMove, auth.PermMove, once(containerHandler.HandleMove)))
+
+	// Container holds; only supervisors release them
+	mux.Handle("/holds", single(auth.PermPlan, auth.PermPlan, once(holdHandler.HandleHold)))
+	mux.Handle("/holds/release", single(auth.PermOverrideHold, auth.PermOverrideHold, once(holdHandler.HandleRelease)))
 
 	// Bulk operation endpoints (concurrent)
-	mux.HandleFunc("/bulk/suggestion", bulkHandler.HandleBulkSuggestion)
-	mux.HandleFunc("/bulk/placement", bulkHandler.HandleBulkPlacement)
+	mux.Handle("/bulk/suggestion", bulk(auth.PermSuggest, once(bulkHandler.HandleBulkSuggestion)))
+	mux.Handle("/bulk/placement", bulk(auth.PermPlace, once(bulkHandler.HandleBulkPlacement)))
+	// Background bulk jobs; the service checks the permission of the job's kind
+	mux.Handle("/bulk/jobs", single(auth.PermView, auth.PermView, once(bulkJobHandler.HandleBulkJobs)))
+
+	// Block closures and maintenance windows
+	mux.Handle("/closures", single(auth.PermView, auth.PermPlan, closureHandler.HandleClosures))
+
+	// Yard equipment and workload
+	mux.Handle("/equipment", single(auth.PermView, auth.PermPlan, equipmentHandler.HandleEquipment))
+
+	// Overflow routing between yards
+	mux.Handle("/overflow-rules", single(auth.PermView, auth.PermPlan, yardHandler.HandleOverflowRules))
+
+	// Yard layout and points of interest
+	mux.Handle("/layout", single(auth.PermView, auth.PermView, layoutHandler.HandleLayout))
+	mux.Handle("/layout/points", single(auth.PermPlan, auth.PermPlan, layoutHandler.HandleCreatePoint))
+
+	// Versioned block and yard plan editing (ETag / If-Match)
+	mux.Handle("/blocks", single(auth.PermView, auth.PermPlan, planningHandler.HandleBlocks))
+	mux.Handle("/plans", single(auth.PermView, auth.PermPlan, planningHandler.HandlePlans))
+
+	// Equipment operator job list
+	mux.Handle("/jobs", single(auth.PermView, auth.PermView, workOrderHandler.HandleJobs))
+	mux.Handle("/jobs/next", single(auth.PermOperate, auth.PermOperate, workOrderHandler.HandleNextJob))
+	mux.Handle("/jobs/start", single(auth.PermOperate, auth.PermOperate, workOrderHandler.HandleStartJob))
+	mux.Handle("/jobs/confirm", single(auth.PermOperate, auth.PermOperate, workOrderHandler.HandleConfirmJob))
+	mux.Handle("/jobs/fail", single(auth.PermOperate, auth.PermOperate, workOrderHandler.HandleFailJob))
+
+	// Role bindings
+	mux.Handle("/admin/role-bindings", single(auth.PermAdmin, auth.PermAdmin, adminHandler.HandleRoleBindings))
 
 	// Health check
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -106,12 +271,24 @@ func main() {
 		w.Write([]byte("OK"))
 	})
 
+	// Authenticate every endpoint except the health check
+	var protected http.Handler = middleware.ContentType(mux)
+	if cfg.AuthEnabled {
+		authenticator, err := newAuthenticator(cfg, stores.APIKeys)
+		if err != nil {
+			log.Fatal("Failed to set up authentication:", err)
+		}
+		protected = middleware.Auth(authenticator, []string{"/health"},
+			middleware.LoadGrants(stores.Roles, protected))
+		log.Println("Authentication enabled")
+	} else {
+		log.Println("Warning: authentication is disabled (ALLOW_INSECURE=true), every endpoint is open")
+	}
+
 	// Apply middleware
 	handlerWithMiddleware := middleware.Recovery(
 		middleware.Logger(
-			middleware.CORS(
-				middleware.ContentType(mux),
-			),
+			middleware.CORS(cfg.CORSAllowedOrigins, protected),
 		),
 	)
 
@@ -119,7 +296,18 @@ func main() {
 	addr := ":" + cfg.ServerPort
 	log.Printf("Server starting on %s", addr)
 	log.Printf("Cache enabled: %v", cfg.EnableCache)
-	if err := http.ListenAndServe(addr, handlerWithMiddleware); err != nil {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handlerWithMiddleware,
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+	// Leave handlers time to answer once their own timeout expires
+	if timeout := max(cfg.RequestTimeout, cfg.BulkTimeout); timeout > 0 {
+		server.ReadTimeout = timeout
+		server.WriteTimeout = timeout + 5*time.Second
+	}
+	if err := server.ListenAndServe(); err != nil {
 		log.Fatal("Server failed to start:", err)
 	}
 }
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"log"
+	"strconv"
+
+	"github.com/dwipurnomo515/yard-planning/config"
+	"github.com/dwipurnomo515/yard-planning/migrations"
+	"github.com/dwipurnomo515/yard-planning/pkg/database"
+	"github.com/dwipurnomo515/yard-planning/pkg/migrate"
+)
+
+const migrateUsage = "usage: api migrate status|up|down|to N|seed"
+
+// openSQLDatabase connects to the configured SQL backend and returns the
+// migrator and seed fixture that belong to it
+func openSQLDatabase(cfg *config.Config) (*sql.DB, *migrate.Migrator, string, error) {
+	var (
+		db      *sql.DB
+		dialect string
+		fsys    fs.FS
+		seed    string
+		err     error
+	)
+
+	switch cfg.Storage {
+	case "postgres":
+		db, err = database.NewPostgresDB(database.DBConfig{
+			Host:     cfg.DBHost,
+			Port:     cfg.DBPort,
+			User:     cfg.DBUser,
+			Password: cfg.DBPassword,
+			DBName:   cfg.DBName,
+		})
+		dialect, fsys, seed = migrate.DialectPostgres, migrations.Postgres(), migrations.PostgresSeed
+	case "sqlite":
+		db, err = database.NewSQLiteDB(database.SQLiteConfig{Path: cfg.SQLitePath})
+		dialect, fsys, seed = migrate.DialectSQLite, migrations.SQLite(), migrations.SQLiteSeed
+	default:
+		return nil, nil, "", fmt.Errorf("storage %q has no database", cfg.Storage)
+	}
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	migrator, err := migrate.New(db, dialect, fsys)
+	if err != nil {
+		db.Close()
+		return nil, nil, "", err
+	}
+
+	return db, migrator, seed, nil
+}
+
+// runMigrate implements the migrate subcommand
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(migrateUsage)
+	}
+
+	db, migrator, seed, err := openSQLDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	switch args[0] {
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%03d  %-28s %s\n", s.Version, s.Name, state)
+		}
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("Applied %d migration(s)", applied)
+	case "down":
+		if err := migrator.Down(ctx); err != nil {
+			return err
+		}
+		log.Println("Rolled back 1 migration")
+	case "to":
+		if len(args) < 2 {
+			return fmt.Errorf(migrateUsage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		changed, err := migrator.To(ctx, version)
+		if err != nil {
+			return err
+		}
+		log.Printf("Migrated to version %d (%d migration(s) changed)", version, changed)
+	case "seed":
+		if err := migrator.Seed(ctx, seed); err != nil {
+			return err
+		}
+		log.Println("Seed data loaded")
+	default:
+		return fmt.Errorf(migrateUsage)
+	}
+
+	return nil
+}
+
+// prepareDatabase applies pending migrations and loads the seed fixture when
+// configured, and warns when the schema is behind the binary
+func prepareDatabase(cfg *config.Config, migrator *migrate.Migrator, seed string) error {
+	ctx := context.Background()
+
+	if cfg.AutoMigrate {
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("Auto-migrate applied %d migration(s)", applied)
+	} else {
+		version, err := migrator.Version(ctx)
+		if err != nil {
+			return err
+		}
+		if version < migrator.Latest() {
+			log.Printf("Warning: database schema is at version %d, latest is %d. Run 'api migrate up'.",
+				version, migrator.Latest())
+		}
+	}
+
+	if cfg.SeedData {
+		if err := migrator.Seed(ctx, seed); err != nil {
+			return err
+		}
+		log.Println("Seed data loaded")
+	}
+
+	return nil
+}
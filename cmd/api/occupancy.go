@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/occupancy"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/pkg/cache"
+)
+
+// startOccupancyIndex loads the occupancy index and wraps the container store
+// so that every placement, pickup and move keeps the index current
+func startOccupancyIndex(stores repository.Stores, checkInterval time.Duration) (repository.Stores, *occupancy.Index, error) {
+	ctx := context.Background()
+	index := occupancy.NewIndex()
+	if err := index.Load(ctx, stores); err != nil {
+		return stores, nil, err
+	}
+
+	if checkInterval > 0 {
+		go checkOccupancy(index, stores, checkInterval)
+	}
+
+	stores.Containers = occupancy.Track(stores.Containers, index)
+	stores.Atomic = occupancy.TrackAtomic(stores.Atomic, index)
+	return stores, index, nil
+}
+
+// checkOccupancy compares the index with the database and reloads it when
+// they disagree, e.g. after containers were changed directly in the database
+func checkOccupancy(index *occupancy.Index, stores repository.Stores, interval time.Duration) {
+	ctx := context.Background()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		mismatches, err := index.Check(ctx, stores)
+		if err != nil {
+			log.Printf("Occupancy check failed: %v", err)
+			continue
+		}
+		if len(mismatches) == 0 {
+			continue
+		}
+
+		log.Printf("Occupancy index differs from the database in %d cells (first: block %d %+v), reloading",
+			len(mismatches), mismatches[0].BlockID, mismatches[0].Cell)
+		if err := index.Load(ctx, stores); err != nil {
+			log.Printf("Occupancy reload failed: %v", err)
+		}
+	}
+}
+
+// blockChange is a block changed by a write on this instance
+type blockChange struct {
+	yardID, blockID int
+}
+
+// shareYardEvents keeps API instances in step: blocks changed by a local write
+// are published over Redis, and for blocks changed by other instances the
+// index is reloaded and the local suggestion cache is cleared. Events sent
+// while Redis is unreachable are lost, so a reconnect reloads everything. The
+// returned stores publish the writes made through them, with or without index.
+func shareYardEvents(
+	redisClient *cache.RedisClient,
+	stores repository.Stores,
+	index *occupancy.Index,
+	localCache *cache.LocalCache,
+) repository.Stores {
+	ctx := context.Background()
+	events := cache.NewYardEvents(redisClient)
+
+	// Publishing happens outside of the request path; a dropped event is
+	// repaired by the periodic check of the index and the expiry of cached
+	// suggestions
+	changes := make(chan blockChange, 256)
+	published := occupancy.Notify(stores, func(yardID, blockID int) {
+		select {
+		case changes <- blockChange{yardID, blockID}:
+		default:
+			log.Printf("Yard event queue full, block %d change not published", blockID)
+		}
+	})
+	go func() {
+		for change := range changes {
+			if err := events.Publish(ctx, change.yardID, change.blockID); err != nil {
+				log.Printf("Failed to publish yard event: %v", err)
+			}
+		}
+	}()
+
+	if index == nil && localCache == nil {
+		return published
+	}
+
+	clearSuggestions := func() {
+		if localCache != nil {
+			localCache.DeletePattern(ctx, "suggestion:*")
+		}
+	}
+
+	go events.Subscribe(ctx,
+		func(event cache.YardEvent) {
+			clearSuggestions()
+			if index == nil {
+				return
+			}
+			if err := index.LoadBlock(ctx, stores, event.BlockID); err != nil {
+				log.Printf("Failed to reload block %d: %v", event.BlockID, err)
+			}
+		},
+		func() {
+			clearSuggestions()
+			if index == nil {
+				return
+			}
+			if err := index.Load(ctx, stores); err != nil {
+				log.Printf("Failed to reload occupancy index: %v", err)
+			}
+		},
+	)
+
+	return published
+}
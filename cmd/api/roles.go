@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/dwipurnomo515/yard-planning/config"
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/service"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
+)
+
+const rolesUsage = "usage: api roles [-tenant TENANT] grant SUBJECT ROLE [YARD]|list [SUBJECT]|revoke ID"
+
+// runRoles implements the roles subcommand, which is how the first ADMIN of
+// a tenant is bound before the admin API can be used
+func runRoles(cfg *config.Config, args []string) error {
+	bindingTenant, args, err := parseTenant("roles", args)
+	if err != nil {
+		return fmt.Errorf(rolesUsage)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf(rolesUsage)
+	}
+
+	db, _, _, err := openSQLDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	roles := service.NewRoleBindingService(repository.NewYardRepository(db), repository.NewRoleBindingRepository(db))
+
+	ctx := tenant.WithTenant(context.Background(), bindingTenant)
+	switch args[0] {
+	case "grant":
+		if len(args) < 3 {
+			return fmt.Errorf(rolesUsage)
+		}
+		req := model.RoleBindingRequest{Subject: args[1], Role: args[2]}
+		if len(args) > 3 {
+			req.Yard = args[3]
+		}
+		binding, err := roles.CreateBinding(ctx, req)
+		if err != nil {
+			return err
+		}
+		log.Printf("Granted %s to %s in yard %s (binding %d)", binding.Role, binding.Subject, binding.Yard, binding.ID)
+	case "list":
+		subject := ""
+		if len(args) > 1 {
+			subject = args[1]
+		}
+		bindings, err := roles.ListBindings(ctx, subject)
+		if err != nil {
+			return err
+		}
+		for _, b := range bindings {
+			fmt.Printf("%4d  %-32s %-20s %s\n", b.ID, b.Subject, b.Role, b.Yard)
+		}
+	case "revoke":
+		if len(args) < 2 {
+			return fmt.Errorf(rolesUsage)
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid binding id %q", args[1])
+		}
+		if err := roles.DeleteBinding(ctx, id); err != nil {
+			return err
+		}
+		log.Printf("Revoked role binding %d", id)
+	default:
+		return fmt.Errorf(rolesUsage)
+	}
+
+	return nil
+}
@@ -3,11 +3,24 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
+	// Storage selects the repository backend: "postgres", "sqlite" or "memory" (demo mode)
+	Storage string
+	// SQLitePath is the database file used by the sqlite backend
+	SQLitePath string
+
+	// AutoMigrate applies pending migrations on startup
+	AutoMigrate bool
+	// SeedData loads the sample yards on startup
+	SeedData bool
+
 	DBHost      string
 	DBPort      string
 	DBUser      string
@@ -19,6 +32,57 @@ type Config struct {
 	RedisPass   string
 	RedisDB     int
 	EnableCache bool
+
+	// CacheMode selects the suggestion cache: "redis", "local" (in-process LRU) or "tiered" (LRU in front of Redis)
+	CacheMode string
+	// CacheLocalSize is the maximum number of keys in the in-process cache
+	CacheLocalSize int
+	// CacheLocalTTL caps how long a key is kept in the in-process cache
+	CacheLocalTTL time.Duration
+
+	// WorkloadPenalty is the suggestion score added per queued work order
+	WorkloadPenalty float64
+	// DistanceWeight is the suggestion score added per meter to the gate or berth
+	DistanceWeight float64
+
+	// OccupancyIndex keeps occupied cells in memory for suggestions
+	OccupancyIndex bool
+	// OccupancyCheckInterval is how often the index is compared with the database (0 disables)
+	OccupancyCheckInterval time.Duration
+
+	// WorkOrderConfirmation delays position changes until the operator confirms
+	// the job; when disabled the work order only records the change
+	WorkOrderConfirmation bool
+
+	// RequestTimeout limits single operations such as a suggestion or placement (0 disables)
+	RequestTimeout time.Duration
+	// BulkTimeout limits bulk suggestion and placement requests (0 disables)
+	BulkTimeout time.Duration
+	// BulkJobWorkers is how many bulk jobs run in the background at once
+	BulkJobWorkers int
+	// BulkJobResumeInterval is how often unfinished jobs are queued again, to
+	// take over the jobs of stopped instances (0 disables)
+	BulkJobResumeInterval time.Duration
+
+	// StrictJSON rejects request bodies with fields the endpoint does not know
+	StrictJSON bool
+
+	// IdempotencyTTL is how long the response of a request with an Idempotency-Key is replayed
+	IdempotencyTTL time.Duration
+
+	// AuthEnabled requires an API key or JWT bearer token on every endpoint except /health
+	AuthEnabled bool
+	// AllowInsecure lets the API start with authentication disabled, for local development only
+	AllowInsecure bool
+	// JWTJWKSFile is the local JWKS file with the HS256/RS256 keys that sign bearer tokens
+	JWTJWKSFile string
+	// JWTIssuer and JWTAudience, when set, must match the iss and aud claims
+	JWTIssuer   string
+	JWTAudience string
+
+	// CORSAllowedOrigins lists the origins browsers may call the API from ("*" allows any,
+	// empty allows none)
+	CORSAllowedOrigins []string
 }
 
 // LoadConfig loads configuration from environment variables
@@ -27,13 +91,56 @@ func LoadConfig() *Config {
 		log.Println("⚠️  .env file not found, using default or system environment variables")
 	}
 
+	storage := getEnv("STORAGE", "postgres")
+
 	return &Config{
+		Storage:    storage,
+		SQLitePath: getEnv("SQLITE_PATH", "yard_planning.db"),
+
+		// SQLite deployments are single-site, so they prepare their own database by default
+		AutoMigrate: getEnvBool("AUTO_MIGRATE", storage == "sqlite"),
+		SeedData:    getEnvBool("SEED_DATA", storage == "sqlite"),
+
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnv("DB_PORT", "5432"),
 		DBUser:     getEnv("DB_USER", "postgres"),
 		DBPassword: getEnv("DB_PASSWORD", "postgres"),
 		DBName:     getEnv("DB_NAME", "yard_planning"),
 		ServerPort: getEnv("SERVER_PORT", "8080"),
+
+		RedisHost:   getEnv("REDIS_HOST", "localhost"),
+		RedisPort:   getEnv("REDIS_PORT", "6379"),
+		RedisPass:   getEnv("REDIS_PASSWORD", ""),
+		RedisDB:     getEnvInt("REDIS_DB", 0),
+		EnableCache: getEnvBool("ENABLE_CACHE", false),
+
+		CacheMode:      getEnv("CACHE_MODE", "redis"),
+		CacheLocalSize: getEnvInt("CACHE_LOCAL_SIZE", 10000),
+		CacheLocalTTL:  getEnvDuration("CACHE_LOCAL_TTL", 30*time.Second),
+
+		WorkloadPenalty:       getEnvFloat("WORKLOAD_PENALTY", 1),
+		DistanceWeight:        getEnvFloat("DISTANCE_WEIGHT", 0.05),
+		WorkOrderConfirmation: getEnvBool("WORK_ORDER_CONFIRMATION", true),
+
+		OccupancyIndex:         getEnvBool("OCCUPANCY_INDEX", true),
+		OccupancyCheckInterval: getEnvDuration("OCCUPANCY_CHECK_INTERVAL", 5*time.Minute),
+
+		RequestTimeout:        getEnvDuration("REQUEST_TIMEOUT", 10*time.Second),
+		BulkTimeout:           getEnvDuration("BULK_TIMEOUT", 60*time.Second),
+		BulkJobWorkers:        getEnvInt("BULK_JOB_WORKERS", 2),
+		BulkJobResumeInterval: getEnvDuration("BULK_JOB_RESUME_INTERVAL", time.Minute),
+
+		StrictJSON: getEnvBool("STRICT_JSON", false),
+
+		IdempotencyTTL: getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+
+		AuthEnabled:   getEnvBool("AUTH_ENABLED", true),
+		AllowInsecure: getEnvBool("ALLOW_INSECURE", false),
+		JWTJWKSFile:   getEnv("JWT_JWKS_FILE", ""),
+		JWTIssuer:     getEnv("JWT_ISSUER", ""),
+		JWTAudience:   getEnv("JWT_AUDIENCE", ""),
+
+		CORSAllowedOrigins: getEnvList("CORS_ALLOWED_ORIGINS", nil),
 	}
 }
 
@@ -44,3 +151,50 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvList reads a comma separated list
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
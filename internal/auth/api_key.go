@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+)
+
+// APIKeyPrefix starts every generated API key, which tells keys apart from JWTs
+const APIKeyPrefix = "yk_"
+
+// prefixLength is how many characters of a key are stored to recognise it
+const prefixLength = len(APIKeyPrefix) + 6
+
+// NewAPIKey generates a random API key. The plain key is returned once and
+// must be handed to the client; only its hash is kept in the returned record.
+func NewAPIKey(name string, expiresAt *time.Time) (string, *model.APIKey, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("error generating api key: %w", err)
+	}
+
+	plain := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return plain, &model.APIKey{
+		Name:      name,
+		Prefix:    plain[:prefixLength],
+		Hash:      HashAPIKey(plain),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// HashAPIKey returns the hex encoded SHA-256 hash under which a key is stored.
+// Keys are long random strings, so a fast unsalted hash is sufficient.
+func HashAPIKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// isAPIKey reports whether a credential looks like an API key rather than a JWT
+func isAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, APIKeyPrefix)
+}
@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/repository/memory"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var hmacSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// signToken builds a compact JWT; sign receives the signing input
+func signToken(t *testing.T, header, claims map[string]interface{}, sign func([]byte) []byte) string {
+	h, err := json.Marshal(header)
+	require.NoError(t, err)
+	c, err := json.Marshal(claims)
+	require.NoError(t, err)
+	input := b64(h) + "." + b64(c)
+	return input + "." + b64(sign([]byte(input)))
+}
+
+func hs256(data []byte) []byte {
+	mac := hmac.New(sha256.New, hmacSecret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func newVerifier(t *testing.T, rsaKey *rsa.PublicKey) *JWTVerifier {
+	keys := []map[string]string{{"kty": "oct", "kid": "hmac-1", "k": b64(hmacSecret)}}
+	if rsaKey != nil {
+		keys = append(keys, map[string]string{
+			"kty": "RSA", "kid": "rsa-1", "alg": "RS256",
+			"n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+		})
+	}
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	require.NoError(t, err)
+
+	verifier, err := ParseJWKS(data, "https://idp.example.com", "yard-api")
+	require.NoError(t, err)
+	return verifier
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub": "planner-7",
+		"iss": "https://idp.example.com",
+		"aud": []string{"yard-api"},
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestJWTVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	verifier := newVerifier(t, &rsaKey.PublicKey)
+
+	rs256 := func(data []byte) []byte {
+		digest := sha256.Sum256(data)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+		return sig
+	}
+
+	t.Run("HS256", func(t *testing.T) {
+		token := signToken(t, map[string]interface{}{"alg": "HS256", "kid": "hmac-1"}, validClaims(), hs256)
+		claims, kid, err := verifier.Verify(token)
+		require.NoError(t, err)
+		assert.Equal(t, "planner-7", claims.Subject)
+		assert.Equal(t, "hmac-1", kid)
+	})
+
+	t.Run("RS256 without kid", func(t *testing.T) {
+		token := signToken(t, map[string]interface{}{"alg": "RS256"}, validClaims(), rs256)
+		_, kid, err := verifier.Verify(token)
+		require.NoError(t, err)
+		assert.Equal(t, "rsa-1", kid)
+	})
+
+	rejected := []struct {
+		name   string
+		header map[string]interface{}
+		claims func(map[string]interface{})
+		sign   func([]byte) []byte
+	}{
+		{"alg none", map[string]interface{}{"alg": "none"}, nil, func([]byte) []byte { return nil }},
+		{"wrong secret", map[string]interface{}{"alg": "HS256"}, nil, func(data []byte) []byte {
+			mac := hmac.New(sha256.New, []byte("another secret of thirty-two bytes"))
+			mac.Write(data)
+			return mac.Sum(nil)
+		}},
+		{"RS256 header with HMAC signature", map[string]interface{}{"alg": "RS256"}, nil, hs256},
+		{"unknown kid", map[string]interface{}{"alg": "HS256", "kid": "other"}, nil, hs256},
+		{"expired", map[string]interface{}{"alg": "HS256"}, func(c map[string]interface{}) {
+			c["exp"] = time.Now().Add(-time.Hour).Unix()
+		}, hs256},
+		{"not yet valid", map[string]interface{}{"alg": "HS256"}, func(c map[string]interface{}) {
+			c["nbf"] = time.Now().Add(time.Hour).Unix()
+		}, hs256},
+		{"no exp", map[string]interface{}{"alg": "HS256"}, func(c map[string]interface{}) { delete(c, "exp") }, hs256},
+		{"wrong issuer", map[string]interface{}{"alg": "HS256"}, func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" }, hs256},
+		{"wrong audience", map[string]interface{}{"alg": "HS256"}, func(c map[string]interface{}) { c["aud"] = "other-api" }, hs256},
+	}
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			if tt.claims != nil {
+				tt.claims(claims)
+			}
+			_, _, err := verifier.Verify(signToken(t, tt.header, claims, tt.sign))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseJWKS_RejectsWeakKeys(t *testing.T) {
+	_, err := ParseJWKS([]byte(`{"keys":[{"kty":"oct","k":"c2hvcnQ"}]}`), "", "")
+	assert.Error(t, err)
+
+	_, err = ParseJWKS([]byte(`{"keys":[{"kty":"EC","crv":"P-256"}]}`), "", "")
+	assert.Error(t, err, "a JWKS without usable keys is rejected")
+}
+
+func TestAuthenticator(t *testing.T) {
+	ctx := context.Background()
+	keys := memory.NewStore().Stores().APIKeys
+
+	plain, key, err := NewAPIKey("gate-system", nil)
+	require.NoError(t, err)
+	require.NoError(t, keys.Create(ctx, key))
+	assert.Equal(t, plain[:len(key.Prefix)], key.Prefix)
+	assert.NotContains(t, key.Hash, plain)
+
+	expiredPlain, expired, err := NewAPIKey("old-gate", nil)
+	require.NoError(t, err)
+	require.NoError(t, keys.Create(ctx, expired))
+	require.NoError(t, keys.Expire(ctx, expired.ID, time.Now().Add(-time.Minute)))
+
+	authenticator := NewAuthenticator(keys, newVerifier(t, nil))
+	token := signToken(t, map[string]interface{}{"alg": "HS256"}, validClaims(), hs256)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		subject string
+		method  string
+	}{
+		{"api key as bearer", map[string]string{"Authorization": "Bearer " + plain}, "gate-system", MethodAPIKey},
+		{"api key header", map[string]string{"X-API-Key": plain}, "gate-system", MethodAPIKey},
+		{"jwt", map[string]string{"Authorization": "Bearer " + token}, "planner-7", MethodJWT},
+		{"missing", nil, "", ""},
+		{"basic auth", map[string]string{"Authorization": "Basic dXNlcjpwYXNz"}, "", ""},
+		{"unknown api key", map[string]string{"X-API-Key": "yk_unknown"}, "", ""},
+		{"expired api key", map[string]string{"X-API-Key": expiredPlain}, "", ""},
+		{"malformed jwt", map[string]string{"Authorization": "Bearer not-a-token"}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/suggestion", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+
+			identity, err := authenticator.Authenticate(r)
+			if tt.subject == "" {
+				assert.Equal(t, apperror.CodeUnauthorized, apperror.CodeOf(err), "%v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.subject, identity.Subject)
+			assert.Equal(t, tt.method, identity.Method)
+		})
+	}
+
+	t.Run("tenant", func(t *testing.T) {
+		acmePlain, acmeKey, err := NewAPIKey("acme-gate", nil)
+		require.NoError(t, err)
+		acmeKey.Tenant = "acme"
+		require.NoError(t, keys.Create(ctx, acmeKey))
+
+		claims := validClaims()
+		claims["tenant"] = "acme"
+		acmeToken := signToken(t, map[string]interface{}{"alg": "HS256"}, claims, hs256)
+
+		for credential, want := range map[string]string{plain: tenant.Default, token: tenant.Default, acmePlain: "acme", acmeToken: "acme"} {
+			r := httptest.NewRequest("GET", "/suggestion", nil)
+			r.Header.Set("Authorization", "Bearer "+credential)
+			identity, err := authenticator.Authenticate(r)
+			require.NoError(t, err)
+			assert.Equal(t, want, identity.Tenant, identity.Subject)
+		}
+	})
+
+	t.Run("jwt disabled", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/suggestion", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		_, err := NewAuthenticator(keys, nil).Authenticate(r)
+		assert.Equal(t, apperror.CodeUnauthorized, apperror.CodeOf(err))
+	})
+}
+
+func TestActor(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "anonymous", Actor(ctx))
+
+	ctx = WithIdentity(ctx, Identity{Subject: "gate-system", Method: MethodAPIKey, KeyID: "1"})
+	assert.Equal(t, fmt.Sprintf("%s:%s", MethodAPIKey, "gate-system"), Actor(ctx))
+}
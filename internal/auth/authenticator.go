@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+// Authenticator identifies the caller of a request. API keys are sent as
+// "Authorization: Bearer yk_..." or in the X-API-Key header, JWTs as
+// "Authorization: Bearer <token>".
+type Authenticator struct {
+	keys repository.APIKeyStore
+	jwt  *JWTVerifier
+	now  func() time.Time
+}
+
+// NewAuthenticator creates an authenticator. Either source may be nil to
+// disable that method.
+func NewAuthenticator(keys repository.APIKeyStore, jwt *JWTVerifier) *Authenticator {
+	return &Authenticator{keys: keys, jwt: jwt, now: time.Now}
+}
+
+// Authenticate returns the identity of the request or an UNAUTHORIZED error
+func (a *Authenticator) Authenticate(r *http.Request) (Identity, error) {
+	credential := r.Header.Get("X-API-Key")
+	if credential == "" {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			return Identity{}, unauthorized("missing credentials")
+		}
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			return Identity{}, unauthorized("Authorization header must be 'Bearer <token>'")
+		}
+		credential = strings.TrimSpace(token)
+	}
+
+	if isAPIKey(credential) {
+		return a.authenticateAPIKey(r, credential)
+	}
+	return a.authenticateJWT(credential)
+}
+
+func (a *Authenticator) authenticateAPIKey(r *http.Request, plain string) (Identity, error) {
+	if a.keys == nil {
+		return Identity{}, unauthorized("API keys are not accepted")
+	}
+
+	key, err := a.keys.GetByHash(r.Context(), HashAPIKey(plain))
+	if err != nil {
+		if apperror.CodeOf(err) == apperror.CodeNotFound {
+			return Identity{}, unauthorized("invalid API key")
+		}
+		return Identity{}, err
+	}
+	if !key.IsActive(a.now()) {
+		return Identity{}, unauthorized("API key has expired")
+	}
+
+	return Identity{Subject: key.Name, Method: MethodAPIKey, KeyID: strconv.Itoa(key.ID), Tenant: key.Tenant}, nil
+}
+
+func (a *Authenticator) authenticateJWT(token string) (Identity, error) {
+	if a.jwt == nil {
+		return Identity{}, unauthorized("bearer tokens are not accepted")
+	}
+
+	claims, kid, err := a.jwt.Verify(token)
+	if err != nil {
+		return Identity{}, apperror.Wrap(apperror.CodeUnauthorized, err, "invalid bearer token")
+	}
+
+	// Tokens without a tenant claim act for the default tenant
+	t := claims.Tenant
+	if t == "" {
+		t = tenant.Default
+	}
+
+	return Identity{Subject: claims.Subject, Method: MethodJWT, KeyID: kid, Tenant: t}, nil
+}
+
+func unauthorized(message string) *apperror.Error {
+	return apperror.New(apperror.CodeUnauthorized, "%s", message)
+}
@@ -0,0 +1,47 @@
+// Package auth authenticates API requests with static API keys or JWT bearer
+// tokens and carries the resulting identity in the request context.
+package auth
+
+import "context"
+
+// Authentication methods
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Identity is the authenticated caller of a request
+type Identity struct {
+	// Subject names the caller: the key name for API keys, the sub claim for JWTs
+	Subject string
+	// Method is MethodAPIKey or MethodJWT
+	Method string
+	// KeyID is the API key id or the kid of the JWT signing key
+	KeyID string
+	// Tenant is the terminal operator the caller acts for: the tenant of the
+	// API key or the tenant claim of the JWT
+	Tenant string
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx that carries the identity
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the identity attached to ctx
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
+
+// Actor returns the caller to record in audit and event records, e.g.
+// "api_key:gate-system", or "anonymous" when authentication is disabled
+func Actor(ctx context.Context) string {
+	id, ok := FromContext(ctx)
+	if !ok {
+		return "anonymous"
+	}
+	return id.Method + ":" + id.Subject
+}
@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+// Supported JWT signing algorithms
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+)
+
+// clockSkew is the tolerance applied to the exp and nbf claims
+const clockSkew = 30 * time.Second
+
+// jwk is a key of a JSON Web Key Set. Only symmetric (oct) and RSA keys are
+// read; other key types are ignored.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// verificationKey is a parsed JWKS key with the algorithm it verifies
+type verificationKey struct {
+	kid    string
+	alg    string
+	secret []byte
+	public *rsa.PublicKey
+}
+
+// JWTVerifier checks HS256 and RS256 bearer tokens against the keys of a
+// local JWKS file
+type JWTVerifier struct {
+	keys     []verificationKey
+	issuer   string
+	audience string
+	now      func() time.Time
+}
+
+// LoadJWKS reads a JWKS file. Tokens must be issued by issuer and addressed
+// to audience unless those are empty.
+func LoadJWKS(path, issuer, audience string) (*JWTVerifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading JWKS file: %w", err)
+	}
+	return ParseJWKS(data, issuer, audience)
+}
+
+// ParseJWKS parses a JWKS document, see LoadJWKS
+func ParseJWKS(data []byte, issuer, audience string) (*JWTVerifier, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("error parsing JWKS: %w", err)
+	}
+
+	v := &JWTVerifier{issuer: issuer, audience: audience, now: time.Now}
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := parseJWK(k)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing JWKS key %d: %w", i, err)
+		}
+		if key != nil {
+			v.keys = append(v.keys, *key)
+		}
+	}
+	if len(v.keys) == 0 {
+		return nil, fmt.Errorf("JWKS contains no HS256 or RS256 signing keys")
+	}
+
+	return v, nil
+}
+
+func parseJWK(k jwk) (*verificationKey, error) {
+	switch k.Kty {
+	case "oct":
+		if k.Alg != "" && k.Alg != AlgHS256 {
+			return nil, fmt.Errorf("unsupported algorithm %q for oct key", k.Alg)
+		}
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil || len(secret) < 32 {
+			return nil, fmt.Errorf("oct key must be a base64url secret of at least 32 bytes")
+		}
+		return &verificationKey{kid: k.Kid, alg: AlgHS256, secret: secret}, nil
+	case "RSA":
+		if k.Alg != "" && k.Alg != AlgRS256 {
+			return nil, fmt.Errorf("unsupported algorithm %q for RSA key", k.Alg)
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		public := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if public.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA key must have at least 2048 bits")
+		}
+		return &verificationKey{kid: k.Kid, alg: AlgRS256, public: public}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// Claims are the registered JWT claims the API uses, plus the private
+// tenant claim naming the terminal operator of the caller
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+	IssuedAt  int64    `json:"iat"`
+	Tenant    string   `json:"tenant"`
+}
+
+// audience accepts the aud claim as a single string or an array
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("aud must be a string or an array of strings")
+	}
+	*a = list
+	return nil
+}
+
+// Verify checks the signature and claims of a compact JWT and returns its
+// claims and the kid of the key that signed it
+func (v *JWTVerifier) Verify(token string) (*Claims, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, "", errors.New("token is not a compact JWT")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+		Typ string `json:"typ"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, "", fmt.Errorf("invalid token header: %w", err)
+	}
+	if header.Alg != AlgHS256 && header.Alg != AlgRS256 {
+		return nil, "", fmt.Errorf("unsupported token algorithm %q", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, "", errors.New("invalid token signature encoding")
+	}
+
+	// Only keys of the algorithm named in the header are tried, so an RSA
+	// public key can never be used as an HMAC secret
+	signed := []byte(parts[0] + "." + parts[1])
+	kid, matched := "", false
+	for _, key := range v.keys {
+		if key.alg != header.Alg || (header.Kid != "" && key.kid != header.Kid) {
+			continue
+		}
+		if key.verify(signed, signature) {
+			kid, matched = key.kid, true
+			break
+		}
+	}
+	if !matched {
+		return nil, "", errors.New("token signature is not valid for any known key")
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, "", fmt.Errorf("invalid token claims: %w", err)
+	}
+	if err := v.checkClaims(&claims); err != nil {
+		return nil, "", err
+	}
+
+	return &claims, kid, nil
+}
+
+func (v *JWTVerifier) checkClaims(c *Claims) error {
+	now := v.now()
+	if c.ExpiresAt == 0 {
+		return errors.New("token has no exp claim")
+	}
+	if now.After(time.Unix(c.ExpiresAt, 0).Add(clockSkew)) {
+		return errors.New("token has expired")
+	}
+	if c.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(c.NotBefore, 0)) {
+		return errors.New("token is not valid yet")
+	}
+	if c.Subject == "" {
+		return errors.New("token has no sub claim")
+	}
+	if v.issuer != "" && c.Issuer != v.issuer {
+		return fmt.Errorf("token issuer %q is not accepted", c.Issuer)
+	}
+	if v.audience != "" {
+		for _, aud := range c.Audience {
+			if aud == v.audience {
+				return nil
+			}
+		}
+		return errors.New("token is not addressed to this API")
+	}
+	return nil
+}
+
+func (k verificationKey) verify(signed, signature []byte) bool {
+	switch k.alg {
+	case AlgHS256:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(signed)
+		return hmac.Equal(mac.Sum(nil), signature)
+	case AlgRS256:
+		digest := sha256.Sum256(signed)
+		return rsa.VerifyPKCS1v15(k.public, crypto.SHA256, digest[:], signature) == nil
+	default:
+		return false
+	}
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
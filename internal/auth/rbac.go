@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"sort"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+// Permission is an action a role allows
+type Permission string
+
+const (
+	// PermView reads yard data: layout, closures, equipment, jobs and overflow rules
+	PermView Permission = "view"
+	// PermSuggest asks for container positions
+	PermSuggest Permission = "suggest"
+	// PermPlace places containers
+	PermPlace Permission = "place"
+	// PermPickup removes containers from the yard
+	PermPickup Permission = "pickup"
+	// PermMove relocates stored containers (forced moves, rehandles)
+	PermMove Permission = "move"
+	// PermOverrideHold releases container holds and picks up held containers
+	PermOverrideHold Permission = "override_hold"
+	// PermPlan manages closures, overflow rules, equipment and points of
+	// interest, and puts containers on hold
+	PermPlan Permission = "plan"
+	// PermOperate works through the equipment job list
+	PermOperate Permission = "operate"
+	// PermAdmin manages role bindings; it is only granted for all yards
+	PermAdmin Permission = "admin"
+)
+
+// rolePermissions lists the permissions of every role
+var rolePermissions = map[string][]Permission{
+	model.RoleGateClerk:         {PermView, PermSuggest, PermPlace},
+	model.RoleYardPlanner:       {PermView, PermSuggest, PermPlace, PermPickup, PermPlan},
+	model.RoleEquipmentOperator: {PermView, PermOperate},
+	model.RoleSupervisor:        {PermView, PermSuggest, PermPlace, PermPickup, PermMove, PermOverrideHold, PermPlan, PermOperate},
+	model.RoleAdmin:             {PermView, PermSuggest, PermPlace, PermPickup, PermMove, PermOverrideHold, PermPlan, PermOperate, PermAdmin},
+}
+
+// RolePermissions returns the permissions of a role
+func RolePermissions(role string) []Permission {
+	return rolePermissions[role]
+}
+
+// Grants are the permissions of a caller per yard code. Permissions granted
+// for model.AllYards apply to every yard.
+type Grants struct {
+	subject string
+	yards   map[string]map[Permission]bool
+}
+
+// NewGrants collects the permissions of a caller's role bindings
+func NewGrants(subject string, bindings []model.RoleBinding) *Grants {
+	g := &Grants{subject: subject, yards: make(map[string]map[Permission]bool)}
+	for _, b := range bindings {
+		yard := b.Yard
+		if yard == "" {
+			yard = model.AllYards
+		}
+		if g.yards[yard] == nil {
+			g.yards[yard] = make(map[Permission]bool)
+		}
+		for _, p := range rolePermissions[b.Role] {
+			// Administration is global, a yard binding cannot grant it
+			if p == PermAdmin && yard != model.AllYards {
+				continue
+			}
+			g.yards[yard][p] = true
+		}
+	}
+	return g
+}
+
+// Allows reports whether the permission is granted in the yard
+func (g *Grants) Allows(perm Permission, yard string) bool {
+	return g.yards[model.AllYards][perm] || (yard != model.AllYards && g.yards[yard][perm])
+}
+
+// AllowsAnywhere reports whether the permission is granted in at least one yard
+func (g *Grants) AllowsAnywhere(perm Permission) bool {
+	for _, perms := range g.yards {
+		if perms[perm] {
+			return true
+		}
+	}
+	return false
+}
+
+// Yards returns the yard codes the permission is granted in, or
+// [model.AllYards] when it is granted everywhere
+func (g *Grants) Yards(perm Permission) []string {
+	if g.yards[model.AllYards][perm] {
+		return []string{model.AllYards}
+	}
+	var yards []string
+	for yard, perms := range g.yards {
+		if perms[perm] {
+			yards = append(yards, yard)
+		}
+	}
+	sort.Strings(yards)
+	return yards
+}
+
+type grantsKey struct{}
+
+// WithGrants returns a copy of ctx that carries the caller's permissions
+func WithGrants(ctx context.Context, g *Grants) context.Context {
+	return context.WithValue(ctx, grantsKey{}, g)
+}
+
+// GrantsFromContext returns the permissions attached to ctx
+func GrantsFromContext(ctx context.Context) (*Grants, bool) {
+	g, ok := ctx.Value(grantsKey{}).(*Grants)
+	return g, ok
+}
+
+// Allowed reports whether the caller of ctx has the permission in the yard.
+// Without grants in ctx, i.e. when authorization is disabled or for internal
+// work, everything is allowed.
+func Allowed(ctx context.Context, perm Permission, yard string) bool {
+	g, ok := GrantsFromContext(ctx)
+	return !ok || g.Allows(perm, yard)
+}
+
+// Authorize returns a FORBIDDEN error unless the caller of ctx has the
+// permission in the yard
+func Authorize(ctx context.Context, perm Permission, yard string) error {
+	if Allowed(ctx, perm, yard) {
+		return nil
+	}
+	g, _ := GrantsFromContext(ctx)
+	if yard == model.AllYards {
+		return apperror.New(apperror.CodeForbidden, "%s may not %s", g.subject, perm)
+	}
+	return apperror.New(apperror.CodeForbidden, "%s may not %s in yard %s", g.subject, perm, yard)
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrants(t *testing.T) {
+	grants := NewGrants("api_key:gate-system", []model.RoleBinding{
+		{Role: model.RoleGateClerk, Yard: "YRD1"},
+		{Role: model.RoleYardPlanner, Yard: "DEPOT1"},
+	})
+
+	tests := []struct {
+		perm  Permission
+		yard  string
+		allow bool
+	}{
+		{PermSuggest, "YRD1", true},
+		{PermPlace, "YRD1", true},
+		{PermPickup, "YRD1", false},
+		{PermMove, "YRD1", false},
+		{PermPlan, "YRD1", false},
+		{PermPickup, "DEPOT1", true},
+		{PermPlan, "DEPOT1", true},
+		{PermMove, "DEPOT1", false},
+		{PermSuggest, "OTHER", false},
+		{PermAdmin, model.AllYards, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.allow, grants.Allows(tt.perm, tt.yard), "%s in %s", tt.perm, tt.yard)
+	}
+
+	assert.True(t, grants.AllowsAnywhere(PermPlan))
+	assert.False(t, grants.AllowsAnywhere(PermOperate))
+	assert.Equal(t, []string{"DEPOT1", "YRD1"}, grants.Yards(PermSuggest))
+}
+
+func TestGrants_AdminIsGlobal(t *testing.T) {
+	// ADMIN bound to a single yard grants its yard permissions only
+	grants := NewGrants("jwt:ops", []model.RoleBinding{{Role: model.RoleAdmin, Yard: "DEPOT1"}})
+	assert.True(t, grants.Allows(PermMove, "DEPOT1"))
+	assert.False(t, grants.AllowsAnywhere(PermAdmin))
+
+	grants = NewGrants("jwt:ops", []model.RoleBinding{{Role: model.RoleAdmin, Yard: model.AllYards}})
+	assert.True(t, grants.Allows(PermAdmin, model.AllYards))
+}
+
+func TestGrants_AllYards(t *testing.T) {
+	grants := NewGrants("jwt:supervisor", []model.RoleBinding{{Role: model.RoleSupervisor, Yard: model.AllYards}})
+
+	assert.True(t, grants.Allows(PermMove, "YRD1"))
+	assert.True(t, grants.Allows(PermMove, "ANY"))
+	assert.False(t, grants.Allows(PermAdmin, model.AllYards))
+	assert.Equal(t, []string{model.AllYards}, grants.Yards(PermMove))
+}
+
+func TestAuthorize(t *testing.T) {
+	ctx := context.Background()
+	assert.NoError(t, Authorize(ctx, PermMove, "YRD1"), "authorization disabled")
+
+	ctx = WithGrants(ctx, NewGrants("api_key:gate-system", []model.RoleBinding{{Role: model.RoleGateClerk, Yard: "YRD1"}}))
+	assert.NoError(t, Authorize(ctx, PermPlace, "YRD1"))
+
+	err := Authorize(ctx, PermMove, "YRD1")
+	assert.Equal(t, apperror.CodeForbidden, apperror.CodeOf(err))
+	assert.Contains(t, err.Error(), "api_key:gate-system may not move in yard YRD1")
+}
+
+func TestRolePermissions_SupervisorOnly(t *testing.T) {
+	for _, perm := range []Permission{PermMove, PermOverrideHold} {
+		for _, role := range []string{model.RoleGateClerk, model.RoleYardPlanner, model.RoleEquipmentOperator} {
+			grants := NewGrants("jwt:"+role, []model.RoleBinding{{Role: role, Yard: model.AllYards}})
+			assert.False(t, grants.AllowsAnywhere(perm), "%s may %s", role, perm)
+		}
+		for _, role := range []string{model.RoleSupervisor, model.RoleAdmin} {
+			grants := NewGrants("jwt:"+role, []model.RoleBinding{{Role: role, Yard: "YRD1"}})
+			assert.True(t, grants.Allows(perm, "YRD1"), "%s may not %s", role, perm)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/service"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/dwipurnomo515/yard-planning/pkg/response"
+)
+
+type AdminHandler struct {
+	service *service.RoleBindingService
+}
+
+func NewAdminHandler(service *service.RoleBindingService) *AdminHandler {
+	return &AdminHandler{service: service}
+}
+
+// HandleRoleBindings handles GET /admin/role-bindings[?subject=...],
+// POST /admin/role-bindings and DELETE /admin/role-bindings?id=...
+func (h *AdminHandler) HandleRoleBindings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	switch r.Method {
+	case http.MethodGet:
+		bindings, err := h.service.ListBindings(ctx, r.URL.Query().Get("subject"))
+		if err != nil {
+			response.Fail(w, err)
+			return
+		}
+
+		response.Success(w, bindings)
+
+	case http.MethodPost:
+		var req model.RoleBindingRequest
+		if err := decode(r, &req); err != nil {
+			response.Fail(w, err)
+			return
+		}
+
+		binding, err := h.service.CreateBinding(ctx, req)
+		if err != nil {
+			response.Fail(w, err)
+			return
+		}
+
+		response.Created(w, binding)
+
+	case http.MethodDelete:
+		idParam := r.URL.Query().Get("id")
+		if err := requireQuery("id", idParam); err != nil {
+			response.Fail(w, err)
+			return
+		}
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			response.Fail(w, apperror.Validation("id", "must be a number"))
+			return
+		}
+
+		if err := h.service.DeleteBinding(ctx, id); err != nil {
+			response.Fail(w, err)
+			return
+		}
+
+		response.Success(w, map[string]interface{}{"deleted": id})
+
+	default:
+		response.Fail(w, methodNotAllowed(r))
+	}
+}
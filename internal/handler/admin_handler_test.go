@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/dwipurnomo515/yard-planning/internal/auth"
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository/memory"
+	"github.com/dwipurnomo515/yard-planning/internal/service"
+	"github.com/dwipurnomo515/yard-planning/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHandler_RoleBindings(t *testing.T) {
+	store := memory.NewStore()
+	require.NoError(t, store.Seed())
+	stores := store.Stores()
+	h := NewAdminHandler(service.NewRoleBindingService(stores.Yards, stores.Roles))
+
+	admin := []model.RoleBinding{{Role: model.RoleAdmin, Yard: model.AllYards}}
+	planner := []model.RoleBinding{{Role: model.RoleYardPlanner, Yard: "YRD1"}}
+
+	req := model.RoleBindingRequest{Subject: "api_key:gate-system", Role: model.RoleGateClerk, Yard: "YRD1"}
+	assert.Equal(t, http.StatusForbidden, doJSONAs(t, planner, h.HandleRoleBindings, req, nil))
+
+	var binding model.RoleBinding
+	require.Equal(t, http.StatusCreated, doJSONAs(t, admin, h.HandleRoleBindings, req, &binding))
+	assert.Equal(t, "YRD1", binding.Yard)
+	assert.Equal(t, http.StatusConflict, doJSONAs(t, admin, h.HandleRoleBindings, req, nil))
+
+	var errResp response.ErrorResponse
+	bad := model.RoleBindingRequest{Subject: "jwt:ops", Role: model.RoleAdmin, Yard: "YRD1"}
+	assert.Equal(t, http.StatusUnprocessableEntity, doJSONAs(t, admin, h.HandleRoleBindings, bad, &errResp))
+	assert.Equal(t, "yard", errResp.Details[0].Field)
+
+	unknownYard := model.RoleBindingRequest{Subject: "jwt:ops", Role: model.RoleSupervisor, Yard: "NOPE"}
+	assert.Equal(t, http.StatusNotFound, doJSONAs(t, admin, h.HandleRoleBindings, unknownYard, nil))
+
+	// Bindings without a yard apply to all yards
+	global := model.RoleBindingRequest{Subject: "jwt:ops", Role: model.RoleSupervisor}
+	require.Equal(t, http.StatusCreated, doJSONAs(t, admin, h.HandleRoleBindings, global, &binding))
+	assert.Equal(t, model.AllYards, binding.Yard)
+
+	adminRequest := func(method, target string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, nil)
+		r = r.WithContext(auth.WithGrants(r.Context(), auth.NewGrants("jwt:root", admin)))
+		rec := httptest.NewRecorder()
+		h.HandleRoleBindings(rec, r)
+		return rec
+	}
+
+	rec := adminRequest(http.MethodGet, "/admin/role-bindings?subject=jwt:ops")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"role":"SUPERVISOR"`)
+	assert.NotContains(t, rec.Body.String(), "gate-system")
+
+	rec = adminRequest(http.MethodDelete, "/admin/role-bindings?id="+strconv.Itoa(binding.ID))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = adminRequest(http.MethodDelete, "/admin/role-bindings?id="+strconv.Itoa(binding.ID))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = adminRequest(http.MethodDelete, "/admin/role-bindings")
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	bindings, err := stores.Roles.GetAll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, bindings, 1)
+	assert.Equal(t, "api_key:gate-system", bindings[0].Subject)
+}
@@ -1,22 +1,22 @@
 package handler
 
 import (
-	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"sync"
 
 	"github.com/dwipurnomo515/yard-planning/internal/model"
 	"github.com/dwipurnomo515/yard-planning/internal/service"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
 	"github.com/dwipurnomo515/yard-planning/pkg/response"
-	"github.com/dwipurnomo515/yard-planning/pkg/worker"
 )
 
 type BulkHandler struct {
-	service *service.ContainerService
+	service service.ContainerOperations
 }
 
-func NewBulkHandler(service *service.ContainerService) *BulkHandler {
+func NewBulkHandler(service service.ContainerOperations) *BulkHandler {
 	return &BulkHandler{service: service}
 }
 
@@ -25,6 +25,13 @@ type BulkSuggestionRequest struct {
 	Containers []model.SuggestionRequest `json:"containers"`
 }
 
+func (r BulkSuggestionRequest) Validate(v *model.Validator) {
+	v.Check(len(r.Containers) > 0, "containers", "must not be empty")
+	for i, c := range r.Containers {
+		c.Validate(v.At(fmt.Sprintf("containers[%d]", i)))
+	}
+}
+
 // BulkSuggestionResponse represents bulk suggestion response
 type BulkSuggestionResponse struct {
 	Results []SuggestionResult `json:"results"`
@@ -33,66 +40,48 @@ type BulkSuggestionResponse struct {
 type SuggestionResult struct {
 	ContainerNumber   string          `json:"container_number"`
 	SuggestedPosition *model.Position `json:"suggested_position,omitempty"`
+	Yard              string          `json:"yard,omitempty"`
+	Overflow          bool            `json:"overflow,omitempty"`
+	Reason            string          `json:"reason,omitempty"`
 	Error             string          `json:"error,omitempty"`
+	Code              apperror.Code   `json:"code,omitempty"`
 }
 
-// HandleBulkSuggestion handles bulk suggestion requests concurrently
+// HandleBulkSuggestion plans the positions of a batch of containers together.
+// Results are in the order of the request.
 func (h *BulkHandler) HandleBulkSuggestion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	if r.Method != http.MethodPost {
-		response.Error(w, http.StatusMethodNotAllowed, http.ErrNotSupported)
+		response.Fail(w, methodNotAllowed(r))
 		return
 	}
 
 	var req BulkSuggestionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Error(w, http.StatusBadRequest, err)
+	if err := decode(r, &req); err != nil {
+		response.Fail(w, err)
 		return
 	}
 
-	if len(req.Containers) == 0 {
-		response.Error(w, http.StatusBadRequest, http.ErrMissingBoundary)
+	// The batch is planned together, so containers never share a cell
+	planned, err := h.service.SuggestBatch(ctx, req.Containers)
+	if err != nil {
+		response.Fail(w, err)
 		return
 	}
 
-	// Use worker pool for concurrent processing
-	pool := worker.NewPool(5, func(ctx context.Context, job worker.Job) (interface{}, error) {
-		suggReq := job.Payload.(model.SuggestionRequest)
-		position, err := h.service.GetSuggestion(suggReq)
-		if err != nil {
-			return nil, err
-		}
-		return position, nil
-	})
-
-	pool.Start()
-
-	// Submit jobs
-	go func() {
-		for _, container := range req.Containers {
-			pool.Submit(worker.Job{
-				ID:      container.ContainerNumber,
-				Payload: container,
-			})
-		}
-		pool.Stop()
-	}()
-
-	// Collect results
-	results := make([]SuggestionResult, 0, len(req.Containers))
-	for result := range pool.Results() {
-		suggReq := result.Job.Payload.(model.SuggestionRequest)
+	results := make([]SuggestionResult, len(req.Containers))
+	for i, result := range planned {
+		results[i] = SuggestionResult{ContainerNumber: req.Containers[i].ContainerNumber}
 		if result.Err != nil {
-			results = append(results, SuggestionResult{
-				ContainerNumber: suggReq.ContainerNumber,
-				Error:           result.Err.Error(),
-			})
-		} else {
-			position := result.Value.(*model.Position)
-			results = append(results, SuggestionResult{
-				ContainerNumber:   suggReq.ContainerNumber,
-				SuggestedPosition: position,
-			})
+			results[i].Error = result.Err.Error()
+			results[i].Code = apperror.CodeOf(result.Err)
+			continue
 		}
+		suggestion := result.Suggestion
+		results[i].SuggestedPosition = &suggestion.Position
+		results[i].Yard = suggestion.Yard
+		results[i].Overflow = suggestion.Overflow
+		results[i].Reason = suggestion.Reason
 	}
 
 	resp := BulkSuggestionResponse{Results: results}
@@ -102,76 +91,134 @@ func (h *BulkHandler) HandleBulkSuggestion(w http.ResponseWriter, r *http.Reques
 // BulkPlacementRequest represents bulk placement request
 type BulkPlacementRequest struct {
 	Containers []model.PlacementRequest `json:"containers"`
+	// Atomic places all containers or none of them
+	Atomic bool `json:"atomic,omitempty"`
+}
+
+func (r BulkPlacementRequest) Validate(v *model.Validator) {
+	v.Check(len(r.Containers) > 0, "containers", "must not be empty")
+	for i, c := range r.Containers {
+		c.Validate(v.At(fmt.Sprintf("containers[%d]", i)))
+	}
 }
 
 type PlacementResult struct {
-	ContainerNumber string `json:"container_number"`
-	Success         bool   `json:"success"`
-	Error           string `json:"error,omitempty"`
+	ContainerNumber string        `json:"container_number"`
+	Success         bool          `json:"success"`
+	Error           string        `json:"error,omitempty"`
+	Code            apperror.Code `json:"code,omitempty"`
+	// ConflictsWith lists the items of an atomic batch this item clashes with
+	ConflictsWith []int `json:"conflicts_with,omitempty"`
+	// RolledBack marks an item of an atomic batch that was not placed because
+	// of other items
+	RolledBack bool `json:"rolled_back,omitempty"`
 }
 
 type BulkPlacementResponse struct {
-	Results []PlacementResult `json:"results"`
+	Results    []PlacementResult `json:"results"`
+	RolledBack bool              `json:"rolled_back,omitempty"`
+	Error      string            `json:"error,omitempty"`
 }
 
-// HandleBulkPlacement handles bulk placement with concurrent execution
+// HandleBulkPlacement handles bulk placement with concurrent execution of
+// independent stacks. Results are in the order of the request.
 func (h *BulkHandler) HandleBulkPlacement(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	if r.Method != http.MethodPost {
-		response.Error(w, http.StatusMethodNotAllowed, http.ErrNotSupported)
+		response.Fail(w, methodNotAllowed(r))
 		return
 	}
 
 	var req BulkPlacementRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Error(w, http.StatusBadRequest, err)
+	if err := decode(r, &req); err != nil {
+		response.Fail(w, err)
 		return
 	}
 
-	if len(req.Containers) == 0 {
-		response.Error(w, http.StatusBadRequest, http.ErrMissingBoundary)
+	if req.Atomic {
+		h.placeAtomic(w, r, req.Containers)
 		return
 	}
 
-	// Use goroutines with mutex for concurrent placement
-	var (
-		wg      sync.WaitGroup
-		mu      sync.Mutex
-		results []PlacementResult
-	)
+	// Items stacked on each other are placed bottom up, one after another.
+	// Independent stacks are placed concurrently.
+	var wg sync.WaitGroup
+	results := make([]PlacementResult, len(req.Containers))
 
 	// Limit concurrency to avoid overwhelming the database
 	semaphore := make(chan struct{}, 10)
 
-	for _, container := range req.Containers {
+	for _, stack := range service.PlacementStacks(req.Containers) {
 		wg.Add(1)
-		go func(c model.PlacementRequest) {
+		go func(stack []int) {
 			defer wg.Done()
 
-			// Acquire semaphore
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-
-			err := h.service.PlaceContainer(c)
-
-			mu.Lock()
-			if err != nil {
-				results = append(results, PlacementResult{
-					ContainerNumber: c.ContainerNumber,
-					Success:         false,
-					Error:           err.Error(),
-				})
-			} else {
-				results = append(results, PlacementResult{
-					ContainerNumber: c.ContainerNumber,
-					Success:         true,
-				})
+			// Acquire semaphore, unless the request is gone before it is our turn
+			var err error
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+
+			for _, i := range stack {
+				c := req.Containers[i]
+				placeErr := err
+				if placeErr == nil {
+					if placeErr = ctx.Err(); placeErr == nil {
+						placeErr = h.service.PlaceContainer(ctx, c)
+					}
+				}
+
+				// Every goroutine writes only the results of its own stack
+				results[i] = PlacementResult{ContainerNumber: c.ContainerNumber, Success: placeErr == nil}
+				if placeErr != nil {
+					results[i].Error = placeErr.Error()
+					results[i].Code = apperror.CodeOf(placeErr)
+				}
 			}
-			mu.Unlock()
-		}(container)
+		}(stack)
 	}
 
 	wg.Wait()
 
+	// Containers placed before the request was cancelled stay placed, so the
+	// results are still returned
 	resp := BulkPlacementResponse{Results: results}
 	response.Success(w, resp)
 }
+
+// placeAtomic places a batch in one transaction. When it is rolled back the
+// results tell which items caused it and which were only rolled back.
+func (h *BulkHandler) placeAtomic(w http.ResponseWriter, r *http.Request, containers []model.PlacementRequest) {
+	err := h.service.PlaceBatch(r.Context(), containers)
+
+	var batchErr *service.BatchError
+	if err != nil && !errors.As(err, &batchErr) {
+		response.Fail(w, err)
+		return
+	}
+
+	results := make([]PlacementResult, len(containers))
+	for i, c := range containers {
+		results[i] = PlacementResult{ContainerNumber: c.ContainerNumber, Success: err == nil, RolledBack: err != nil}
+	}
+	if err == nil {
+		response.Success(w, BulkPlacementResponse{Results: results})
+		return
+	}
+
+	for _, item := range batchErr.Items {
+		result := &results[item.Index]
+		result.RolledBack = false
+		result.Error = item.Err.Error()
+		result.Code = apperror.CodeOf(item.Err)
+		result.ConflictsWith = item.ConflictsWith
+	}
+	response.JSON(w, http.StatusConflict, BulkPlacementResponse{
+		Results:    results,
+		RolledBack: true,
+		Error:      batchErr.Error(),
+	})
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/service"
+	"github.com/dwipurnomo515/yard-planning/pkg/response"
+)
+
+// BulkJobHandler serves bulk operations that run in the background. Clients
+// poll a job for its progress and the results of the items processed so far.
+type BulkJobHandler struct {
+	service *service.BulkJobService
+}
+
+func NewBulkJobHandler(service *service.BulkJobService) *BulkJobHandler {
+	return &BulkJobHandler{service: service}
+}
+
+// HandleBulkJobs handles POST /bulk/jobs, GET /bulk/jobs?id=... and
+// DELETE /bulk/jobs?id=...
+func (h *BulkJobHandler) HandleBulkJobs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	switch r.Method {
+	case http.MethodPost:
+		var req model.BulkJobRequest
+		if err := decode(r, &req); err != nil {
+			response.Fail(w, err)
+			return
+		}
+
+		job, err := h.service.Create(ctx, req)
+		if err != nil {
+			response.Fail(w, err)
+			return
+		}
+
+		response.Accepted(w, job)
+
+	case http.MethodGet:
+		id, err := queryID(r)
+		if err != nil {
+			response.Fail(w, err)
+			return
+		}
+
+		job, err := h.service.Get(ctx, id)
+		if err != nil {
+			response.Fail(w, err)
+			return
+		}
+
+		response.Success(w, job)
+
+	case http.MethodDelete:
+		id, err := queryID(r)
+		if err != nil {
+			response.Fail(w, err)
+			return
+		}
+
+		job, err := h.service.Cancel(ctx, id)
+		if err != nil {
+			response.Fail(w, err)
+			return
+		}
+
+		response.Success(w, job)
+
+	default:
+		response.Fail(w, methodNotAllowed(r))
+	}
+}
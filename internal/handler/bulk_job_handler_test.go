@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/service"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBulkJobs(t *testing.T) (*BulkJobHandler, *service.BulkJobService, *service.ContainerService, repository.Stores) {
+	containerService, stores := newTestService(t)
+	jobs := service.NewBulkJobService(stores.BulkJobs, stores.Roles, containerService)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	results := jobs.Start(ctx, 2)
+	go func() {
+		for range results {
+		}
+	}()
+
+	return NewBulkJobHandler(jobs), jobs, containerService, stores
+}
+
+// doJob sends a request for a job and decodes the response
+func doJob(t *testing.T, h *BulkJobHandler, method string, id int, out interface{}) int {
+	rec := httptest.NewRecorder()
+	h.HandleBulkJobs(rec, httptest.NewRequest(method, "/bulk/jobs?id="+strconv.Itoa(id), nil))
+	if out != nil {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), out))
+	}
+	return rec.Code
+}
+
+// waitForJob polls a job until it is finished
+func waitForJob(t *testing.T, h *BulkJobHandler, id int) model.BulkJob {
+	var job model.BulkJob
+	require.Eventually(t, func() bool {
+		job = model.BulkJob{}
+		require.Equal(t, http.StatusOK, doJob(t, h, http.MethodGet, id, &job))
+		return job.IsFinished()
+	}, 5*time.Second, 10*time.Millisecond)
+	return job
+}
+
+func TestBulkJobHandler_Placement(t *testing.T) {
+	ctx := context.Background()
+	h, _, _, stores := newTestBulkJobs(t)
+
+	// Item 0 stacks on item 1, item 2 aims at the cell of item 1
+	req := model.BulkJobRequest{Kind: model.BulkJobPlacement, Placements: []model.PlacementRequest{
+		{Yard: "YRD1", ContainerNumber: "BLKU0000018", Block: "LC01", Slot: 1, Row: 1, Tier: 2},
+		{Yard: "YRD1", ContainerNumber: "BLKU0000023", Block: "LC01", Slot: 1, Row: 1, Tier: 1},
+		{Yard: "YRD1", ContainerNumber: "BLKU0000039", Block: "LC01", Slot: 1, Row: 1, Tier: 1},
+	}}
+
+	var created model.BulkJob
+	require.Equal(t, http.StatusAccepted, doJSON(t, h.HandleBulkJobs, req, &created))
+	assert.NotZero(t, created.ID)
+	assert.Equal(t, model.BulkJobQueued, created.Status)
+	assert.Equal(t, 3, created.Total)
+
+	job := waitForJob(t, h, created.ID)
+	assert.Equal(t, model.BulkJobCompleted, job.Status)
+	assert.Equal(t, 3, job.Processed)
+	assert.Equal(t, 2, job.Succeeded)
+	assert.Equal(t, 1, job.Failed)
+	require.Len(t, job.Results, 3)
+	for i, result := range job.Results {
+		assert.Equal(t, i, result.Index)
+		assert.Equal(t, req.Placements[i].ContainerNumber, result.ContainerNumber)
+	}
+	assert.True(t, job.Results[0].Success, job.Results[0].Error)
+	assert.True(t, job.Results[1].Success, job.Results[1].Error)
+	assert.Equal(t, string(apperror.CodePositionOccupied), job.Results[2].Code)
+
+	containers, err := stores.Containers.GetByBlock(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, containers, 2)
+
+	// Finished jobs can not be cancelled
+	assert.Equal(t, http.StatusConflict, doJob(t, h, http.MethodDelete, created.ID, nil))
+}
+
+func TestBulkJobHandler_Suggestion(t *testing.T) {
+	h, _, _, _ := newTestBulkJobs(t)
+
+	req := model.BulkJobRequest{Kind: model.BulkJobSuggestion, Suggestions: []model.SuggestionRequest{
+		{Yard: "YRD1", ContainerNumber: "BLKU0000018", ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY"},
+		{Yard: "YRD1", ContainerNumber: "BLKU0000023", ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY"},
+	}}
+
+	var created model.BulkJob
+	require.Equal(t, http.StatusAccepted, doJSON(t, h.HandleBulkJobs, req, &created))
+
+	job := waitForJob(t, h, created.ID)
+	assert.Equal(t, model.BulkJobCompleted, job.Status)
+	require.Len(t, job.Results, 2)
+	for _, result := range job.Results {
+		require.True(t, result.Success, result.Error)
+		assert.Equal(t, "YRD1", result.Yard)
+	}
+	assert.NotEqual(t, *job.Results[0].Position, *job.Results[1].Position)
+}
+
+func TestBulkJobHandler_Validation(t *testing.T) {
+	h, _, _, _ := newTestBulkJobs(t)
+
+	var resp struct {
+		Code apperror.Code `json:"code"`
+	}
+	req := model.BulkJobRequest{Kind: model.BulkJobMove}
+	assert.Equal(t, http.StatusUnprocessableEntity, doJSON(t, h.HandleBulkJobs, req, &resp))
+	assert.Equal(t, apperror.CodeValidationFailed, resp.Code)
+
+	assert.Equal(t, http.StatusNotFound, doJob(t, h, http.MethodGet, 99, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, doJob(t, h, http.MethodPut, 1, nil))
+}
+
+func TestBulkJobHandler_Cancel(t *testing.T) {
+	ctx := context.Background()
+	h, _, _, stores := newTestBulkJobs(t)
+
+	// A job still waiting for a worker
+	request, err := json.Marshal(model.BulkJobRequest{Kind: model.BulkJobPlacement, Placements: []model.PlacementRequest{
+		{Yard: "YRD1", ContainerNumber: "BLKU0000018", Block: "LC01", Slot: 1, Row: 1, Tier: 1},
+	}})
+	require.NoError(t, err)
+	job := &model.BulkJob{Kind: model.BulkJobPlacement, Request: request, Total: 1}
+	require.NoError(t, stores.BulkJobs.Create(ctx, job))
+
+	var cancelled model.BulkJob
+	require.Equal(t, http.StatusOK, doJob(t, h, http.MethodDelete, job.ID, &cancelled))
+	assert.Equal(t, model.BulkJobCancelled, cancelled.Status)
+	assert.Equal(t, "cancelled by anonymous", cancelled.Error)
+	assert.Empty(t, cancelled.Results)
+
+	assert.Equal(t, http.StatusConflict, doJob(t, h, http.MethodDelete, job.ID, nil))
+}
+
+func TestBulkJobHandler_Resume(t *testing.T) {
+	ctx := context.Background()
+	h, jobs, containerService, stores := newTestBulkJobs(t)
+
+	// A job cut short by a restart after its first item
+	req := model.BulkJobRequest{Kind: model.BulkJobPlacement, Placements: []model.PlacementRequest{
+		{Yard: "YRD1", ContainerNumber: "BLKU0000018", Block: "LC01", Slot: 1, Row: 1, Tier: 1},
+		{Yard: "YRD1", ContainerNumber: "BLKU0000023", Block: "LC01", Slot: 1, Row: 1, Tier: 2},
+	}}
+	request, err := json.Marshal(req)
+	require.NoError(t, err)
+	job := &model.BulkJob{Kind: model.BulkJobPlacement, Request: request, Total: 2}
+	require.NoError(t, stores.BulkJobs.Create(ctx, job))
+	_, err = stores.BulkJobs.SetStatus(ctx, job.ID, model.BulkJobRunning, "", model.BulkJobQueued)
+	require.NoError(t, err)
+
+	first := req.Placements[0]
+	require.NoError(t, containerService.PlaceContainer(ctx, first))
+	require.NoError(t, stores.BulkJobs.AddResult(ctx, job.ID, model.BulkJobResult{
+		Index: 0, ContainerNumber: first.ContainerNumber, Success: true, Yard: "YRD1",
+		Position: &model.Position{Block: "LC01", Slot: 1, Row: 1, Tier: 1},
+	}))
+
+	resumed, err := jobs.Resume(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, resumed)
+
+	finished := waitForJob(t, h, job.ID)
+	assert.Equal(t, model.BulkJobCompleted, finished.Status)
+	assert.Equal(t, 2, finished.Succeeded)
+	require.Len(t, finished.Results, 2)
+	assert.True(t, finished.Results[1].Success, finished.Results[1].Error)
+
+	containers, err := stores.Containers.GetByBlock(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, containers, 2)
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/service"
+	"github.com/dwipurnomo515/yard-planning/pkg/response"
+)
+
+type ClosureHandler struct {
+	service *service.ClosureService
+}
+
+func NewClosureHandler(service *service.ClosureService) *ClosureHandler {
+	return &ClosureHandler{service: service}
+}
+
+// HandleClosures handles GET /closures?yard=... and POST /closures
+func (h *ClosureHandler) HandleClosures(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.handleList(w, r)
+	case http.MethodPost:
+		h.handleCreate(w, r)
+	default:
+		response.Fail(w, methodNotAllowed(r))
+	}
+}
+
+func (h *ClosureHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	yard := r.URL.Query().Get("yard")
+	if err := requireQuery("yard", yard); err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	closures, err := h.service.ListClosures(ctx, yard)
+	if err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	response.Success(w, closures)
+}
+
+func (h *ClosureHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req model.ClosureRequest
+	if err := decode(r, &req); err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	closure, err := h.service.CreateClosure(ctx, req)
+	if err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	response.Created(w, closure)
+}
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"github.com/dwipurnomo515/yard-planning/internal/model"
@@ -10,47 +9,38 @@ import (
 )
 
 type ContainerHandler struct {
-	service *service.ContainerService
+	service service.ContainerOperations
 }
 
-func NewContainerHandler(service *service.ContainerService) *ContainerHandler {
+func NewContainerHandler(service service.ContainerOperations) *ContainerHandler {
 	return &ContainerHandler{service: service}
 }
 
 // HandleSuggestion handles POST /suggestion
 func (h *ContainerHandler) HandleSuggestion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	if r.Method != http.MethodPost {
-		response.Error(w, http.StatusMethodNotAllowed,
-			http.ErrNotSupported)
+		response.Fail(w, methodNotAllowed(r))
 		return
 	}
 
 	var req model.SuggestionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Error(w, http.StatusBadRequest, err)
+	if err := decode(r, &req); err != nil {
+		response.Fail(w, err)
 		return
 	}
 
-	// Validate required fields
-	if req.Yard == "" {
-		response.Error(w, http.StatusBadRequest,
-			http.ErrMissingBoundary)
-		return
-	}
-	if req.ContainerNumber == "" {
-		response.Error(w, http.StatusBadRequest,
-			http.ErrMissingBoundary)
-		return
-	}
-
-	position, err := h.service.GetSuggestion(req)
+	suggestion, err := h.service.GetSuggestion(ctx, req)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, err)
+		response.Fail(w, err)
 		return
 	}
 
 	resp := model.SuggestionResponse{
-		SuggestedPosition: *position,
+		SuggestedPosition: suggestion.Position,
+		Yard:              suggestion.Yard,
+		Overflow:          suggestion.Overflow,
+		Reason:            suggestion.Reason,
 	}
 
 	response.Success(w, resp)
@@ -58,33 +48,21 @@ func (h *ContainerHandler) HandleSuggestion(w http.ResponseWriter, r *http.Reque
 
 // HandlePlacement handles POST /placement
 func (h *ContainerHandler) HandlePlacement(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	if r.Method != http.MethodPost {
-		response.Error(w, http.StatusMethodNotAllowed,
-			http.ErrNotSupported)
+		response.Fail(w, methodNotAllowed(r))
 		return
 	}
 
 	var req model.PlacementRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Error(w, http.StatusBadRequest, err)
+	if err := decode(r, &req); err != nil {
+		response.Fail(w, err)
 		return
 	}
 
-	// Validate required fields
-	if req.Yard == "" || req.ContainerNumber == "" || req.Block == "" {
-		response.Error(w, http.StatusBadRequest,
-			http.ErrMissingBoundary)
-		return
-	}
-	if req.Slot < 1 || req.Row < 1 || req.Tier < 1 {
-		response.Error(w, http.StatusBadRequest,
-			http.ErrMissingBoundary)
-		return
-	}
-
-	err := h.service.PlaceContainer(req)
+	err := h.service.PlaceContainer(ctx, req)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, err)
+		response.Fail(w, err)
 		return
 	}
 
@@ -97,32 +75,52 @@ func (h *ContainerHandler) HandlePlacement(w http.ResponseWriter, r *http.Reques
 
 // HandlePickup handles POST /pickup
 func (h *ContainerHandler) HandlePickup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	if r.Method != http.MethodPost {
-		response.Error(w, http.StatusMethodNotAllowed,
-			http.ErrNotSupported)
+		response.Fail(w, methodNotAllowed(r))
 		return
 	}
 
 	var req model.PickupRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.Error(w, http.StatusBadRequest, err)
+	if err := decode(r, &req); err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	err := h.service.PickupContainer(ctx, req)
+	if err != nil {
+		response.Fail(w, err)
 		return
 	}
 
-	// Validate required fields
-	if req.Yard == "" || req.ContainerNumber == "" {
-		response.Error(w, http.StatusBadRequest,
-			http.ErrMissingBoundary)
+	resp := model.PickupResponse{
+		Message: "Success",
+	}
+
+	response.Success(w, resp)
+}
+
+// HandleMove handles POST /move
+func (h *ContainerHandler) HandleMove(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.Method != http.MethodPost {
+		response.Fail(w, methodNotAllowed(r))
+		return
+	}
+
+	var req model.MoveRequest
+	if err := decode(r, &req); err != nil {
+		response.Fail(w, err)
 		return
 	}
 
-	err := h.service.PickupContainer(req)
+	err := h.service.MoveContainer(ctx, req)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, err)
+		response.Fail(w, err)
 		return
 	}
 
-	resp := model.PickupResponse{
+	resp := model.MoveResponse{
 		Message: "Success",
 	}
 
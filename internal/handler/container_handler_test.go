@@ -0,0 +1,575 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dwipurnomo515/yard-planning/internal/auth"
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/repository/memory"
+	"github.com/dwipurnomo515/yard-planning/internal/service"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/dwipurnomo515/yard-planning/pkg/cache"
+	"github.com/dwipurnomo515/yard-planning/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T) (*service.ContainerService, repository.Stores) {
+	store := memory.NewStore()
+	require.NoError(t, store.Seed())
+	stores := store.Stores()
+
+	containerService := service.NewContainerService(
+		stores.Yards,
+		stores.Blocks,
+		stores.Plans,
+		stores.Containers,
+		stores.Closures,
+		stores.Equipment,
+		stores.WorkOrders,
+	)
+	containerService.SetTransactions(stores.Atomic)
+	return containerService, stores
+}
+
+func doJSON(t *testing.T, handle http.HandlerFunc, body interface{}, out interface{}) int {
+	payload, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handle(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(payload)))
+	if out != nil {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), out))
+	}
+	return rec.Code
+}
+
+func TestContainerHandler_SuggestPlacePickup(t *testing.T) {
+	ctx := context.Background()
+	containerService, stores := newTestService(t)
+	h := NewContainerHandler(containerService)
+
+	suggestionReq := model.SuggestionRequest{
+		Yard: "YRD1", ContainerNumber: "ABCU1234560",
+		ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY",
+	}
+
+	var suggestion model.SuggestionResponse
+	require.Equal(t, http.StatusOK, doJSON(t, h.HandleSuggestion, suggestionReq, &suggestion))
+	assert.Equal(t, "YRD1", suggestion.Yard)
+	assert.False(t, suggestion.Overflow)
+	assert.Equal(t, "LC01", suggestion.SuggestedPosition.Block)
+	assert.Equal(t, 1, suggestion.SuggestedPosition.Tier)
+
+	placement := model.PlacementRequest{
+		Yard:            "YRD1",
+		ContainerNumber: "ABCU1234560",
+		Block:           suggestion.SuggestedPosition.Block,
+		Slot:            suggestion.SuggestedPosition.Slot,
+		Row:             suggestion.SuggestedPosition.Row,
+		Tier:            suggestion.SuggestedPosition.Tier,
+	}
+	assert.Equal(t, http.StatusOK, doJSON(t, h.HandlePlacement, placement, nil))
+
+	// The same cell can not be used twice
+	var errResp response.ErrorResponse
+	placement.ContainerNumber = "ABCU7654323"
+	assert.Equal(t, http.StatusConflict, doJSON(t, h.HandlePlacement, placement, &errResp))
+	assert.Equal(t, apperror.CodePositionOccupied, errResp.Code)
+
+	// The next suggestion avoids the occupied cell
+	var next model.SuggestionResponse
+	suggestionReq.ContainerNumber = "ABCU7654323"
+	require.Equal(t, http.StatusOK, doJSON(t, h.HandleSuggestion, suggestionReq, &next))
+	assert.NotEqual(t, suggestion.SuggestedPosition, next.SuggestedPosition)
+
+	pickup := model.PickupRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560"}
+	assert.Equal(t, http.StatusOK, doJSON(t, h.HandlePickup, pickup, nil))
+
+	containers, err := stores.Containers.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, containers)
+}
+
+func TestContainerHandler_SuggestionOverflow(t *testing.T) {
+	ctx := context.Background()
+	containerService, stores := newTestService(t)
+	h := NewContainerHandler(containerService)
+
+	closures := service.NewClosureService(stores.Yards, stores.Blocks, stores.Closures)
+	_, err := closures.CreateClosure(ctx, model.ClosureRequest{
+		Yard:     "YRD1",
+		Reason:   "pavement works",
+		StartsAt: time.Now().Add(-time.Hour),
+		EndsAt:   time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	var suggestion model.SuggestionResponse
+	req := model.SuggestionRequest{
+		Yard: "YRD1", ContainerNumber: "ABCU1234560",
+		ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY",
+	}
+	require.Equal(t, http.StatusOK, doJSON(t, h.HandleSuggestion, req, &suggestion))
+	assert.Equal(t, "DEPOT1", suggestion.Yard)
+	assert.True(t, suggestion.Overflow)
+	assert.Equal(t, "OD01", suggestion.SuggestedPosition.Block)
+}
+
+func TestContainerHandler_ErrorCodes(t *testing.T) {
+	containerService, _ := newTestService(t)
+	h := NewContainerHandler(containerService)
+
+	tests := []struct {
+		name    string
+		handle  http.HandlerFunc
+		body    interface{}
+		status  int
+		code    apperror.Code
+		details []string
+	}{
+		{
+			name:    "missing fields",
+			handle:  h.HandlePlacement,
+			body:    model.PlacementRequest{Yard: "YRD1", Slot: 1, Row: 1, Tier: 1},
+			status:  http.StatusUnprocessableEntity,
+			code:    apperror.CodeValidationFailed,
+			details: []string{"container_number", "block"},
+		},
+		{
+			name:    "invalid position",
+			handle:  h.HandleMove,
+			body:    model.MoveRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 1},
+			status:  http.StatusUnprocessableEntity,
+			code:    apperror.CodeValidationFailed,
+			details: []string{"row", "tier"},
+		},
+		{
+			name:   "unknown yard",
+			handle: h.HandleSuggestion,
+			body: model.SuggestionRequest{
+				Yard: "NOPE", ContainerNumber: "ABCU1234560",
+				ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY",
+			},
+			status: http.StatusNotFound,
+			code:   apperror.CodeYardNotFound,
+		},
+		{
+			name:   "unknown container",
+			handle: h.HandlePickup,
+			body:   model.PickupRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560"},
+			status: http.StatusNotFound,
+			code:   apperror.CodeContainerNotFound,
+		},
+		{
+			name:   "malformed body",
+			handle: h.HandlePickup,
+			body:   "not an object",
+			status: http.StatusBadRequest,
+			code:   apperror.CodeInvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp response.ErrorResponse
+			assert.Equal(t, tt.status, doJSON(t, tt.handle, tt.body, &resp))
+			assert.Equal(t, tt.code, resp.Code)
+
+			var fields []string
+			for _, detail := range resp.Details {
+				fields = append(fields, detail.Field)
+			}
+			assert.Equal(t, tt.details, fields)
+		})
+	}
+}
+
+func TestBulkHandler_ValidationPaths(t *testing.T) {
+	containerService, _ := newTestService(t)
+	h := NewBulkHandler(containerService)
+
+	req := BulkPlacementRequest{Containers: []model.PlacementRequest{
+		{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 1, Row: 1, Tier: 1},
+		{Yard: "YRD1", ContainerNumber: "ABCU1234561", Block: "LC01", Slot: 2, Row: 0, Tier: 1},
+	}}
+
+	var resp response.ErrorResponse
+	require.Equal(t, http.StatusUnprocessableEntity, doJSON(t, h.HandleBulkPlacement, req, &resp))
+
+	var fields []string
+	for _, detail := range resp.Details {
+		fields = append(fields, detail.Field)
+	}
+	assert.Equal(t, []string{"containers[1].container_number", "containers[1].row"}, fields)
+}
+
+func TestContainerHandler_StrictJSON(t *testing.T) {
+	containerService, _ := newTestService(t)
+	h := NewContainerHandler(containerService)
+
+	body := map[string]interface{}{
+		"yard": "YRD1", "container_number": "ABCU1234560", "colour": "blue",
+	}
+
+	// Unknown fields are ignored unless strict decoding is enabled
+	assert.Equal(t, http.StatusNotFound, doJSON(t, h.HandlePickup, body, nil))
+
+	SetStrictJSON(true)
+	defer SetStrictJSON(false)
+
+	var resp response.ErrorResponse
+	require.Equal(t, http.StatusUnprocessableEntity, doJSON(t, h.HandlePickup, body, &resp))
+	require.Len(t, resp.Details, 1)
+	assert.Equal(t, "colour", resp.Details[0].Field)
+}
+
+func TestBulkHandler_SuggestionPlansBatchJointly(t *testing.T) {
+	containerService, _ := newTestService(t)
+	h := NewBulkHandler(containerService)
+
+	// LC01 has 15 cells for 20ft containers on tier 1
+	var req BulkSuggestionRequest
+	for _, number := range []string{
+		"BLKU0000018", "BLKU0000023", "BLKU0000039", "BLKU0000044", "BLKU0000050",
+		"BLKU0000065", "BLKU0000070", "BLKU0000086", "BLKU0000091", "BLKU0000105",
+		"BLKU0000110", "BLKU0000126", "BLKU0000131", "BLKU0000147", "BLKU0000152",
+		"BLKU0000168", "BLKU0000173", "BLKU0000189", "BLKU0000194", "BLKU0000208",
+	} {
+		req.Containers = append(req.Containers, model.SuggestionRequest{
+			Yard: "YRD1", ContainerNumber: number, ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY",
+		})
+	}
+
+	var resp BulkSuggestionResponse
+	require.Equal(t, http.StatusOK, doJSON(t, h.HandleBulkSuggestion, req, &resp))
+	require.Len(t, resp.Results, len(req.Containers))
+
+	taken := make(map[model.Position]bool)
+	stacked := 0
+	for i, result := range resp.Results {
+		assert.Equal(t, req.Containers[i].ContainerNumber, result.ContainerNumber)
+		require.NotNil(t, result.SuggestedPosition, result.Error)
+		position := *result.SuggestedPosition
+		assert.False(t, taken[position], "cell suggested twice: %+v", position)
+		taken[position] = true
+		if position.Tier > 1 {
+			stacked++
+		}
+	}
+	assert.Equal(t, 5, stacked)
+
+	// Stacked containers stand on cells suggested to the batch
+	for position := range taken {
+		if position.Tier > 1 {
+			below := position
+			below.Tier--
+			assert.True(t, taken[below], "nothing below %+v", position)
+		}
+	}
+
+	// The same batch against the same yard gets the same plan
+	var again BulkSuggestionResponse
+	require.Equal(t, http.StatusOK, doJSON(t, h.HandleBulkSuggestion, req, &again))
+	assert.Equal(t, resp, again)
+}
+
+func TestBulkHandler_PlacementSameCell(t *testing.T) {
+	ctx := context.Background()
+	containerService, stores := newTestService(t)
+	h := NewBulkHandler(containerService)
+
+	req := BulkPlacementRequest{Containers: []model.PlacementRequest{
+		{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 1, Row: 1, Tier: 1},
+		{Yard: "YRD1", ContainerNumber: "ABCU7654323", Block: "LC01", Slot: 1, Row: 1, Tier: 1},
+	}}
+
+	var resp BulkPlacementResponse
+	require.Equal(t, http.StatusOK, doJSON(t, h.HandleBulkPlacement, req, &resp))
+	require.Len(t, resp.Results, 2)
+
+	placed := 0
+	for _, result := range resp.Results {
+		if result.Success {
+			placed++
+		}
+	}
+	assert.Equal(t, 1, placed)
+
+	containers, err := stores.Containers.GetByBlock(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, containers, 1)
+}
+
+func TestBulkHandler_PlacementStacksBottomUp(t *testing.T) {
+	containerService, _ := newTestService(t)
+	h := NewBulkHandler(containerService)
+
+	req := BulkPlacementRequest{Containers: []model.PlacementRequest{
+		{Yard: "YRD1", ContainerNumber: "ABCU1234540", Block: "LC01", Slot: 1, Row: 1, Tier: 3},
+		{Yard: "YRD1", ContainerNumber: "ABCU1234555", Block: "LC01", Slot: 1, Row: 1, Tier: 2},
+		{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 1, Row: 1, Tier: 1},
+		{Yard: "YRD1", ContainerNumber: "ABCU1234576", Block: "LC01", Slot: 2, Row: 1, Tier: 1},
+	}}
+
+	var resp BulkPlacementResponse
+	require.Equal(t, http.StatusOK, doJSON(t, h.HandleBulkPlacement, req, &resp))
+	require.Len(t, resp.Results, 4)
+	for i, result := range resp.Results {
+		assert.Equal(t, req.Containers[i].ContainerNumber, result.ContainerNumber)
+		assert.True(t, result.Success, result.Error)
+	}
+}
+
+func TestBulkHandler_AtomicPlacement(t *testing.T) {
+	ctx := context.Background()
+	containerService, stores := newTestService(t)
+	h := NewBulkHandler(containerService)
+
+	// Items 0 and 2 aim at the same cell, item 1 stacks on item 0 and item 3
+	// is fine on its own
+	req := BulkPlacementRequest{Atomic: true, Containers: []model.PlacementRequest{
+		{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 1, Row: 1, Tier: 1},
+		{Yard: "YRD1", ContainerNumber: "ABCU1234576", Block: "LC01", Slot: 1, Row: 1, Tier: 2},
+		{Yard: "YRD1", ContainerNumber: "ABCU1234508", Block: "LC01", Slot: 1, Row: 1, Tier: 1},
+		{Yard: "YRD1", ContainerNumber: "ABCU1234513", Block: "LC01", Slot: 2, Row: 1, Tier: 1},
+	}}
+
+	var resp BulkPlacementResponse
+	require.Equal(t, http.StatusConflict, doJSON(t, h.HandleBulkPlacement, req, &resp))
+	assert.True(t, resp.RolledBack)
+	require.Len(t, resp.Results, 4)
+
+	assert.Equal(t, apperror.CodePositionOccupied, resp.Results[0].Code)
+	assert.Equal(t, []int{2}, resp.Results[0].ConflictsWith)
+	assert.Equal(t, apperror.CodeConflict, resp.Results[1].Code)
+	assert.Equal(t, []int{0}, resp.Results[1].ConflictsWith)
+	assert.Equal(t, []int{0}, resp.Results[2].ConflictsWith)
+	assert.True(t, resp.Results[3].RolledBack)
+	assert.Empty(t, resp.Results[3].Error)
+
+	containers, err := stores.Containers.GetByBlock(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, containers)
+
+	// Without the clash the stack is placed bottom up, whatever the order
+	req.Containers = []model.PlacementRequest{req.Containers[1], req.Containers[0], req.Containers[3]}
+	resp = BulkPlacementResponse{}
+	require.Equal(t, http.StatusOK, doJSON(t, h.HandleBulkPlacement, req, &resp))
+	assert.False(t, resp.RolledBack)
+	for i, result := range resp.Results {
+		assert.True(t, result.Success)
+		assert.Equal(t, req.Containers[i].ContainerNumber, result.ContainerNumber)
+	}
+
+	containers, err = stores.Containers.GetByBlock(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, containers, 3)
+
+	// A container already in the yard rolls the whole batch back
+	req.Containers = []model.PlacementRequest{
+		{Yard: "YRD1", ContainerNumber: "ABCU1234529", Block: "LC01", Slot: 3, Row: 1, Tier: 1},
+		{Yard: "YRD1", ContainerNumber: "ABCU1234513", Block: "LC01", Slot: 4, Row: 1, Tier: 1},
+	}
+	resp = BulkPlacementResponse{}
+	require.Equal(t, http.StatusConflict, doJSON(t, h.HandleBulkPlacement, req, &resp))
+	assert.True(t, resp.Results[0].RolledBack)
+	assert.Equal(t, apperror.CodeConflict, resp.Results[1].Code)
+
+	_, err = stores.Containers.GetByNumber(ctx, "ABCU1234529")
+	assert.Error(t, err)
+}
+
+func TestBulkHandler_CancelledRequest(t *testing.T) {
+	containerService, stores := newTestService(t)
+	h := NewBulkHandler(containerService)
+
+	// A client that disconnected before the work started
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	post := func(handle http.HandlerFunc, body interface{}) *httptest.ResponseRecorder {
+		payload, err := json.Marshal(body)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(payload)).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		handle(rec, req)
+		return rec
+	}
+
+	suggestions := BulkSuggestionRequest{Containers: []model.SuggestionRequest{
+		{Yard: "YRD1", ContainerNumber: "ABCU1234560", ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY"},
+		{Yard: "YRD1", ContainerNumber: "ABCU7654323", ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY"},
+	}}
+	assert.Equal(t, http.StatusServiceUnavailable, post(h.HandleBulkSuggestion, suggestions).Code)
+
+	placements := BulkPlacementRequest{Containers: []model.PlacementRequest{
+		{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 1, Row: 1, Tier: 1},
+		{Yard: "YRD1", ContainerNumber: "ABCU7654323", Block: "LC01", Slot: 2, Row: 1, Tier: 1},
+	}}
+	rec := post(h.HandleBulkPlacement, placements)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp BulkPlacementResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Results, 2)
+	for _, result := range resp.Results {
+		assert.False(t, result.Success)
+		assert.Equal(t, context.Canceled.Error(), result.Error)
+		assert.Equal(t, apperror.CodeTimeout, result.Code)
+	}
+
+	containers, err := stores.Containers.GetAll(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, containers)
+}
+
+func TestContainerHandler_CachedPlacementInvalidatesSuggestions(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+	redisClient, err := cache.NewRedisClient(cache.RedisConfig{
+		Host: redisServer.Host(),
+		Port: redisServer.Port(),
+	})
+	require.NoError(t, err)
+	defer redisClient.Close()
+
+	store := memory.NewStore()
+	require.NoError(t, store.Seed())
+	stores := store.Stores()
+	cachedService := service.NewCachedContainerService(
+		stores.Yards,
+		stores.Blocks,
+		stores.Plans,
+		stores.Containers,
+		stores.Closures,
+		stores.Equipment,
+		stores.WorkOrders,
+		redisClient,
+	)
+	h := NewContainerHandler(cachedService)
+
+	var suggestion model.SuggestionResponse
+	req := model.SuggestionRequest{
+		Yard: "YRD1", ContainerNumber: "ABCU1234560",
+		ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY",
+	}
+	require.Equal(t, http.StatusOK, doJSON(t, h.HandleSuggestion, req, &suggestion))
+	assert.Len(t, keys(redisServer, "suggestion:default:YRD1:"), 1)
+
+	placement := model.PlacementRequest{
+		Yard:            "YRD1",
+		ContainerNumber: "ABCU1234560",
+		Block:           suggestion.SuggestedPosition.Block,
+		Slot:            suggestion.SuggestedPosition.Slot,
+		Row:             suggestion.SuggestedPosition.Row,
+		Tier:            suggestion.SuggestedPosition.Tier,
+	}
+	require.Equal(t, http.StatusOK, doJSON(t, h.HandlePlacement, placement, nil))
+	assert.Empty(t, keys(redisServer, "suggestion:default:YRD1:"))
+	assert.True(t, redisServer.Exists("container:default:ABCU1234560"))
+
+	pickup := model.PickupRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560"}
+	require.Equal(t, http.StatusOK, doJSON(t, h.HandlePickup, pickup, nil))
+	assert.False(t, redisServer.Exists("container:default:ABCU1234560"))
+}
+
+func keys(redisServer *miniredis.Miniredis, prefix string) []string {
+	var matched []string
+	for _, key := range redisServer.Keys() {
+		if strings.HasPrefix(key, prefix) {
+			matched = append(matched, key)
+		}
+	}
+	return matched
+}
+
+// doJSONAs is doJSON for a caller with the given role bindings
+func doJSONAs(t *testing.T, bindings []model.RoleBinding, handle http.HandlerFunc, body interface{}, out interface{}) int {
+	payload, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(payload))
+	r = r.WithContext(auth.WithGrants(r.Context(), auth.NewGrants("api_key:test", bindings)))
+	rec := httptest.NewRecorder()
+	handle(rec, r)
+	if out != nil {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), out))
+	}
+	return rec.Code
+}
+
+func TestContainerHandler_YardRoles(t *testing.T) {
+	ctx := context.Background()
+	containerService, stores := newTestService(t)
+	h := NewContainerHandler(containerService)
+	clerk := []model.RoleBinding{{Role: model.RoleGateClerk, Yard: "YRD1"}}
+
+	placement := model.PlacementRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 1, Row: 1, Tier: 1}
+	require.Equal(t, http.StatusOK, doJSONAs(t, clerk, h.HandlePlacement, placement, nil))
+
+	// Gate clerks may not move or pick up containers
+	var errResp response.ErrorResponse
+	move := model.MoveRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 2, Row: 1, Tier: 1}
+	assert.Equal(t, http.StatusForbidden, doJSONAs(t, clerk, h.HandleMove, move, &errResp))
+	assert.Equal(t, apperror.CodeForbidden, errResp.Code)
+	pickup := model.PickupRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560"}
+	assert.Equal(t, http.StatusForbidden, doJSONAs(t, clerk, h.HandlePickup, pickup, nil))
+
+	// A supervisor of another yard can not reach into this one
+	supervisor := []model.RoleBinding{{Role: model.RoleSupervisor, Yard: "DEPOT1"}}
+	pickup.Yard = "DEPOT1"
+	assert.Equal(t, http.StatusNotFound, doJSONAs(t, supervisor, h.HandlePickup, pickup, nil))
+	move.Yard = "YRD1"
+	assert.Equal(t, http.StatusForbidden, doJSONAs(t, supervisor, h.HandleMove, move, nil))
+
+	// Overflow yards the caller has no role in are skipped
+	closures := service.NewClosureService(stores.Yards, stores.Blocks, stores.Closures)
+	_, err := closures.CreateClosure(ctx, model.ClosureRequest{
+		Yard: "YRD1", Reason: "pavement works",
+		StartsAt: time.Now().Add(-time.Hour), EndsAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	suggestion := model.SuggestionRequest{
+		Yard: "YRD1", ContainerNumber: "ABCU7654323",
+		ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY",
+	}
+	assert.Equal(t, http.StatusServiceUnavailable, doJSONAs(t, clerk, h.HandleSuggestion, suggestion, nil))
+	clerk = append(clerk, model.RoleBinding{Role: model.RoleGateClerk, Yard: "DEPOT1"})
+	assert.Equal(t, http.StatusOK, doJSONAs(t, clerk, h.HandleSuggestion, suggestion, nil))
+}
+
+func TestBulkHandler_PlacementYardRoles(t *testing.T) {
+	containerService, stores := newTestService(t)
+	h := NewBulkHandler(containerService)
+	clerk := []model.RoleBinding{{Role: model.RoleGateClerk, Yard: "YRD1"}}
+
+	req := BulkPlacementRequest{Containers: []model.PlacementRequest{
+		{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 1, Row: 1, Tier: 1},
+		{Yard: "DEPOT1", ContainerNumber: "ABCU7654323", Block: "OD01", Slot: 1, Row: 1, Tier: 1},
+	}}
+
+	var resp BulkPlacementResponse
+	require.Equal(t, http.StatusOK, doJSONAs(t, clerk, h.HandleBulkPlacement, req, &resp))
+	require.Len(t, resp.Results, 2)
+	for _, result := range resp.Results {
+		switch result.ContainerNumber {
+		case "ABCU1234560":
+			assert.True(t, result.Success, result.Error)
+		default:
+			assert.False(t, result.Success)
+			assert.Equal(t, apperror.CodeForbidden, result.Code)
+		}
+	}
+
+	containers, err := stores.Containers.GetAll(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, containers, 1)
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+)
+
+// strictJSON makes decode reject fields that are not part of the request
+var strictJSON bool
+
+// SetStrictJSON enables or disables rejecting unknown JSON fields
+func SetStrictJSON(strict bool) {
+	strictJSON = strict
+}
+
+// decode reads a JSON request body into req and validates it. Malformed JSON
+// is an INVALID_REQUEST error; unknown fields (in strict mode), values of the
+// wrong type and failed checks are reported together as VALIDATION_FAILED.
+func decode(r *http.Request, req model.Validatable) error {
+	decoder := json.NewDecoder(r.Body)
+	if strictJSON {
+		decoder.DisallowUnknownFields()
+	}
+
+	v := model.NewValidator()
+	if err := decoder.Decode(req); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &typeErr) && typeErr.Field != "":
+			v.Fail(typeErr.Field, fmt.Sprintf("must be a %s", typeErr.Type))
+			return v.Err()
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+			v.Fail(field, "is not a known field")
+			return v.Err()
+		default:
+			return invalidBody(err)
+		}
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return invalidBody(errors.New("body must contain a single JSON object"))
+	}
+
+	req.Validate(v)
+	return v.Err()
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/service"
+	"github.com/dwipurnomo515/yard-planning/pkg/response"
+)
+
+type EquipmentHandler struct {
+	service *service.EquipmentService
+}
+
+func NewEquipmentHandler(service *service.EquipmentService) *EquipmentHandler {
+	return &EquipmentHandler{service: service}
+}
+
+// HandleEquipment handles GET /equipment?yard=... and POST /equipment
+func (h *EquipmentHandler) HandleEquipment(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.handleList(w, r)
+	case http.MethodPost:
+		h.handleCreate(w, r)
+	default:
+		response.Fail(w, methodNotAllowed(r))
+	}
+}
+
+func (h *EquipmentHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	yard := r.URL.Query().Get("yard")
+	if err := requireQuery("yard", yard); err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	equipment, err := h.service.ListEquipment(ctx, yard)
+	if err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	response.Success(w, equipment)
+}
+
+func (h *EquipmentHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req model.EquipmentRequest
+	if err := decode(r, &req); err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	equipment, err := h.service.CreateEquipment(ctx, req)
+	if err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	response.Created(w, equipment)
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+// methodNotAllowed is returned for requests with an unsupported method
+func methodNotAllowed(r *http.Request) error {
+	return apperror.New(apperror.CodeMethodNotAllowed, "method %s is not allowed", r.Method)
+}
+
+// invalidBody is returned when the request body is not valid JSON
+func invalidBody(err error) error {
+	return apperror.Wrap(apperror.CodeInvalidRequest, err, "invalid request body")
+}
+
+// requireQuery returns a validation error listing every empty query
+// parameter, given as name/value pairs, or nil when all are set
+func requireQuery(pairs ...string) error {
+	var missing []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] == "" {
+			missing = append(missing, pairs[i])
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return apperror.Required(missing...)
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/dwipurnomo515/yard-planning/pkg/response"
+)
+
+// setETag sends the version of a resource as its entity tag
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", `"`+strconv.Itoa(version)+`"`)
+}
+
+// ifMatch returns the version named by the If-Match header of a write. The
+// header is required; "*" matches any version and is returned as 0.
+func ifMatch(r *http.Request) (int, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" {
+		return 0, apperror.New(apperror.CodePreconditionRequired,
+			"If-Match header with the ETag of the current version is required")
+	}
+	if value == "*" {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(value, `"`), `"`))
+	if err != nil || version < 1 || !strings.HasPrefix(value, `"`) {
+		return 0, apperror.New(apperror.CodeInvalidRequest,
+			"If-Match must be an ETag returned by the API, such as \"1\"")
+	}
+	return version, nil
+}
+
+// failStale writes a failed write. When the resource was changed in the
+// meantime, the current version is loaded and sent along so the client can
+// merge its changes.
+func failStale(w http.ResponseWriter, err error, current func() (interface{}, int, error)) {
+	if apperror.CodeOf(err) == apperror.CodePreconditionFailed {
+		if resource, version, getErr := current(); getErr == nil {
+			setETag(w, version)
+			response.FailWithCurrent(w, err, resource)
+			return
+		}
+	}
+	response.Fail(w, err)
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/service"
+	"github.com/dwipurnomo515/yard-planning/pkg/response"
+)
+
+type HoldHandler struct {
+	service *service.HoldService
+}
+
+func NewHoldHandler(service *service.HoldService) *HoldHandler {
+	return &HoldHandler{service: service}
+}
+
+// HandleHold handles POST /holds
+func (h *HoldHandler) HandleHold(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.Method != http.MethodPost {
+		response.Fail(w, methodNotAllowed(r))
+		return
+	}
+
+	var req model.HoldRequest
+	if err := decode(r, &req); err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	if err := h.service.HoldContainer(ctx, req); err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	response.Success(w, model.HoldResponse{Message: "Success"})
+}
+
+// HandleRelease handles POST /holds/release
+func (h *HoldHandler) HandleRelease(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.Method != http.MethodPost {
+		response.Fail(w, methodNotAllowed(r))
+		return
+	}
+
+	var req model.HoldRequest
+	if err := decode(r, &req); err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	if err := h.service.ReleaseHold(ctx, req); err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	response.Success(w, model.HoldResponse{Message: "Success"})
+}
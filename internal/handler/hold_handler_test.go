@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/service"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/dwipurnomo515/yard-planning/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHoldHandler_OnlySupervisorsOverrideHolds(t *testing.T) {
+	containerService, stores := newTestService(t)
+	containers := NewContainerHandler(containerService)
+	h := NewHoldHandler(service.NewHoldService(stores.Yards, stores.Containers))
+	planner := []model.RoleBinding{{Role: model.RoleYardPlanner, Yard: "YRD1"}}
+	supervisor := []model.RoleBinding{{Role: model.RoleSupervisor, Yard: "YRD1"}}
+
+	placement := model.PlacementRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 1, Row: 1, Tier: 1}
+	require.Equal(t, http.StatusOK, doJSON(t, containers.HandlePlacement, placement, nil))
+
+	hold := model.HoldRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560", Reason: "customs inspection"}
+	require.Equal(t, http.StatusOK, doJSONAs(t, planner, h.HandleHold, hold, nil))
+	container, err := stores.Containers.GetByNumber(context.Background(), "ABCU1234560")
+	require.NoError(t, err)
+	assert.Equal(t, "customs inspection", container.Hold)
+
+	// A held container does not leave the yard without an override
+	var errResp response.ErrorResponse
+	pickup := model.PickupRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560"}
+	assert.Equal(t, http.StatusConflict, doJSONAs(t, planner, containers.HandlePickup, pickup, &errResp))
+	assert.Equal(t, apperror.CodeContainerHeld, errResp.Code)
+
+	// Planners place holds but can neither override nor release them
+	pickup.OverrideHold = true
+	errResp = response.ErrorResponse{}
+	assert.Equal(t, http.StatusForbidden, doJSONAs(t, planner, containers.HandlePickup, pickup, &errResp))
+	assert.Equal(t, apperror.CodeForbidden, errResp.Code)
+	assert.Equal(t, http.StatusForbidden, doJSONAs(t, planner, h.HandleRelease, hold, nil))
+
+	// A supervisor releases the hold
+	require.Equal(t, http.StatusOK, doJSONAs(t, supervisor, h.HandleRelease, hold, nil))
+	assert.Equal(t, http.StatusConflict, doJSONAs(t, supervisor, h.HandleRelease, hold, nil), "not on hold anymore")
+
+	// or picks the held container up directly
+	require.Equal(t, http.StatusOK, doJSONAs(t, planner, h.HandleHold, hold, nil))
+	assert.Equal(t, http.StatusOK, doJSONAs(t, supervisor, containers.HandlePickup, pickup, nil))
+}
+
+func TestHoldHandler_Validation(t *testing.T) {
+	_, stores := newTestService(t)
+	h := NewHoldHandler(service.NewHoldService(stores.Yards, stores.Containers))
+
+	var errResp response.ErrorResponse
+	hold := model.HoldRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560"}
+	assert.Equal(t, http.StatusUnprocessableEntity, doJSON(t, h.HandleHold, hold, &errResp))
+	assert.Equal(t, apperror.CodeValidationFailed, errResp.Code)
+
+	hold.Reason = "damaged"
+	assert.Equal(t, http.StatusNotFound, doJSON(t, h.HandleHold, hold, nil))
+}
+
+// Every supervisor-only route refuses the other roles, and the equipment
+// operator in particular
+func TestSupervisorOnlyRoutes(t *testing.T) {
+	containerService, stores := newTestService(t)
+	containers := NewContainerHandler(containerService)
+	holds := NewHoldHandler(service.NewHoldService(stores.Yards, stores.Containers))
+	jobs := NewBulkJobHandler(service.NewBulkJobService(stores.BulkJobs, stores.Roles, containerService))
+
+	placement := model.PlacementRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 1, Row: 1, Tier: 1}
+	require.Equal(t, http.StatusOK, doJSON(t, containers.HandlePlacement, placement, nil))
+	hold := model.HoldRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560", Reason: "customs inspection"}
+	require.Equal(t, http.StatusOK, doJSON(t, holds.HandleHold, hold, nil))
+
+	move := model.MoveRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 2, Row: 1, Tier: 1}
+	routes := []struct {
+		name   string
+		handle http.HandlerFunc
+		body   interface{}
+	}{
+		{"move", containers.HandleMove, move},
+		{"bulk move job", jobs.HandleBulkJobs, model.BulkJobRequest{Kind: model.BulkJobMove, Moves: []model.MoveRequest{move}}},
+		{"release hold", holds.HandleRelease, hold},
+		{"override hold", containers.HandlePickup, model.PickupRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560", OverrideHold: true}},
+	}
+	roles := []string{model.RoleEquipmentOperator, model.RoleGateClerk, model.RoleYardPlanner}
+
+	for _, route := range routes {
+		for _, role := range roles {
+			var errResp response.ErrorResponse
+			bindings := []model.RoleBinding{{Role: role, Yard: "YRD1"}}
+			assert.Equal(t, http.StatusForbidden, doJSONAs(t, bindings, route.handle, route.body, &errResp), "%s as %s", route.name, role)
+			assert.Equal(t, apperror.CodeForbidden, errResp.Code, "%s as %s", route.name, role)
+		}
+	}
+
+	container, err := stores.Containers.GetByNumber(context.Background(), "ABCU1234560")
+	require.NoError(t, err)
+	assert.Equal(t, 1, container.Slot)
+	assert.Equal(t, "customs inspection", container.Hold)
+}
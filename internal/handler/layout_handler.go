@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/service"
+	"github.com/dwipurnomo515/yard-planning/pkg/response"
+)
+
+type LayoutHandler struct {
+	service *service.LayoutService
+}
+
+func NewLayoutHandler(service *service.LayoutService) *LayoutHandler {
+	return &LayoutHandler{service: service}
+}
+
+// HandleLayout handles GET /layout?yard=...
+func (h *LayoutHandler) HandleLayout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.Method != http.MethodGet {
+		response.Fail(w, methodNotAllowed(r))
+		return
+	}
+
+	yard := r.URL.Query().Get("yard")
+	if err := requireQuery("yard", yard); err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	layout, err := h.service.GetLayout(ctx, yard)
+	if err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	response.Success(w, layout)
+}
+
+// HandleCreatePoint handles POST /layout/points
+func (h *LayoutHandler) HandleCreatePoint(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.Method != http.MethodPost {
+		response.Fail(w, methodNotAllowed(r))
+		return
+	}
+
+	var req model.YardPointRequest
+	if err := decode(r, &req); err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	point, err := h.service.CreatePoint(ctx, req)
+	if err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	response.Created(w, point)
+}
@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/service"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/dwipurnomo515/yard-planning/pkg/response"
+)
+
+// PlanningHandler serves blocks and yard plans. Responses carry the version of
+// the resource as ETag, and updates and deletes must send it back in If-Match.
+type PlanningHandler struct {
+	service *service.PlanningService
+}
+
+func NewPlanningHandler(service *service.PlanningService) *PlanningHandler {
+	return &PlanningHandler{service: service}
+}
+
+// HandleBlocks handles GET and PUT /blocks?yard=...&block=...
+func (h *PlanningHandler) HandleBlocks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	yard := r.URL.Query().Get("yard")
+	code := r.URL.Query().Get("block")
+
+	switch r.Method {
+	case http.MethodGet:
+		if err := requireQuery("yard", yard, "block", code); err != nil {
+			response.Fail(w, err)
+			return
+		}
+
+		block, err := h.service.GetBlock(ctx, yard, code)
+		if err != nil {
+			response.Fail(w, err)
+			return
+		}
+
+		setETag(w, block.Version)
+		response.Success(w, block)
+
+	case http.MethodPut:
+		if err := requireQuery("yard", yard, "block", code); err != nil {
+			response.Fail(w, err)
+			return
+		}
+		version, err := ifMatch(r)
+		if err != nil {
+			response.Fail(w, err)
+			return
+		}
+
+		var req model.BlockRequest
+		if err := decode(r, &req); err != nil {
+			response.Fail(w, err)
+			return
+		}
+
+		block, err := h.service.UpdateBlock(ctx, yard, code, req, version)
+		if err != nil {
+			failStale(w, err, func() (interface{}, int, error) {
+				current, err := h.service.GetBlock(ctx, yard, code)
+				if err != nil {
+					return nil, 0, err
+				}
+				return current, current.Version, nil
+			})
+			return
+		}
+
+		setETag(w, block.Version)
+		response.Success(w, block)
+
+	default:
+		response.Fail(w, methodNotAllowed(r))
+	}
+}
+
+// HandlePlans handles GET /plans?yard=...&block=..., GET /plans?id=...,
+// POST /plans, PUT /plans?id=... and DELETE /plans?id=...
+func (h *PlanningHandler) HandlePlans(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Has("id") {
+			h.handleGetPlan(w, r)
+		} else {
+			h.handleListPlans(w, r)
+		}
+	case http.MethodPost:
+		h.handleCreatePlan(w, r)
+	case http.MethodPut:
+		h.handleUpdatePlan(w, r)
+	case http.MethodDelete:
+		h.handleDeletePlan(w, r)
+	default:
+		response.Fail(w, methodNotAllowed(r))
+	}
+}
+
+func (h *PlanningHandler) handleListPlans(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	yard := r.URL.Query().Get("yard")
+	block := r.URL.Query().Get("block")
+	if err := requireQuery("yard", yard, "block", block); err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	plans, err := h.service.ListPlans(ctx, yard, block)
+	if err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	response.Success(w, plans)
+}
+
+func (h *PlanningHandler) handleGetPlan(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := queryID(r)
+	if err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	plan, err := h.service.GetPlan(ctx, id)
+	if err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	setETag(w, plan.Version)
+	response.Success(w, plan)
+}
+
+func (h *PlanningHandler) handleCreatePlan(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var req model.YardPlanRequest
+	if err := decode(r, &req); err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	plan, err := h.service.CreatePlan(ctx, req)
+	if err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	setETag(w, plan.Version)
+	response.Created(w, plan)
+}
+
+func (h *PlanningHandler) handleUpdatePlan(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := queryID(r)
+	if err != nil {
+		response.Fail(w, err)
+		return
+	}
+	version, err := ifMatch(r)
+	if err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	var req model.YardPlanRequest
+	if err := decode(r, &req); err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	plan, err := h.service.UpdatePlan(ctx, id, req, version)
+	if err != nil {
+		failStale(w, err, h.currentPlan(r, id))
+		return
+	}
+
+	setETag(w, plan.Version)
+	response.Success(w, plan)
+}
+
+func (h *PlanningHandler) handleDeletePlan(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := queryID(r)
+	if err != nil {
+		response.Fail(w, err)
+		return
+	}
+	version, err := ifMatch(r)
+	if err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	if err := h.service.DeletePlan(ctx, id, version); err != nil {
+		failStale(w, err, h.currentPlan(r, id))
+		return
+	}
+
+	response.Success(w, map[string]interface{}{"deleted": id})
+}
+
+// currentPlan loads the current version of a plan for failStale
+func (h *PlanningHandler) currentPlan(r *http.Request, id int) func() (interface{}, int, error) {
+	return func() (interface{}, int, error) {
+		plan, err := h.service.GetPlan(r.Context(), id)
+		if err != nil {
+			return nil, 0, err
+		}
+		return plan, plan.Version, nil
+	}
+}
+
+// queryID reads the id query parameter of a yard plan or bulk job
+func queryID(r *http.Request) (int, error) {
+	idParam := r.URL.Query().Get("id")
+	if err := requireQuery("id", idParam); err != nil {
+		return 0, err
+	}
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return 0, apperror.Validation("id", "must be a number")
+	}
+	return id, nil
+}
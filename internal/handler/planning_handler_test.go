@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository/memory"
+	"github.com/dwipurnomo515/yard-planning/internal/service"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/dwipurnomo515/yard-planning/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPlanningHandler(t *testing.T) *PlanningHandler {
+	store := memory.NewStore()
+	require.NoError(t, store.Seed())
+	stores := store.Stores()
+	return NewPlanningHandler(service.NewPlanningService(stores.Yards, stores.Blocks, stores.Plans, stores.Containers))
+}
+
+// doVersioned sends a request with an optional If-Match header
+func doVersioned(handle http.HandlerFunc, method, target, ifMatch string, body interface{}) *httptest.ResponseRecorder {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+	r := httptest.NewRequest(method, target, bytes.NewReader(payload))
+	if ifMatch != "" {
+		r.Header.Set("If-Match", ifMatch)
+	}
+	rec := httptest.NewRecorder()
+	handle(rec, r)
+	return rec
+}
+
+func TestPlanningHandler_PlanETags(t *testing.T) {
+	h := newTestPlanningHandler(t)
+
+	rec := doVersioned(h.HandlePlans, http.MethodGet, "/plans?id=1", "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"1"`, rec.Header().Get("ETag"))
+
+	update := model.YardPlanRequest{
+		Yard: "YRD1", Block: "LC01", SlotStart: 1, SlotEnd: 2, RowStart: 1, RowEnd: 5,
+		ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY",
+	}
+
+	// Writes need If-Match
+	rec = doVersioned(h.HandlePlans, http.MethodPut, "/plans?id=1", "", update)
+	assert.Equal(t, http.StatusPreconditionRequired, rec.Code)
+	rec = doVersioned(h.HandlePlans, http.MethodPut, "/plans?id=1", "1", update)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// The first planner wins, the second gets the current plan to merge with
+	rec = doVersioned(h.HandlePlans, http.MethodPut, "/plans?id=1", `"1"`, update)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+
+	update.RowEnd = 3
+	rec = doVersioned(h.HandlePlans, http.MethodPut, "/plans?id=1", `"1"`, update)
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+
+	var stale struct {
+		response.ErrorResponse
+		Current model.YardPlan `json:"current"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stale))
+	assert.Equal(t, apperror.CodePreconditionFailed, stale.Code)
+	assert.Equal(t, 2, stale.Current.Version)
+	assert.Equal(t, 2, stale.Current.SlotEnd)
+	assert.Equal(t, 5, stale.Current.RowEnd)
+
+	rec = doVersioned(h.HandlePlans, http.MethodDelete, "/plans?id=1", `"1"`, nil)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	rec = doVersioned(h.HandlePlans, http.MethodDelete, "/plans?id=1", `"2"`, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = doVersioned(h.HandlePlans, http.MethodGet, "/plans?id=1", "", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Plans must fit in the block
+	update.SlotEnd = 11
+	rec = doVersioned(h.HandlePlans, http.MethodPost, "/plans", "", update)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestPlanningHandler_BlockETags(t *testing.T) {
+	h := newTestPlanningHandler(t)
+
+	rec := doVersioned(h.HandleBlocks, http.MethodGet, "/blocks?yard=YRD1&block=LC01", "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"1"`, rec.Header().Get("ETag"))
+	var block model.Block
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &block))
+
+	req := model.BlockRequest{
+		Name: block.Name, MaxSlot: 12, MaxRow: block.MaxRow, MaxTier: block.MaxTier,
+		BlockGeometry: block.BlockGeometry,
+	}
+	rec = doVersioned(h.HandleBlocks, http.MethodPut, "/blocks?yard=YRD1&block=LC01", "*", req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+
+	// The seeded 40ft plan covers slot 7
+	req.MaxSlot = 6
+	rec = doVersioned(h.HandleBlocks, http.MethodPut, "/blocks?yard=YRD1&block=LC01", `"2"`, req)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = doVersioned(h.HandleBlocks, http.MethodPut, "/blocks?yard=YRD1&block=LC01", `"1"`, req)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"max_slot":12`)
+}
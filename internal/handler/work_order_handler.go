@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/service"
+	"github.com/dwipurnomo515/yard-planning/pkg/response"
+)
+
+type WorkOrderHandler struct {
+	service *service.WorkOrderService
+}
+
+func NewWorkOrderHandler(service *service.WorkOrderService) *WorkOrderHandler {
+	return &WorkOrderHandler{service: service}
+}
+
+// HandleJobs handles GET /jobs?yard=...
+func (h *WorkOrderHandler) HandleJobs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.Method != http.MethodGet {
+		response.Fail(w, methodNotAllowed(r))
+		return
+	}
+
+	yard := r.URL.Query().Get("yard")
+	if err := requireQuery("yard", yard); err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	jobs, err := h.service.ListPendingJobs(ctx, yard)
+	if err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	response.Success(w, jobs)
+}
+
+// HandleNextJob handles GET /jobs/next?yard=...&equipment=...
+func (h *WorkOrderHandler) HandleNextJob(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.Method != http.MethodGet {
+		response.Fail(w, methodNotAllowed(r))
+		return
+	}
+
+	yard := r.URL.Query().Get("yard")
+	equipment := r.URL.Query().Get("equipment")
+	if err := requireQuery("yard", yard, "equipment", equipment); err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	job, err := h.service.NextJob(ctx, yard, equipment)
+	if err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	response.Success(w, model.NextJobResponse{Job: job})
+}
+
+// HandleStartJob handles POST /jobs/start
+func (h *WorkOrderHandler) HandleStartJob(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.handleAction(w, r, func(req model.JobActionRequest) (*model.WorkOrder, error) {
+		return h.service.StartJob(ctx, req.ID)
+	})
+}
+
+// HandleConfirmJob handles POST /jobs/confirm
+func (h *WorkOrderHandler) HandleConfirmJob(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.handleAction(w, r, func(req model.JobActionRequest) (*model.WorkOrder, error) {
+		return h.service.ConfirmJob(ctx, req.ID)
+	})
+}
+
+// HandleFailJob handles POST /jobs/fail
+func (h *WorkOrderHandler) HandleFailJob(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	h.handleAction(w, r, func(req model.JobActionRequest) (*model.WorkOrder, error) {
+		return h.service.FailJob(ctx, req.ID, req.Reason)
+	})
+}
+
+func (h *WorkOrderHandler) handleAction(
+	w http.ResponseWriter,
+	r *http.Request,
+	action func(req model.JobActionRequest) (*model.WorkOrder, error),
+) {
+	if r.Method != http.MethodPost {
+		response.Fail(w, methodNotAllowed(r))
+		return
+	}
+
+	var req model.JobActionRequest
+	if err := decode(r, &req); err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	job, err := action(req)
+	if err != nil {
+		response.Fail(w, err)
+		return
+	}
+
+	response.Success(w, job)
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/service"
+	"github.com/dwipurnomo515/yard-planning/pkg/response"
+)
+
+type YardHandler struct {
+	service *service.YardService
+}
+
+func NewYardHandler(service *service.YardService) *YardHandler {
+	return &YardHandler{service: service}
+}
+
+// HandleOverflowRules handles GET /overflow-rules?yard=... and POST /overflow-rules
+func (h *YardHandler) HandleOverflowRules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	switch r.Method {
+	case http.MethodGet:
+		yard := r.URL.Query().Get("yard")
+		if err := requireQuery("yard", yard); err != nil {
+			response.Fail(w, err)
+			return
+		}
+
+		rules, err := h.service.ListOverflowRules(ctx, yard)
+		if err != nil {
+			response.Fail(w, err)
+			return
+		}
+
+		response.Success(w, rules)
+
+	case http.MethodPost:
+		var req model.OverflowRuleRequest
+		if err := decode(r, &req); err != nil {
+			response.Fail(w, err)
+			return
+		}
+
+		rule, err := h.service.CreateOverflowRule(ctx, req)
+		if err != nil {
+			response.Fail(w, err)
+			return
+		}
+
+		response.Created(w, rule)
+
+	default:
+		response.Fail(w, methodNotAllowed(r))
+	}
+}
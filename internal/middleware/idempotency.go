@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/auth"
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/dwipurnomo515/yard-planning/pkg/response"
+)
+
+// IdempotencyHeader is the request header that makes a POST safe to retry
+const IdempotencyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength is the longest key the idempotency_keys table stores
+const maxIdempotencyKeyLength = 255
+
+// Idempotency makes POST requests sent with an Idempotency-Key header safe to
+// retry. The first request is processed and its response stored; a retry with
+// the same key and body gets the stored response with the header
+// "Idempotent-Replayed: true", and the same key with another request fails
+// with IDEMPOTENCY_KEY_REUSED. Keys are scoped to the tenant and the caller
+// and expire after ttl. Server errors are not stored, so a request that failed
+// with one can be retried with the same key.
+func Idempotency(store repository.IdempotencyStore, ttl time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyHeader)
+		if key == "" || r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			response.Fail(w, apperror.New(apperror.CodeInvalidRequest,
+				"%s must be at most %d characters", IdempotencyHeader, maxIdempotencyKeyLength))
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			response.Fail(w, apperror.Wrap(apperror.CodeInvalidRequest, err, "error reading request body"))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := r.Context()
+		record := &model.IdempotencyRecord{
+			Subject:     auth.Actor(ctx),
+			Key:         key,
+			RequestHash: requestHash(r, body),
+		}
+		existing, err := reserve(ctx, store, record, ttl)
+		if err != nil {
+			response.Fail(w, err)
+			return
+		}
+
+		if existing != nil {
+			switch {
+			case existing.RequestHash != record.RequestHash:
+				response.Fail(w, apperror.New(apperror.CodeIdempotencyReused,
+					"%s %q was already used for a different request", IdempotencyHeader, key))
+			case !existing.IsComplete():
+				response.Fail(w, apperror.New(apperror.CodeConflict,
+					"a request with %s %q is still being processed", IdempotencyHeader, key))
+			default:
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(existing.StatusCode)
+				w.Write(existing.Response)
+			}
+			return
+		}
+
+		rec := &recordingWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		// The response has been sent, so it is stored even if the request was
+		// cancelled in the meantime
+		ctx = context.WithoutCancel(ctx)
+		if rec.statusCode >= http.StatusInternalServerError {
+			err = store.Delete(ctx, record.ID)
+		} else {
+			err = store.Complete(ctx, record.ID, rec.statusCode, rec.body.Bytes())
+		}
+		if err != nil {
+			log.Printf("Failed to store response for %s %q: %v", IdempotencyHeader, key, err)
+		}
+	})
+}
+
+// reserve records the request, or returns the record of an earlier request
+// with the same key. Expired records are replaced.
+func reserve(ctx context.Context, store repository.IdempotencyStore, record *model.IdempotencyRecord, ttl time.Duration) (*model.IdempotencyRecord, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		err := store.Reserve(ctx, record)
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.Is(err, repository.ErrDuplicate) {
+			return nil, err
+		}
+
+		existing, err := store.Get(ctx, record.Subject, record.Key)
+		if apperror.CodeOf(err) == apperror.CodeNotFound {
+			// Removed after a server error in the meantime
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ttl > 0 && time.Since(existing.CreatedAt) > ttl {
+			if err := store.Delete(ctx, existing.ID); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return existing, nil
+	}
+
+	return nil, apperror.New(apperror.CodeConflict,
+		"a request with %s %q is still being processed", IdempotencyHeader, record.Key)
+}
+
+// requestHash identifies a request by its method, URI and body
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter passes a response through and keeps a copy of it
+type recordingWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	rw.statusCode = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(data []byte) (int, error) {
+	rw.body.Write(data)
+	return rw.ResponseWriter.Write(data)
+}
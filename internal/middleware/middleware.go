@@ -1,9 +1,17 @@
 package middleware
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/auth"
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/dwipurnomo515/yard-planning/pkg/response"
 )
 
 // Logger middleware logs HTTP requests
@@ -12,16 +20,17 @@ func Logger(next http.Handler) http.Handler {
 		start := time.Now()
 
 		// Create a response writer wrapper to capture status code
-		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK, actor: "-"}
 
 		next.ServeHTTP(rw, r)
 
 		log.Printf(
-			"%s %s %d %s",
+			"%s %s %d %s %s",
 			r.Method,
 			r.RequestURI,
 			rw.statusCode,
 			time.Since(start),
+			rw.actor,
 		)
 	})
 }
@@ -32,7 +41,7 @@ func Recovery(next http.Handler) http.Handler {
 		defer func() {
 			if err := recover(); err != nil {
 				log.Printf("panic: %v", err)
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				response.Fail(w, apperror.New(apperror.CodeInternal, "internal error"))
 			}
 		}()
 
@@ -40,12 +49,31 @@ func Recovery(next http.Handler) http.Handler {
 	})
 }
 
-// CORS middleware adds CORS headers
-func CORS(next http.Handler) http.Handler {
+// CORS middleware adds CORS headers for the allowed origins. An entry "*"
+// allows every origin; otherwise the Origin of the request is echoed when it
+// is in the list.
+func CORS(allowedOrigins []string, next http.Handler) http.Handler {
+	allowAll := false
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowed[origin] = true
+	}
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
+		origin := r.Header.Get("Origin")
+		switch {
+		case allowAll:
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		case origin != "" && allowed[origin]:
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		w.Header().Add("Vary", "Origin")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, Idempotency-Key, If-Match")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag")
 
 		// Handle preflight requests
 		if r.Method == http.MethodOptions {
@@ -57,13 +85,95 @@ func CORS(next http.Handler) http.Handler {
 	})
 }
 
+// Auth rejects requests without valid credentials and attaches the identity
+// of the caller to the request context. Preflight requests and the public
+// paths, e.g. the health check, are not authenticated.
+func Auth(authenticator *auth.Authenticator, publicPaths []string, next http.Handler) http.Handler {
+	public := make(map[string]bool, len(publicPaths))
+	for _, path := range publicPaths {
+		public[path] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions || public[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		identity, err := authenticator.Authenticate(r)
+		if err != nil {
+			if apperror.CodeOf(err) == apperror.CodeUnauthorized {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="yard-planning"`)
+			}
+			response.Fail(w, err)
+			return
+		}
+
+		// Every repository call of the request is scoped to the caller's tenant
+		ctx := auth.WithIdentity(r.Context(), identity)
+		ctx = tenant.WithTenant(ctx, identity.Tenant)
+
+		// Let the request log show who made the request
+		if rw, ok := w.(*responseWriter); ok {
+			rw.actor = auth.Actor(ctx)
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// LoadGrants attaches the permissions of the authenticated caller, taken from
+// their role bindings, to the request context. It must run after Auth; a
+// request without identity gets no permissions at all.
+func LoadGrants(roles repository.RoleBindingStore, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subject := ""
+		var bindings []model.RoleBinding
+		if _, ok := auth.FromContext(r.Context()); ok {
+			subject = auth.Actor(r.Context())
+			var err error
+			if bindings, err = roles.GetBySubject(r.Context(), subject); err != nil {
+				response.Fail(w, err)
+				return
+			}
+		}
+
+		ctx := auth.WithGrants(r.Context(), auth.NewGrants(subject, bindings))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Require rejects callers that do not have the permission in any yard. Reads
+// (GET) need the read permission, every other method the write permission.
+// Services check the permission again for the yard of each request or item.
+func Require(read, write auth.Permission, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		grants, ok := auth.GrantsFromContext(r.Context())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		perm := write
+		if r.Method == http.MethodGet {
+			perm = read
+		}
+		if !grants.AllowsAnywhere(perm) {
+			response.Fail(w, auth.Authorize(r.Context(), perm, model.AllYards))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 // ContentType middleware ensures requests have correct content type
 func ContentType(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost || r.Method == http.MethodPut {
 			contentType := r.Header.Get("Content-Type")
 			if contentType != "application/json" {
-				http.Error(w, "Content-Type must be application/json", http.StatusUnsupportedMediaType)
+				response.Fail(w, apperror.New(apperror.CodeUnsupportedMedia, "Content-Type must be application/json"))
 				return
 			}
 		}
@@ -72,10 +182,25 @@ func ContentType(next http.Handler) http.Handler {
 	})
 }
 
+// Timeout cancels the request context after the given duration, which stops
+// the database and cache work of the handler. Zero means no timeout.
+func Timeout(timeout time.Duration, next http.Handler) http.Handler {
+	if timeout <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 // responseWriter wraps http.ResponseWriter to capture status code
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	actor      string
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/auth"
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository/memory"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/dwipurnomo515/yard-planning/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuth(t *testing.T) {
+	keys := memory.NewStore().Stores().APIKeys
+	plain, key, err := auth.NewAPIKey("gate-system", nil)
+	require.NoError(t, err)
+	require.NoError(t, keys.Create(context.Background(), key))
+
+	var actor, scope string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor = auth.Actor(r.Context())
+		scope = tenant.FromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	h := Auth(auth.NewAuthenticator(keys, nil), []string{"/health"}, next)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		status int
+		actor  string
+	}{
+		{"valid key", http.MethodPost, "/placement", plain, http.StatusOK, "api_key:gate-system"},
+		{"missing key", http.MethodPost, "/placement", "", http.StatusUnauthorized, ""},
+		{"wrong key", http.MethodPost, "/placement", "yk_wrong", http.StatusUnauthorized, ""},
+		{"health is public", http.MethodGet, "/health", "", http.StatusOK, "anonymous"},
+		{"preflight", http.MethodOptions, "/placement", "", http.StatusOK, "anonymous"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actor = ""
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.key != "" {
+				r.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.actor, actor)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, tenant.Default, scope)
+			}
+			if tt.status == http.StatusUnauthorized {
+				assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+				assert.Contains(t, w.Body.String(), `"code":"UNAUTHORIZED"`)
+			}
+		})
+	}
+}
+
+func TestCORS(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	request := func(h http.Handler, origin string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/layout", nil)
+		r.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	h := CORS([]string{"https://planner.example.com"}, next)
+	w := request(h, "https://planner.example.com")
+	assert.Equal(t, "https://planner.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+
+	w = request(h, "https://evil.example.com")
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = request(CORS([]string{"*"}, next), "https://any.example.com")
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestLoadGrantsAndRequire(t *testing.T) {
+	store := memory.NewStore().Stores()
+	ctx := context.Background()
+	require.NoError(t, store.Roles.Create(ctx, &model.RoleBinding{
+		Subject: "api_key:gate-system", Role: model.RoleGateClerk, Yard: "YRD1",
+	}))
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	closures := LoadGrants(store.Roles, Require(auth.PermView, auth.PermPlan, ok))
+
+	tests := []struct {
+		name     string
+		identity *auth.Identity
+		method   string
+		status   int
+	}{
+		{"clerk reads", &auth.Identity{Subject: "gate-system", Method: auth.MethodAPIKey}, http.MethodGet, http.StatusOK},
+		{"clerk cannot plan", &auth.Identity{Subject: "gate-system", Method: auth.MethodAPIKey}, http.MethodPost, http.StatusForbidden},
+		{"caller without bindings", &auth.Identity{Subject: "unknown", Method: auth.MethodJWT}, http.MethodGet, http.StatusForbidden},
+		{"no identity", nil, http.MethodGet, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/closures", nil)
+			if tt.identity != nil {
+				r = r.WithContext(auth.WithIdentity(r.Context(), *tt.identity))
+			}
+			w := httptest.NewRecorder()
+			closures.ServeHTTP(w, r)
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+
+	// Supervisor-only routes refuse an equipment operator before any handler runs
+	require.NoError(t, store.Roles.Create(ctx, &model.RoleBinding{
+		Subject: "jwt:rtg-driver", Role: model.RoleEquipmentOperator, Yard: "YRD1",
+	}))
+	operator := auth.Identity{Subject: "rtg-driver", Method: auth.MethodJWT}
+	for path, perm := range map[string]auth.Permission{
+		"/move":          auth.PermMove,
+		"/holds/release": auth.PermOverrideHold,
+	} {
+		r := httptest.NewRequest(http.MethodPost, path, nil)
+		r = r.WithContext(auth.WithIdentity(r.Context(), operator))
+		w := httptest.NewRecorder()
+		LoadGrants(store.Roles, Require(perm, perm, ok)).ServeHTTP(w, r)
+		assert.Equal(t, http.StatusForbidden, w.Code, path)
+	}
+
+	// Without LoadGrants, i.e. with authentication disabled, nothing is checked
+	w := httptest.NewRecorder()
+	Require(auth.PermAdmin, auth.PermAdmin, ok).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/role-bindings", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestIdempotency(t *testing.T) {
+	store := memory.NewStore().Stores().Idempotency
+	calls := 0
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/fails" {
+			response.Fail(w, apperror.New(apperror.CodeInternal, "database unavailable"))
+			return
+		}
+		response.Created(w, map[string]int{"call": calls})
+	})
+	h := Idempotency(store, time.Hour, next)
+
+	send := func(path, key, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if key != "" {
+			r.Header.Set(IdempotencyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	first := send("/placement", "gate-42", `{"container_number":"ABCU1234560"}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+
+	replay := send("/placement", "gate-42", `{"container_number":"ABCU1234560"}`)
+	assert.Equal(t, http.StatusCreated, replay.Code)
+	assert.Equal(t, "true", replay.Header().Get("Idempotent-Replayed"))
+	assert.JSONEq(t, first.Body.String(), replay.Body.String())
+	assert.Equal(t, 1, calls)
+
+	reused := send("/placement", "gate-42", `{"container_number":"MSCU7654321"}`)
+	assert.Equal(t, http.StatusConflict, reused.Code)
+	assert.Contains(t, reused.Body.String(), `"code":"IDEMPOTENCY_KEY_REUSED"`)
+
+	reused = send("/pickup", "gate-42", `{"container_number":"ABCU1234560"}`)
+	assert.Equal(t, http.StatusConflict, reused.Code, "the key is bound to the endpoint too")
+
+	send("/placement", "", `{"container_number":"ABCU1234560"}`)
+	assert.Equal(t, 2, calls, "requests without a key are always processed")
+
+	// Server errors are not stored, so the request can be retried
+	assert.Equal(t, http.StatusInternalServerError, send("/fails", "gate-43", `{}`).Code)
+	assert.Equal(t, http.StatusInternalServerError, send("/fails", "gate-43", `{}`).Code)
+	assert.Equal(t, 4, calls)
+
+	// A request that is still being processed
+	require.NoError(t, store.Reserve(context.Background(), &model.IdempotencyRecord{
+		Subject: auth.Actor(context.Background()), Key: "gate-44",
+		RequestHash: requestHash(httptest.NewRequest(http.MethodPost, "/placement", nil), []byte(`{}`)),
+	}))
+	pending := send("/placement", "gate-44", `{}`)
+	assert.Equal(t, http.StatusConflict, pending.Code)
+	assert.Contains(t, pending.Body.String(), `"code":"CONFLICT"`)
+}
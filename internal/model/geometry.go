@@ -0,0 +1,91 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// Container movements used to pick the point of interest a box should be close to
+const (
+	MovementImport = "IMPORT"
+	MovementExport = "EXPORT"
+)
+
+// Yard point types
+const (
+	PointTypeGate  = "GATE"
+	PointTypeBerth = "BERTH"
+	PointTypeRail  = "RAIL"
+)
+
+// Point is a location in the yard coordinate system, in meters
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// BlockGeometry places a block in the yard. The origin is the outer corner of
+// slot 1 / row 1, slots run along the orientation angle (degrees, counter
+// clockwise from the x axis) and rows run perpendicular to it.
+type BlockGeometry struct {
+	OriginX     float64 `json:"origin_x"`
+	OriginY     float64 `json:"origin_y"`
+	Orientation float64 `json:"orientation"`
+	SlotPitch   float64 `json:"slot_pitch"`
+	RowPitch    float64 `json:"row_pitch"`
+}
+
+// CellCenter returns the yard coordinates of the center of a container of the
+// given size standing at slot/row
+func (g BlockGeometry) CellCenter(slot, row, containerSize int) Point {
+	slots := float64(containerSize / 20)
+	along := (float64(slot-1) + slots/2) * g.SlotPitch
+	across := (float64(row) - 0.5) * g.RowPitch
+
+	rad := g.Orientation * math.Pi / 180
+	sin, cos := math.Sin(rad), math.Cos(rad)
+
+	return Point{
+		X: g.OriginX + along*cos - across*sin,
+		Y: g.OriginY + along*sin + across*cos,
+	}
+}
+
+// Distance returns the travel distance between two points. Yard traffic follows
+// the driving lanes of the grid, so the rectilinear distance is used.
+func Distance(a, b Point) float64 {
+	return math.Abs(a.X-b.X) + math.Abs(a.Y-b.Y)
+}
+
+// YardPoint is a named point of interest in a yard (gate, berth, rail head)
+type YardPoint struct {
+	ID        int       `json:"id"`
+	YardID    int       `json:"yard_id"`
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	PointType string    `json:"point_type"`
+	X         float64   `json:"x"`
+	Y         float64   `json:"y"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Location returns the coordinates of the point
+func (p YardPoint) Location() Point {
+	return Point{X: p.X, Y: p.Y}
+}
+
+// YardLayout describes the physical layout of a yard
+type YardLayout struct {
+	Yard   string      `json:"yard"`
+	Blocks []Block     `json:"blocks"`
+	Points []YardPoint `json:"points"`
+}
+
+type YardPointRequest struct {
+	Yard      string  `json:"yard"`
+	Code      string  `json:"code"`
+	Name      string  `json:"name"`
+	PointType string  `json:"point_type"`
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+}
@@ -8,19 +8,22 @@ type Yard struct {
 	Code        string    `json:"code"`
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
+	Tenant      string    `json:"-"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 // Block represents a storage block in a yard
 type Block struct {
-	ID        int       `json:"id"`
-	YardID    int       `json:"yard_id"`
-	Code      string    `json:"code"`
-	Name      string    `json:"name"`
-	MaxSlot   int       `json:"max_slot"`
-	MaxRow    int       `json:"max_row"`
-	MaxTier   int       `json:"max_tier"`
+	ID      int    `json:"id"`
+	YardID  int    `json:"yard_id"`
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	MaxSlot int    `json:"max_slot"`
+	MaxRow  int    `json:"max_row"`
+	MaxTier int    `json:"max_tier"`
+	BlockGeometry
+	Version   int       `json:"version"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -37,10 +40,34 @@ type YardPlan struct {
 	ContainerHeight  float64   `json:"container_height"`
 	ContainerType    string    `json:"container_type"`
 	StackingPriority string    `json:"stacking_priority"`
+	Version          int       `json:"version"`
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
 }
 
+// BlockRequest replaces the name, dimensions and geometry of a block
+type BlockRequest struct {
+	Name    string `json:"name"`
+	MaxSlot int    `json:"max_slot"`
+	MaxRow  int    `json:"max_row"`
+	MaxTier int    `json:"max_tier"`
+	BlockGeometry
+}
+
+// YardPlanRequest creates a yard plan or replaces one
+type YardPlanRequest struct {
+	Yard             string  `json:"yard"`
+	Block            string  `json:"block"`
+	SlotStart        int     `json:"slot_start"`
+	SlotEnd          int     `json:"slot_end"`
+	RowStart         int     `json:"row_start"`
+	RowEnd           int     `json:"row_end"`
+	ContainerSize    int     `json:"container_size"`
+	ContainerHeight  float64 `json:"container_height"`
+	ContainerType    string  `json:"container_type"`
+	StackingPriority string  `json:"stacking_priority,omitempty"`
+}
+
 // Container represents a physical container in the yard
 type Container struct {
 	ID              int       `json:"id"`
@@ -53,7 +80,11 @@ type Container struct {
 	ContainerSize   int       `json:"container_size"`
 	ContainerHeight float64   `json:"container_height"`
 	ContainerType   string    `json:"container_type"`
+	Tenant          string    `json:"-"`
 	PlacedAt        time.Time `json:"placed_at"`
+	// Hold is the reason the container is held; held containers are only
+	// picked up when a supervisor overrides the hold
+	Hold string `json:"hold,omitempty"`
 }
 
 // Position represents a container position
@@ -71,10 +102,25 @@ type SuggestionRequest struct {
 	ContainerSize   int     `json:"container_size"`
 	ContainerHeight float64 `json:"container_height"`
 	ContainerType   string  `json:"container_type"`
+	// Movement is IMPORT (leaves through the gate) or EXPORT (loaded at Berth)
+	Movement string `json:"movement,omitempty"`
+	Berth    string `json:"berth,omitempty"`
+}
+
+// Suggestion is a suggested position together with the yard it was found in
+// and why that yard was chosen
+type Suggestion struct {
+	Position Position `json:"position"`
+	Yard     string   `json:"yard"`
+	Overflow bool     `json:"overflow"`
+	Reason   string   `json:"reason"`
 }
 
 type SuggestionResponse struct {
 	SuggestedPosition Position `json:"suggested_position"`
+	Yard              string   `json:"yard"`
+	Overflow          bool     `json:"overflow"`
+	Reason            string   `json:"reason"`
 }
 
 type PlacementRequest struct {
@@ -93,8 +139,360 @@ type PlacementResponse struct {
 type PickupRequest struct {
 	Yard            string `json:"yard"`
 	ContainerNumber string `json:"container_number"`
+	Destination     string `json:"destination,omitempty"`
+	// OverrideHold picks up a held container; only supervisors may set it
+	OverrideHold bool `json:"override_hold,omitempty"`
+}
+
+type MoveRequest struct {
+	Yard            string `json:"yard"`
+	ContainerNumber string `json:"container_number"`
+	Block           string `json:"block"`
+	Slot            int    `json:"slot"`
+	Row             int    `json:"row"`
+	Tier            int    `json:"tier"`
+	Rehandle        bool   `json:"rehandle,omitempty"`
+}
+
+type MoveResponse struct {
+	Message string `json:"message"`
 }
 
 type PickupResponse struct {
 	Message string `json:"message"`
 }
+
+// HoldRequest puts a container on hold (customs, damage, dispute) or, on
+// release, lifts its hold
+type HoldRequest struct {
+	Yard            string `json:"yard"`
+	ContainerNumber string `json:"container_number"`
+	Reason          string `json:"reason,omitempty"`
+}
+
+type HoldResponse struct {
+	Message string `json:"message"`
+}
+
+// OverflowRule routes suggestions to another yard when a yard has no matching capacity.
+// Rules of a yard are tried in ascending priority.
+type OverflowRule struct {
+	ID             int       `json:"id"`
+	YardID         int       `json:"yard_id"`
+	OverflowYardID int       `json:"overflow_yard_id"`
+	OverflowYard   string    `json:"overflow_yard"`
+	Priority       int       `json:"priority"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type OverflowRuleRequest struct {
+	Yard         string `json:"yard"`
+	OverflowYard string `json:"overflow_yard"`
+	Priority     int    `json:"priority"`
+}
+
+// BlockClosure represents a period during which part of a yard cannot receive
+// containers (crane repair, pavement works, ...). A nil BlockID closes the
+// whole yard, nil slot/row ranges close the whole block.
+type BlockClosure struct {
+	ID        int       `json:"id"`
+	YardID    int       `json:"yard_id"`
+	BlockID   *int      `json:"block_id,omitempty"`
+	BlockCode string    `json:"block,omitempty"`
+	SlotStart *int      `json:"slot_start,omitempty"`
+	SlotEnd   *int      `json:"slot_end,omitempty"`
+	RowStart  *int      `json:"row_start,omitempty"`
+	RowEnd    *int      `json:"row_end,omitempty"`
+	Reason    string    `json:"reason"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsActiveAt reports whether the closure is in effect at the given time
+func (c BlockClosure) IsActiveAt(t time.Time) bool {
+	return !t.Before(c.StartsAt) && t.Before(c.EndsAt)
+}
+
+// Covers reports whether the closure includes the given cell
+func (c BlockClosure) Covers(blockID, slot, row int) bool {
+	if c.BlockID == nil {
+		return true
+	}
+	if *c.BlockID != blockID {
+		return false
+	}
+	if c.SlotStart != nil && (slot < *c.SlotStart || slot > *c.SlotEnd) {
+		return false
+	}
+	if c.RowStart != nil && (row < *c.RowStart || row > *c.RowEnd) {
+		return false
+	}
+	return true
+}
+
+type ClosureRequest struct {
+	Yard      string    `json:"yard"`
+	Block     string    `json:"block,omitempty"`
+	SlotStart *int      `json:"slot_start,omitempty"`
+	SlotEnd   *int      `json:"slot_end,omitempty"`
+	RowStart  *int      `json:"row_start,omitempty"`
+	RowEnd    *int      `json:"row_end,omitempty"`
+	Reason    string    `json:"reason"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+}
+
+type ClosureListResponse struct {
+	Active   []BlockClosure `json:"active"`
+	Upcoming []BlockClosure `json:"upcoming"`
+}
+
+// Equipment types
+const (
+	EquipmentTypeRTG          = "RTG"
+	EquipmentTypeRMG          = "RMG"
+	EquipmentTypeReachStacker = "REACH_STACKER"
+)
+
+// Equipment represents a yard machine that performs container moves
+type Equipment struct {
+	ID                int       `json:"id"`
+	YardID            int       `json:"yard_id"`
+	Code              string    `json:"code"`
+	EquipmentType     string    `json:"equipment_type"`
+	Active            bool      `json:"active"`
+	BlockIDs          []int     `json:"-"`
+	Blocks            []string  `json:"blocks"`
+	OutstandingOrders int       `json:"outstanding_work_orders"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// Serves reports whether the equipment works the given block
+func (e Equipment) Serves(blockID int) bool {
+	for _, id := range e.BlockIDs {
+		if id == blockID {
+			return true
+		}
+	}
+	return false
+}
+
+// Work order operations
+const (
+	WorkOrderPlacement = "PLACEMENT"
+	WorkOrderPickup    = "PICKUP"
+	WorkOrderMove      = "MOVE"
+	WorkOrderRehandle  = "REHANDLE"
+)
+
+// Work order statuses: CREATED -> DISPATCHED -> IN_PROGRESS -> COMPLETED/FAILED
+const (
+	WorkOrderStatusCreated    = "CREATED"
+	WorkOrderStatusDispatched = "DISPATCHED"
+	WorkOrderStatusInProgress = "IN_PROGRESS"
+	WorkOrderStatusCompleted  = "COMPLETED"
+	WorkOrderStatusFailed     = "FAILED"
+)
+
+// workOrderTransitions lists the statuses each status may move to
+var workOrderTransitions = map[string][]string{
+	WorkOrderStatusCreated:    {WorkOrderStatusDispatched, WorkOrderStatusFailed},
+	WorkOrderStatusDispatched: {WorkOrderStatusInProgress, WorkOrderStatusCompleted, WorkOrderStatusFailed},
+	WorkOrderStatusInProgress: {WorkOrderStatusCompleted, WorkOrderStatusFailed},
+}
+
+// CanTransitionWorkOrder reports whether a work order may move from one status to another
+func CanTransitionWorkOrder(from, to string) bool {
+	for _, s := range workOrderTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkOrderLocation is a cell a work order picks a container from or puts it to
+type WorkOrderLocation struct {
+	BlockID int    `json:"block_id"`
+	Block   string `json:"block"`
+	Slot    int    `json:"slot"`
+	Row     int    `json:"row"`
+	Tier    int    `json:"tier"`
+}
+
+// WorkOrder represents a container move to be performed by a piece of equipment.
+// From is empty for placements and To is empty for pickups.
+type WorkOrder struct {
+	ID              int                `json:"id"`
+	YardID          int                `json:"yard_id"`
+	EquipmentID     *int               `json:"equipment_id,omitempty"`
+	EquipmentCode   string             `json:"equipment,omitempty"`
+	ContainerNumber string             `json:"container_number"`
+	ContainerSize   int                `json:"container_size"`
+	ContainerHeight float64            `json:"container_height"`
+	ContainerType   string             `json:"container_type"`
+	Operation       string             `json:"operation"`
+	From            *WorkOrderLocation `json:"from,omitempty"`
+	To              *WorkOrderLocation `json:"to,omitempty"`
+	Destination     string             `json:"destination,omitempty"`
+	PositionApplied bool               `json:"position_applied"`
+	Status          string             `json:"status"`
+	FailureReason   string             `json:"failure_reason,omitempty"`
+	CreatedAt       time.Time          `json:"created_at"`
+	DispatchedAt    *time.Time         `json:"dispatched_at,omitempty"`
+	StartedAt       *time.Time         `json:"started_at,omitempty"`
+	CompletedAt     *time.Time         `json:"completed_at,omitempty"`
+}
+
+// IsPending reports whether the work order still has to be performed
+func (o WorkOrder) IsPending() bool {
+	return o.Status != WorkOrderStatusCompleted && o.Status != WorkOrderStatusFailed
+}
+
+type EquipmentRequest struct {
+	Yard          string   `json:"yard"`
+	Code          string   `json:"code"`
+	EquipmentType string   `json:"equipment_type"`
+	Blocks        []string `json:"blocks"`
+}
+
+type JobActionRequest struct {
+	ID     int    `json:"id"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type NextJobResponse struct {
+	Job *WorkOrder `json:"job"`
+}
+
+// APIKey is a static API key. Only the SHA-256 hash of the key is stored; the
+// prefix identifies the key in listings without revealing it.
+type APIKey struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Hash      string     `json:"-"`
+	Tenant    string     `json:"tenant"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// IsActive reports whether the key can still be used at the given time
+func (k APIKey) IsActive(at time.Time) bool {
+	return k.ExpiresAt == nil || at.Before(*k.ExpiresAt)
+}
+
+// Roles that can be bound to a caller
+const (
+	RoleGateClerk         = "GATE_CLERK"
+	RoleYardPlanner       = "YARD_PLANNER"
+	RoleEquipmentOperator = "EQUIPMENT_OPERATOR"
+	RoleSupervisor        = "SUPERVISOR"
+	RoleAdmin             = "ADMIN"
+)
+
+// AllYards is the yard code of a role binding that applies to every yard
+const AllYards = "*"
+
+// RoleBinding grants a role to a caller in one yard or, with AllYards, in all of them
+type RoleBinding struct {
+	ID        int       `json:"id"`
+	Subject   string    `json:"subject"`
+	Role      string    `json:"role"`
+	Yard      string    `json:"yard"`
+	Tenant    string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RoleBindingRequest struct {
+	Subject string `json:"subject"`
+	Role    string `json:"role"`
+	Yard    string `json:"yard"`
+}
+
+// IdempotencyRecord is the stored response of a request sent with an
+// Idempotency-Key header. StatusCode is 0 while the request is processed.
+type IdempotencyRecord struct {
+	ID          int
+	Tenant      string
+	Subject     string
+	Key         string
+	RequestHash string
+	StatusCode  int
+	Response    []byte
+	CreatedAt   time.Time
+}
+
+// IsComplete reports whether the response of the request has been stored
+func (r IdempotencyRecord) IsComplete() bool {
+	return r.StatusCode != 0
+}
+
+// Bulk job kinds
+const (
+	BulkJobSuggestion = "SUGGESTION"
+	BulkJobPlacement  = "PLACEMENT"
+	BulkJobMove       = "MOVE"
+)
+
+// Bulk job statuses
+const (
+	BulkJobQueued    = "QUEUED"
+	BulkJobRunning   = "RUNNING"
+	BulkJobCompleted = "COMPLETED"
+	BulkJobFailed    = "FAILED"
+	BulkJobCancelled = "CANCELLED"
+)
+
+// BulkJobRequest starts a bulk job. Only the list matching the kind is set.
+type BulkJobRequest struct {
+	Kind        string              `json:"kind"`
+	Suggestions []SuggestionRequest `json:"suggestions,omitempty"`
+	Placements  []PlacementRequest  `json:"placements,omitempty"`
+	Moves       []MoveRequest       `json:"moves,omitempty"`
+}
+
+// Size returns the number of items of the request
+func (r BulkJobRequest) Size() int {
+	return len(r.Suggestions) + len(r.Placements) + len(r.Moves)
+}
+
+// BulkJob is a bulk suggestion, placement or move processed in the
+// background. Processed, Succeeded and Failed count the items that have a
+// result so far; Results is only filled when a job is read with its results.
+type BulkJob struct {
+	ID        int             `json:"id"`
+	Tenant    string          `json:"-"`
+	Subject   string          `json:"-"`
+	Kind      string          `json:"kind"`
+	Status    string          `json:"status"`
+	Request   []byte          `json:"-"`
+	Total     int             `json:"total"`
+	Processed int             `json:"processed"`
+	Succeeded int             `json:"succeeded"`
+	Failed    int             `json:"failed"`
+	Error     string          `json:"error,omitempty"`
+	Results   []BulkJobResult `json:"results,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// IsFinished reports whether a job will not process any more items
+func (j BulkJob) IsFinished() bool {
+	return j.Status == BulkJobCompleted || j.Status == BulkJobFailed || j.Status == BulkJobCancelled
+}
+
+// BulkJobResult is the outcome of one item of a bulk job. Index is the
+// position of the item in the request.
+type BulkJobResult struct {
+	Index           int       `json:"index"`
+	ContainerNumber string    `json:"container_number"`
+	Success         bool      `json:"success"`
+	Yard            string    `json:"yard,omitempty"`
+	Position        *Position `json:"position,omitempty"`
+	Overflow        bool      `json:"overflow,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	Code            string    `json:"code,omitempty"`
+}
@@ -0,0 +1,299 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+// Validatable is a request DTO that checks its own fields
+type Validatable interface {
+	Validate(v *Validator)
+}
+
+// Validate runs the checks of a request and returns every violation as one
+// VALIDATION_FAILED error, or nil when the request is valid
+func Validate(req Validatable) error {
+	v := NewValidator()
+	req.Validate(v)
+	return v.Err()
+}
+
+// Validator collects field violations. Field names are JSON paths relative
+// to the validator, e.g. "containers[2].container_size" for a nested one.
+type Validator struct {
+	path       string
+	violations *[]apperror.FieldError
+}
+
+// NewValidator creates an empty validator for a request body
+func NewValidator() *Validator {
+	return &Validator{violations: &[]apperror.FieldError{}}
+}
+
+// At returns a validator for a nested object or array element that reports
+// into the same list, e.g. v.At("containers[2]")
+func (v *Validator) At(path string) *Validator {
+	return &Validator{path: v.field(path), violations: v.violations}
+}
+
+// Fail records a violation of a field
+func (v *Validator) Fail(field, message string) {
+	*v.violations = append(*v.violations, apperror.FieldError{Field: v.field(field), Message: message})
+}
+
+// Check records a violation when ok is false
+func (v *Validator) Check(ok bool, field, message string) {
+	if !ok {
+		v.Fail(field, message)
+	}
+}
+
+// Required checks that a string field is set
+func (v *Validator) Required(field, value string) {
+	v.Check(strings.TrimSpace(value) != "", field, "is required")
+}
+
+// OneOf checks that a string field has one of the allowed values. An empty
+// value is only accepted when the field is optional.
+func (v *Validator) OneOf(field, value string, optional bool, allowed ...string) {
+	if value == "" && optional {
+		return
+	}
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.Fail(field, "must be one of "+strings.Join(allowed, ", "))
+}
+
+// Min checks that an integer field is at least min
+func (v *Validator) Min(field string, value, min int) {
+	v.Check(value >= min, field, fmt.Sprintf("must be at least %d", min))
+}
+
+// ContainerNumber checks an ISO 6346 container number: three letters of the
+// owner code, the category U, J or Z, six digits and the check digit
+func (v *Validator) ContainerNumber(field, value string) {
+	if value == "" {
+		v.Fail(field, "is required")
+		return
+	}
+	if !containerNumberPattern.MatchString(value) {
+		v.Fail(field, "must be an ISO 6346 container number such as MSCU1234566")
+		return
+	}
+	if want := checkDigit(value[:10]); int(value[10]-'0') != want {
+		v.Fail(field, fmt.Sprintf("has an invalid check digit, expected %d", want))
+	}
+}
+
+// Err returns the collected violations as a VALIDATION_FAILED error
+func (v *Validator) Err() error {
+	if len(*v.violations) == 0 {
+		return nil
+	}
+	err := apperror.New(apperror.CodeValidationFailed, "request has %d invalid fields", len(*v.violations))
+	if len(*v.violations) == 1 {
+		first := (*v.violations)[0]
+		err.Message = fmt.Sprintf("%s %s", first.Field, first.Message)
+	}
+	err.Details = *v.violations
+	return err
+}
+
+func (v *Validator) field(name string) string {
+	switch {
+	case v.path == "":
+		return name
+	case strings.HasPrefix(name, "["):
+		return v.path + name
+	default:
+		return v.path + "." + name
+	}
+}
+
+var containerNumberPattern = regexp.MustCompile(`^[A-Z]{3}[UJZ][0-9]{7}$`)
+
+// checkDigit computes the ISO 6346 check digit of the first ten characters.
+// Letters count from 10 upwards, skipping multiples of 11.
+func checkDigit(code string) int {
+	sum := 0
+	for i, c := range code {
+		var value int
+		if c >= 'A' && c <= 'Z' {
+			value = int(c-'A') + 10
+			value += (value - 1) / 10
+		} else {
+			value = int(c - '0')
+		}
+		sum += value << i
+	}
+	return sum % 11 % 10
+}
+
+// Container types
+const (
+	ContainerTypeDry     = "DRY"
+	ContainerTypeReefer  = "REEFER"
+	ContainerTypeOpenTop = "OPEN_TOP"
+)
+
+// validateSpec checks the size, height and type of a container
+func validateSpec(v *Validator, size int, height float64, containerType string) {
+	v.Check(size == 20 || size == 40, "container_size", "must be 20 or 40")
+	v.Check(height == 8.6 || height == 9.6, "container_height", "must be 8.6 or 9.6")
+	v.OneOf("container_type", containerType, false, ContainerTypeDry, ContainerTypeReefer, ContainerTypeOpenTop)
+}
+
+// validatePosition checks the slot, row and tier of a target position
+func validatePosition(v *Validator, block string, slot, row, tier int) {
+	v.Required("block", block)
+	v.Min("slot", slot, 1)
+	v.Min("row", row, 1)
+	v.Min("tier", tier, 1)
+}
+
+func (r SuggestionRequest) Validate(v *Validator) {
+	v.Required("yard", r.Yard)
+	v.ContainerNumber("container_number", r.ContainerNumber)
+	validateSpec(v, r.ContainerSize, r.ContainerHeight, r.ContainerType)
+	v.OneOf("movement", r.Movement, true, MovementImport, MovementExport)
+}
+
+func (r PlacementRequest) Validate(v *Validator) {
+	v.Required("yard", r.Yard)
+	v.ContainerNumber("container_number", r.ContainerNumber)
+	validatePosition(v, r.Block, r.Slot, r.Row, r.Tier)
+}
+
+func (r PickupRequest) Validate(v *Validator) {
+	v.Required("yard", r.Yard)
+	v.ContainerNumber("container_number", r.ContainerNumber)
+}
+
+func (r HoldRequest) Validate(v *Validator) {
+	v.Required("yard", r.Yard)
+	v.ContainerNumber("container_number", r.ContainerNumber)
+	v.Check(len(r.Reason) <= 255, "reason", "must be at most 255 characters")
+}
+
+func (r MoveRequest) Validate(v *Validator) {
+	v.Required("yard", r.Yard)
+	v.ContainerNumber("container_number", r.ContainerNumber)
+	validatePosition(v, r.Block, r.Slot, r.Row, r.Tier)
+}
+
+func (r OverflowRuleRequest) Validate(v *Validator) {
+	v.Required("yard", r.Yard)
+	v.Required("overflow_yard", r.OverflowYard)
+	v.Check(r.Yard == "" || r.Yard != r.OverflowYard, "overflow_yard", "a yard cannot overflow into itself")
+	v.Min("priority", r.Priority, 1)
+}
+
+func (r ClosureRequest) Validate(v *Validator) {
+	v.Required("yard", r.Yard)
+	v.Check(!r.StartsAt.IsZero(), "starts_at", "is required")
+	v.Check(!r.EndsAt.IsZero(), "ends_at", "is required")
+	if !r.StartsAt.IsZero() && !r.EndsAt.IsZero() {
+		v.Check(r.EndsAt.After(r.StartsAt), "ends_at", "must be after starts_at")
+	}
+	validateRange(v, "slot", r.SlotStart, r.SlotEnd)
+	validateRange(v, "row", r.RowStart, r.RowEnd)
+	if r.SlotStart != nil || r.SlotEnd != nil || r.RowStart != nil || r.RowEnd != nil {
+		v.Required("block", r.Block)
+	}
+}
+
+// validateRange checks an optional start/end pair; the upper bound depends on
+// the block and is checked by the service
+func validateRange(v *Validator, name string, start, end *int) {
+	if start == nil && end == nil {
+		return
+	}
+	if start == nil || end == nil {
+		v.Fail(name+"_start", "needs both a start and an end")
+		return
+	}
+	v.Min(name+"_start", *start, 1)
+	v.Check(*end >= *start, name+"_end", "must not be before "+name+"_start")
+}
+
+func (r BlockRequest) Validate(v *Validator) {
+	v.Required("name", r.Name)
+	v.Min("max_slot", r.MaxSlot, 1)
+	v.Min("max_row", r.MaxRow, 1)
+	v.Min("max_tier", r.MaxTier, 1)
+	v.Check(r.SlotPitch > 0, "slot_pitch", "must be positive")
+	v.Check(r.RowPitch > 0, "row_pitch", "must be positive")
+}
+
+func (r YardPlanRequest) Validate(v *Validator) {
+	v.Required("yard", r.Yard)
+	v.Required("block", r.Block)
+	validateRange(v, "slot", &r.SlotStart, &r.SlotEnd)
+	validateRange(v, "row", &r.RowStart, &r.RowEnd)
+	validateSpec(v, r.ContainerSize, r.ContainerHeight, r.ContainerType)
+	v.Check(len(r.StackingPriority) <= 20, "stacking_priority", "must be at most 20 characters")
+}
+
+func (r EquipmentRequest) Validate(v *Validator) {
+	v.Required("yard", r.Yard)
+	v.Required("code", r.Code)
+	v.OneOf("equipment_type", r.EquipmentType, false,
+		EquipmentTypeRTG, EquipmentTypeRMG, EquipmentTypeReachStacker)
+	for i, block := range r.Blocks {
+		v.Required(fmt.Sprintf("blocks[%d]", i), block)
+	}
+}
+
+func (r JobActionRequest) Validate(v *Validator) {
+	v.Min("id", r.ID, 1)
+}
+
+func (r YardPointRequest) Validate(v *Validator) {
+	v.Required("yard", r.Yard)
+	v.Required("code", r.Code)
+	v.OneOf("point_type", r.PointType, false, PointTypeGate, PointTypeBerth, PointTypeRail)
+}
+
+func (r RoleBindingRequest) Validate(v *Validator) {
+	v.Required("subject", r.Subject)
+	v.OneOf("role", r.Role, false,
+		RoleGateClerk, RoleYardPlanner, RoleEquipmentOperator, RoleSupervisor, RoleAdmin)
+	v.Check(r.Role != RoleAdmin || r.Yard == "" || r.Yard == AllYards, "yard", "ADMIN can only be bound to all yards")
+}
+
+func (r BulkJobRequest) Validate(v *Validator) {
+	v.OneOf("kind", r.Kind, false, BulkJobSuggestion, BulkJobPlacement, BulkJobMove)
+
+	// Exactly the list of the job's kind is filled
+	lists := []struct {
+		kind, field string
+		size        int
+	}{
+		{BulkJobSuggestion, "suggestions", len(r.Suggestions)},
+		{BulkJobPlacement, "placements", len(r.Placements)},
+		{BulkJobMove, "moves", len(r.Moves)},
+	}
+	for _, list := range lists {
+		if list.kind == r.Kind {
+			v.Check(list.size > 0, list.field, "must not be empty")
+		} else if list.size > 0 {
+			v.Fail(list.field, "must be empty for kind "+r.Kind)
+		}
+	}
+
+	for i, c := range r.Suggestions {
+		c.Validate(v.At(fmt.Sprintf("suggestions[%d]", i)))
+	}
+	for i, c := range r.Placements {
+		c.Validate(v.At(fmt.Sprintf("placements[%d]", i)))
+	}
+	for i, c := range r.Moves {
+		c.Validate(v.At(fmt.Sprintf("moves[%d]", i)))
+	}
+}
@@ -0,0 +1,114 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fields(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var appErr *apperror.Error
+	require.True(t, errors.As(err, &appErr), "%v", err)
+	assert.Equal(t, apperror.CodeValidationFailed, appErr.Code)
+
+	result := make(map[string]string)
+	for _, detail := range appErr.Details {
+		result[detail.Field] = detail.Message
+	}
+	return result
+}
+
+func TestValidator_ContainerNumber(t *testing.T) {
+	tests := []struct {
+		number string
+		valid  bool
+	}{
+		{"ABCU1234560", true},
+		{"MSCU1234566", true},
+		{"CSQU3054383", true},
+		{"ABCU1234561", false}, // wrong check digit
+		{"ABCX1234560", false}, // category must be U, J or Z
+		{"abcu1234560", false},
+		{"ABCU123456", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		v := NewValidator()
+		v.ContainerNumber("container_number", tt.number)
+		if tt.valid {
+			assert.NoError(t, v.Err(), tt.number)
+		} else {
+			assert.Error(t, v.Err(), tt.number)
+		}
+	}
+}
+
+func TestValidate_ReportsEveryViolation(t *testing.T) {
+	err := Validate(SuggestionRequest{
+		ContainerNumber: "ABCU1234561",
+		ContainerSize:   -20,
+		ContainerHeight: 8.6,
+		ContainerType:   "FLAT",
+		Movement:        "TRANSHIP",
+	})
+
+	got := fields(t, err)
+	assert.Len(t, got, 5)
+	for _, field := range []string{"yard", "container_number", "container_size", "container_type", "movement"} {
+		assert.Contains(t, got, field)
+	}
+}
+
+func TestValidator_NestedPaths(t *testing.T) {
+	v := NewValidator()
+	for i, req := range []PlacementRequest{
+		{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 1, Row: 1, Tier: 1},
+		{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 0, Row: 1, Tier: 1},
+	} {
+		req.Validate(v.At(fmt.Sprintf("containers[%d]", i)))
+	}
+
+	assert.Equal(t, map[string]string{"containers[1].slot": "must be at least 1"}, fields(t, v.Err()))
+}
+
+func TestClosureRequest_Validate(t *testing.T) {
+	start := 5
+	now := time.Now()
+
+	got := fields(t, Validate(ClosureRequest{
+		Yard:      "YRD1",
+		SlotStart: &start,
+		StartsAt:  now,
+		EndsAt:    now.Add(-time.Hour),
+	}))
+	assert.Equal(t, map[string]string{
+		"ends_at":    "must be after starts_at",
+		"slot_start": "needs both a start and an end",
+		"block":      "is required",
+	}, got)
+}
+
+func TestYardPlanRequest_Validate(t *testing.T) {
+	got := fields(t, Validate(YardPlanRequest{
+		Yard:            "YRD1",
+		Block:           "LC01",
+		SlotStart:       4,
+		SlotEnd:         2,
+		RowStart:        0,
+		RowEnd:          3,
+		ContainerSize:   20,
+		ContainerHeight: 8.6,
+		ContainerType:   ContainerTypeDry,
+	}))
+	assert.Equal(t, map[string]string{
+		"slot_end":  "must not be before slot_start",
+		"row_start": "must be at least 1",
+	}, got)
+}
@@ -0,0 +1,149 @@
+// Package occupancy keeps the occupied cells of every block in memory, so
+// suggestions can check availability without querying the database.
+package occupancy
+
+import "math/bits"
+
+// Cell is one slot/row/tier position of a block
+type Cell struct {
+	Slot int `json:"slot"`
+	Row  int `json:"row"`
+	Tier int `json:"tier"`
+}
+
+// Grid is a bitset over the slot × row × tier cells of a block. A 40ft
+// container occupies two consecutive slots.
+type Grid struct {
+	slots, rows, tiers int
+	bits               []uint64
+}
+
+// NewGrid creates an empty grid for a block of the given dimensions
+func NewGrid(slots, rows, tiers int) *Grid {
+	return &Grid{
+		slots: slots,
+		rows:  rows,
+		tiers: tiers,
+		bits:  make([]uint64, (slots*rows*tiers+63)/64),
+	}
+}
+
+// Occupy marks the slots used by a container of the given size. Cells
+// outside the block are ignored.
+func (g *Grid) Occupy(slot, row, tier, containerSize int) {
+	for s := 0; s < span(containerSize); s++ {
+		if i, ok := g.index(slot+s, row, tier); ok {
+			g.bits[i/64] |= 1 << (i % 64)
+		}
+	}
+}
+
+// Release clears the slots used by a container of the given size
+func (g *Grid) Release(slot, row, tier, containerSize int) {
+	for s := 0; s < span(containerSize); s++ {
+		if i, ok := g.index(slot+s, row, tier); ok {
+			g.bits[i/64] &^= 1 << (i % 64)
+		}
+	}
+}
+
+// Free reports whether every slot a container of the given size would use is
+// inside the block and empty
+func (g *Grid) Free(slot, row, tier, containerSize int) bool {
+	for s := 0; s < span(containerSize); s++ {
+		i, ok := g.index(slot+s, row, tier)
+		if !ok || g.bits[i/64]&(1<<(i%64)) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Supported reports whether a container at the position stands on the ground
+// or on occupied cells over its whole length
+func (g *Grid) Supported(slot, row, tier, containerSize int) bool {
+	if tier == 1 {
+		return true
+	}
+	for s := 0; s < span(containerSize); s++ {
+		if !g.Occupied(slot+s, row, tier-1) {
+			return false
+		}
+	}
+	return true
+}
+
+// Occupied reports whether a single cell is in use
+func (g *Grid) Occupied(slot, row, tier int) bool {
+	i, ok := g.index(slot, row, tier)
+	return ok && g.bits[i/64]&(1<<(i%64)) != 0
+}
+
+// Count returns the number of occupied cells
+func (g *Grid) Count() int {
+	n := 0
+	for _, word := range g.bits {
+		n += bits.OnesCount64(word)
+	}
+	return n
+}
+
+// Clone returns an independent copy of the grid
+func (g *Grid) Clone() *Grid {
+	clone := *g
+	clone.bits = append([]uint64(nil), g.bits...)
+	return &clone
+}
+
+// Resize returns a grid of other dimensions with the occupied cells that are
+// still inside the block
+func (g *Grid) Resize(slots, rows, tiers int) *Grid {
+	resized := NewGrid(slots, rows, tiers)
+	for w, word := range g.bits {
+		for word != 0 {
+			cell := g.cell(w*64 + bits.TrailingZeros64(word))
+			if i, ok := resized.index(cell.Slot, cell.Row, cell.Tier); ok {
+				resized.bits[i/64] |= 1 << (i % 64)
+			}
+			word &= word - 1
+		}
+	}
+	return resized
+}
+
+// Diff returns the cells whose state differs from another grid of the same block
+func (g *Grid) Diff(other *Grid) []Cell {
+	var cells []Cell
+	for w := range g.bits {
+		changed := g.bits[w] ^ other.bits[w]
+		for changed != 0 {
+			i := w*64 + bits.TrailingZeros64(changed)
+			cells = append(cells, g.cell(i))
+			changed &= changed - 1
+		}
+	}
+	return cells
+}
+
+func (g *Grid) index(slot, row, tier int) (int, bool) {
+	if slot < 1 || slot > g.slots || row < 1 || row > g.rows || tier < 1 || tier > g.tiers {
+		return 0, false
+	}
+	return ((tier-1)*g.rows+(row-1))*g.slots + (slot - 1), true
+}
+
+func (g *Grid) cell(i int) Cell {
+	return Cell{
+		Slot: i%g.slots + 1,
+		Row:  (i/g.slots)%g.rows + 1,
+		Tier: i/(g.slots*g.rows) + 1,
+	}
+}
+
+// span returns the number of slots used by a container size
+func span(containerSize int) int {
+	if containerSize == 40 {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,188 @@
+package occupancy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
+)
+
+// Index holds the occupancy grid of every block. It is loaded from storage at
+// startup and kept up to date by the ContainerStore wrapper returned by Track.
+type Index struct {
+	mu     sync.RWMutex
+	blocks map[int]*Grid
+}
+
+// Mismatch is a cell where the index and the database disagree
+type Mismatch struct {
+	BlockID int  `json:"block_id"`
+	Cell    Cell `json:"cell"`
+	// Indexed is the state of the cell in the index
+	Indexed bool `json:"indexed"`
+}
+
+// NewIndex creates an empty index
+func NewIndex() *Index {
+	return &Index{blocks: make(map[int]*Grid)}
+}
+
+// Load replaces the index with the blocks and containers in storage
+func (x *Index) Load(ctx context.Context, stores repository.Stores) error {
+	grids, err := build(ctx, stores)
+	if err != nil {
+		return err
+	}
+
+	x.mu.Lock()
+	x.blocks = grids
+	x.mu.Unlock()
+
+	return nil
+}
+
+// LoadBlock replaces the grid of one block with its containers in storage
+func (x *Index) LoadBlock(ctx context.Context, stores repository.Stores, blockID int) error {
+	ctx = tenant.WithAllTenants(ctx)
+	block, err := stores.Blocks.GetByID(ctx, blockID)
+	if err != nil {
+		return err
+	}
+	containers, err := stores.Containers.GetByBlock(ctx, blockID)
+	if err != nil {
+		return err
+	}
+
+	grid := NewGrid(block.MaxSlot, block.MaxRow, block.MaxTier)
+	for _, c := range containers {
+		grid.Occupy(c.Slot, c.Row, c.Tier, c.ContainerSize)
+	}
+
+	x.mu.Lock()
+	x.blocks[blockID] = grid
+	x.mu.Unlock()
+
+	return nil
+}
+
+// Check compares the index with storage and returns the cells that differ.
+// A write that completes while checking can show up as a mismatch.
+func (x *Index) Check(ctx context.Context, stores repository.Stores) ([]Mismatch, error) {
+	grids, err := build(ctx, stores)
+	if err != nil {
+		return nil, err
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	var mismatches []Mismatch
+	for blockID, stored := range grids {
+		indexed, ok := x.blocks[blockID]
+		if !ok {
+			indexed = NewGrid(stored.slots, stored.rows, stored.tiers)
+		}
+		for _, cell := range stored.Diff(indexed) {
+			mismatches = append(mismatches, Mismatch{
+				BlockID: blockID,
+				Cell:    cell,
+				Indexed: indexed.Occupied(cell.Slot, cell.Row, cell.Tier),
+			})
+		}
+	}
+
+	return mismatches, nil
+}
+
+// Snapshot returns a copy of the grid of a block, or false if the block is not indexed
+func (x *Index) Snapshot(blockID int) (*Grid, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	grid, ok := x.blocks[blockID]
+	if !ok {
+		return nil, false
+	}
+	return grid.Clone(), true
+}
+
+// Occupy records a container placed in the yard
+func (x *Index) Occupy(c model.Container) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if grid, ok := x.blocks[c.BlockID]; ok {
+		grid.Occupy(c.Slot, c.Row, c.Tier, c.ContainerSize)
+	}
+}
+
+// Release records a container leaving the yard
+func (x *Index) Release(c model.Container) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if grid, ok := x.blocks[c.BlockID]; ok {
+		grid.Release(c.Slot, c.Row, c.Tier, c.ContainerSize)
+	}
+}
+
+// Move records a container moving to another cell
+func (x *Index) Move(c model.Container, blockID, slot, row, tier int) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if grid, ok := x.blocks[c.BlockID]; ok {
+		grid.Release(c.Slot, c.Row, c.Tier, c.ContainerSize)
+	}
+	if grid, ok := x.blocks[blockID]; ok {
+		grid.Occupy(slot, row, tier, c.ContainerSize)
+	}
+}
+
+// Resize records new dimensions of a block
+func (x *Index) Resize(block model.Block) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if grid, ok := x.blocks[block.ID]; ok {
+		x.blocks[block.ID] = grid.Resize(block.MaxSlot, block.MaxRow, block.MaxTier)
+	}
+}
+
+// build reads the occupancy of every block from storage. Occupancy is
+// physical, so the yards of all tenants are read.
+func build(ctx context.Context, stores repository.Stores) (map[int]*Grid, error) {
+	ctx = tenant.WithAllTenants(ctx)
+	yards, err := stores.Yards.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	grids := make(map[int]*Grid)
+	for _, yard := range yards {
+		blocks, err := stores.Blocks.GetByYardID(ctx, yard.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, block := range blocks {
+			grids[block.ID] = NewGrid(block.MaxSlot, block.MaxRow, block.MaxTier)
+		}
+	}
+
+	containers, err := stores.Containers.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range containers {
+		grid, ok := grids[c.BlockID]
+		if !ok {
+			return nil, fmt.Errorf("container '%s' is in unknown block %d", c.ContainerNumber, c.BlockID)
+		}
+		grid.Occupy(c.Slot, c.Row, c.Tier, c.ContainerSize)
+	}
+
+	return grids, nil
+}
@@ -0,0 +1,204 @@
+package occupancy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/repository/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrid_FortyFootSpan(t *testing.T) {
+	grid := NewGrid(10, 5, 3)
+
+	grid.Occupy(4, 2, 1, 40)
+	assert.True(t, grid.Occupied(4, 2, 1))
+	assert.True(t, grid.Occupied(5, 2, 1))
+	assert.Equal(t, 2, grid.Count())
+
+	// A 20ft container next to the span is fine, one overlapping it is not
+	assert.True(t, grid.Free(6, 2, 1, 20))
+	assert.False(t, grid.Free(5, 2, 1, 20))
+	assert.False(t, grid.Free(3, 2, 1, 40))
+
+	// The last slot of the block can not hold a 40ft container
+	assert.False(t, grid.Free(10, 1, 1, 40))
+
+	// Stacking needs support over the whole length
+	assert.True(t, grid.Supported(4, 2, 2, 40))
+	assert.True(t, grid.Supported(5, 2, 2, 20))
+	assert.False(t, grid.Supported(5, 2, 2, 40))
+
+	grid.Release(4, 2, 1, 40)
+	assert.Zero(t, grid.Count())
+}
+
+func TestGrid_Diff(t *testing.T) {
+	a := NewGrid(10, 5, 3)
+	b := NewGrid(10, 5, 3)
+	a.Occupy(1, 1, 1, 20)
+	a.Occupy(10, 5, 3, 20)
+	b.Occupy(1, 1, 1, 20)
+
+	assert.Equal(t, []Cell{{Slot: 10, Row: 5, Tier: 3}}, a.Diff(b))
+	assert.Empty(t, b.Diff(b.Clone()))
+}
+
+func TestGrid_Resize(t *testing.T) {
+	grid := NewGrid(10, 5, 3)
+	grid.Occupy(2, 3, 1, 40)
+	grid.Occupy(10, 5, 3, 20)
+
+	resized := grid.Resize(12, 4, 3)
+	assert.True(t, resized.Occupied(2, 3, 1))
+	assert.True(t, resized.Occupied(3, 3, 1))
+	assert.True(t, resized.Free(11, 4, 1, 40))
+	// The cell in row 5 is outside the smaller block
+	assert.Equal(t, 2, resized.Count())
+}
+
+func TestIndex_TrackAndCheck(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	require.NoError(t, store.Seed())
+	stores := store.Stores()
+
+	index := NewIndex()
+	require.NoError(t, index.Load(ctx, stores))
+	tracked := Track(stores.Containers, index)
+
+	container := &model.Container{
+		ContainerNumber: "ABCU1234560", YardID: 1, BlockID: 1,
+		Slot: 4, Row: 1, Tier: 1,
+		ContainerSize: 40, ContainerHeight: 8.6, ContainerType: "DRY",
+	}
+	require.NoError(t, tracked.Create(ctx, container))
+	grid, ok := index.Snapshot(1)
+	require.True(t, ok)
+	assert.False(t, grid.Free(5, 1, 1, 20))
+
+	require.NoError(t, tracked.UpdatePosition(ctx, "ABCU1234560", 1, 6, 2, 1))
+	grid, _ = index.Snapshot(1)
+	assert.True(t, grid.Free(4, 1, 1, 40))
+	assert.False(t, grid.Free(7, 2, 1, 20))
+
+	mismatches, err := index.Check(ctx, stores)
+	require.NoError(t, err)
+	assert.Empty(t, mismatches)
+
+	// Writes that bypass the tracker are found by the check
+	require.NoError(t, stores.Containers.Delete(ctx, "ABCU1234560"))
+	mismatches, err = index.Check(ctx, stores)
+	require.NoError(t, err)
+	assert.Len(t, mismatches, 2)
+	assert.True(t, mismatches[0].Indexed)
+
+	require.NoError(t, index.Load(ctx, stores))
+	mismatches, err = index.Check(ctx, stores)
+	require.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
+
+func TestIndex_LoadBlock(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	require.NoError(t, store.Seed())
+	stores := store.Stores()
+
+	index := NewIndex()
+	require.NoError(t, index.Load(ctx, stores))
+
+	// Another instance wrote to block 2; this instance only reloads it
+	require.NoError(t, stores.Containers.Create(ctx, &model.Container{
+		ContainerNumber: "ABCU1234560", YardID: 2, BlockID: 2,
+		Slot: 1, Row: 1, Tier: 1,
+		ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY",
+	}))
+	require.NoError(t, index.LoadBlock(ctx, stores, 2))
+	grid, _ := index.Snapshot(2)
+	assert.True(t, grid.Occupied(1, 1, 1))
+}
+
+// Notify reports committed writes without an index
+func TestNotify(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	require.NoError(t, store.Seed())
+
+	var changed []int
+	stores := Notify(store.Stores(), func(yardID, blockID int) { changed = append(changed, blockID) })
+
+	container := &model.Container{
+		ContainerNumber: "ABCU1234560", YardID: 2, BlockID: 2,
+		Slot: 1, Row: 1, Tier: 1,
+		ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY",
+	}
+
+	// Rolled back writes are not reported
+	err := stores.Atomic(ctx, func(tx repository.Stores) error {
+		require.NoError(t, tx.Containers.Create(ctx, container))
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+	assert.Empty(t, changed)
+
+	require.NoError(t, stores.Atomic(ctx, func(tx repository.Stores) error {
+		return tx.Containers.Create(ctx, container)
+	}))
+	assert.Equal(t, []int{2}, changed)
+
+	// Blocks changed outside of transactions are reported right away
+	block, err := stores.Blocks.GetByID(ctx, 2)
+	require.NoError(t, err)
+	require.NoError(t, stores.Blocks.Update(ctx, block, block.Version))
+	require.NoError(t, stores.Containers.Delete(ctx, container.ContainerNumber))
+	assert.Equal(t, []int{2, 2, 2}, changed)
+}
+
+func TestTrackAtomic(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	require.NoError(t, store.Seed())
+	stores := store.Stores()
+
+	index := NewIndex()
+	require.NoError(t, index.Load(ctx, stores))
+	atomic := TrackAtomic(stores.Atomic, index)
+
+	place := func(tx repository.Stores, number string, tier int) error {
+		return tx.Containers.Create(ctx, &model.Container{
+			ContainerNumber: number, YardID: 1, BlockID: 1,
+			Slot: 1, Row: 1, Tier: tier,
+			ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY",
+		})
+	}
+
+	// Rolled back writes never reach the index
+	err := atomic(ctx, func(tx repository.Stores) error {
+		require.NoError(t, place(tx, "ABCU1234560", 1))
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+	grid, _ := index.Snapshot(1)
+	assert.Zero(t, grid.Count())
+
+	require.NoError(t, atomic(ctx, func(tx repository.Stores) error {
+		if err := place(tx, "ABCU1234560", 1); err != nil {
+			return err
+		}
+		return tx.Atomic(ctx, func(nested repository.Stores) error {
+			return place(nested, "ABCU7654321", 2)
+		})
+	}))
+	grid, _ = index.Snapshot(1)
+	assert.True(t, grid.Occupied(1, 1, 1))
+	assert.True(t, grid.Occupied(1, 1, 2))
+
+	mismatches, err := index.Check(ctx, stores)
+	require.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
@@ -0,0 +1,174 @@
+package occupancy
+
+import (
+	"context"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+)
+
+// ContainerStore updates an Index after every successful write of the
+// container store it wraps. Reads are passed through unchanged.
+type ContainerStore struct {
+	repository.ContainerStore
+	index tracker
+}
+
+// tracker receives the changes written through a ContainerStore or BlockStore
+type tracker interface {
+	Occupy(c model.Container)
+	Release(c model.Container)
+	Move(c model.Container, blockID, slot, row, tier int)
+	Resize(block model.Block)
+}
+
+// Track wraps a container store so its writes are reflected in the index
+func Track(containers repository.ContainerStore, index *Index) *ContainerStore {
+	return &ContainerStore{ContainerStore: containers, index: index}
+}
+
+// TrackAtomic wraps a transactor so containers and blocks written in a
+// transaction are reflected in the index once it has committed
+func TrackAtomic(atomic repository.Transactor, index *Index) repository.Transactor {
+	return trackAtomic(atomic, index)
+}
+
+// Notify wraps the container and block stores and the transactor of stores,
+// so fn is called for every block a write changed once the write has
+// committed, e.g. to tell other API instances about it. It does not need an
+// index.
+func Notify(stores repository.Stores, fn func(yardID, blockID int)) repository.Stores {
+	n := notifier(fn)
+	stores.Containers = &ContainerStore{ContainerStore: stores.Containers, index: n}
+	stores.Blocks = &BlockStore{BlockStore: stores.Blocks, index: n}
+	stores.Atomic = trackAtomic(stores.Atomic, n)
+	return stores
+}
+
+func trackAtomic(atomic repository.Transactor, index tracker) repository.Transactor {
+	return func(ctx context.Context, fn func(tx repository.Stores) error) error {
+		changes := &journal{index: index}
+		err := atomic(ctx, func(tx repository.Stores) error {
+			return fn(changes.track(tx))
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, apply := range changes.pending {
+			apply()
+		}
+		return nil
+	}
+}
+
+// journal holds the changes of a transaction until it has committed
+type journal struct {
+	index   tracker
+	pending []func()
+}
+
+// track wraps the container and block stores of a transaction, and of the
+// transactions nested in it, so their writes are recorded in the journal
+func (j *journal) track(tx repository.Stores) repository.Stores {
+	tx.Containers = &ContainerStore{ContainerStore: tx.Containers, index: j}
+	tx.Blocks = &BlockStore{BlockStore: tx.Blocks, index: j}
+	atomic := tx.Atomic
+	tx.Atomic = func(ctx context.Context, fn func(tx repository.Stores) error) error {
+		return atomic(ctx, func(nested repository.Stores) error {
+			return fn(j.track(nested))
+		})
+	}
+	return tx
+}
+
+func (j *journal) Occupy(c model.Container) {
+	j.pending = append(j.pending, func() { j.index.Occupy(c) })
+}
+
+func (j *journal) Release(c model.Container) {
+	j.pending = append(j.pending, func() { j.index.Release(c) })
+}
+
+func (j *journal) Move(c model.Container, blockID, slot, row, tier int) {
+	j.pending = append(j.pending, func() { j.index.Move(c, blockID, slot, row, tier) })
+}
+
+func (j *journal) Resize(block model.Block) {
+	j.pending = append(j.pending, func() { j.index.Resize(block) })
+}
+
+// notifier reports the blocks changed by writes
+type notifier func(yardID, blockID int)
+
+func (n notifier) Occupy(c model.Container) {
+	n(c.YardID, c.BlockID)
+}
+
+func (n notifier) Release(c model.Container) {
+	n(c.YardID, c.BlockID)
+}
+
+func (n notifier) Move(c model.Container, blockID, slot, row, tier int) {
+	n(c.YardID, c.BlockID)
+	if blockID != c.BlockID {
+		n(c.YardID, blockID)
+	}
+}
+
+func (n notifier) Resize(block model.Block) {
+	n(block.YardID, block.ID)
+}
+
+var _ repository.ContainerStore = (*ContainerStore)(nil)
+
+// Create inserts a container and marks its cells as occupied
+func (s *ContainerStore) Create(ctx context.Context, container *model.Container) error {
+	if err := s.ContainerStore.Create(ctx, container); err != nil {
+		return err
+	}
+	s.index.Occupy(*container)
+	return nil
+}
+
+// Delete removes a container and frees its cells
+func (s *ContainerStore) Delete(ctx context.Context, containerNumber string) error {
+	container, _ := s.ContainerStore.GetByNumber(ctx, containerNumber)
+	if err := s.ContainerStore.Delete(ctx, containerNumber); err != nil {
+		return err
+	}
+	if container != nil {
+		s.index.Release(*container)
+	}
+	return nil
+}
+
+// UpdatePosition moves a container and its cells
+func (s *ContainerStore) UpdatePosition(ctx context.Context, containerNumber string, blockID, slot, row, tier int) error {
+	container, _ := s.ContainerStore.GetByNumber(ctx, containerNumber)
+	if err := s.ContainerStore.UpdatePosition(ctx, containerNumber, blockID, slot, row, tier); err != nil {
+		return err
+	}
+	if container != nil {
+		s.index.Move(*container, blockID, slot, row, tier)
+	}
+	return nil
+}
+
+// BlockStore updates an Index after every successful block update of the
+// block store it wraps
+type BlockStore struct {
+	repository.BlockStore
+	index tracker
+}
+
+var _ repository.BlockStore = (*BlockStore)(nil)
+
+// Update saves a block and resizes its grid
+func (s *BlockStore) Update(ctx context.Context, block *model.Block, version int) error {
+	if err := s.BlockStore.Update(ctx, block, version); err != nil {
+		return err
+	}
+	s.index.Resize(*block)
+	return nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+type APIKeyRepository struct {
+	db dbtx
+}
+
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// Create inserts a new API key. Only the hash of the key is stored. A key
+// without a tenant belongs to the default tenant.
+func (r *APIKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	query := `
+		INSERT INTO api_keys (name, prefix, key_hash, expires_at, tenant)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	if key.Tenant == "" {
+		key.Tenant = tenant.Default
+	}
+	err := r.db.QueryRowContext(ctx, query, key.Name, key.Prefix, key.Hash, key.ExpiresAt, key.Tenant).
+		Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating api key: %w", uniqueErr(err))
+	}
+
+	return nil
+}
+
+// GetByHash retrieves the API key with the given SHA-256 hash
+func (r *APIKeyRepository) GetByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	keys, err := r.query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1`, hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, apperror.New(apperror.CodeNotFound, "api key not found")
+	}
+
+	return &keys[0], nil
+}
+
+// GetAll retrieves all API keys, including expired ones
+func (r *APIKeyRepository) GetAll(ctx context.Context) ([]model.APIKey, error) {
+	return r.query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY id`)
+}
+
+// Expire sets the time after which an API key is no longer accepted
+func (r *APIKeyRepository) Expire(ctx context.Context, id int, at time.Time) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE api_keys SET expires_at = $2 WHERE id = $1`, id, at)
+	if err != nil {
+		return fmt.Errorf("error expiring api key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperror.New(apperror.CodeNotFound, "api key %d not found", id)
+	}
+
+	return nil
+}
+
+const apiKeyColumns = `id, name, prefix, key_hash, tenant, expires_at, created_at`
+
+func (r *APIKeyRepository) query(ctx context.Context, query string, args ...interface{}) ([]model.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []model.APIKey
+	for rows.Next() {
+		var (
+			key       model.APIKey
+			expiresAt sql.NullTime
+		)
+		if err := rows.Scan(&key.ID, &key.Name, &key.Prefix, &key.Hash, &key.Tenant, &expiresAt, &key.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning api key: %w", err)
+		}
+		key.ExpiresAt = nullTimePtr(expiresAt)
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating api keys: %w", err)
+	}
+
+	return keys, nil
+}
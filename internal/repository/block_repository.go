@@ -1,14 +1,16 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
 	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
 )
 
 type BlockRepository struct {
-	db *sql.DB
+	db dbtx
 }
 
 func NewBlockRepository(db *sql.DB) *BlockRepository {
@@ -16,15 +18,16 @@ func NewBlockRepository(db *sql.DB) *BlockRepository {
 }
 
 // GetByYardAndCode retrieves a block by yard ID and block code
-func (r *BlockRepository) GetByYardAndCode(yardID int, code string) (*model.Block, error) {
+func (r *BlockRepository) GetByYardAndCode(ctx context.Context, yardID int, code string) (*model.Block, error) {
 	query := `
-		SELECT id, yard_id, code, name, max_slot, max_row, max_tier, created_at, updated_at
+		SELECT id, yard_id, code, name, max_slot, max_row, max_tier,
+		       origin_x, origin_y, orientation, slot_pitch, row_pitch, version, created_at, updated_at
 		FROM blocks
 		WHERE yard_id = $1 AND code = $2
 	`
 
 	var block model.Block
-	err := r.db.QueryRow(query, yardID, code).Scan(
+	err := r.db.QueryRowContext(ctx, query, yardID, code).Scan(
 		&block.ID,
 		&block.YardID,
 		&block.Code,
@@ -32,12 +35,18 @@ func (r *BlockRepository) GetByYardAndCode(yardID int, code string) (*model.Bloc
 		&block.MaxSlot,
 		&block.MaxRow,
 		&block.MaxTier,
+		&block.OriginX,
+		&block.OriginY,
+		&block.Orientation,
+		&block.SlotPitch,
+		&block.RowPitch,
+		&block.Version,
 		&block.CreatedAt,
 		&block.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("block with code '%s' not found in yard", code)
+		return nil, apperror.New(apperror.CodeBlockNotFound, "block with code '%s' not found in yard", code)
 	}
 
 	if err != nil {
@@ -48,15 +57,16 @@ func (r *BlockRepository) GetByYardAndCode(yardID int, code string) (*model.Bloc
 }
 
 // GetByYardID retrieves all blocks for a specific yard
-func (r *BlockRepository) GetByYardID(yardID int) ([]model.Block, error) {
+func (r *BlockRepository) GetByYardID(ctx context.Context, yardID int) ([]model.Block, error) {
 	query := `
-		SELECT id, yard_id, code, name, max_slot, max_row, max_tier, created_at, updated_at
+		SELECT id, yard_id, code, name, max_slot, max_row, max_tier,
+		       origin_x, origin_y, orientation, slot_pitch, row_pitch, version, created_at, updated_at
 		FROM blocks
 		WHERE yard_id = $1
 		ORDER BY code
 	`
 
-	rows, err := r.db.Query(query, yardID)
+	rows, err := r.db.QueryContext(ctx, query, yardID)
 	if err != nil {
 		return nil, fmt.Errorf("error querying blocks: %w", err)
 	}
@@ -73,6 +83,12 @@ func (r *BlockRepository) GetByYardID(yardID int) ([]model.Block, error) {
 			&block.MaxSlot,
 			&block.MaxRow,
 			&block.MaxTier,
+			&block.OriginX,
+			&block.OriginY,
+			&block.Orientation,
+			&block.SlotPitch,
+			&block.RowPitch,
+			&block.Version,
 			&block.CreatedAt,
 			&block.UpdatedAt,
 		)
@@ -86,15 +102,16 @@ func (r *BlockRepository) GetByYardID(yardID int) ([]model.Block, error) {
 }
 
 // GetByID retrieves a block by ID
-func (r *BlockRepository) GetByID(id int) (*model.Block, error) {
+func (r *BlockRepository) GetByID(ctx context.Context, id int) (*model.Block, error) {
 	query := `
-		SELECT id, yard_id, code, name, max_slot, max_row, max_tier, created_at, updated_at
+		SELECT id, yard_id, code, name, max_slot, max_row, max_tier,
+		       origin_x, origin_y, orientation, slot_pitch, row_pitch, version, created_at, updated_at
 		FROM blocks
 		WHERE id = $1
 	`
 
 	var block model.Block
-	err := r.db.QueryRow(query, id).Scan(
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&block.ID,
 		&block.YardID,
 		&block.Code,
@@ -102,12 +119,18 @@ func (r *BlockRepository) GetByID(id int) (*model.Block, error) {
 		&block.MaxSlot,
 		&block.MaxRow,
 		&block.MaxTier,
+		&block.OriginX,
+		&block.OriginY,
+		&block.Orientation,
+		&block.SlotPitch,
+		&block.RowPitch,
+		&block.Version,
 		&block.CreatedAt,
 		&block.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("block with id %d not found", id)
+		return nil, apperror.New(apperror.CodeBlockNotFound, "block with id %d not found", id)
 	}
 
 	if err != nil {
@@ -116,3 +139,42 @@ func (r *BlockRepository) GetByID(id int) (*model.Block, error) {
 
 	return &block, nil
 }
+
+// Update saves the name, dimensions and geometry of a block and increments its
+// version. The block must still be at the given version; 0 matches any.
+func (r *BlockRepository) Update(ctx context.Context, block *model.Block, version int) error {
+	query := `
+		UPDATE blocks
+		SET name = $3, max_slot = $4, max_row = $5, max_tier = $6,
+		    origin_x = $7, origin_y = $8, orientation = $9, slot_pitch = $10, row_pitch = $11,
+		    version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND ($2 = 0 OR version = $2)
+		RETURNING version, updated_at
+	`
+
+	err := r.db.QueryRowContext(ctx,
+		query,
+		block.ID,
+		version,
+		block.Name,
+		block.MaxSlot,
+		block.MaxRow,
+		block.MaxTier,
+		block.OriginX,
+		block.OriginY,
+		block.Orientation,
+		block.SlotPitch,
+		block.RowPitch,
+	).Scan(&block.Version, &block.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return staleVersion(ctx, r.db, "blocks", block.ID, version,
+			apperror.New(apperror.CodeBlockNotFound, "block with id %d not found", block.ID))
+	}
+
+	if err != nil {
+		return fmt.Errorf("error updating block: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+type BulkJobRepository struct {
+	db dbtx
+}
+
+func NewBulkJobRepository(db *sql.DB) *BulkJobRepository {
+	return &BulkJobRepository{db: db}
+}
+
+const bulkJobColumns = `id, tenant, subject, kind, status, request, total, succeeded, failed, error, created_at, updated_at`
+
+// Create inserts a queued job in the caller's tenant
+func (r *BulkJobRepository) Create(ctx context.Context, job *model.BulkJob) error {
+	query := `
+		INSERT INTO bulk_jobs (tenant, subject, kind, status, request, total, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		RETURNING id
+	`
+
+	job.Tenant = ownerTenant(ctx)
+	job.Status = model.BulkJobQueued
+	job.CreatedAt = time.Now().UTC()
+	job.UpdatedAt = job.CreatedAt
+	err := r.db.QueryRowContext(ctx, query,
+		job.Tenant, job.Subject, job.Kind, job.Status, string(job.Request), job.Total, job.CreatedAt,
+	).Scan(&job.ID)
+	if err != nil {
+		return fmt.Errorf("error creating bulk job: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a job of the caller's tenant without its results
+func (r *BulkJobRepository) GetByID(ctx context.Context, id int) (*model.BulkJob, error) {
+	query := `SELECT ` + bulkJobColumns + ` FROM bulk_jobs WHERE id = $1 AND ($2 = '' OR tenant = $2)`
+
+	jobs, err := r.query(ctx, query, id, tenant.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, apperror.New(apperror.CodeNotFound, "bulk job %d not found", id)
+	}
+
+	return &jobs[0], nil
+}
+
+// GetUnfinished retrieves the queued and running jobs visible in ctx
+func (r *BulkJobRepository) GetUnfinished(ctx context.Context) ([]model.BulkJob, error) {
+	query := `
+		SELECT ` + bulkJobColumns + `
+		FROM bulk_jobs
+		WHERE status IN ($1, $2) AND ($3 = '' OR tenant = $3)
+		ORDER BY id
+	`
+
+	return r.query(ctx, query, model.BulkJobQueued, model.BulkJobRunning, tenant.FromContext(ctx))
+}
+
+// GetResults retrieves the results of a job in item order
+func (r *BulkJobRepository) GetResults(ctx context.Context, id int) ([]model.BulkJobResult, error) {
+	query := `
+		SELECT res.result
+		FROM bulk_job_results res
+		JOIN bulk_jobs j ON j.id = res.job_id
+		WHERE res.job_id = $1 AND ($2 = '' OR j.tenant = $2)
+		ORDER BY res.item_index
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, id, tenant.FromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("error querying bulk job results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []model.BulkJobResult
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("error scanning bulk job result: %w", err)
+		}
+		var result model.BulkJobResult
+		if err := json.Unmarshal([]byte(data), &result); err != nil {
+			return nil, fmt.Errorf("error decoding bulk job result: %w", err)
+		}
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+// AddResult records the result of an item and counts it in the progress of
+// the job, both or neither
+func (r *BulkJobRepository) AddResult(ctx context.Context, id int, result model.BulkJobResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("error encoding bulk job result: %w", err)
+	}
+
+	counter := "failed"
+	if result.Success {
+		counter = "succeeded"
+	}
+
+	return withTx(ctx, r.db, func(tx dbtx) error {
+		query := `
+			UPDATE bulk_jobs SET ` + counter + ` = ` + counter + ` + 1, updated_at = $3
+			WHERE id = $1 AND ($2 = '' OR tenant = $2)
+		`
+		res, err := tx.ExecContext(ctx, query, id, tenant.FromContext(ctx), time.Now().UTC())
+		if err != nil {
+			return fmt.Errorf("error updating bulk job progress: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return fmt.Errorf("error getting rows affected: %w", err)
+		} else if n == 0 {
+			return apperror.New(apperror.CodeNotFound, "bulk job %d not found", id)
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO bulk_job_results (job_id, item_index, result) VALUES ($1, $2, $3)`,
+			id, result.Index, string(data))
+		if err != nil {
+			return fmt.Errorf("error adding bulk job result: %w", uniqueErr(err))
+		}
+		return nil
+	})
+}
+
+// SetStatus changes the status of a job whose status is one of from, and
+// reports whether it did
+func (r *BulkJobRepository) SetStatus(ctx context.Context, id int, status, message string, from ...string) (bool, error) {
+	args := []interface{}{id, tenant.FromContext(ctx), status, message, time.Now().UTC()}
+	placeholders := make([]string, len(from))
+	for i, s := range from {
+		args = append(args, s)
+		placeholders[i] = fmt.Sprintf("$%d", len(args))
+	}
+
+	query := `
+		UPDATE bulk_jobs SET status = $3, error = $4, updated_at = $5
+		WHERE id = $1 AND ($2 = '' OR tenant = $2) AND status IN (` + strings.Join(placeholders, ", ") + `)
+	`
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("error updating bulk job status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *BulkJobRepository) query(ctx context.Context, query string, args ...interface{}) ([]model.BulkJob, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying bulk jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []model.BulkJob
+	for rows.Next() {
+		var (
+			job     model.BulkJob
+			request string
+		)
+		err := rows.Scan(
+			&job.ID,
+			&job.Tenant,
+			&job.Subject,
+			&job.Kind,
+			&job.Status,
+			&request,
+			&job.Total,
+			&job.Succeeded,
+			&job.Failed,
+			&job.Error,
+			&job.CreatedAt,
+			&job.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning bulk job: %w", err)
+		}
+		job.Request = []byte(request)
+		job.Processed = job.Succeeded + job.Failed
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+)
+
+type ClosureRepository struct {
+	db dbtx
+}
+
+func NewClosureRepository(db *sql.DB) *ClosureRepository {
+	return &ClosureRepository{db: db}
+}
+
+// Create inserts a new block closure
+func (r *ClosureRepository) Create(ctx context.Context, closure *model.BlockClosure) error {
+	query := `
+		INSERT INTO block_closures (
+			yard_id, block_id, slot_start, slot_end, row_start, row_end,
+			reason, starts_at, ends_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRowContext(ctx,
+		query,
+		closure.YardID,
+		closure.BlockID,
+		closure.SlotStart,
+		closure.SlotEnd,
+		closure.RowStart,
+		closure.RowEnd,
+		closure.Reason,
+		closure.StartsAt,
+		closure.EndsAt,
+	).Scan(&closure.ID, &closure.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("error creating block closure: %w", err)
+	}
+
+	return nil
+}
+
+// GetActiveByYardID retrieves closures of a yard that are in effect at the given time
+func (r *ClosureRepository) GetActiveByYardID(ctx context.Context, yardID int, at time.Time) ([]model.BlockClosure, error) {
+	query := `
+		SELECT c.id, c.yard_id, c.block_id, COALESCE(b.code, ''), c.slot_start, c.slot_end,
+		       c.row_start, c.row_end, c.reason, c.starts_at, c.ends_at, c.created_at
+		FROM block_closures c
+		LEFT JOIN blocks b ON b.id = c.block_id
+		WHERE c.yard_id = $1
+		  AND c.starts_at <= $2
+		  AND c.ends_at > $2
+		ORDER BY c.starts_at, c.id
+	`
+
+	return r.query(ctx, query, yardID, at)
+}
+
+// GetCurrentAndUpcomingByYardID retrieves closures of a yard that have not ended yet
+func (r *ClosureRepository) GetCurrentAndUpcomingByYardID(ctx context.Context, yardID int, at time.Time) ([]model.BlockClosure, error) {
+	query := `
+		SELECT c.id, c.yard_id, c.block_id, COALESCE(b.code, ''), c.slot_start, c.slot_end,
+		       c.row_start, c.row_end, c.reason, c.starts_at, c.ends_at, c.created_at
+		FROM block_closures c
+		LEFT JOIN blocks b ON b.id = c.block_id
+		WHERE c.yard_id = $1
+		  AND c.ends_at > $2
+		ORDER BY c.starts_at, c.id
+	`
+
+	return r.query(ctx, query, yardID, at)
+}
+
+func (r *ClosureRepository) query(ctx context.Context, query string, args ...interface{}) ([]model.BlockClosure, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying block closures: %w", err)
+	}
+	defer rows.Close()
+
+	var closures []model.BlockClosure
+	for rows.Next() {
+		var (
+			closure                                       model.BlockClosure
+			blockID, slotStart, slotEnd, rowStart, rowEnd sql.NullInt64
+		)
+		err := rows.Scan(
+			&closure.ID,
+			&closure.YardID,
+			&blockID,
+			&closure.BlockCode,
+			&slotStart,
+			&slotEnd,
+			&rowStart,
+			&rowEnd,
+			&closure.Reason,
+			&closure.StartsAt,
+			&closure.EndsAt,
+			&closure.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning block closure: %w", err)
+		}
+		closure.BlockID = nullIntPtr(blockID)
+		closure.SlotStart = nullIntPtr(slotStart)
+		closure.SlotEnd = nullIntPtr(slotEnd)
+		closure.RowStart = nullIntPtr(rowStart)
+		closure.RowEnd = nullIntPtr(rowEnd)
+		closures = append(closures, closure)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating block closures: %w", err)
+	}
+
+	return closures, nil
+}
+
+// nullIntPtr converts a nullable column into an optional int
+func nullIntPtr(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int64)
+	return &i
+}
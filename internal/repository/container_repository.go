@@ -1,32 +1,45 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
 	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
 )
 
+// containerColumns are the columns scanned into a model.Container
+const containerColumns = `id, container_number, yard_id, block_id, slot, row, tier,
+		       container_size, container_height, container_type, placed_at, tenant, hold`
+
+// Block-wide existence checks such as IsPositionOccupied are not scoped to a
+// tenant: a cell is physically taken whoever owns the box in it.
 type ContainerRepository struct {
-	db *sql.DB
+	db dbtx
 }
 
 func NewContainerRepository(db *sql.DB) *ContainerRepository {
 	return &ContainerRepository{db: db}
 }
 
-// Create inserts a new container into the database
-func (r *ContainerRepository) Create(container *model.Container) error {
+// Create inserts a new container into the database. The container belongs
+// to the tenant of its yard, which must be the caller's tenant.
+func (r *ContainerRepository) Create(ctx context.Context, container *model.Container) error {
 	query := `
 		INSERT INTO containers (
-			container_number, yard_id, block_id, slot, row, tier,
+			tenant, container_number, yard_id, block_id, slot, row, tier,
 			container_size, container_height, container_type
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-		RETURNING id, placed_at
+		VALUES (
+			(SELECT y.tenant FROM yards y WHERE y.id = $2 AND ($10 = '' OR y.tenant = $10)),
+			$1, $2, $3, $4, $5, $6, $7, $8, $9
+		)
+		RETURNING id, placed_at, tenant
 	`
 
-	err := r.db.QueryRow(
+	err := r.db.QueryRowContext(ctx,
 		query,
 		container.ContainerNumber,
 		container.YardID,
@@ -37,26 +50,29 @@ func (r *ContainerRepository) Create(container *model.Container) error {
 		container.ContainerSize,
 		container.ContainerHeight,
 		container.ContainerType,
-	).Scan(&container.ID, &container.PlacedAt)
+		tenant.FromContext(ctx),
+	).Scan(&container.ID, &container.PlacedAt, &container.Tenant)
 
+	if isNotNullViolation(err) {
+		return apperror.New(apperror.CodeYardNotFound, "yard with id %d not found", container.YardID)
+	}
 	if err != nil {
-		return fmt.Errorf("error creating container: %w", err)
+		return fmt.Errorf("error creating container: %w", uniqueErr(err))
 	}
 
 	return nil
 }
 
-// GetByNumber retrieves a container by its number
-func (r *ContainerRepository) GetByNumber(containerNumber string) (*model.Container, error) {
+// GetByNumber retrieves a container of the caller's tenant by its number
+func (r *ContainerRepository) GetByNumber(ctx context.Context, containerNumber string) (*model.Container, error) {
 	query := `
-		SELECT id, container_number, yard_id, block_id, slot, row, tier,
-		       container_size, container_height, container_type, placed_at
+		SELECT ` + containerColumns + `
 		FROM containers
-		WHERE container_number = $1
+		WHERE container_number = $1 AND ($2 = '' OR tenant = $2)
 	`
 
 	var container model.Container
-	err := r.db.QueryRow(query, containerNumber).Scan(
+	err := r.db.QueryRowContext(ctx, query, containerNumber, tenant.FromContext(ctx)).Scan(
 		&container.ID,
 		&container.ContainerNumber,
 		&container.YardID,
@@ -68,10 +84,12 @@ func (r *ContainerRepository) GetByNumber(containerNumber string) (*model.Contai
 		&container.ContainerHeight,
 		&container.ContainerType,
 		&container.PlacedAt,
+		&container.Tenant,
+		&container.Hold,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("container '%s' not found", containerNumber)
+		return nil, apperror.New(apperror.CodeContainerNotFound, "container '%s' not found", containerNumber)
 	}
 
 	if err != nil {
@@ -81,11 +99,11 @@ func (r *ContainerRepository) GetByNumber(containerNumber string) (*model.Contai
 	return &container, nil
 }
 
-// Delete removes a container from the database
-func (r *ContainerRepository) Delete(containerNumber string) error {
-	query := `DELETE FROM containers WHERE container_number = $1`
+// Delete removes a container of the caller's tenant from the database
+func (r *ContainerRepository) Delete(ctx context.Context, containerNumber string) error {
+	query := `DELETE FROM containers WHERE container_number = $1 AND ($2 = '' OR tenant = $2)`
 
-	result, err := r.db.Exec(query, containerNumber)
+	result, err := r.db.ExecContext(ctx, query, containerNumber, tenant.FromContext(ctx))
 	if err != nil {
 		return fmt.Errorf("error deleting container: %w", err)
 	}
@@ -96,7 +114,57 @@ func (r *ContainerRepository) Delete(containerNumber string) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("container '%s' not found", containerNumber)
+		return apperror.New(apperror.CodeContainerNotFound, "container '%s' not found", containerNumber)
+	}
+
+	return nil
+}
+
+// UpdatePosition moves a container of the caller's tenant to another
+// position. The target block must be in the yard of the container.
+func (r *ContainerRepository) UpdatePosition(ctx context.Context, containerNumber string, blockID, slot, row, tier int) error {
+	query := `
+		UPDATE containers
+		SET block_id = $2, slot = $3, row = $4, tier = $5
+		WHERE container_number = $1
+		  AND ($6 = '' OR tenant = $6)
+		  AND EXISTS (SELECT 1 FROM blocks b WHERE b.id = $2 AND b.yard_id = containers.yard_id)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, containerNumber, blockID, slot, row, tier, tenant.FromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("error moving container: %w", uniqueErr(err))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperror.New(apperror.CodeContainerNotFound, "container '%s' not found", containerNumber)
+	}
+
+	return nil
+}
+
+// SetHold puts a container of the caller's tenant on hold for the given
+// reason; an empty reason releases the hold
+func (r *ContainerRepository) SetHold(ctx context.Context, containerNumber, reason string) error {
+	query := `UPDATE containers SET hold = $2 WHERE container_number = $1 AND ($3 = '' OR tenant = $3)`
+
+	result, err := r.db.ExecContext(ctx, query, containerNumber, reason, tenant.FromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("error setting container hold: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperror.New(apperror.CodeContainerNotFound, "container '%s' not found", containerNumber)
 	}
 
 	return nil
@@ -104,7 +172,7 @@ func (r *ContainerRepository) Delete(containerNumber string) error {
 
 // IsPositionOccupied checks if a specific position is occupied
 // For 40ft containers, checks both slots
-func (r *ContainerRepository) IsPositionOccupied(blockID, slot, row, tier int, containerSize int) (bool, error) {
+func (r *ContainerRepository) IsPositionOccupied(ctx context.Context, blockID, slot, row, tier int, containerSize int) (bool, error) {
 	var query string
 	var args []interface{}
 
@@ -133,7 +201,7 @@ func (r *ContainerRepository) IsPositionOccupied(blockID, slot, row, tier int, c
 	}
 
 	var occupied bool
-	err := r.db.QueryRow(query, args...).Scan(&occupied)
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&occupied)
 	if err != nil {
 		return false, fmt.Errorf("error checking position: %w", err)
 	}
@@ -142,20 +210,20 @@ func (r *ContainerRepository) IsPositionOccupied(blockID, slot, row, tier int, c
 }
 
 // GetOccupiedPositionsInArea retrieves all occupied positions within a specific area
-func (r *ContainerRepository) GetOccupiedPositionsInArea(blockID, slotStart, slotEnd, rowStart, rowEnd int) ([]model.Container, error) {
+func (r *ContainerRepository) GetOccupiedPositionsInArea(ctx context.Context, blockID, slotStart, slotEnd, rowStart, rowEnd int) ([]model.Container, error) {
 	query := `
-		SELECT id, container_number, yard_id, block_id, slot, row, tier,
-		       container_size, container_height, container_type, placed_at
+		SELECT ` + containerColumns + `
 		FROM containers
 		WHERE block_id = $1
 		  AND slot >= $2
 		  AND slot <= $3
 		  AND row >= $4
 		  AND row <= $5
+		  AND ($6 = '' OR tenant = $6)
 		ORDER BY slot, row, tier
 	`
 
-	rows, err := r.db.Query(query, blockID, slotStart, slotEnd, rowStart, rowEnd)
+	rows, err := r.db.QueryContext(ctx, query, blockID, slotStart, slotEnd, rowStart, rowEnd, tenant.FromContext(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("error querying containers: %w", err)
 	}
@@ -176,6 +244,8 @@ func (r *ContainerRepository) GetOccupiedPositionsInArea(blockID, slotStart, slo
 			&container.ContainerHeight,
 			&container.ContainerType,
 			&container.PlacedAt,
+			&container.Tenant,
+			&container.Hold,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning container: %w", err)
@@ -187,7 +257,7 @@ func (r *ContainerRepository) GetOccupiedPositionsInArea(blockID, slotStart, slo
 }
 
 // IsContainerBlocked checks if there's a container above the given position
-func (r *ContainerRepository) IsContainerBlocked(blockID, slot, row, tier int) (bool, error) {
+func (r *ContainerRepository) IsContainerBlocked(ctx context.Context, blockID, slot, row, tier int) (bool, error) {
 	query := `
 		SELECT COUNT(*) > 0
 		FROM containers
@@ -198,7 +268,7 @@ func (r *ContainerRepository) IsContainerBlocked(blockID, slot, row, tier int) (
 	`
 
 	var blocked bool
-	err := r.db.QueryRow(query, blockID, slot, row, tier).Scan(&blocked)
+	err := r.db.QueryRowContext(ctx, query, blockID, slot, row, tier).Scan(&blocked)
 	if err != nil {
 		return false, fmt.Errorf("error checking if blocked: %w", err)
 	}
@@ -206,16 +276,16 @@ func (r *ContainerRepository) IsContainerBlocked(blockID, slot, row, tier int) (
 	return blocked, nil
 }
 
-// GetAll retrieves all containers
-func (r *ContainerRepository) GetAll() ([]model.Container, error) {
+// GetAll retrieves all containers of the caller's tenant
+func (r *ContainerRepository) GetAll(ctx context.Context) ([]model.Container, error) {
 	query := `
-		SELECT id, container_number, yard_id, block_id, slot, row, tier,
-		       container_size, container_height, container_type, placed_at
+		SELECT ` + containerColumns + `
 		FROM containers
+		WHERE $1 = '' OR tenant = $1
 		ORDER BY placed_at DESC
 	`
 
-	rows, err := r.db.Query(query)
+	rows, err := r.db.QueryContext(ctx, query, tenant.FromContext(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("error querying containers: %w", err)
 	}
@@ -236,6 +306,8 @@ func (r *ContainerRepository) GetAll() ([]model.Container, error) {
 			&container.ContainerHeight,
 			&container.ContainerType,
 			&container.PlacedAt,
+			&container.Tenant,
+			&container.Hold,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning container: %w", err)
@@ -247,16 +319,15 @@ func (r *ContainerRepository) GetAll() ([]model.Container, error) {
 }
 
 // GetByBlock retrieves all containers in a specific block
-func (r *ContainerRepository) GetByBlock(blockID int) ([]model.Container, error) {
+func (r *ContainerRepository) GetByBlock(ctx context.Context, blockID int) ([]model.Container, error) {
 	query := `
-		SELECT id, container_number, yard_id, block_id, slot, row, tier,
-		       container_size, container_height, container_type, placed_at
+		SELECT ` + containerColumns + `
 		FROM containers
-		WHERE block_id = $1
+		WHERE block_id = $1 AND ($2 = '' OR tenant = $2)
 		ORDER BY slot, row, tier
 	`
 
-	rows, err := r.db.Query(query, blockID)
+	rows, err := r.db.QueryContext(ctx, query, blockID, tenant.FromContext(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("error querying containers: %w", err)
 	}
@@ -277,6 +348,8 @@ func (r *ContainerRepository) GetByBlock(blockID int) ([]model.Container, error)
 			&container.ContainerHeight,
 			&container.ContainerType,
 			&container.PlacedAt,
+			&container.Tenant,
+			&container.Hold,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning container: %w", err)
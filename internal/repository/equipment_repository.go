@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+type EquipmentRepository struct {
+	db dbtx
+}
+
+func NewEquipmentRepository(db *sql.DB) *EquipmentRepository {
+	return &EquipmentRepository{db: db}
+}
+
+// GetByYardID retrieves all equipment of a yard together with the blocks it
+// serves and its number of outstanding work orders
+func (r *EquipmentRepository) GetByYardID(ctx context.Context, yardID int) ([]model.Equipment, error) {
+	query := `
+		SELECT e.id, e.yard_id, e.code, e.equipment_type, e.active, e.created_at, e.updated_at,
+		       (SELECT COUNT(*) FROM work_orders w
+		        WHERE w.equipment_id = e.id AND w.status NOT IN ('COMPLETED', 'FAILED'))
+		FROM equipment e
+		WHERE e.yard_id = $1
+		ORDER BY e.code
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, yardID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying equipment: %w", err)
+	}
+	defer rows.Close()
+
+	var equipment []model.Equipment
+	index := make(map[int]int)
+	for rows.Next() {
+		var e model.Equipment
+		err := rows.Scan(
+			&e.ID,
+			&e.YardID,
+			&e.Code,
+			&e.EquipmentType,
+			&e.Active,
+			&e.CreatedAt,
+			&e.UpdatedAt,
+			&e.OutstandingOrders,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning equipment: %w", err)
+		}
+		e.Blocks = []string{}
+		index[e.ID] = len(equipment)
+		equipment = append(equipment, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating equipment: %w", err)
+	}
+
+	blockQuery := `
+		SELECT eb.equipment_id, b.id, b.code
+		FROM equipment_blocks eb
+		JOIN equipment e ON e.id = eb.equipment_id
+		JOIN blocks b ON b.id = eb.block_id
+		WHERE e.yard_id = $1
+		ORDER BY b.code
+	`
+
+	blockRows, err := r.db.QueryContext(ctx, blockQuery, yardID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying equipment blocks: %w", err)
+	}
+	defer blockRows.Close()
+
+	for blockRows.Next() {
+		var (
+			equipmentID, blockID int
+			blockCode            string
+		)
+		if err := blockRows.Scan(&equipmentID, &blockID, &blockCode); err != nil {
+			return nil, fmt.Errorf("error scanning equipment block: %w", err)
+		}
+		if i, ok := index[equipmentID]; ok {
+			equipment[i].BlockIDs = append(equipment[i].BlockIDs, blockID)
+			equipment[i].Blocks = append(equipment[i].Blocks, blockCode)
+		}
+	}
+
+	return equipment, nil
+}
+
+// GetByYardAndCode retrieves a piece of equipment by yard ID and equipment code
+func (r *EquipmentRepository) GetByYardAndCode(ctx context.Context, yardID int, code string) (*model.Equipment, error) {
+	equipment, err := r.GetByYardID(ctx, yardID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range equipment {
+		if equipment[i].Code == code {
+			return &equipment[i], nil
+		}
+	}
+
+	return nil, apperror.New(apperror.CodeEquipmentNotFound, "equipment with code '%s' not found in yard", code)
+}
+
+// Create inserts a new piece of equipment and the blocks it serves
+func (r *EquipmentRepository) Create(ctx context.Context, equipment *model.Equipment) error {
+	return withTx(ctx, r.db, func(tx dbtx) error {
+		query := `
+			INSERT INTO equipment (yard_id, code, equipment_type, active)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, created_at, updated_at
+		`
+
+		err := tx.QueryRowContext(ctx,
+			query,
+			equipment.YardID,
+			equipment.Code,
+			equipment.EquipmentType,
+			equipment.Active,
+		).Scan(&equipment.ID, &equipment.CreatedAt, &equipment.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("error creating equipment: %w", uniqueErr(err))
+		}
+
+		for _, blockID := range equipment.BlockIDs {
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO equipment_blocks (equipment_id, block_id) VALUES ($1, $2)`,
+				equipment.ID,
+				blockID,
+			)
+			if err != nil {
+				return fmt.Errorf("error assigning equipment block: %w", uniqueErr(err))
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// PostgreSQL error codes of constraint violations
+const (
+	uniqueViolation  = "23505"
+	notNullViolation = "23502"
+)
+
+// uniqueErr marks a unique constraint violation of any supported database as
+// ErrDuplicate, keeping the driver message. Other errors are returned as is.
+func uniqueErr(err error) error {
+	if isUniqueViolation(err) {
+		return fmt.Errorf("%w: %v", ErrDuplicate, err)
+	}
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == uniqueViolation
+	}
+
+	// SQLite reports SQLITE_CONSTRAINT_UNIQUE and SQLITE_CONSTRAINT_PRIMARYKEY
+	// with a stable message prefix
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// isNotNullViolation reports whether err is a NOT NULL constraint violation
+func isNotNullViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == notNullViolation
+	}
+	return err != nil && strings.Contains(err.Error(), "NOT NULL constraint failed")
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type FenceRepository struct {
+	db dbtx
+}
+
+func NewFenceRepository(db *sql.DB) *FenceRepository {
+	return &FenceRepository{db: db}
+}
+
+// Check records token as the newest fencing token of a lock, unless a newer
+// one has been recorded
+func (r *FenceRepository) Check(ctx context.Context, key string, token int64) error {
+	query := `
+		INSERT INTO lock_fences (lock_key, token, updated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (lock_key) DO UPDATE
+		SET token = excluded.token, updated_at = excluded.updated_at
+		WHERE lock_fences.token <= excluded.token
+	`
+
+	result, err := r.db.ExecContext(ctx, query, key, token)
+	if err != nil {
+		return fmt.Errorf("error checking fencing token: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: lock %s, token %d", ErrFenced, key, token)
+	}
+
+	return nil
+}
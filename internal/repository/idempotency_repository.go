@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+type IdempotencyRepository struct {
+	db dbtx
+}
+
+func NewIdempotencyRepository(db *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve records a request of the caller's tenant before it is processed
+func (r *IdempotencyRepository) Reserve(ctx context.Context, record *model.IdempotencyRecord) error {
+	query := `
+		INSERT INTO idempotency_keys (tenant, subject, idempotency_key, request_hash, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+
+	// created_at is set here so DeleteBefore compares times of one clock
+	record.Tenant = ownerTenant(ctx)
+	record.CreatedAt = time.Now().UTC()
+	err := r.db.QueryRowContext(ctx, query, record.Tenant, record.Subject, record.Key, record.RequestHash, record.CreatedAt).
+		Scan(&record.ID)
+	if err != nil {
+		return fmt.Errorf("error reserving idempotency key: %w", uniqueErr(err))
+	}
+
+	return nil
+}
+
+// Get retrieves the record of a key of the caller in the caller's tenant
+func (r *IdempotencyRepository) Get(ctx context.Context, subject, key string) (*model.IdempotencyRecord, error) {
+	query := `
+		SELECT id, tenant, subject, idempotency_key, request_hash, status_code, response_body, created_at
+		FROM idempotency_keys
+		WHERE tenant = $1 AND subject = $2 AND idempotency_key = $3
+	`
+
+	var (
+		record   model.IdempotencyRecord
+		response sql.NullString
+	)
+	err := r.db.QueryRowContext(ctx, query, ownerTenant(ctx), subject, key).Scan(
+		&record.ID,
+		&record.Tenant,
+		&record.Subject,
+		&record.Key,
+		&record.RequestHash,
+		&record.StatusCode,
+		&response,
+		&record.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, apperror.New(apperror.CodeNotFound, "idempotency key not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying idempotency key: %w", err)
+	}
+	if response.Valid {
+		record.Response = []byte(response.String)
+	}
+
+	return &record, nil
+}
+
+// Complete stores the response of a reserved request
+func (r *IdempotencyRepository) Complete(ctx context.Context, id, statusCode int, response []byte) error {
+	query := `UPDATE idempotency_keys SET status_code = $2, response_body = $3 WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id, statusCode, string(response))
+	if err != nil {
+		return fmt.Errorf("error completing idempotency key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperror.New(apperror.CodeNotFound, "idempotency key %d not found", id)
+	}
+
+	return nil
+}
+
+// Delete removes a record, e.g. when its request failed and may be retried
+func (r *IdempotencyRepository) Delete(ctx context.Context, id int) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("error deleting idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteBefore removes the records of all tenants created before the given time
+func (r *IdempotencyRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE created_at < $1`, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("error deleting idempotency keys: %w", err)
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+// ErrDuplicate is returned by storage backends when a write violates a
+// uniqueness rule. It is a CONFLICT domain error.
+var ErrDuplicate error = apperror.New(apperror.CodeConflict, "duplicate key")
+
+// ErrCellReserved is returned by WorkOrderStore.Create when an unfinished
+// work order already puts a container into the cell. It is a
+// POSITION_RESERVED domain error.
+var ErrCellReserved error = apperror.New(apperror.CodePositionReserved, "position is reserved by a pending work order")
+
+// ErrFenced is returned by FenceStore.Check when a newer owner of the lock has
+// written since. It is a CONFLICT domain error.
+var ErrFenced error = apperror.New(apperror.CodeConflict, "the lock was taken over by a newer owner")
+
+// YardStore provides access to yards, their points of interest and overflow
+// rules. Yard codes are unique per tenant.
+type YardStore interface {
+	// GetByCode returns the yard with the code in the tenant of ctx
+	GetByCode(ctx context.Context, code string) (*model.Yard, error)
+	GetAll(ctx context.Context) ([]model.Yard, error)
+	GetPointsByYardID(ctx context.Context, yardID int) ([]model.YardPoint, error)
+	CreatePoint(ctx context.Context, point *model.YardPoint) error
+	GetOverflowRules(ctx context.Context, yardID int) ([]model.OverflowRule, error)
+	CreateOverflowRule(ctx context.Context, rule *model.OverflowRule) error
+}
+
+// BlockStore provides access to the blocks of a yard
+type BlockStore interface {
+	GetByYardAndCode(ctx context.Context, yardID int, code string) (*model.Block, error)
+	GetByYardID(ctx context.Context, yardID int) ([]model.Block, error)
+	GetByID(ctx context.Context, id int) (*model.Block, error)
+	// Update saves a block that is still at the given version and increments
+	// the version; a changed block returns PRECONDITION_FAILED
+	Update(ctx context.Context, block *model.Block, version int) error
+}
+
+// YardPlanStore provides access to yard plans
+type YardPlanStore interface {
+	FindMatchingPlan(ctx context.Context, blockID int, size int, height float64, containerType string) (*model.YardPlan, error)
+	GetByBlockID(ctx context.Context, blockID int) ([]model.YardPlan, error)
+	GetByID(ctx context.Context, id int) (*model.YardPlan, error)
+	Create(ctx context.Context, plan *model.YardPlan) error
+	// Update and Delete only change a plan that is still at the given version;
+	// a changed plan returns PRECONDITION_FAILED
+	Update(ctx context.Context, plan *model.YardPlan, version int) error
+	Delete(ctx context.Context, id, version int) error
+}
+
+// ContainerStore provides access to the containers stored in the yard. A cell
+// (block_id, slot, row, tier) and a container number can only be used once.
+type ContainerStore interface {
+	Create(ctx context.Context, container *model.Container) error
+	GetByNumber(ctx context.Context, containerNumber string) (*model.Container, error)
+	Delete(ctx context.Context, containerNumber string) error
+	UpdatePosition(ctx context.Context, containerNumber string, blockID, slot, row, tier int) error
+	// SetHold puts a container on hold; an empty reason releases the hold
+	SetHold(ctx context.Context, containerNumber, reason string) error
+	IsPositionOccupied(ctx context.Context, blockID, slot, row, tier int, containerSize int) (bool, error)
+	GetOccupiedPositionsInArea(ctx context.Context, blockID, slotStart, slotEnd, rowStart, rowEnd int) ([]model.Container, error)
+	IsContainerBlocked(ctx context.Context, blockID, slot, row, tier int) (bool, error)
+	GetAll(ctx context.Context) ([]model.Container, error)
+	GetByBlock(ctx context.Context, blockID int) ([]model.Container, error)
+}
+
+// ClosureStore provides access to block closures
+type ClosureStore interface {
+	Create(ctx context.Context, closure *model.BlockClosure) error
+	GetActiveByYardID(ctx context.Context, yardID int, at time.Time) ([]model.BlockClosure, error)
+	GetCurrentAndUpcomingByYardID(ctx context.Context, yardID int, at time.Time) ([]model.BlockClosure, error)
+}
+
+// EquipmentStore provides access to yard equipment
+type EquipmentStore interface {
+	GetByYardID(ctx context.Context, yardID int) ([]model.Equipment, error)
+	GetByYardAndCode(ctx context.Context, yardID int, code string) (*model.Equipment, error)
+	Create(ctx context.Context, equipment *model.Equipment) error
+}
+
+// WorkOrderStore provides access to equipment work orders
+type WorkOrderStore interface {
+	// Create inserts a work order; an order reserving a cell that an
+	// unfinished order already reserves returns ErrCellReserved
+	Create(ctx context.Context, order *model.WorkOrder) error
+	GetByID(ctx context.Context, id int) (*model.WorkOrder, error)
+	GetPendingByYardID(ctx context.Context, yardID int) ([]model.WorkOrder, error)
+	HasPendingForContainer(ctx context.Context, containerNumber string) (bool, error)
+	GetActiveForEquipment(ctx context.Context, equipmentID int) (*model.WorkOrder, error)
+	DispatchNext(ctx context.Context, equipmentID int) (*model.WorkOrder, error)
+	UpdateStatus(ctx context.Context, id int, from, to, reason string) error
+}
+
+// APIKeyStore provides access to the static API keys. Keys are never deleted;
+// revoking or rotating a key sets its expiry.
+type APIKeyStore interface {
+	Create(ctx context.Context, key *model.APIKey) error
+	GetByHash(ctx context.Context, hash string) (*model.APIKey, error)
+	GetAll(ctx context.Context) ([]model.APIKey, error)
+	Expire(ctx context.Context, id int, at time.Time) error
+}
+
+// RoleBindingStore provides access to the roles granted to callers
+type RoleBindingStore interface {
+	Create(ctx context.Context, binding *model.RoleBinding) error
+	GetBySubject(ctx context.Context, subject string) ([]model.RoleBinding, error)
+	GetAll(ctx context.Context) ([]model.RoleBinding, error)
+	Delete(ctx context.Context, id int) error
+}
+
+// IdempotencyStore keeps the responses of requests sent with an
+// Idempotency-Key. Keys are scoped to the tenant of ctx and the caller.
+type IdempotencyStore interface {
+	// Reserve records a request before it is processed; a key that is already
+	// recorded returns ErrDuplicate
+	Reserve(ctx context.Context, record *model.IdempotencyRecord) error
+	Get(ctx context.Context, subject, key string) (*model.IdempotencyRecord, error)
+	Complete(ctx context.Context, id, statusCode int, response []byte) error
+	Delete(ctx context.Context, id int) error
+	// DeleteBefore removes the records created before the given time
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// BulkJobStore keeps background bulk jobs and the results of their items.
+// Jobs are scoped to the tenant of ctx.
+type BulkJobStore interface {
+	// Create inserts a job as QUEUED
+	Create(ctx context.Context, job *model.BulkJob) error
+	GetByID(ctx context.Context, id int) (*model.BulkJob, error)
+	// GetUnfinished returns the queued and running jobs, oldest first
+	GetUnfinished(ctx context.Context) ([]model.BulkJob, error)
+	GetResults(ctx context.Context, id int) ([]model.BulkJobResult, error)
+	// AddResult records the result of an item and counts it in the progress
+	// of the job; an item that already has a result returns ErrDuplicate
+	AddResult(ctx context.Context, id int, result model.BulkJobResult) error
+	// SetStatus changes the status of a job whose status is one of from, and
+	// reports whether it did
+	SetStatus(ctx context.Context, id int, status, message string, from ...string) (bool, error)
+}
+
+// FenceStore records the fencing tokens of the locks that guard writes. A
+// write under a lock checks its token in the same transaction, so the write of
+// an owner whose lock expired is rolled back.
+type FenceStore interface {
+	// Check records token as the newest token of the lock key; a token lower
+	// than one recorded before returns ErrFenced
+	Check(ctx context.Context, key string, token int64) error
+}
+
+// Transactor runs fn with repositories whose writes are committed together
+// when fn returns nil and discarded when it returns an error. The
+// repositories passed to fn must not be used after it returns.
+type Transactor func(ctx context.Context, fn func(tx Stores) error) error
+
+// Stores bundles the repositories of one storage backend
+type Stores struct {
+	Yards       YardStore
+	Blocks      BlockStore
+	Plans       YardPlanStore
+	Containers  ContainerStore
+	Closures    ClosureStore
+	Equipment   EquipmentStore
+	WorkOrders  WorkOrderStore
+	APIKeys     APIKeyStore
+	Roles       RoleBindingStore
+	Idempotency IdempotencyStore
+	BulkJobs    BulkJobStore
+	Fences      FenceStore
+	// Atomic runs a group of writes in one transaction
+	Atomic Transactor
+}
+
+// NewSQLStores creates the SQL repositories on top of a database connection
+func NewSQLStores(db *sql.DB) Stores {
+	return sqlStores(db)
+}
+
+// sqlStores creates the SQL repositories on top of a connection or a
+// transaction. Stores of a transaction run nested groups of writes in it.
+func sqlStores(db dbtx) Stores {
+	return Stores{
+		Yards:       &YardRepository{db: db},
+		Blocks:      &BlockRepository{db: db},
+		Plans:       &YardPlanRepository{db: db},
+		Containers:  &ContainerRepository{db: db},
+		Closures:    &ClosureRepository{db: db},
+		Equipment:   &EquipmentRepository{db: db},
+		WorkOrders:  &WorkOrderRepository{db: db},
+		APIKeys:     &APIKeyRepository{db: db},
+		Roles:       &RoleBindingRepository{db: db},
+		Idempotency: &IdempotencyRepository{db: db},
+		BulkJobs:    &BulkJobRepository{db: db},
+		Fences:      &FenceRepository{db: db},
+		Atomic: func(ctx context.Context, fn func(tx Stores) error) error {
+			return withTx(ctx, db, func(tx dbtx) error {
+				return fn(sqlStores(tx))
+			})
+		},
+	}
+}
+
+// Compile-time checks that the SQL repositories implement the interfaces
+var (
+	_ YardStore        = (*YardRepository)(nil)
+	_ BlockStore       = (*BlockRepository)(nil)
+	_ YardPlanStore    = (*YardPlanRepository)(nil)
+	_ ContainerStore   = (*ContainerRepository)(nil)
+	_ ClosureStore     = (*ClosureRepository)(nil)
+	_ EquipmentStore   = (*EquipmentRepository)(nil)
+	_ WorkOrderStore   = (*WorkOrderRepository)(nil)
+	_ APIKeyStore      = (*APIKeyRepository)(nil)
+	_ RoleBindingStore = (*RoleBindingRepository)(nil)
+	_ IdempotencyStore = (*IdempotencyRepository)(nil)
+	_ BulkJobStore     = (*BulkJobRepository)(nil)
+	_ FenceStore       = (*FenceRepository)(nil)
+)
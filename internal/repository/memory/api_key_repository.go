@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+type APIKeyRepository struct {
+	store *Store
+}
+
+// Create inserts a new API key. Only the hash of the key is stored. A key
+// without a tenant belongs to the default tenant.
+func (r *APIKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range s.apiKeys {
+		if k.Hash == key.Hash {
+			return fmt.Errorf("error creating api key: %w", repository.ErrDuplicate)
+		}
+	}
+
+	if key.Tenant == "" {
+		key.Tenant = tenant.Default
+	}
+	key.ID = s.nextID("api_keys")
+	key.CreatedAt = time.Now()
+	stored := *key
+	stored.ExpiresAt = timePtr(key.ExpiresAt)
+	s.apiKeys = append(s.apiKeys, stored)
+
+	return nil
+}
+
+// GetByHash retrieves the API key with the given SHA-256 hash
+func (r *APIKeyRepository) GetByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, k := range s.apiKeys {
+		if k.Hash == hash {
+			key := k
+			key.ExpiresAt = timePtr(k.ExpiresAt)
+			return &key, nil
+		}
+	}
+
+	return nil, apperror.New(apperror.CodeNotFound, "api key not found")
+}
+
+// GetAll retrieves all API keys, including expired ones
+func (r *APIKeyRepository) GetAll(ctx context.Context) ([]model.APIKey, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var keys []model.APIKey
+	for _, k := range s.apiKeys {
+		key := k
+		key.ExpiresAt = timePtr(k.ExpiresAt)
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// Expire sets the time after which an API key is no longer accepted
+func (r *APIKeyRepository) Expire(ctx context.Context, id int, at time.Time) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.apiKeys {
+		if s.apiKeys[i].ID == id {
+			s.apiKeys[i].ExpiresAt = &at
+			return nil
+		}
+	}
+
+	return apperror.New(apperror.CodeNotFound, "api key %d not found", id)
+}
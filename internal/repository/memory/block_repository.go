@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+type BlockRepository struct {
+	store *Store
+}
+
+// Create inserts a new block. Unset geometry pitches get the schema defaults.
+func (r *BlockRepository) Create(ctx context.Context, block *model.Block) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if block.MaxSlot < 1 || block.MaxRow < 1 || block.MaxTier < 1 {
+		return fmt.Errorf("error creating block: max_slot, max_row and max_tier must be positive")
+	}
+	for _, b := range s.blocks {
+		if b.YardID == block.YardID && b.Code == block.Code {
+			return fmt.Errorf("error creating block: %w (yard_id, code)", repository.ErrDuplicate)
+		}
+	}
+	if block.SlotPitch == 0 {
+		block.SlotPitch = 6.5
+	}
+	if block.RowPitch == 0 {
+		block.RowPitch = 2.8
+	}
+
+	now := time.Now()
+	block.ID = s.nextID("blocks")
+	block.Version = 1
+	block.CreatedAt = now
+	block.UpdatedAt = now
+	s.blocks = append(s.blocks, *block)
+
+	return nil
+}
+
+// GetByYardAndCode retrieves a block by yard ID and block code
+func (r *BlockRepository) GetByYardAndCode(ctx context.Context, yardID int, code string) (*model.Block, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, b := range s.blocks {
+		if b.YardID == yardID && b.Code == code {
+			block := b
+			return &block, nil
+		}
+	}
+
+	return nil, apperror.New(apperror.CodeBlockNotFound, "block with code '%s' not found in yard", code)
+}
+
+// GetByYardID retrieves all blocks for a specific yard
+func (r *BlockRepository) GetByYardID(ctx context.Context, yardID int) ([]model.Block, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var blocks []model.Block
+	for _, b := range s.blocks {
+		if b.YardID == yardID {
+			blocks = append(blocks, b)
+		}
+	}
+	sort.SliceStable(blocks, func(i, j int) bool { return blocks[i].Code < blocks[j].Code })
+
+	return blocks, nil
+}
+
+// GetByID retrieves a block by ID
+func (r *BlockRepository) GetByID(ctx context.Context, id int) (*model.Block, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, b := range s.blocks {
+		if b.ID == id {
+			block := b
+			return &block, nil
+		}
+	}
+
+	return nil, apperror.New(apperror.CodeBlockNotFound, "block with id %d not found", id)
+}
+
+// Update saves the name, dimensions and geometry of a block and increments its
+// version. The block must still be at the given version; 0 matches any.
+func (r *BlockRepository) Update(ctx context.Context, block *model.Block, version int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if block.MaxSlot < 1 || block.MaxRow < 1 || block.MaxTier < 1 {
+		return fmt.Errorf("error updating block: max_slot, max_row and max_tier must be positive")
+	}
+	for i := range s.blocks {
+		b := &s.blocks[i]
+		if b.ID != block.ID {
+			continue
+		}
+		if version != 0 && b.Version != version {
+			return staleVersion(version, b.Version)
+		}
+
+		b.Name = block.Name
+		b.MaxSlot = block.MaxSlot
+		b.MaxRow = block.MaxRow
+		b.MaxTier = block.MaxTier
+		b.BlockGeometry = block.BlockGeometry
+		b.Version++
+		b.UpdatedAt = time.Now()
+		*block = *b
+		return nil
+	}
+
+	return apperror.New(apperror.CodeBlockNotFound, "block with id %d not found", block.ID)
+}
+
+// staleVersion is returned when a versioned write finds a newer version
+func staleVersion(version, current int) error {
+	return apperror.New(apperror.CodePreconditionFailed,
+		"version %d does not match the current version %d", version, current)
+}
@@ -0,0 +1,169 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+type BulkJobRepository struct {
+	store *Store
+}
+
+// bulkJobResult is a row of the results of bulk jobs
+type bulkJobResult struct {
+	jobID  int
+	result model.BulkJobResult
+}
+
+// Create inserts a queued job in the caller's tenant
+func (r *BulkJobRepository) Create(ctx context.Context, job *model.BulkJob) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job.ID = s.nextID("bulk_jobs")
+	job.Tenant = ownerTenant(ctx)
+	job.Status = model.BulkJobQueued
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = job.CreatedAt
+
+	stored := *job
+	stored.Request = slices.Clone(job.Request)
+	stored.Results = nil
+	s.bulkJobs = append(s.bulkJobs, stored)
+
+	return nil
+}
+
+// GetByID retrieves a job of the caller's tenant without its results
+func (r *BulkJobRepository) GetByID(ctx context.Context, id int) (*model.BulkJob, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if i := s.bulkJob(ctx, id); i >= 0 {
+		job := s.copyBulkJob(i)
+		return &job, nil
+	}
+
+	return nil, apperror.New(apperror.CodeNotFound, "bulk job %d not found", id)
+}
+
+// GetUnfinished retrieves the queued and running jobs visible in ctx
+func (r *BulkJobRepository) GetUnfinished(ctx context.Context) ([]model.BulkJob, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var jobs []model.BulkJob
+	for i, job := range s.bulkJobs {
+		if tenant.Matches(ctx, job.Tenant) && !job.IsFinished() {
+			jobs = append(jobs, s.copyBulkJob(i))
+		}
+	}
+
+	return jobs, nil
+}
+
+// GetResults retrieves the results of a job in item order
+func (r *BulkJobRepository) GetResults(ctx context.Context, id int) ([]model.BulkJobResult, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.bulkJob(ctx, id) < 0 {
+		return nil, nil
+	}
+
+	var results []model.BulkJobResult
+	for _, row := range s.bulkJobResults {
+		if row.jobID == id {
+			results = append(results, copyBulkJobResult(row.result))
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Index < results[j].Index })
+
+	return results, nil
+}
+
+// AddResult records the result of an item and counts it in the progress of the job
+func (r *BulkJobRepository) AddResult(ctx context.Context, id int, result model.BulkJobResult) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.bulkJob(ctx, id)
+	if i < 0 {
+		return apperror.New(apperror.CodeNotFound, "bulk job %d not found", id)
+	}
+	for _, row := range s.bulkJobResults {
+		if row.jobID == id && row.result.Index == result.Index {
+			return fmt.Errorf("error adding bulk job result: %w", repository.ErrDuplicate)
+		}
+	}
+
+	s.bulkJobResults = append(s.bulkJobResults, bulkJobResult{jobID: id, result: copyBulkJobResult(result)})
+	if result.Success {
+		s.bulkJobs[i].Succeeded++
+	} else {
+		s.bulkJobs[i].Failed++
+	}
+	s.bulkJobs[i].UpdatedAt = time.Now()
+
+	return nil
+}
+
+// SetStatus changes the status of a job whose status is one of from, and
+// reports whether it did
+func (r *BulkJobRepository) SetStatus(ctx context.Context, id int, status, message string, from ...string) (bool, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.bulkJob(ctx, id)
+	if i < 0 || !slices.Contains(from, s.bulkJobs[i].Status) {
+		return false, nil
+	}
+
+	s.bulkJobs[i].Status = status
+	s.bulkJobs[i].Error = message
+	s.bulkJobs[i].UpdatedAt = time.Now()
+
+	return true, nil
+}
+
+// bulkJob returns the index of a job visible in ctx, or -1. Callers must
+// hold the lock.
+func (s *Store) bulkJob(ctx context.Context, id int) int {
+	for i, job := range s.bulkJobs {
+		if job.ID == id && tenant.Matches(ctx, job.Tenant) {
+			return i
+		}
+	}
+	return -1
+}
+
+// copyBulkJob returns a copy of a stored job with its progress. Callers must
+// hold the lock.
+func (s *Store) copyBulkJob(i int) model.BulkJob {
+	job := s.bulkJobs[i]
+	job.Request = slices.Clone(job.Request)
+	job.Processed = job.Succeeded + job.Failed
+	return job
+}
+
+func copyBulkJobResult(result model.BulkJobResult) model.BulkJobResult {
+	if result.Position != nil {
+		position := *result.Position
+		result.Position = &position
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+)
+
+type ClosureRepository struct {
+	store *Store
+}
+
+// Create inserts a new block closure
+func (r *ClosureRepository) Create(ctx context.Context, closure *model.BlockClosure) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !closure.EndsAt.After(closure.StartsAt) {
+		return fmt.Errorf("error creating block closure: ends_at must be after starts_at")
+	}
+
+	closure.ID = s.nextID("block_closures")
+	closure.CreatedAt = time.Now()
+	stored := *closure
+	stored.BlockID = intPtr(closure.BlockID)
+	stored.SlotStart = intPtr(closure.SlotStart)
+	stored.SlotEnd = intPtr(closure.SlotEnd)
+	stored.RowStart = intPtr(closure.RowStart)
+	stored.RowEnd = intPtr(closure.RowEnd)
+	s.closures = append(s.closures, stored)
+
+	return nil
+}
+
+// GetActiveByYardID retrieves closures of a yard that are in effect at the given time
+func (r *ClosureRepository) GetActiveByYardID(ctx context.Context, yardID int, at time.Time) ([]model.BlockClosure, error) {
+	return r.filter(func(c model.BlockClosure) bool {
+		return c.YardID == yardID && !c.StartsAt.After(at) && c.EndsAt.After(at)
+	}), nil
+}
+
+// GetCurrentAndUpcomingByYardID retrieves closures of a yard that have not ended yet
+func (r *ClosureRepository) GetCurrentAndUpcomingByYardID(ctx context.Context, yardID int, at time.Time) ([]model.BlockClosure, error) {
+	return r.filter(func(c model.BlockClosure) bool {
+		return c.YardID == yardID && c.EndsAt.After(at)
+	}), nil
+}
+
+func (r *ClosureRepository) filter(match func(model.BlockClosure) bool) []model.BlockClosure {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var closures []model.BlockClosure
+	for _, c := range s.closures {
+		if !match(c) {
+			continue
+		}
+		closure := c
+		closure.BlockID = intPtr(c.BlockID)
+		closure.SlotStart = intPtr(c.SlotStart)
+		closure.SlotEnd = intPtr(c.SlotEnd)
+		closure.RowStart = intPtr(c.RowStart)
+		closure.RowEnd = intPtr(c.RowEnd)
+		closure.BlockCode = ""
+		if closure.BlockID != nil {
+			closure.BlockCode = s.blockCode(*closure.BlockID)
+		}
+		closures = append(closures, closure)
+	}
+	sort.SliceStable(closures, func(i, j int) bool { return closures[i].StartsAt.Before(closures[j].StartsAt) })
+
+	return closures
+}
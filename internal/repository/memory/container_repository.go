@@ -0,0 +1,238 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+type ContainerRepository struct {
+	store *Store
+}
+
+// Create inserts a new container. The container belongs to the tenant of
+// its yard, which must be the caller's tenant.
+func (r *ContainerRepository) Create(ctx context.Context, container *model.Container) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	yard, ok := s.yard(ctx, container.YardID)
+	if !ok {
+		return apperror.New(apperror.CodeYardNotFound, "yard with id %d not found", container.YardID)
+	}
+	container.Tenant = yard.Tenant
+
+	if err := s.checkContainerUnique(container.Tenant, container.ContainerNumber, container.BlockID,
+		container.Slot, container.Row, container.Tier); err != nil {
+		return fmt.Errorf("error creating container: %w", err)
+	}
+
+	container.ID = s.nextID("containers")
+	container.PlacedAt = time.Now()
+	s.containers = append(s.containers, *container)
+
+	return nil
+}
+
+// GetByNumber retrieves a container of the caller's tenant by its number
+func (r *ContainerRepository) GetByNumber(ctx context.Context, containerNumber string) (*model.Container, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, c := range s.containers {
+		if c.ContainerNumber == containerNumber && tenant.Matches(ctx, c.Tenant) {
+			container := c
+			return &container, nil
+		}
+	}
+
+	return nil, apperror.New(apperror.CodeContainerNotFound, "container '%s' not found", containerNumber)
+}
+
+// Delete removes a container of the caller's tenant
+func (r *ContainerRepository) Delete(ctx context.Context, containerNumber string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, c := range s.containers {
+		if c.ContainerNumber == containerNumber && tenant.Matches(ctx, c.Tenant) {
+			s.containers = append(s.containers[:i], s.containers[i+1:]...)
+			return nil
+		}
+	}
+
+	return apperror.New(apperror.CodeContainerNotFound, "container '%s' not found", containerNumber)
+}
+
+// UpdatePosition moves a container of the caller's tenant to another
+// position. The target block must be in the yard of the container.
+func (r *ContainerRepository) UpdatePosition(ctx context.Context, containerNumber string, blockID, slot, row, tier int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, c := range s.containers {
+		if c.ContainerNumber != containerNumber || !tenant.Matches(ctx, c.Tenant) || !s.blockInYard(blockID, c.YardID) {
+			continue
+		}
+		for j, other := range s.containers {
+			if j != i && other.BlockID == blockID &&
+				other.Slot == slot && other.Row == row && other.Tier == tier {
+				return fmt.Errorf("error moving container: %w (block_id, slot, row, tier)", repository.ErrDuplicate)
+			}
+		}
+		s.containers[i].BlockID = blockID
+		s.containers[i].Slot = slot
+		s.containers[i].Row = row
+		s.containers[i].Tier = tier
+		return nil
+	}
+
+	return apperror.New(apperror.CodeContainerNotFound, "container '%s' not found", containerNumber)
+}
+
+// SetHold puts a container of the caller's tenant on hold for the given
+// reason; an empty reason releases the hold
+func (r *ContainerRepository) SetHold(ctx context.Context, containerNumber, reason string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, c := range s.containers {
+		if c.ContainerNumber == containerNumber && tenant.Matches(ctx, c.Tenant) {
+			s.containers[i].Hold = reason
+			return nil
+		}
+	}
+
+	return apperror.New(apperror.CodeContainerNotFound, "container '%s' not found", containerNumber)
+}
+
+// IsPositionOccupied checks if a specific position is occupied
+// For 40ft containers, checks both slots
+func (r *ContainerRepository) IsPositionOccupied(ctx context.Context, blockID, slot, row, tier int, containerSize int) (bool, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, c := range s.containers {
+		if c.BlockID != blockID || c.Row != row || c.Tier != tier {
+			continue
+		}
+		if c.Slot == slot || (containerSize == 40 && c.Slot == slot+1) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GetOccupiedPositionsInArea retrieves all occupied positions within a specific area
+func (r *ContainerRepository) GetOccupiedPositionsInArea(ctx context.Context, blockID, slotStart, slotEnd, rowStart, rowEnd int) ([]model.Container, error) {
+	return r.filter(func(c model.Container) bool {
+		return c.BlockID == blockID && tenant.Matches(ctx, c.Tenant) &&
+			c.Slot >= slotStart && c.Slot <= slotEnd &&
+			c.Row >= rowStart && c.Row <= rowEnd
+	}, byPosition), nil
+}
+
+// IsContainerBlocked checks if there's a container above the given position
+func (r *ContainerRepository) IsContainerBlocked(ctx context.Context, blockID, slot, row, tier int) (bool, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, c := range s.containers {
+		if c.BlockID == blockID && c.Slot == slot && c.Row == row && c.Tier > tier {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GetAll retrieves all containers of the caller's tenant
+func (r *ContainerRepository) GetAll(ctx context.Context) ([]model.Container, error) {
+	return r.filter(func(c model.Container) bool { return tenant.Matches(ctx, c.Tenant) }, func(a, b model.Container) bool {
+		return a.PlacedAt.After(b.PlacedAt)
+	}), nil
+}
+
+// GetByBlock retrieves all containers in a specific block
+func (r *ContainerRepository) GetByBlock(ctx context.Context, blockID int) ([]model.Container, error) {
+	return r.filter(func(c model.Container) bool {
+		return c.BlockID == blockID && tenant.Matches(ctx, c.Tenant)
+	}, byPosition), nil
+}
+
+func (r *ContainerRepository) filter(match func(model.Container) bool, less func(a, b model.Container) bool) []model.Container {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var containers []model.Container
+	for _, c := range s.containers {
+		if match(c) {
+			containers = append(containers, c)
+		}
+	}
+	sort.SliceStable(containers, func(i, j int) bool { return less(containers[i], containers[j]) })
+
+	return containers
+}
+
+// byPosition orders containers by slot, row and tier
+func byPosition(a, b model.Container) bool {
+	if a.Slot != b.Slot {
+		return a.Slot < b.Slot
+	}
+	if a.Row != b.Row {
+		return a.Row < b.Row
+	}
+	return a.Tier < b.Tier
+}
+
+// checkContainerUnique enforces the unique container number per tenant and
+// the unique (block_id, slot, row, tier) cell. Callers must hold the lock.
+func (s *Store) checkContainerUnique(tenantName, containerNumber string, blockID, slot, row, tier int) error {
+	for _, c := range s.containers {
+		if c.Tenant == tenantName && c.ContainerNumber == containerNumber {
+			return fmt.Errorf("%w (container_number)", repository.ErrDuplicate)
+		}
+		if c.BlockID == blockID && c.Slot == slot && c.Row == row && c.Tier == tier {
+			return fmt.Errorf("%w (block_id, slot, row, tier)", repository.ErrDuplicate)
+		}
+	}
+	return nil
+}
+
+// yard returns the yard with the given ID if it is visible in ctx. Callers
+// must hold the lock.
+func (s *Store) yard(ctx context.Context, id int) (model.Yard, bool) {
+	for _, y := range s.yards {
+		if y.ID == id && tenant.Matches(ctx, y.Tenant) {
+			return y, true
+		}
+	}
+	return model.Yard{}, false
+}
+
+// blockInYard reports whether the block belongs to the yard. Callers must
+// hold the lock.
+func (s *Store) blockInYard(blockID, yardID int) bool {
+	for _, b := range s.blocks {
+		if b.ID == blockID {
+			return b.YardID == yardID
+		}
+	}
+	return false
+}
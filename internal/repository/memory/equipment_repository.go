@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+type EquipmentRepository struct {
+	store *Store
+}
+
+// GetByYardID retrieves all equipment of a yard together with the blocks it
+// serves and its number of outstanding work orders
+func (r *EquipmentRepository) GetByYardID(ctx context.Context, yardID int) ([]model.Equipment, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var equipment []model.Equipment
+	for _, e := range s.equipment {
+		if e.YardID != yardID {
+			continue
+		}
+		equipment = append(equipment, s.loadEquipment(e))
+	}
+	sort.SliceStable(equipment, func(i, j int) bool { return equipment[i].Code < equipment[j].Code })
+
+	return equipment, nil
+}
+
+// GetByYardAndCode retrieves a piece of equipment by yard ID and equipment code
+func (r *EquipmentRepository) GetByYardAndCode(ctx context.Context, yardID int, code string) (*model.Equipment, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, e := range s.equipment {
+		if e.YardID == yardID && e.Code == code {
+			equipment := s.loadEquipment(e)
+			return &equipment, nil
+		}
+	}
+
+	return nil, apperror.New(apperror.CodeEquipmentNotFound, "equipment with code '%s' not found in yard", code)
+}
+
+// Create inserts a new piece of equipment and the blocks it serves
+func (r *EquipmentRepository) Create(ctx context.Context, equipment *model.Equipment) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.equipment {
+		if e.YardID == equipment.YardID && e.Code == equipment.Code {
+			return fmt.Errorf("error creating equipment: %w (yard_id, code)", repository.ErrDuplicate)
+		}
+	}
+	for _, blockID := range equipment.BlockIDs {
+		if s.blockCode(blockID) == "" {
+			return fmt.Errorf("error assigning equipment block: block %d does not exist", blockID)
+		}
+	}
+
+	now := time.Now()
+	equipment.ID = s.nextID("equipment")
+	equipment.CreatedAt = now
+	equipment.UpdatedAt = now
+
+	stored := *equipment
+	stored.BlockIDs = append([]int(nil), equipment.BlockIDs...)
+	stored.Blocks = nil
+	stored.OutstandingOrders = 0
+	s.equipment = append(s.equipment, stored)
+
+	return nil
+}
+
+// loadEquipment copies a stored machine and fills in its block codes and
+// outstanding work orders. Callers must hold the lock.
+func (s *Store) loadEquipment(e model.Equipment) model.Equipment {
+	type servedBlock struct {
+		id   int
+		code string
+	}
+	blocks := make([]servedBlock, 0, len(e.BlockIDs))
+	for _, id := range e.BlockIDs {
+		blocks = append(blocks, servedBlock{id: id, code: s.blockCode(id)})
+	}
+	sort.SliceStable(blocks, func(i, j int) bool { return blocks[i].code < blocks[j].code })
+
+	e.BlockIDs = nil
+	e.Blocks = []string{}
+	for _, b := range blocks {
+		e.BlockIDs = append(e.BlockIDs, b.id)
+		e.Blocks = append(e.Blocks, b.code)
+	}
+
+	e.OutstandingOrders = 0
+	for _, o := range s.workOrders {
+		if o.EquipmentID != nil && *o.EquipmentID == e.ID && o.IsPending() {
+			e.OutstandingOrders++
+		}
+	}
+
+	return e
+}
@@ -0,0 +1,30 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+)
+
+type FenceRepository struct {
+	store *Store
+}
+
+// Check records token as the newest fencing token of a lock, unless a newer
+// one has been recorded
+func (r *FenceRepository) Check(ctx context.Context, key string, token int64) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if token < s.fences[key] {
+		return fmt.Errorf("%w: lock %s, token %d", repository.ErrFenced, key, token)
+	}
+	if s.fences == nil {
+		s.fences = make(map[string]int64)
+	}
+	s.fences[key] = token
+
+	return nil
+}
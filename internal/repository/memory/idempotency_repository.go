@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+type IdempotencyRepository struct {
+	store *Store
+}
+
+// Reserve records a request of the caller's tenant before it is processed
+func (r *IdempotencyRepository) Reserve(ctx context.Context, record *model.IdempotencyRecord) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record.Tenant = ownerTenant(ctx)
+	for _, rec := range s.idempotency {
+		if rec.Tenant == record.Tenant && rec.Subject == record.Subject && rec.Key == record.Key {
+			return fmt.Errorf("error reserving idempotency key: %w", repository.ErrDuplicate)
+		}
+	}
+
+	record.ID = s.nextID("idempotency_keys")
+	record.CreatedAt = time.Now()
+	stored := *record
+	stored.Response = nil
+	stored.StatusCode = 0
+	s.idempotency = append(s.idempotency, stored)
+
+	return nil
+}
+
+// Get retrieves the record of a key of the caller in the caller's tenant
+func (r *IdempotencyRepository) Get(ctx context.Context, subject, key string) (*model.IdempotencyRecord, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t := ownerTenant(ctx)
+	for _, rec := range s.idempotency {
+		if rec.Tenant == t && rec.Subject == subject && rec.Key == key {
+			record := rec
+			record.Response = append([]byte(nil), rec.Response...)
+			return &record, nil
+		}
+	}
+
+	return nil, apperror.New(apperror.CodeNotFound, "idempotency key not found")
+}
+
+// Complete stores the response of a reserved request
+func (r *IdempotencyRepository) Complete(ctx context.Context, id, statusCode int, response []byte) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, rec := range s.idempotency {
+		if rec.ID == id {
+			s.idempotency[i].StatusCode = statusCode
+			s.idempotency[i].Response = append([]byte(nil), response...)
+			return nil
+		}
+	}
+
+	return apperror.New(apperror.CodeNotFound, "idempotency key %d not found", id)
+}
+
+// Delete removes a record, e.g. when its request failed and may be retried
+func (r *IdempotencyRepository) Delete(ctx context.Context, id int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, rec := range s.idempotency {
+		if rec.ID == id {
+			s.idempotency = append(s.idempotency[:i], s.idempotency[i+1:]...)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// DeleteBefore removes the records of all tenants created before the given time
+func (r *IdempotencyRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var kept []model.IdempotencyRecord
+	for _, rec := range s.idempotency {
+		if !rec.CreatedAt.Before(before) {
+			kept = append(kept, rec)
+		}
+	}
+	deleted := int64(len(s.idempotency) - len(kept))
+	s.idempotency = kept
+
+	return deleted, nil
+}
@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+type RoleBindingRepository struct {
+	store *Store
+}
+
+// Create grants a role to a subject in a yard of the caller's tenant
+func (r *RoleBindingRepository) Create(ctx context.Context, binding *model.RoleBinding) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	binding.Tenant = ownerTenant(ctx)
+	for _, b := range s.roleBindings {
+		if b.Tenant == binding.Tenant && b.Subject == binding.Subject && b.Role == binding.Role && b.Yard == binding.Yard {
+			return fmt.Errorf("error creating role binding: %w", repository.ErrDuplicate)
+		}
+	}
+
+	binding.ID = s.nextID("role_bindings")
+	binding.CreatedAt = time.Now()
+	s.roleBindings = append(s.roleBindings, *binding)
+
+	return nil
+}
+
+// GetBySubject retrieves the role bindings of a subject in the caller's tenant
+func (r *RoleBindingRepository) GetBySubject(ctx context.Context, subject string) ([]model.RoleBinding, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var bindings []model.RoleBinding
+	for _, b := range s.roleBindings {
+		if b.Subject == subject && tenant.Matches(ctx, b.Tenant) {
+			bindings = append(bindings, b)
+		}
+	}
+
+	return bindings, nil
+}
+
+// GetAll retrieves all role bindings of the caller's tenant
+func (r *RoleBindingRepository) GetAll(ctx context.Context) ([]model.RoleBinding, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var bindings []model.RoleBinding
+	for _, b := range s.roleBindings {
+		if tenant.Matches(ctx, b.Tenant) {
+			bindings = append(bindings, b)
+		}
+	}
+	sort.SliceStable(bindings, func(i, j int) bool { return bindings[i].Subject < bindings[j].Subject })
+
+	return bindings, nil
+}
+
+// Delete removes a role binding of the caller's tenant
+func (r *RoleBindingRepository) Delete(ctx context.Context, id int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, b := range s.roleBindings {
+		if b.ID == id && tenant.Matches(ctx, b.Tenant) {
+			s.roleBindings = append(s.roleBindings[:i], s.roleBindings[i+1:]...)
+			return nil
+		}
+	}
+
+	return apperror.New(apperror.CodeNotFound, "role binding %d not found", id)
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+)
+
+// Seed loads the same sample data as the SQL migrations: yard YRD1 with block
+// LC01, its plans, RTG01, the gate and berth points, and the off-dock depot
+// DEPOT1 used as overflow for YRD1.
+func (s *Store) Seed() error {
+	ctx := context.Background()
+	stores := s.Stores()
+	yards := &YardRepository{store: s}
+	blocks := &BlockRepository{store: s}
+
+	yard := &model.Yard{Code: "YRD1", Name: "Yard 1", Description: "Main container yard"}
+	depot := &model.Yard{Code: "DEPOT1", Name: "Off-dock Depot 1", Description: "Off-dock overflow depot"}
+	for _, y := range []*model.Yard{yard, depot} {
+		if err := yards.Create(ctx, y); err != nil {
+			return fmt.Errorf("error seeding yard %s: %w", y.Code, err)
+		}
+	}
+
+	lc01 := &model.Block{
+		YardID: yard.ID, Code: "LC01", Name: "Loading Container Block 01",
+		MaxSlot: 10, MaxRow: 5, MaxTier: 5,
+		BlockGeometry: model.BlockGeometry{OriginX: 120, OriginY: 60},
+	}
+	od01 := &model.Block{
+		YardID: depot.ID, Code: "OD01", Name: "Off-dock Block 01",
+		MaxSlot: 20, MaxRow: 6, MaxTier: 4,
+	}
+	for _, b := range []*model.Block{lc01, od01} {
+		if err := blocks.Create(ctx, b); err != nil {
+			return fmt.Errorf("error seeding block %s: %w", b.Code, err)
+		}
+	}
+
+	plans := []model.YardPlan{
+		{BlockID: lc01.ID, SlotStart: 1, SlotEnd: 3, RowStart: 1, RowEnd: 5, ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY"},
+		{BlockID: lc01.ID, SlotStart: 4, SlotEnd: 7, RowStart: 1, RowEnd: 5, ContainerSize: 40, ContainerHeight: 8.6, ContainerType: "DRY"},
+		{BlockID: od01.ID, SlotStart: 1, SlotEnd: 10, RowStart: 1, RowEnd: 6, ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY"},
+		{BlockID: od01.ID, SlotStart: 11, SlotEnd: 20, RowStart: 1, RowEnd: 6, ContainerSize: 40, ContainerHeight: 8.6, ContainerType: "DRY"},
+	}
+	for i := range plans {
+		if err := stores.Plans.Create(ctx, &plans[i]); err != nil {
+			return fmt.Errorf("error seeding yard plan: %w", err)
+		}
+	}
+
+	rtg := &model.Equipment{
+		YardID: yard.ID, Code: "RTG01", EquipmentType: model.EquipmentTypeRTG,
+		Active: true, BlockIDs: []int{lc01.ID},
+	}
+	if err := stores.Equipment.Create(ctx, rtg); err != nil {
+		return fmt.Errorf("error seeding equipment: %w", err)
+	}
+
+	points := []model.YardPoint{
+		{YardID: yard.ID, Code: "GATE1", Name: "Main Gate", PointType: model.PointTypeGate, X: 0, Y: 0},
+		{YardID: yard.ID, Code: "BERTH1", Name: "Berth 1", PointType: model.PointTypeBerth, X: 250, Y: 200},
+	}
+	for i := range points {
+		if err := stores.Yards.CreatePoint(ctx, &points[i]); err != nil {
+			return fmt.Errorf("error seeding yard point: %w", err)
+		}
+	}
+
+	rule := &model.OverflowRule{YardID: yard.ID, OverflowYardID: depot.ID, Priority: 1}
+	if err := stores.Yards.CreateOverflowRule(ctx, rule); err != nil {
+		return fmt.Errorf("error seeding overflow rule: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+// Package memory provides an in-memory storage backend with the same semantics
+// as the SQL repositories. It is used by tests and by the demo mode; all data is
+// lost when the process exits.
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
+)
+
+// Store holds the tables of the in-memory backend. Rows are kept in insertion
+// (id) order; every read returns copies so callers can never modify stored rows.
+type Store struct {
+	mu sync.RWMutex
+
+	yards          []model.Yard
+	blocks         []model.Block
+	plans          []model.YardPlan
+	containers     []model.Container
+	closures       []model.BlockClosure
+	equipment      []model.Equipment
+	workOrders     []model.WorkOrder
+	points         []model.YardPoint
+	overflowRules  []model.OverflowRule
+	apiKeys        []model.APIKey
+	roleBindings   []model.RoleBinding
+	idempotency    []model.IdempotencyRecord
+	bulkJobs       []model.BulkJob
+	bulkJobResults []bulkJobResult
+	fences         map[string]int64
+
+	lastID map[string]int
+}
+
+// NewStore creates an empty in-memory store
+func NewStore() *Store {
+	return &Store{lastID: make(map[string]int)}
+}
+
+// Stores returns the repositories backed by this store
+func (s *Store) Stores() repository.Stores {
+	return repository.Stores{
+		Yards:       &YardRepository{store: s},
+		Blocks:      &BlockRepository{store: s},
+		Plans:       &YardPlanRepository{store: s},
+		Containers:  &ContainerRepository{store: s},
+		Closures:    &ClosureRepository{store: s},
+		Equipment:   &EquipmentRepository{store: s},
+		WorkOrders:  &WorkOrderRepository{store: s},
+		APIKeys:     &APIKeyRepository{store: s},
+		Roles:       &RoleBindingRepository{store: s},
+		Idempotency: &IdempotencyRepository{store: s},
+		BulkJobs:    &BulkJobRepository{store: s},
+		Fences:      &FenceRepository{store: s},
+		Atomic:      s.atomic,
+	}
+}
+
+// atomic runs fn on a copy of the store and keeps the copy when fn succeeds.
+// The store is locked meanwhile, so transactions are serializable and other
+// requests wait until they finish.
+func (s *Store) atomic(ctx context.Context, fn func(tx repository.Stores) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := s.clone()
+	if err := fn(tx.Stores()); err != nil {
+		return err
+	}
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	s.yards, s.blocks, s.plans = tx.yards, tx.blocks, tx.plans
+	s.containers, s.closures, s.equipment = tx.containers, tx.closures, tx.equipment
+	s.workOrders, s.points, s.overflowRules = tx.workOrders, tx.points, tx.overflowRules
+	s.apiKeys, s.roleBindings, s.idempotency = tx.apiKeys, tx.roleBindings, tx.idempotency
+	s.bulkJobs, s.bulkJobResults, s.fences = tx.bulkJobs, tx.bulkJobResults, tx.fences
+	s.lastID = tx.lastID
+
+	return nil
+}
+
+// clone copies the tables. Rows are replaced rather than changed through
+// shared pointers, so copying the slices is enough. Callers must hold the lock.
+func (s *Store) clone() *Store {
+	return &Store{
+		yards:          slices.Clone(s.yards),
+		blocks:         slices.Clone(s.blocks),
+		plans:          slices.Clone(s.plans),
+		containers:     slices.Clone(s.containers),
+		closures:       slices.Clone(s.closures),
+		equipment:      slices.Clone(s.equipment),
+		workOrders:     slices.Clone(s.workOrders),
+		points:         slices.Clone(s.points),
+		overflowRules:  slices.Clone(s.overflowRules),
+		apiKeys:        slices.Clone(s.apiKeys),
+		roleBindings:   slices.Clone(s.roleBindings),
+		idempotency:    slices.Clone(s.idempotency),
+		bulkJobs:       slices.Clone(s.bulkJobs),
+		bulkJobResults: slices.Clone(s.bulkJobResults),
+		fences:         maps.Clone(s.fences),
+		lastID:         maps.Clone(s.lastID),
+	}
+}
+
+// nextID returns the next serial value of a table. Callers must hold the write lock.
+func (s *Store) nextID(table string) int {
+	s.lastID[table]++
+	return s.lastID[table]
+}
+
+// blockCode returns the code of a block or "" when it does not exist.
+// Callers must hold the lock.
+func (s *Store) blockCode(id int) string {
+	for _, b := range s.blocks {
+		if b.ID == id {
+			return b.Code
+		}
+	}
+	return ""
+}
+
+// ownerTenant is the tenant new rows such as role bindings are created in.
+// Without a tenant scope they go to the default tenant.
+func ownerTenant(ctx context.Context) string {
+	if t := tenant.FromContext(ctx); t != "" {
+		return t
+	}
+	return tenant.Default
+}
+
+// intPtr returns a pointer to a copy of v
+func intPtr(v *int) *int {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
+
+// Compile-time checks that the in-memory repositories implement the interfaces
+var (
+	_ repository.YardStore        = (*YardRepository)(nil)
+	_ repository.BlockStore       = (*BlockRepository)(nil)
+	_ repository.YardPlanStore    = (*YardPlanRepository)(nil)
+	_ repository.ContainerStore   = (*ContainerRepository)(nil)
+	_ repository.ClosureStore     = (*ClosureRepository)(nil)
+	_ repository.EquipmentStore   = (*EquipmentRepository)(nil)
+	_ repository.WorkOrderStore   = (*WorkOrderRepository)(nil)
+	_ repository.APIKeyStore      = (*APIKeyRepository)(nil)
+	_ repository.RoleBindingStore = (*RoleBindingRepository)(nil)
+	_ repository.IdempotencyStore = (*IdempotencyRepository)(nil)
+	_ repository.BulkJobStore     = (*BulkJobRepository)(nil)
+	_ repository.FenceStore       = (*FenceRepository)(nil)
+)
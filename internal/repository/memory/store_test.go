@@ -0,0 +1,30 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/repository/repotest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.Stores {
+		store := NewStore()
+		require.NoError(t, store.Seed())
+
+		// The yards of another tenant in repotest.FixtureSQL
+		ctx := context.Background()
+		acme := &model.Yard{Code: "ACME1", Name: "Acme Yard 1", Description: "Yard of another operator", Tenant: "acme"}
+		require.NoError(t, (&YardRepository{store: store}).Create(ctx, acme))
+		require.NoError(t, (&BlockRepository{store: store}).Create(ctx, &model.Block{
+			YardID: acme.ID, Code: "AC01", Name: "Acme Block 01", MaxSlot: 10, MaxRow: 5, MaxTier: 5,
+		}))
+		require.NoError(t, (&YardRepository{store: store}).Create(ctx, &model.Yard{
+			Code: "YRD1", Name: "Acme Yard North", Description: "Yard code used by two operators", Tenant: "acme",
+		}))
+		return store.Stores()
+	})
+}
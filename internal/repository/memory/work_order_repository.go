@@ -0,0 +1,269 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+type WorkOrderRepository struct {
+	store *Store
+}
+
+// Create inserts a new work order
+func (r *WorkOrderRepository) Create(ctx context.Context, order *model.WorkOrder) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if reserves(*order) {
+		for _, o := range s.workOrders {
+			if reserves(o) && o.To.BlockID == order.To.BlockID && o.To.Slot == order.To.Slot &&
+				o.To.Row == order.To.Row && o.To.Tier == order.To.Tier {
+				return fmt.Errorf("error creating work order: %w", repository.ErrCellReserved)
+			}
+		}
+	}
+
+	order.ID = s.nextID("work_orders")
+	order.CreatedAt = time.Now()
+	s.workOrders = append(s.workOrders, copyWorkOrder(*order))
+
+	return nil
+}
+
+// GetByID retrieves a work order in a yard of the caller's tenant by ID
+func (r *WorkOrderRepository) GetByID(ctx context.Context, id int) (*model.WorkOrder, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if i := s.workOrderIndex(id); i >= 0 && s.yardVisible(ctx, s.workOrders[i].YardID) {
+		order := s.loadWorkOrder(s.workOrders[i])
+		return &order, nil
+	}
+
+	return nil, apperror.New(apperror.CodeWorkOrderNotFound, "work order %d not found", id)
+}
+
+// GetPendingByYardID retrieves all work orders of a yard that are not finished yet
+func (r *WorkOrderRepository) GetPendingByYardID(ctx context.Context, yardID int) ([]model.WorkOrder, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var orders []model.WorkOrder
+	for _, o := range s.workOrders {
+		if o.YardID == yardID && o.IsPending() {
+			orders = append(orders, s.loadWorkOrder(o))
+		}
+	}
+	sortWorkOrders(orders)
+
+	return orders, nil
+}
+
+// HasPendingForContainer checks if a container of the caller's tenant already
+// has an unfinished work order
+func (r *WorkOrderRepository) HasPendingForContainer(ctx context.Context, containerNumber string) (bool, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, o := range s.workOrders {
+		if o.ContainerNumber == containerNumber && o.IsPending() && s.yardVisible(ctx, o.YardID) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GetActiveForEquipment retrieves the oldest dispatched or in-progress job of a machine
+func (r *WorkOrderRepository) GetActiveForEquipment(ctx context.Context, equipmentID int) (*model.WorkOrder, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var active []model.WorkOrder
+	for _, o := range s.workOrders {
+		if o.EquipmentID != nil && *o.EquipmentID == equipmentID &&
+			(o.Status == model.WorkOrderStatusDispatched || o.Status == model.WorkOrderStatusInProgress) {
+			active = append(active, o)
+		}
+	}
+	if len(active) == 0 {
+		return nil, nil
+	}
+	sortWorkOrders(active)
+
+	order := s.loadWorkOrder(active[0])
+	return &order, nil
+}
+
+// DispatchNext assigns the oldest created job of a machine, or an unassigned job in one
+// of the blocks it serves, to the machine and marks it dispatched. It returns nil when
+// there is no job waiting.
+func (r *WorkOrderRepository) DispatchNext(ctx context.Context, equipmentID int) (*model.WorkOrder, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	served := make(map[int]bool)
+	for _, e := range s.equipment {
+		if e.ID == equipmentID {
+			for _, id := range e.BlockIDs {
+				served[id] = true
+			}
+		}
+	}
+
+	next := -1
+	for i, o := range s.workOrders {
+		if o.Status != model.WorkOrderStatusCreated {
+			continue
+		}
+		mine := o.EquipmentID != nil && *o.EquipmentID == equipmentID
+		unassigned := o.EquipmentID == nil && served[workOrderBlockID(o)]
+		if !mine && !unassigned {
+			continue
+		}
+		if next < 0 || o.CreatedAt.Before(s.workOrders[next].CreatedAt) {
+			next = i
+		}
+	}
+	if next < 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	id := equipmentID
+	s.workOrders[next].Status = model.WorkOrderStatusDispatched
+	s.workOrders[next].EquipmentID = &id
+	s.workOrders[next].DispatchedAt = &now
+
+	order := s.loadWorkOrder(s.workOrders[next])
+	return &order, nil
+}
+
+// UpdateStatus moves a work order from one status to another. It fails when the
+// work order is no longer in the expected status.
+func (r *WorkOrderRepository) UpdateStatus(ctx context.Context, id int, from, to, reason string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.workOrderIndex(id)
+	if i < 0 || s.workOrders[i].Status != from {
+		return apperror.New(apperror.CodeConflict, "work order %d is no longer %s", id, from)
+	}
+
+	now := time.Now()
+	order := &s.workOrders[i]
+	order.Status = to
+	order.FailureReason = reason
+	switch to {
+	case model.WorkOrderStatusInProgress:
+		order.StartedAt = &now
+	case model.WorkOrderStatusCompleted, model.WorkOrderStatusFailed:
+		order.CompletedAt = &now
+	}
+
+	return nil
+}
+
+// workOrderIndex returns the position of a work order or -1. Callers must hold the lock.
+func (s *Store) workOrderIndex(id int) int {
+	for i, o := range s.workOrders {
+		if o.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// loadWorkOrder copies a stored work order and fills in the equipment and
+// block codes. Callers must hold the lock.
+func (s *Store) loadWorkOrder(o model.WorkOrder) model.WorkOrder {
+	order := copyWorkOrder(o)
+	order.EquipmentCode = ""
+	if order.EquipmentID != nil {
+		for _, e := range s.equipment {
+			if e.ID == *order.EquipmentID {
+				order.EquipmentCode = e.Code
+			}
+		}
+	}
+	if order.From != nil {
+		order.From.Block = s.blockCode(order.From.BlockID)
+	}
+	if order.To != nil {
+		order.To.Block = s.blockCode(order.To.BlockID)
+	}
+	return order
+}
+
+// copyWorkOrder returns a deep copy of a work order
+// reserves reports whether an order still has to put a container into its
+// target cell
+func reserves(o model.WorkOrder) bool {
+	return o.IsPending() && !o.PositionApplied && o.To != nil
+}
+
+func copyWorkOrder(o model.WorkOrder) model.WorkOrder {
+	o.EquipmentID = intPtr(o.EquipmentID)
+	if o.From != nil {
+		from := *o.From
+		o.From = &from
+	}
+	if o.To != nil {
+		to := *o.To
+		o.To = &to
+	}
+	o.DispatchedAt = timePtr(o.DispatchedAt)
+	o.StartedAt = timePtr(o.StartedAt)
+	o.CompletedAt = timePtr(o.CompletedAt)
+	return o
+}
+
+// workOrderBlockID returns the target block of a work order, or its source block
+// for pickups
+func workOrderBlockID(o model.WorkOrder) int {
+	if o.To != nil {
+		return o.To.BlockID
+	}
+	if o.From != nil {
+		return o.From.BlockID
+	}
+	return 0
+}
+
+// sortWorkOrders orders work orders by creation time and ID
+func sortWorkOrders(orders []model.WorkOrder) {
+	sort.SliceStable(orders, func(i, j int) bool {
+		if !orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+			return orders[i].CreatedAt.Before(orders[j].CreatedAt)
+		}
+		return orders[i].ID < orders[j].ID
+	})
+}
+
+// yardVisible reports whether the yard belongs to the tenant of ctx. Callers
+// must hold the lock.
+func (s *Store) yardVisible(ctx context.Context, yardID int) bool {
+	_, ok := s.yard(ctx, yardID)
+	return ok
+}
+
+func timePtr(v *time.Time) *time.Time {
+	if v == nil {
+		return nil
+	}
+	t := *v
+	return &t
+}
@@ -0,0 +1,153 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+type YardPlanRepository struct {
+	store *Store
+}
+
+// FindMatchingPlan finds a yard plan that matches the container specifications
+func (r *YardPlanRepository) FindMatchingPlan(ctx context.Context, blockID int, size int, height float64, containerType string) (*model.YardPlan, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, p := range s.plans {
+		if p.BlockID == blockID && p.ContainerSize == size &&
+			p.ContainerHeight == height && p.ContainerType == containerType {
+			plan := p
+			return &plan, nil
+		}
+	}
+
+	return nil, apperror.New(apperror.CodeNotFound, "no yard plan found for container size=%d, height=%.1f, type=%s", size, height, containerType)
+}
+
+// GetByBlockID retrieves all yard plans for a specific block
+func (r *YardPlanRepository) GetByBlockID(ctx context.Context, blockID int) ([]model.YardPlan, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var plans []model.YardPlan
+	for _, p := range s.plans {
+		if p.BlockID == blockID {
+			plans = append(plans, p)
+		}
+	}
+	sort.SliceStable(plans, func(i, j int) bool {
+		if plans[i].SlotStart != plans[j].SlotStart {
+			return plans[i].SlotStart < plans[j].SlotStart
+		}
+		return plans[i].RowStart < plans[j].RowStart
+	})
+
+	return plans, nil
+}
+
+// GetByID retrieves a yard plan by ID
+func (r *YardPlanRepository) GetByID(ctx context.Context, id int) (*model.YardPlan, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, p := range s.plans {
+		if p.ID == id {
+			plan := p
+			return &plan, nil
+		}
+	}
+
+	return nil, apperror.New(apperror.CodeNotFound, "yard plan %d not found", id)
+}
+
+// Create creates a new yard plan
+func (r *YardPlanRepository) Create(ctx context.Context, plan *model.YardPlan) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkPlan(plan); err != nil {
+		return fmt.Errorf("error creating yard plan: %w", err)
+	}
+	if plan.StackingPriority == "" {
+		plan.StackingPriority = "LEFT_TO_RIGHT"
+	}
+
+	now := time.Now()
+	plan.ID = s.nextID("yard_plans")
+	plan.Version = 1
+	plan.CreatedAt = now
+	plan.UpdatedAt = now
+	s.plans = append(s.plans, *plan)
+
+	return nil
+}
+
+// Update saves a yard plan and increments its version. The plan must still be
+// at the given version; 0 matches any.
+func (r *YardPlanRepository) Update(ctx context.Context, plan *model.YardPlan, version int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkPlan(plan); err != nil {
+		return fmt.Errorf("error updating yard plan: %w", err)
+	}
+	for i := range s.plans {
+		p := &s.plans[i]
+		if p.ID != plan.ID {
+			continue
+		}
+		if version != 0 && p.Version != version {
+			return staleVersion(version, p.Version)
+		}
+
+		plan.Version = p.Version + 1
+		plan.CreatedAt = p.CreatedAt
+		plan.UpdatedAt = time.Now()
+		*p = *plan
+		return nil
+	}
+
+	return apperror.New(apperror.CodeNotFound, "yard plan %d not found", plan.ID)
+}
+
+// Delete removes a yard plan that is still at the given version; 0 matches any
+func (r *YardPlanRepository) Delete(ctx context.Context, id, version int) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, p := range s.plans {
+		if p.ID != id {
+			continue
+		}
+		if version != 0 && p.Version != version {
+			return staleVersion(version, p.Version)
+		}
+		s.plans = append(s.plans[:i], s.plans[i+1:]...)
+		return nil
+	}
+
+	return apperror.New(apperror.CodeNotFound, "yard plan %d not found", id)
+}
+
+// checkPlan enforces the constraints of the yard_plans table
+func (s *Store) checkPlan(plan *model.YardPlan) error {
+	if plan.SlotStart < 1 || plan.SlotEnd < plan.SlotStart || plan.RowStart < 1 || plan.RowEnd < plan.RowStart {
+		return fmt.Errorf("invalid slot or row range")
+	}
+	if s.blockCode(plan.BlockID) == "" {
+		return fmt.Errorf("block %d does not exist", plan.BlockID)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+type YardRepository struct {
+	store *Store
+}
+
+// Create inserts a new yard
+func (r *YardRepository) Create(ctx context.Context, yard *model.Yard) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if yard.Tenant == "" {
+		yard.Tenant = tenant.Default
+	}
+	for _, y := range s.yards {
+		if y.Tenant == yard.Tenant && y.Code == yard.Code {
+			return fmt.Errorf("error creating yard: %w (tenant, code)", repository.ErrDuplicate)
+		}
+	}
+	now := time.Now()
+	yard.ID = s.nextID("yards")
+	yard.CreatedAt = now
+	yard.UpdatedAt = now
+	s.yards = append(s.yards, *yard)
+
+	return nil
+}
+
+// GetByCode retrieves a yard of the caller's tenant by its code. Codes are
+// only unique per tenant, so a context of all tenants finds no yard.
+func (r *YardRepository) GetByCode(ctx context.Context, code string) (*model.Yard, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, y := range s.yards {
+		if y.Code == code && y.Tenant == tenant.FromContext(ctx) {
+			yard := y
+			return &yard, nil
+		}
+	}
+
+	return nil, apperror.New(apperror.CodeYardNotFound, "yard with code '%s' not found", code)
+}
+
+// GetAll retrieves all yards of the caller's tenant
+func (r *YardRepository) GetAll(ctx context.Context) ([]model.Yard, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var yards []model.Yard
+	for _, y := range s.yards {
+		if tenant.Matches(ctx, y.Tenant) {
+			yards = append(yards, y)
+		}
+	}
+	sort.SliceStable(yards, func(i, j int) bool { return yards[i].Code < yards[j].Code })
+
+	return yards, nil
+}
+
+// GetPointsByYardID retrieves the points of interest of a yard
+func (r *YardRepository) GetPointsByYardID(ctx context.Context, yardID int) ([]model.YardPoint, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var points []model.YardPoint
+	for _, p := range s.points {
+		if p.YardID == yardID {
+			points = append(points, p)
+		}
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Code < points[j].Code })
+
+	return points, nil
+}
+
+// CreatePoint inserts a new point of interest
+func (r *YardRepository) CreatePoint(ctx context.Context, point *model.YardPoint) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.points {
+		if p.YardID == point.YardID && p.Code == point.Code {
+			return fmt.Errorf("error creating yard point: %w (yard_id, code)", repository.ErrDuplicate)
+		}
+	}
+
+	point.ID = s.nextID("yard_points")
+	point.CreatedAt = time.Now()
+	s.points = append(s.points, *point)
+
+	return nil
+}
+
+// GetOverflowRules retrieves the overflow rules of a yard ordered by priority.
+// Rules into yards of another tenant are never returned.
+func (r *YardRepository) GetOverflowRules(ctx context.Context, yardID int) ([]model.OverflowRule, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var rules []model.OverflowRule
+	for _, rule := range s.overflowRules {
+		if rule.YardID != yardID {
+			continue
+		}
+		for _, y := range s.yards {
+			if y.ID == rule.OverflowYardID && tenant.Matches(ctx, y.Tenant) {
+				rule.OverflowYard = y.Code
+				rules = append(rules, rule)
+			}
+		}
+	}
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Priority < rules[j].Priority })
+
+	return rules, nil
+}
+
+// CreateOverflowRule inserts a new overflow rule
+func (r *YardRepository) CreateOverflowRule(ctx context.Context, rule *model.OverflowRule) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rule.YardID == rule.OverflowYardID {
+		return fmt.Errorf("error creating overflow rule: a yard cannot overflow into itself")
+	}
+	for _, existing := range s.overflowRules {
+		if existing.YardID == rule.YardID && existing.OverflowYardID == rule.OverflowYardID {
+			return fmt.Errorf("error creating overflow rule: %w (yard_id, overflow_yard_id)", repository.ErrDuplicate)
+		}
+	}
+
+	rule.ID = s.nextID("yard_overflow_rules")
+	rule.CreatedAt = time.Now()
+	s.overflowRules = append(s.overflowRules, *rule)
+
+	return nil
+}
@@ -0,0 +1,769 @@
+// Package repotest is a test suite shared by all storage backends. Every
+// backend must pass it so the services behave the same on each of them.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// FixtureSQL loads the suite fixture into an empty SQL database whose serial
+// counters start at 1: yard YRD1 (id 1) with block LC01 (id 1), its plans and
+// RTG01 (id 1), and DEPOT1 (id 2) with block OD01 (id 2) as overflow of YRD1.
+// Yard ACME1 (id 3) with block AC01 (id 3) and a second yard coded YRD1 (id 4)
+// belong to the tenant "acme", all other data to the default tenant.
+const FixtureSQL = `
+INSERT INTO yards (code, name, description) VALUES ('YRD1', 'Yard 1', 'Main container yard');
+INSERT INTO yards (code, name, description) VALUES ('DEPOT1', 'Off-dock Depot 1', 'Off-dock overflow depot');
+INSERT INTO blocks (yard_id, code, name, max_slot, max_row, max_tier, origin_x, origin_y)
+VALUES (1, 'LC01', 'Loading Container Block 01', 10, 5, 5, 120, 60);
+INSERT INTO blocks (yard_id, code, name, max_slot, max_row, max_tier)
+VALUES (2, 'OD01', 'Off-dock Block 01', 20, 6, 4);
+INSERT INTO yard_plans (block_id, slot_start, slot_end, row_start, row_end, container_size, container_height, container_type)
+VALUES (1, 1, 3, 1, 5, 20, 8.6, 'DRY');
+INSERT INTO yard_plans (block_id, slot_start, slot_end, row_start, row_end, container_size, container_height, container_type)
+VALUES (1, 4, 7, 1, 5, 40, 8.6, 'DRY');
+INSERT INTO yard_plans (block_id, slot_start, slot_end, row_start, row_end, container_size, container_height, container_type)
+VALUES (2, 1, 10, 1, 6, 20, 8.6, 'DRY');
+INSERT INTO yard_plans (block_id, slot_start, slot_end, row_start, row_end, container_size, container_height, container_type)
+VALUES (2, 11, 20, 1, 6, 40, 8.6, 'DRY');
+INSERT INTO equipment (yard_id, code, equipment_type) VALUES (1, 'RTG01', 'RTG');
+INSERT INTO equipment_blocks (equipment_id, block_id) VALUES (1, 1);
+INSERT INTO yard_points (yard_id, code, name, point_type, x, y) VALUES (1, 'GATE1', 'Main Gate', 'GATE', 0, 0);
+INSERT INTO yard_points (yard_id, code, name, point_type, x, y) VALUES (1, 'BERTH1', 'Berth 1', 'BERTH', 250, 200);
+INSERT INTO yard_overflow_rules (yard_id, overflow_yard_id, priority) VALUES (1, 2, 1);
+INSERT INTO yards (code, name, description, tenant) VALUES ('ACME1', 'Acme Yard 1', 'Yard of another operator', 'acme');
+INSERT INTO blocks (yard_id, code, name, max_slot, max_row, max_tier)
+VALUES (3, 'AC01', 'Acme Block 01', 10, 5, 5);
+INSERT INTO yards (code, name, description, tenant) VALUES ('YRD1', 'Acme Yard North', 'Yard code used by two operators', 'acme');
+`
+
+// Open returns the stores of a fresh backend loaded with the suite fixture
+type Open func(t *testing.T) repository.Stores
+
+// Run runs the shared repository test suite against a backend
+func Run(t *testing.T, open Open) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, stores repository.Stores)
+	}{
+		{"Fixture", testFixture},
+		{"ContainerUniqueness", testContainerUniqueness},
+		{"ConcurrentPlacement", testConcurrentPlacement},
+		{"IsPositionOccupied", testIsPositionOccupied},
+		{"OccupiedPositionsInArea", testOccupiedPositionsInArea},
+		{"Closures", testClosures},
+		{"Equipment", testEquipment},
+		{"WorkOrders", testWorkOrders},
+		{"OverflowRules", testOverflowRules},
+		{"APIKeys", testAPIKeys},
+		{"RoleBindings", testRoleBindings},
+		{"Tenants", testTenants},
+		{"Idempotency", testIdempotency},
+		{"Versions", testVersions},
+		{"Atomic", testAtomic},
+		{"BulkJobs", testBulkJobs},
+		{"Fences", testFences},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, open(t))
+		})
+	}
+}
+
+func newContainer(number string, slot, row, tier int) *model.Container {
+	return &model.Container{
+		ContainerNumber: number,
+		YardID:          1,
+		BlockID:         1,
+		Slot:            slot,
+		Row:             row,
+		Tier:            tier,
+		ContainerSize:   20,
+		ContainerHeight: 8.6,
+		ContainerType:   "DRY",
+	}
+}
+
+func testFixture(t *testing.T, stores repository.Stores) {
+	ctx := context.Background()
+	yard, err := stores.Yards.GetByCode(ctx, "YRD1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, yard.ID)
+
+	_, err = stores.Yards.GetByCode(ctx, "NOPE")
+	assert.Error(t, err)
+
+	yards, err := stores.Yards.GetAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, yards, 2)
+	assert.Equal(t, "DEPOT1", yards[0].Code)
+
+	block, err := stores.Blocks.GetByYardAndCode(ctx, 1, "LC01")
+	require.NoError(t, err)
+	assert.Equal(t, 10, block.MaxSlot)
+	assert.Equal(t, 120.0, block.OriginX)
+	assert.Equal(t, 6.5, block.SlotPitch)
+
+	plan, err := stores.Plans.FindMatchingPlan(ctx, 1, 40, 8.6, "DRY")
+	require.NoError(t, err)
+	assert.Equal(t, 4, plan.SlotStart)
+	assert.Equal(t, "LEFT_TO_RIGHT", plan.StackingPriority)
+
+	_, err = stores.Plans.FindMatchingPlan(ctx, 1, 20, 9.6, "REEFER")
+	assert.Error(t, err)
+
+	points, err := stores.Yards.GetPointsByYardID(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, points, 2)
+}
+
+func testContainerUniqueness(t *testing.T, stores repository.Stores) {
+	ctx := context.Background()
+	first := newContainer("ABCU1234560", 1, 1, 1)
+	require.NoError(t, stores.Containers.Create(ctx, first))
+	assert.NotZero(t, first.ID)
+
+	err := stores.Containers.Create(ctx, newContainer("ABCU7654321", 1, 1, 1))
+	assert.True(t, errors.Is(err, repository.ErrDuplicate), "same cell: %v", err)
+
+	err = stores.Containers.Create(ctx, newContainer("ABCU1234560", 2, 1, 1))
+	assert.True(t, errors.Is(err, repository.ErrDuplicate), "same number: %v", err)
+
+	require.NoError(t, stores.Containers.Create(ctx, newContainer("ABCU7654321", 2, 1, 1)))
+
+	err = stores.Containers.UpdatePosition(ctx, "ABCU7654321", 1, 1, 1, 1)
+	assert.True(t, errors.Is(err, repository.ErrDuplicate), "move onto occupied cell: %v", err)
+	require.NoError(t, stores.Containers.UpdatePosition(ctx, "ABCU7654321", 1, 1, 1, 2))
+
+	blocked, err := stores.Containers.IsContainerBlocked(ctx, 1, 1, 1, 1)
+	require.NoError(t, err)
+	assert.True(t, blocked)
+
+	require.NoError(t, stores.Containers.Delete(ctx, "ABCU7654321"))
+	assert.Error(t, stores.Containers.Delete(ctx, "ABCU7654321"))
+
+	container, err := stores.Containers.GetByNumber(ctx, "ABCU1234560")
+	require.NoError(t, err)
+	assert.Equal(t, 8.6, container.ContainerHeight)
+	assert.Empty(t, container.Hold)
+
+	require.NoError(t, stores.Containers.SetHold(ctx, "ABCU1234560", "customs inspection"))
+	container, err = stores.Containers.GetByNumber(ctx, "ABCU1234560")
+	require.NoError(t, err)
+	assert.Equal(t, "customs inspection", container.Hold)
+	require.NoError(t, stores.Containers.SetHold(ctx, "ABCU1234560", ""))
+	err = stores.Containers.SetHold(ctx, "ABCU7654321", "damaged")
+	assert.Equal(t, apperror.CodeContainerNotFound, apperror.CodeOf(err), "%v", err)
+}
+
+func testConcurrentPlacement(t *testing.T, stores repository.Stores) {
+	ctx := context.Background()
+	const attempts = 10
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		placed int
+		others []error
+	)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := stores.Containers.Create(ctx, newContainer(fmt.Sprintf("ABCU%07d", i), 3, 3, 1))
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				placed++
+			} else if !errors.Is(err, repository.ErrDuplicate) {
+				others = append(others, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, placed)
+	assert.Empty(t, others)
+}
+
+func testIsPositionOccupied(t *testing.T, stores repository.Stores) {
+	ctx := context.Background()
+	require.NoError(t, stores.Containers.Create(ctx, newContainer("ABCU1234560", 5, 2, 1)))
+
+	tests := []struct {
+		name string
+		slot int
+		size int
+		want bool
+	}{
+		{name: "same slot", slot: 5, size: 20, want: true},
+		{name: "40ft spanning the container", slot: 4, size: 40, want: true},
+		{name: "20ft next to the container", slot: 4, size: 20, want: false},
+		{name: "40ft after the container", slot: 6, size: 40, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			occupied, err := stores.Containers.IsPositionOccupied(ctx, 1, tt.slot, 2, 1, tt.size)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, occupied)
+		})
+	}
+}
+
+func testOccupiedPositionsInArea(t *testing.T, stores repository.Stores) {
+	ctx := context.Background()
+	require.NoError(t, stores.Containers.Create(ctx, newContainer("ABCU0000003", 2, 1, 2)))
+	require.NoError(t, stores.Containers.Create(ctx, newContainer("ABCU0000001", 1, 2, 1)))
+	require.NoError(t, stores.Containers.Create(ctx, newContainer("ABCU0000002", 2, 1, 1)))
+	require.NoError(t, stores.Containers.Create(ctx, newContainer("ABCU0000004", 6, 1, 1)))
+
+	containers, err := stores.Containers.GetOccupiedPositionsInArea(ctx, 1, 1, 3, 1, 5)
+	require.NoError(t, err)
+
+	var numbers []string
+	for _, c := range containers {
+		numbers = append(numbers, c.ContainerNumber)
+	}
+	assert.Equal(t, []string{"ABCU0000001", "ABCU0000002", "ABCU0000003"}, numbers)
+
+	all, err := stores.Containers.GetByBlock(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, all, 4)
+}
+
+func testClosures(t *testing.T, stores repository.Stores) {
+	ctx := context.Background()
+	now := time.Now()
+	blockID, slotStart, slotEnd := 1, 2, 3
+
+	active := &model.BlockClosure{
+		YardID: 1, Reason: "crane repair",
+		StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour),
+	}
+	upcoming := &model.BlockClosure{
+		YardID: 1, BlockID: &blockID, SlotStart: &slotStart, SlotEnd: &slotEnd,
+		Reason: "pavement works", StartsAt: now.Add(2 * time.Hour), EndsAt: now.Add(3 * time.Hour),
+	}
+	// Times in another zone must compare by instant, not by wall clock
+	wib := time.FixedZone("WIB", 7*3600)
+	ended := &model.BlockClosure{
+		YardID: 1, Reason: "done",
+		StartsAt: now.Add(-3 * time.Hour).In(wib), EndsAt: now.Add(-2 * time.Hour).In(wib),
+	}
+	for _, c := range []*model.BlockClosure{active, upcoming, ended} {
+		require.NoError(t, stores.Closures.Create(ctx, c))
+	}
+
+	inEffect, err := stores.Closures.GetActiveByYardID(ctx, 1, now)
+	require.NoError(t, err)
+	require.Len(t, inEffect, 1)
+	assert.Equal(t, active.ID, inEffect[0].ID)
+	assert.Nil(t, inEffect[0].BlockID)
+
+	listed, err := stores.Closures.GetCurrentAndUpcomingByYardID(ctx, 1, now)
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	assert.Equal(t, "LC01", listed[1].BlockCode)
+	assert.Equal(t, 3, *listed[1].SlotEnd)
+}
+
+func testEquipment(t *testing.T, stores repository.Stores) {
+	ctx := context.Background()
+	rtg, err := stores.Equipment.GetByYardAndCode(ctx, 1, "RTG01")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"LC01"}, rtg.Blocks)
+	assert.True(t, rtg.Active)
+
+	duplicate := &model.Equipment{YardID: 1, Code: "RTG01", EquipmentType: model.EquipmentTypeRTG, Active: true}
+	err = stores.Equipment.Create(ctx, duplicate)
+	assert.True(t, errors.Is(err, repository.ErrDuplicate), "%v", err)
+
+	stacker := &model.Equipment{
+		YardID: 1, Code: "RS01", EquipmentType: model.EquipmentTypeReachStacker, BlockIDs: []int{1},
+	}
+	require.NoError(t, stores.Equipment.Create(ctx, stacker))
+
+	equipment, err := stores.Equipment.GetByYardID(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, equipment, 2)
+	assert.Equal(t, "RS01", equipment[0].Code)
+	assert.False(t, equipment[0].Active)
+}
+
+func testWorkOrders(t *testing.T, stores repository.Stores) {
+	ctx := context.Background()
+	order := &model.WorkOrder{
+		YardID: 1, ContainerNumber: "ABCU1234560", ContainerSize: 20, ContainerHeight: 8.6,
+		ContainerType: "DRY", Operation: model.WorkOrderPlacement, Status: model.WorkOrderStatusCreated,
+		To: &model.WorkOrderLocation{BlockID: 1, Slot: 1, Row: 1, Tier: 1},
+	}
+	require.NoError(t, stores.WorkOrders.Create(ctx, order))
+
+	pending, err := stores.WorkOrders.HasPendingForContainer(ctx, "ABCU1234560")
+	require.NoError(t, err)
+	assert.True(t, pending)
+
+	// A cell is reserved by one unfinished order at a time; orders that
+	// already changed the yard reserve nothing
+	reserving := *order
+	reserving.ContainerNumber = "ABCU7654323"
+	err = stores.WorkOrders.Create(ctx, &reserving)
+	assert.True(t, errors.Is(err, repository.ErrCellReserved), "%v", err)
+	assert.Equal(t, apperror.CodePositionReserved, apperror.CodeOf(err))
+	applied := reserving
+	applied.PositionApplied = true
+	require.NoError(t, stores.WorkOrders.Create(ctx, &applied))
+	require.NoError(t, stores.WorkOrders.UpdateStatus(ctx, applied.ID, model.WorkOrderStatusCreated, model.WorkOrderStatusFailed, "not needed"))
+
+	rtg, err := stores.Equipment.GetByYardAndCode(ctx, 1, "RTG01")
+	require.NoError(t, err)
+
+	active, err := stores.WorkOrders.GetActiveForEquipment(ctx, rtg.ID)
+	require.NoError(t, err)
+	assert.Nil(t, active)
+
+	dispatched, err := stores.WorkOrders.DispatchNext(ctx, rtg.ID)
+	require.NoError(t, err)
+	require.NotNil(t, dispatched)
+	assert.Equal(t, order.ID, dispatched.ID)
+	assert.Equal(t, model.WorkOrderStatusDispatched, dispatched.Status)
+	assert.Equal(t, "RTG01", dispatched.EquipmentCode)
+	assert.Equal(t, "LC01", dispatched.To.Block)
+	assert.Nil(t, dispatched.From)
+	assert.NotNil(t, dispatched.DispatchedAt)
+
+	next, err := stores.WorkOrders.DispatchNext(ctx, rtg.ID)
+	require.NoError(t, err)
+	assert.Nil(t, next)
+
+	rtg, err = stores.Equipment.GetByYardAndCode(ctx, 1, "RTG01")
+	require.NoError(t, err)
+	assert.Equal(t, 1, rtg.OutstandingOrders)
+
+	err = stores.WorkOrders.UpdateStatus(ctx, order.ID, model.WorkOrderStatusCreated, model.WorkOrderStatusInProgress, "")
+	assert.Error(t, err)
+
+	require.NoError(t, stores.WorkOrders.UpdateStatus(ctx, order.ID,
+		model.WorkOrderStatusDispatched, model.WorkOrderStatusFailed, "spreader fault"))
+
+	failed, err := stores.WorkOrders.GetByID(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "spreader fault", failed.FailureReason)
+	assert.NotNil(t, failed.CompletedAt)
+
+	orders, err := stores.WorkOrders.GetPendingByYardID(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, orders)
+
+	// A failed order frees its cell
+	require.NoError(t, stores.WorkOrders.Create(ctx, &reserving))
+}
+
+func testOverflowRules(t *testing.T, stores repository.Stores) {
+	ctx := context.Background()
+	rules, err := stores.Yards.GetOverflowRules(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, "DEPOT1", rules[0].OverflowYard)
+
+	err = stores.Yards.CreateOverflowRule(ctx, &model.OverflowRule{YardID: 1, OverflowYardID: 2, Priority: 2})
+	assert.True(t, errors.Is(err, repository.ErrDuplicate), "%v", err)
+
+	err = stores.Yards.CreateOverflowRule(ctx, &model.OverflowRule{YardID: 1, OverflowYardID: 1, Priority: 2})
+	assert.Error(t, err)
+}
+
+func testAPIKeys(t *testing.T, stores repository.Stores) {
+	ctx := context.Background()
+	key := &model.APIKey{Name: "gate-system", Prefix: "yk_abcd", Hash: fmt.Sprintf("%064x", 1)}
+	require.NoError(t, stores.APIKeys.Create(ctx, key))
+	assert.NotZero(t, key.ID)
+
+	duplicate := &model.APIKey{Name: "copy", Prefix: "yk_abcd", Hash: key.Hash}
+	err := stores.APIKeys.Create(ctx, duplicate)
+	assert.True(t, errors.Is(err, repository.ErrDuplicate), "%v", err)
+
+	found, err := stores.APIKeys.GetByHash(ctx, key.Hash)
+	require.NoError(t, err)
+	assert.Equal(t, "gate-system", found.Name)
+	assert.Nil(t, found.ExpiresAt)
+
+	_, err = stores.APIKeys.GetByHash(ctx, fmt.Sprintf("%064x", 2))
+	assert.Error(t, err)
+
+	expiry := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	require.NoError(t, stores.APIKeys.Expire(ctx, key.ID, expiry))
+	assert.Error(t, stores.APIKeys.Expire(ctx, key.ID+100, expiry))
+
+	keys, err := stores.APIKeys.GetAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.NotNil(t, keys[0].ExpiresAt)
+	assert.WithinDuration(t, expiry, *keys[0].ExpiresAt, time.Second)
+	assert.True(t, keys[0].IsActive(time.Now()))
+	assert.False(t, keys[0].IsActive(expiry.Add(time.Minute)))
+}
+
+func testRoleBindings(t *testing.T, stores repository.Stores) {
+	ctx := context.Background()
+	clerk := &model.RoleBinding{Subject: "api_key:gate-system", Role: model.RoleGateClerk, Yard: "YRD1"}
+	require.NoError(t, stores.Roles.Create(ctx, clerk))
+	require.NoError(t, stores.Roles.Create(ctx, &model.RoleBinding{Subject: "api_key:gate-system", Role: model.RoleGateClerk, Yard: "DEPOT1"}))
+	require.NoError(t, stores.Roles.Create(ctx, &model.RoleBinding{Subject: "jwt:admin", Role: model.RoleAdmin, Yard: model.AllYards}))
+
+	err := stores.Roles.Create(ctx, &model.RoleBinding{Subject: "api_key:gate-system", Role: model.RoleGateClerk, Yard: "YRD1"})
+	assert.True(t, errors.Is(err, repository.ErrDuplicate), "%v", err)
+
+	bindings, err := stores.Roles.GetBySubject(ctx, "api_key:gate-system")
+	require.NoError(t, err)
+	require.Len(t, bindings, 2)
+	assert.Equal(t, "YRD1", bindings[0].Yard)
+
+	require.NoError(t, stores.Roles.Delete(ctx, clerk.ID))
+	assert.Error(t, stores.Roles.Delete(ctx, clerk.ID))
+
+	all, err := stores.Roles.GetAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, "api_key:gate-system", all[0].Subject)
+	assert.Equal(t, "jwt:admin", all[1].Subject)
+}
+
+func testTenants(t *testing.T, stores repository.Stores) {
+	ctx := context.Background()
+	acme := tenant.WithTenant(ctx, "acme")
+
+	yards, err := stores.Yards.GetAll(acme)
+	require.NoError(t, err)
+	require.Len(t, yards, 2)
+	assert.Equal(t, "ACME1", yards[0].Code)
+
+	// Yard codes are unique per tenant and looked up in the caller's tenant
+	yard, err := stores.Yards.GetByCode(acme, "YRD1")
+	require.NoError(t, err)
+	assert.Equal(t, 4, yard.ID)
+	assert.Equal(t, "acme", yard.Tenant)
+	yard, err = stores.Yards.GetByCode(ctx, "YRD1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, yard.ID)
+	_, err = stores.Yards.GetByCode(acme, "DEPOT1")
+	assert.Equal(t, apperror.CodeYardNotFound, apperror.CodeOf(err), "%v", err)
+	_, err = stores.Yards.GetByCode(tenant.WithAllTenants(ctx), "YRD1")
+	assert.Equal(t, apperror.CodeYardNotFound, apperror.CodeOf(err), "%v", err)
+	rules, err := stores.Yards.GetOverflowRules(acme, 1)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	// Container numbers are unique per tenant, and a tenant cannot place into
+	// another tenant's yard
+	own := newContainer("ABCU1234560", 1, 1, 1)
+	require.NoError(t, stores.Containers.Create(ctx, own))
+	assert.Equal(t, tenant.Default, own.Tenant)
+	err = stores.Containers.Create(acme, newContainer("MSCU7654321", 1, 2, 1))
+	assert.Equal(t, apperror.CodeYardNotFound, apperror.CodeOf(err), "%v", err)
+
+	theirs := newContainer("ABCU1234560", 1, 1, 1)
+	theirs.YardID, theirs.BlockID = 3, 3
+	require.NoError(t, stores.Containers.Create(acme, theirs))
+	assert.Equal(t, "acme", theirs.Tenant)
+
+	found, err := stores.Containers.GetByNumber(acme, "ABCU1234560")
+	require.NoError(t, err)
+	assert.Equal(t, 3, found.BlockID)
+	found, err = stores.Containers.GetByNumber(ctx, "ABCU1234560")
+	require.NoError(t, err)
+	assert.Equal(t, 1, found.BlockID)
+
+	// Another tenant's boxes can be neither read nor moved nor removed
+	require.NoError(t, stores.Containers.Create(ctx, newContainer("MSCU7654321", 2, 1, 1)))
+	_, err = stores.Containers.GetByNumber(acme, "MSCU7654321")
+	assert.Equal(t, apperror.CodeContainerNotFound, apperror.CodeOf(err), "%v", err)
+	err = stores.Containers.UpdatePosition(acme, "MSCU7654321", 3, 5, 5, 1)
+	assert.Equal(t, apperror.CodeContainerNotFound, apperror.CodeOf(err), "%v", err)
+	err = stores.Containers.Delete(acme, "MSCU7654321")
+	assert.Equal(t, apperror.CodeContainerNotFound, apperror.CodeOf(err), "%v", err)
+	err = stores.Containers.SetHold(acme, "MSCU7654321", "damaged")
+	assert.Equal(t, apperror.CodeContainerNotFound, apperror.CodeOf(err), "%v", err)
+
+	// Nor can a tenant move its own box into another tenant's yard
+	err = stores.Containers.UpdatePosition(acme, "ABCU1234560", 1, 9, 5, 1)
+	assert.Equal(t, apperror.CodeContainerNotFound, apperror.CodeOf(err), "%v", err)
+
+	containers, err := stores.Containers.GetAll(acme)
+	require.NoError(t, err)
+	require.Len(t, containers, 1)
+	inBlock, err := stores.Containers.GetByBlock(acme, 1)
+	require.NoError(t, err)
+	assert.Empty(t, inBlock)
+	all, err := stores.Containers.GetAll(tenant.WithAllTenants(ctx))
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	order := &model.WorkOrder{
+		YardID: 1, ContainerNumber: "MSCU7654321", ContainerSize: 20, ContainerHeight: 8.6,
+		ContainerType: "DRY", Operation: model.WorkOrderPickup, Status: model.WorkOrderStatusCreated,
+		From: &model.WorkOrderLocation{BlockID: 1, Slot: 2, Row: 1, Tier: 1},
+	}
+	require.NoError(t, stores.WorkOrders.Create(ctx, order))
+	_, err = stores.WorkOrders.GetByID(acme, order.ID)
+	assert.Equal(t, apperror.CodeWorkOrderNotFound, apperror.CodeOf(err), "%v", err)
+	pending, err := stores.WorkOrders.HasPendingForContainer(acme, "MSCU7654321")
+	require.NoError(t, err)
+	assert.False(t, pending)
+
+	// The same subject can hold different roles in each tenant
+	require.NoError(t, stores.Roles.Create(ctx, &model.RoleBinding{Subject: "jwt:ops", Role: model.RoleAdmin, Yard: model.AllYards}))
+	binding := &model.RoleBinding{Subject: "jwt:ops", Role: model.RoleAdmin, Yard: model.AllYards}
+	require.NoError(t, stores.Roles.Create(acme, binding))
+	assert.Equal(t, "acme", binding.Tenant)
+	bindings, err := stores.Roles.GetBySubject(ctx, "jwt:ops")
+	require.NoError(t, err)
+	require.Len(t, bindings, 1)
+	assert.Equal(t, tenant.Default, bindings[0].Tenant)
+	assert.Error(t, stores.Roles.Delete(ctx, binding.ID))
+}
+
+func testIdempotency(t *testing.T, stores repository.Stores) {
+	ctx := context.Background()
+	record := &model.IdempotencyRecord{Subject: "api_key:gate-system", Key: "retry-1", RequestHash: fmt.Sprintf("%064x", 1)}
+	require.NoError(t, stores.Idempotency.Reserve(ctx, record))
+	assert.NotZero(t, record.ID)
+
+	again := &model.IdempotencyRecord{Subject: "api_key:gate-system", Key: "retry-1", RequestHash: fmt.Sprintf("%064x", 2)}
+	err := stores.Idempotency.Reserve(ctx, again)
+	assert.True(t, errors.Is(err, repository.ErrDuplicate), "%v", err)
+
+	// Keys are scoped to the caller and the tenant
+	require.NoError(t, stores.Idempotency.Reserve(ctx, &model.IdempotencyRecord{Subject: "jwt:planner-7", Key: "retry-1", RequestHash: record.RequestHash}))
+	require.NoError(t, stores.Idempotency.Reserve(tenant.WithTenant(ctx, "acme"), &model.IdempotencyRecord{Subject: "api_key:gate-system", Key: "retry-1", RequestHash: record.RequestHash}))
+
+	found, err := stores.Idempotency.Get(ctx, "api_key:gate-system", "retry-1")
+	require.NoError(t, err)
+	assert.False(t, found.IsComplete())
+	assert.Equal(t, record.RequestHash, found.RequestHash)
+
+	require.NoError(t, stores.Idempotency.Complete(ctx, record.ID, 200, []byte(`{"success":true}`)))
+	found, err = stores.Idempotency.Get(ctx, "api_key:gate-system", "retry-1")
+	require.NoError(t, err)
+	assert.Equal(t, 200, found.StatusCode)
+	assert.JSONEq(t, `{"success":true}`, string(found.Response))
+
+	_, err = stores.Idempotency.Get(ctx, "api_key:gate-system", "retry-2")
+	assert.Equal(t, apperror.CodeNotFound, apperror.CodeOf(err), "%v", err)
+
+	require.NoError(t, stores.Idempotency.Delete(ctx, record.ID))
+	_, err = stores.Idempotency.Get(ctx, "api_key:gate-system", "retry-1")
+	assert.Error(t, err)
+
+	deleted, err := stores.Idempotency.DeleteBefore(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+	deleted, err = stores.Idempotency.DeleteBefore(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+}
+
+func testBulkJobs(t *testing.T, stores repository.Stores) {
+	ctx := context.Background()
+	job := &model.BulkJob{Subject: "api_key:gate-system", Kind: model.BulkJobPlacement, Request: []byte(`{"kind":"PLACEMENT"}`), Total: 3}
+	require.NoError(t, stores.BulkJobs.Create(ctx, job))
+	assert.NotZero(t, job.ID)
+	assert.Equal(t, model.BulkJobQueued, job.Status)
+
+	// Jobs of another tenant are not found
+	_, err := stores.BulkJobs.GetByID(tenant.WithTenant(ctx, "acme"), job.ID)
+	assert.Equal(t, apperror.CodeNotFound, apperror.CodeOf(err), "%v", err)
+
+	changed, err := stores.BulkJobs.SetStatus(ctx, job.ID, model.BulkJobRunning, "", model.BulkJobQueued)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	changed, err = stores.BulkJobs.SetStatus(ctx, job.ID, model.BulkJobRunning, "", model.BulkJobQueued)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	position := &model.Position{Block: "LC01", Slot: 1, Row: 1, Tier: 1}
+	require.NoError(t, stores.BulkJobs.AddResult(ctx, job.ID, model.BulkJobResult{Index: 2, ContainerNumber: "ABCU1234560", Success: true, Yard: "YRD1", Position: position}))
+	require.NoError(t, stores.BulkJobs.AddResult(ctx, job.ID, model.BulkJobResult{Index: 0, ContainerNumber: "ABCU7654323", Error: "position is already occupied", Code: "POSITION_OCCUPIED"}))
+	err = stores.BulkJobs.AddResult(ctx, job.ID, model.BulkJobResult{Index: 0, Success: true})
+	assert.True(t, errors.Is(err, repository.ErrDuplicate), "%v", err)
+
+	found, err := stores.BulkJobs.GetByID(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.BulkJobRunning, found.Status)
+	assert.Equal(t, "api_key:gate-system", found.Subject)
+	assert.JSONEq(t, `{"kind":"PLACEMENT"}`, string(found.Request))
+	assert.Equal(t, 2, found.Processed)
+	assert.Equal(t, 1, found.Succeeded)
+	assert.Equal(t, 1, found.Failed)
+
+	results, err := stores.BulkJobs.GetResults(ctx, job.ID)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, 0, results[0].Index)
+	assert.Equal(t, "POSITION_OCCUPIED", results[0].Code)
+	assert.Equal(t, position, results[1].Position)
+
+	unfinished, err := stores.BulkJobs.GetUnfinished(ctx)
+	require.NoError(t, err)
+	require.Len(t, unfinished, 1)
+	assert.Equal(t, job.ID, unfinished[0].ID)
+
+	changed, err = stores.BulkJobs.SetStatus(ctx, job.ID, model.BulkJobCancelled, "cancelled by api_key:gate-system", model.BulkJobQueued, model.BulkJobRunning)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	unfinished, err = stores.BulkJobs.GetUnfinished(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, unfinished)
+}
+
+func testVersions(t *testing.T, stores repository.Stores) {
+	ctx := context.Background()
+	block, err := stores.Blocks.GetByYardAndCode(ctx, 1, "LC01")
+	require.NoError(t, err)
+	assert.Equal(t, 1, block.Version)
+
+	block.Name = "Lapangan C01"
+	block.MaxSlot = 12
+	require.NoError(t, stores.Blocks.Update(ctx, block, 1))
+	assert.Equal(t, 2, block.Version)
+
+	// A writer that read version 1 is turned away, and 0 matches any version
+	stale := *block
+	err = stores.Blocks.Update(ctx, &stale, 1)
+	assert.Equal(t, apperror.CodePreconditionFailed, apperror.CodeOf(err), "%v", err)
+	require.NoError(t, stores.Blocks.Update(ctx, &stale, 0))
+	assert.Equal(t, 3, stale.Version)
+
+	found, err := stores.Blocks.GetByID(ctx, block.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Lapangan C01", found.Name)
+	assert.Equal(t, 12, found.MaxSlot)
+	assert.Equal(t, 3, found.Version)
+
+	missing := model.Block{ID: 999, Name: "X", MaxSlot: 1, MaxRow: 1, MaxTier: 1}
+	err = stores.Blocks.Update(ctx, &missing, 1)
+	assert.Equal(t, apperror.CodeBlockNotFound, apperror.CodeOf(err), "%v", err)
+
+	plan := &model.YardPlan{
+		BlockID: block.ID, SlotStart: 1, SlotEnd: 2, RowStart: 1, RowEnd: 2,
+		ContainerSize: 20, ContainerHeight: 9.6, ContainerType: "REEFER", StackingPriority: "LEFT_TO_RIGHT",
+	}
+	require.NoError(t, stores.Plans.Create(ctx, plan))
+	assert.Equal(t, 1, plan.Version)
+
+	plan.SlotEnd = 3
+	require.NoError(t, stores.Plans.Update(ctx, plan, 1))
+	assert.Equal(t, 2, plan.Version)
+
+	found2, err := stores.Plans.GetByID(ctx, plan.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, found2.SlotEnd)
+	assert.Equal(t, 2, found2.Version)
+
+	err = stores.Plans.Update(ctx, plan, 1)
+	assert.Equal(t, apperror.CodePreconditionFailed, apperror.CodeOf(err), "%v", err)
+	err = stores.Plans.Delete(ctx, plan.ID, 1)
+	assert.Equal(t, apperror.CodePreconditionFailed, apperror.CodeOf(err), "%v", err)
+
+	require.NoError(t, stores.Plans.Delete(ctx, plan.ID, 2))
+	_, err = stores.Plans.GetByID(ctx, plan.ID)
+	assert.Equal(t, apperror.CodeNotFound, apperror.CodeOf(err), "%v", err)
+	err = stores.Plans.Delete(ctx, plan.ID, 2)
+	assert.Equal(t, apperror.CodeNotFound, apperror.CodeOf(err), "%v", err)
+}
+
+func testAtomic(t *testing.T, stores repository.Stores) {
+	ctx := context.Background()
+	errRollback := errors.New("rollback")
+
+	err := stores.Atomic(ctx, func(tx repository.Stores) error {
+		require.NoError(t, tx.Containers.Create(ctx, newContainer("ABCU0000001", 1, 1, 1)))
+		require.NoError(t, tx.Containers.Create(ctx, newContainer("ABCU0000002", 1, 1, 2)))
+
+		// Writes of the transaction are visible inside it
+		occupied, err := tx.Containers.IsPositionOccupied(ctx, 1, 1, 1, 2, 20)
+		require.NoError(t, err)
+		assert.True(t, occupied)
+		return errRollback
+	})
+	assert.Equal(t, errRollback, err)
+
+	containers, err := stores.Containers.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, containers)
+
+	err = stores.Atomic(ctx, func(tx repository.Stores) error {
+		if err := tx.Containers.Create(ctx, newContainer("ABCU0000001", 1, 1, 1)); err != nil {
+			return err
+		}
+		// A failed write rolls back the earlier ones
+		return tx.Containers.Create(ctx, newContainer("ABCU0000002", 1, 1, 1))
+	})
+	assert.True(t, errors.Is(err, repository.ErrDuplicate), "%v", err)
+	containers, err = stores.Containers.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, containers)
+
+	require.NoError(t, stores.Atomic(ctx, func(tx repository.Stores) error {
+		if err := tx.Containers.Create(ctx, newContainer("ABCU0000001", 1, 1, 1)); err != nil {
+			return err
+		}
+		return tx.Atomic(ctx, func(nested repository.Stores) error {
+			return nested.Containers.Create(ctx, newContainer("ABCU0000002", 1, 1, 2))
+		})
+	}))
+	containers, err = stores.Containers.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, containers, 2)
+}
+
+func testFences(t *testing.T, stores repository.Stores) {
+	ctx := context.Background()
+	require.NoError(t, stores.Fences.Check(ctx, "yard:1", 5))
+	require.NoError(t, stores.Fences.Check(ctx, "yard:1", 5), "the same owner writes again")
+	require.NoError(t, stores.Fences.Check(ctx, "yard:2", 1), "locks are fenced separately")
+
+	// An older owner is fenced off once a newer one has written
+	require.NoError(t, stores.Fences.Check(ctx, "yard:1", 7))
+	err := stores.Fences.Check(ctx, "yard:1", 6)
+	assert.True(t, errors.Is(err, repository.ErrFenced), "%v", err)
+	assert.Equal(t, apperror.CodeConflict, apperror.CodeOf(err))
+
+	// A fenced write rolls back the transaction it is part of
+	err = stores.Atomic(ctx, func(tx repository.Stores) error {
+		if err := tx.Containers.Create(ctx, newContainer("ABCU0000001", 1, 1, 1)); err != nil {
+			return err
+		}
+		return tx.Fences.Check(ctx, "yard:1", 5)
+	})
+	assert.True(t, errors.Is(err, repository.ErrFenced), "%v", err)
+	containers, err := stores.Containers.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, containers)
+
+	// A rolled back check does not record its token
+	err = stores.Atomic(ctx, func(tx repository.Stores) error {
+		if err := tx.Fences.Check(ctx, "yard:2", 9); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+	require.NoError(t, stores.Fences.Check(ctx, "yard:2", 8))
+}
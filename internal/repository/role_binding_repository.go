@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+type RoleBindingRepository struct {
+	db dbtx
+}
+
+func NewRoleBindingRepository(db *sql.DB) *RoleBindingRepository {
+	return &RoleBindingRepository{db: db}
+}
+
+// Create grants a role to a subject in a yard of the caller's tenant
+func (r *RoleBindingRepository) Create(ctx context.Context, binding *model.RoleBinding) error {
+	query := `
+		INSERT INTO role_bindings (tenant, subject, role, yard_code)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	binding.Tenant = ownerTenant(ctx)
+	err := r.db.QueryRowContext(ctx, query, binding.Tenant, binding.Subject, binding.Role, binding.Yard).
+		Scan(&binding.ID, &binding.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating role binding: %w", uniqueErr(err))
+	}
+
+	return nil
+}
+
+// GetBySubject retrieves the role bindings of a subject in the caller's tenant
+func (r *RoleBindingRepository) GetBySubject(ctx context.Context, subject string) ([]model.RoleBinding, error) {
+	return r.query(ctx, `SELECT `+roleBindingColumns+` FROM role_bindings
+		WHERE subject = $1 AND ($2 = '' OR tenant = $2) ORDER BY id`, subject, tenant.FromContext(ctx))
+}
+
+// GetAll retrieves all role bindings of the caller's tenant
+func (r *RoleBindingRepository) GetAll(ctx context.Context) ([]model.RoleBinding, error) {
+	return r.query(ctx, `SELECT `+roleBindingColumns+` FROM role_bindings
+		WHERE $1 = '' OR tenant = $1 ORDER BY subject, id`, tenant.FromContext(ctx))
+}
+
+// Delete removes a role binding of the caller's tenant
+func (r *RoleBindingRepository) Delete(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM role_bindings WHERE id = $1 AND ($2 = '' OR tenant = $2)`,
+		id, tenant.FromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("error deleting role binding: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperror.New(apperror.CodeNotFound, "role binding %d not found", id)
+	}
+
+	return nil
+}
+
+const roleBindingColumns = `id, tenant, subject, role, yard_code, created_at`
+
+// ownerTenant is the tenant new rows such as role bindings are created in.
+// Without a tenant scope they go to the default tenant.
+func ownerTenant(ctx context.Context) string {
+	if t := tenant.FromContext(ctx); t != "" {
+		return t
+	}
+	return tenant.Default
+}
+
+func (r *RoleBindingRepository) query(ctx context.Context, query string, args ...interface{}) ([]model.RoleBinding, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying role bindings: %w", err)
+	}
+	defer rows.Close()
+
+	var bindings []model.RoleBinding
+	for rows.Next() {
+		var b model.RoleBinding
+		if err := rows.Scan(&b.ID, &b.Tenant, &b.Subject, &b.Role, &b.Yard, &b.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning role binding: %w", err)
+		}
+		bindings = append(bindings, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating role bindings: %w", err)
+	}
+
+	return bindings, nil
+}
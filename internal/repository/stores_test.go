@@ -0,0 +1,65 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/repository/repotest"
+	"github.com/dwipurnomo515/yard-planning/migrations"
+	"github.com/dwipurnomo515/yard-planning/pkg/database"
+	"github.com/dwipurnomo515/yard-planning/pkg/migrate"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteStores(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.Stores {
+		db, err := database.NewSQLiteDB(database.SQLiteConfig{Path: t.TempDir() + "/yard.db"})
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		migrator, err := migrate.New(db, migrate.DialectSQLite, migrations.SQLite())
+		require.NoError(t, err)
+		_, err = migrator.Up(context.Background())
+		require.NoError(t, err)
+		_, err = db.Exec(repotest.FixtureSQL)
+		require.NoError(t, err)
+
+		return repository.NewSQLStores(db)
+	})
+}
+
+// TestPostgresStores runs the suite against the database in TEST_DATABASE_URL.
+// Pending migrations are applied first; all data in the database is deleted.
+func TestPostgresStores(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	migrator, err := migrate.New(db, migrate.DialectPostgres, migrations.Postgres())
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	repotest.Run(t, func(t *testing.T) repository.Stores {
+		_, err := db.Exec(`
+			TRUNCATE yard_overflow_rules, yard_points, work_orders, equipment_blocks, equipment,
+			         block_closures, containers, yard_plans, blocks, yards, api_keys, role_bindings,
+			         idempotency_keys, bulk_job_results, bulk_jobs, lock_fences
+			RESTART IDENTITY CASCADE
+		`)
+		require.NoError(t, err)
+		_, err = db.Exec(repotest.FixtureSQL)
+		require.NoError(t, err)
+
+		return repository.NewSQLStores(db)
+	})
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+// staleVersion explains why a versioned update of a row matched nothing: the
+// row is gone, or it was changed since the caller read the expected version
+func staleVersion(ctx context.Context, db dbtx, table string, id, version int, notFound error) error {
+	var current int
+	err := db.QueryRowContext(ctx, "SELECT version FROM "+table+" WHERE id = $1", id).Scan(&current)
+	if err == sql.ErrNoRows {
+		return notFound
+	}
+	if err != nil {
+		return fmt.Errorf("error querying version: %w", err)
+	}
+
+	return apperror.New(apperror.CodePreconditionFailed,
+		"version %d does not match the current version %d", version, current)
+}
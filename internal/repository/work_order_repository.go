@@ -0,0 +1,323 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+type WorkOrderRepository struct {
+	db dbtx
+}
+
+func NewWorkOrderRepository(db *sql.DB) *WorkOrderRepository {
+	return &WorkOrderRepository{db: db}
+}
+
+const workOrderColumns = `
+	w.id, w.yard_id, w.equipment_id, COALESCE(e.code, ''), w.container_number,
+	w.container_size, w.container_height, w.container_type, w.operation,
+	w.from_block_id, COALESCE(fb.code, ''), w.from_slot, w.from_row, w.from_tier,
+	w.block_id, COALESCE(tb.code, ''), w.slot, w.row, w.tier,
+	w.destination, w.position_applied, w.status, w.failure_reason,
+	w.created_at, w.dispatched_at, w.started_at, w.completed_at
+`
+
+const workOrderJoins = `
+	FROM work_orders w
+	LEFT JOIN equipment e ON e.id = w.equipment_id
+	LEFT JOIN blocks fb ON fb.id = w.from_block_id
+	LEFT JOIN blocks tb ON tb.id = w.block_id
+`
+
+// workOrderTenantFilter limits work orders to yards of the tenant bound to
+// the placeholder; an empty tenant matches every yard
+func workOrderTenantFilter(placeholder string) string {
+	return `(` + placeholder + ` = '' OR EXISTS (
+		SELECT 1 FROM yards ty WHERE ty.id = w.yard_id AND ty.tenant = ` + placeholder + `))`
+}
+
+// Create inserts a new work order
+func (r *WorkOrderRepository) Create(ctx context.Context, order *model.WorkOrder) error {
+	query := `
+		INSERT INTO work_orders (
+			yard_id, equipment_id, container_number, container_size, container_height,
+			container_type, operation, from_block_id, from_slot, from_row, from_tier,
+			block_id, slot, row, tier, destination, position_applied, status
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		RETURNING id, created_at
+	`
+
+	fromBlock, fromSlot, fromRow, fromTier := locationArgs(order.From)
+	toBlock, toSlot, toRow, toTier := locationArgs(order.To)
+
+	err := r.db.QueryRowContext(ctx,
+		query,
+		order.YardID,
+		order.EquipmentID,
+		order.ContainerNumber,
+		order.ContainerSize,
+		order.ContainerHeight,
+		order.ContainerType,
+		order.Operation,
+		fromBlock,
+		fromSlot,
+		fromRow,
+		fromTier,
+		toBlock,
+		toSlot,
+		toRow,
+		toTier,
+		order.Destination,
+		order.PositionApplied,
+		order.Status,
+	).Scan(&order.ID, &order.CreatedAt)
+
+	if isUniqueViolation(err) {
+		return fmt.Errorf("error creating work order: %w", ErrCellReserved)
+	}
+	if err != nil {
+		return fmt.Errorf("error creating work order: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a work order in a yard of the caller's tenant by ID
+func (r *WorkOrderRepository) GetByID(ctx context.Context, id int) (*model.WorkOrder, error) {
+	query := `SELECT ` + workOrderColumns + workOrderJoins + `
+		WHERE w.id = $1 AND ` + workOrderTenantFilter("$2")
+
+	orders, err := r.query(ctx, query, id, tenant.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, apperror.New(apperror.CodeWorkOrderNotFound, "work order %d not found", id)
+	}
+
+	return &orders[0], nil
+}
+
+// GetPendingByYardID retrieves all work orders of a yard that are not finished yet
+func (r *WorkOrderRepository) GetPendingByYardID(ctx context.Context, yardID int) ([]model.WorkOrder, error) {
+	query := `SELECT ` + workOrderColumns + workOrderJoins + `
+		WHERE w.yard_id = $1
+		  AND w.status NOT IN ('COMPLETED', 'FAILED')
+		ORDER BY w.created_at, w.id
+	`
+
+	return r.query(ctx, query, yardID)
+}
+
+// HasPendingForContainer checks if a container of the caller's tenant already
+// has an unfinished work order
+func (r *WorkOrderRepository) HasPendingForContainer(ctx context.Context, containerNumber string) (bool, error) {
+	query := `
+		SELECT COUNT(*) > 0
+		FROM work_orders w
+		WHERE w.container_number = $1
+		  AND w.status NOT IN ('COMPLETED', 'FAILED')
+		  AND ` + workOrderTenantFilter("$2")
+
+	var pending bool
+	err := r.db.QueryRowContext(ctx, query, containerNumber, tenant.FromContext(ctx)).Scan(&pending)
+	if err != nil {
+		return false, fmt.Errorf("error checking pending work orders: %w", err)
+	}
+
+	return pending, nil
+}
+
+// GetActiveForEquipment retrieves the oldest dispatched or in-progress job of a machine
+func (r *WorkOrderRepository) GetActiveForEquipment(ctx context.Context, equipmentID int) (*model.WorkOrder, error) {
+	query := `SELECT ` + workOrderColumns + workOrderJoins + `
+		WHERE w.equipment_id = $1
+		  AND w.status IN ('DISPATCHED', 'IN_PROGRESS')
+		ORDER BY w.created_at, w.id
+		LIMIT 1
+	`
+
+	orders, err := r.query(ctx, query, equipmentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, nil
+	}
+
+	return &orders[0], nil
+}
+
+// DispatchNext assigns the oldest created job of a machine, or an unassigned job in one
+// of the blocks it serves, to the machine and marks it dispatched. It returns nil when
+// there is no job waiting.
+func (r *WorkOrderRepository) DispatchNext(ctx context.Context, equipmentID int) (*model.WorkOrder, error) {
+	query := `
+		UPDATE work_orders
+		SET status = 'DISPATCHED', equipment_id = $1, dispatched_at = $2
+		WHERE id = (
+			SELECT id FROM work_orders
+			WHERE status = 'CREATED'
+			  AND (
+				equipment_id = $1
+				OR (equipment_id IS NULL AND COALESCE(block_id, from_block_id) IN (
+					SELECT block_id FROM equipment_blocks WHERE equipment_id = $1
+				))
+			  )
+			ORDER BY created_at, id
+			LIMIT 1
+		)
+		  AND status = 'CREATED'
+		RETURNING id
+	`
+
+	var id int
+	err := r.db.QueryRowContext(ctx, query, equipmentID, time.Now()).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error dispatching work order: %w", err)
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// UpdateStatus moves a work order from one status to another. It fails when the
+// work order is no longer in the expected status.
+func (r *WorkOrderRepository) UpdateStatus(ctx context.Context, id int, from, to, reason string) error {
+	var startedAt, completedAt *time.Time
+	now := time.Now()
+	switch to {
+	case model.WorkOrderStatusInProgress:
+		startedAt = &now
+	case model.WorkOrderStatusCompleted, model.WorkOrderStatusFailed:
+		completedAt = &now
+	}
+
+	query := `
+		UPDATE work_orders
+		SET status = $3,
+		    started_at = COALESCE($4, started_at),
+		    completed_at = COALESCE($5, completed_at),
+		    failure_reason = $6
+		WHERE id = $1 AND status = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, from, to, startedAt, completedAt, reason)
+	if err != nil {
+		return fmt.Errorf("error updating work order: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return apperror.New(apperror.CodeConflict, "work order %d is no longer %s", id, from)
+	}
+
+	return nil
+}
+
+func (r *WorkOrderRepository) query(ctx context.Context, query string, args ...interface{}) ([]model.WorkOrder, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying work orders: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []model.WorkOrder
+	for rows.Next() {
+		var (
+			order                                    model.WorkOrder
+			equipmentID                              sql.NullInt64
+			fromBlockID, fromSlot, fromRow, fromTier sql.NullInt64
+			toBlockID, toSlot, toRow, toTier         sql.NullInt64
+			fromBlock, toBlock                       string
+			dispatchedAt, startedAt, completedAt     sql.NullTime
+		)
+		err := rows.Scan(
+			&order.ID,
+			&order.YardID,
+			&equipmentID,
+			&order.EquipmentCode,
+			&order.ContainerNumber,
+			&order.ContainerSize,
+			&order.ContainerHeight,
+			&order.ContainerType,
+			&order.Operation,
+			&fromBlockID,
+			&fromBlock,
+			&fromSlot,
+			&fromRow,
+			&fromTier,
+			&toBlockID,
+			&toBlock,
+			&toSlot,
+			&toRow,
+			&toTier,
+			&order.Destination,
+			&order.PositionApplied,
+			&order.Status,
+			&order.FailureReason,
+			&order.CreatedAt,
+			&dispatchedAt,
+			&startedAt,
+			&completedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning work order: %w", err)
+		}
+		order.EquipmentID = nullIntPtr(equipmentID)
+		if fromBlockID.Valid {
+			order.From = &model.WorkOrderLocation{
+				BlockID: int(fromBlockID.Int64),
+				Block:   fromBlock,
+				Slot:    int(fromSlot.Int64),
+				Row:     int(fromRow.Int64),
+				Tier:    int(fromTier.Int64),
+			}
+		}
+		if toBlockID.Valid {
+			order.To = &model.WorkOrderLocation{
+				BlockID: int(toBlockID.Int64),
+				Block:   toBlock,
+				Slot:    int(toSlot.Int64),
+				Row:     int(toRow.Int64),
+				Tier:    int(toTier.Int64),
+			}
+		}
+		order.DispatchedAt = nullTimePtr(dispatchedAt)
+		order.StartedAt = nullTimePtr(startedAt)
+		order.CompletedAt = nullTimePtr(completedAt)
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+// locationArgs converts an optional location into nullable query arguments
+func locationArgs(loc *model.WorkOrderLocation) (blockID, slot, row, tier *int) {
+	if loc == nil {
+		return nil, nil, nil, nil
+	}
+	return &loc.BlockID, &loc.Slot, &loc.Row, &loc.Tier
+}
+
+// nullTimePtr converts a nullable column into an optional time
+func nullTimePtr(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := v.Time
+	return &t
+}
@@ -1,14 +1,16 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
 	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
 )
 
 type YardPlanRepository struct {
-	db *sql.DB
+	db dbtx
 }
 
 func NewYardPlanRepository(db *sql.DB) *YardPlanRepository {
@@ -16,11 +18,11 @@ func NewYardPlanRepository(db *sql.DB) *YardPlanRepository {
 }
 
 // FindMatchingPlan finds a yard plan that matches the container specifications
-func (r *YardPlanRepository) FindMatchingPlan(blockID int, size int, height float64, containerType string) (*model.YardPlan, error) {
+func (r *YardPlanRepository) FindMatchingPlan(ctx context.Context, blockID int, size int, height float64, containerType string) (*model.YardPlan, error) {
 	query := `
 		SELECT id, block_id, slot_start, slot_end, row_start, row_end,
 		       container_size, container_height, container_type, stacking_priority,
-		       created_at, updated_at
+		       version, created_at, updated_at
 		FROM yard_plans
 		WHERE block_id = $1
 		  AND container_size = $2
@@ -30,7 +32,7 @@ func (r *YardPlanRepository) FindMatchingPlan(blockID int, size int, height floa
 	`
 
 	var plan model.YardPlan
-	err := r.db.QueryRow(query, blockID, size, height, containerType).Scan(
+	err := r.db.QueryRowContext(ctx, query, blockID, size, height, containerType).Scan(
 		&plan.ID,
 		&plan.BlockID,
 		&plan.SlotStart,
@@ -41,12 +43,13 @@ func (r *YardPlanRepository) FindMatchingPlan(blockID int, size int, height floa
 		&plan.ContainerHeight,
 		&plan.ContainerType,
 		&plan.StackingPriority,
+		&plan.Version,
 		&plan.CreatedAt,
 		&plan.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("no yard plan found for container size=%d, height=%.1f, type=%s", size, height, containerType)
+		return nil, apperror.New(apperror.CodeNotFound, "no yard plan found for container size=%d, height=%.1f, type=%s", size, height, containerType)
 	}
 
 	if err != nil {
@@ -57,17 +60,17 @@ func (r *YardPlanRepository) FindMatchingPlan(blockID int, size int, height floa
 }
 
 // GetByBlockID retrieves all yard plans for a specific block
-func (r *YardPlanRepository) GetByBlockID(blockID int) ([]model.YardPlan, error) {
+func (r *YardPlanRepository) GetByBlockID(ctx context.Context, blockID int) ([]model.YardPlan, error) {
 	query := `
 		SELECT id, block_id, slot_start, slot_end, row_start, row_end,
 		       container_size, container_height, container_type, stacking_priority,
-		       created_at, updated_at
+		       version, created_at, updated_at
 		FROM yard_plans
 		WHERE block_id = $1
 		ORDER BY slot_start, row_start
 	`
 
-	rows, err := r.db.Query(query, blockID)
+	rows, err := r.db.QueryContext(ctx, query, blockID)
 	if err != nil {
 		return nil, fmt.Errorf("error querying yard plans: %w", err)
 	}
@@ -87,6 +90,7 @@ func (r *YardPlanRepository) GetByBlockID(blockID int) ([]model.YardPlan, error)
 			&plan.ContainerHeight,
 			&plan.ContainerType,
 			&plan.StackingPriority,
+			&plan.Version,
 			&plan.CreatedAt,
 			&plan.UpdatedAt,
 		)
@@ -99,18 +103,56 @@ func (r *YardPlanRepository) GetByBlockID(blockID int) ([]model.YardPlan, error)
 	return plans, nil
 }
 
+// GetByID retrieves a yard plan by ID
+func (r *YardPlanRepository) GetByID(ctx context.Context, id int) (*model.YardPlan, error) {
+	query := `
+		SELECT id, block_id, slot_start, slot_end, row_start, row_end,
+		       container_size, container_height, container_type, stacking_priority,
+		       version, created_at, updated_at
+		FROM yard_plans
+		WHERE id = $1
+	`
+
+	var plan model.YardPlan
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&plan.ID,
+		&plan.BlockID,
+		&plan.SlotStart,
+		&plan.SlotEnd,
+		&plan.RowStart,
+		&plan.RowEnd,
+		&plan.ContainerSize,
+		&plan.ContainerHeight,
+		&plan.ContainerType,
+		&plan.StackingPriority,
+		&plan.Version,
+		&plan.CreatedAt,
+		&plan.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, apperror.New(apperror.CodeNotFound, "yard plan %d not found", id)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error querying yard plan: %w", err)
+	}
+
+	return &plan, nil
+}
+
 // Create creates a new yard plan
-func (r *YardPlanRepository) Create(plan *model.YardPlan) error {
+func (r *YardPlanRepository) Create(ctx context.Context, plan *model.YardPlan) error {
 	query := `
 		INSERT INTO yard_plans (
 			block_id, slot_start, slot_end, row_start, row_end,
 			container_size, container_height, container_type, stacking_priority
 		)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-		RETURNING id, created_at, updated_at
+		RETURNING id, version, created_at, updated_at
 	`
 
-	err := r.db.QueryRow(
+	err := r.db.QueryRowContext(ctx,
 		query,
 		plan.BlockID,
 		plan.SlotStart,
@@ -121,7 +163,7 @@ func (r *YardPlanRepository) Create(plan *model.YardPlan) error {
 		plan.ContainerHeight,
 		plan.ContainerType,
 		plan.StackingPriority,
-	).Scan(&plan.ID, &plan.CreatedAt, &plan.UpdatedAt)
+	).Scan(&plan.ID, &plan.Version, &plan.CreatedAt, &plan.UpdatedAt)
 
 	if err != nil {
 		return fmt.Errorf("error creating yard plan: %w", err)
@@ -129,3 +171,64 @@ func (r *YardPlanRepository) Create(plan *model.YardPlan) error {
 
 	return nil
 }
+
+// Update saves a yard plan and increments its version. The plan must still be
+// at the given version; 0 matches any.
+func (r *YardPlanRepository) Update(ctx context.Context, plan *model.YardPlan, version int) error {
+	query := `
+		UPDATE yard_plans
+		SET block_id = $3, slot_start = $4, slot_end = $5, row_start = $6, row_end = $7,
+		    container_size = $8, container_height = $9, container_type = $10, stacking_priority = $11,
+		    version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND ($2 = 0 OR version = $2)
+		RETURNING version, created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(ctx,
+		query,
+		plan.ID,
+		version,
+		plan.BlockID,
+		plan.SlotStart,
+		plan.SlotEnd,
+		plan.RowStart,
+		plan.RowEnd,
+		plan.ContainerSize,
+		plan.ContainerHeight,
+		plan.ContainerType,
+		plan.StackingPriority,
+	).Scan(&plan.Version, &plan.CreatedAt, &plan.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return staleVersion(ctx, r.db, "yard_plans", plan.ID, version,
+			apperror.New(apperror.CodeNotFound, "yard plan %d not found", plan.ID))
+	}
+
+	if err != nil {
+		return fmt.Errorf("error updating yard plan: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a yard plan that is still at the given version; 0 matches any
+func (r *YardPlanRepository) Delete(ctx context.Context, id, version int) error {
+	query := `DELETE FROM yard_plans WHERE id = $1 AND ($2 = 0 OR version = $2)`
+
+	result, err := r.db.ExecContext(ctx, query, id, version)
+	if err != nil {
+		return fmt.Errorf("error deleting yard plan: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return staleVersion(ctx, r.db, "yard_plans", id, version,
+			apperror.New(apperror.CodeNotFound, "yard plan %d not found", id))
+	}
+
+	return nil
+}
@@ -1,40 +1,45 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
 	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
 )
 
 type YardRepository struct {
-	db *sql.DB
+	db dbtx
 }
 
 func NewYardRepository(db *sql.DB) *YardRepository {
 	return &YardRepository{db: db}
 }
 
-// GetByCode retrieves a yard by its code
-func (r *YardRepository) GetByCode(code string) (*model.Yard, error) {
+// GetByCode retrieves a yard of the caller's tenant by its code. Codes are
+// only unique per tenant, so a context of all tenants finds no yard.
+func (r *YardRepository) GetByCode(ctx context.Context, code string) (*model.Yard, error) {
 	query := `
-		SELECT id, code, name, description, created_at, updated_at
+		SELECT id, code, name, description, created_at, updated_at, tenant
 		FROM yards
-		WHERE code = $1
+		WHERE code = $1 AND tenant = $2
 	`
 
 	var yard model.Yard
-	err := r.db.QueryRow(query, code).Scan(
+	err := r.db.QueryRowContext(ctx, query, code, tenant.FromContext(ctx)).Scan(
 		&yard.ID,
 		&yard.Code,
 		&yard.Name,
 		&yard.Description,
 		&yard.CreatedAt,
 		&yard.UpdatedAt,
+		&yard.Tenant,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("yard with code '%s' not found", code)
+		return nil, apperror.New(apperror.CodeYardNotFound, "yard with code '%s' not found", code)
 	}
 
 	if err != nil {
@@ -44,15 +49,16 @@ func (r *YardRepository) GetByCode(code string) (*model.Yard, error) {
 	return &yard, nil
 }
 
-// GetAll retrieves all yards
-func (r *YardRepository) GetAll() ([]model.Yard, error) {
+// GetAll retrieves all yards of the caller's tenant
+func (r *YardRepository) GetAll(ctx context.Context) ([]model.Yard, error) {
 	query := `
-		SELECT id, code, name, description, created_at, updated_at
+		SELECT id, code, name, description, created_at, updated_at, tenant
 		FROM yards
+		WHERE $1 = '' OR tenant = $1
 		ORDER BY code
 	`
 
-	rows, err := r.db.Query(query)
+	rows, err := r.db.QueryContext(ctx, query, tenant.FromContext(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("error querying yards: %w", err)
 	}
@@ -68,6 +74,7 @@ func (r *YardRepository) GetAll() ([]model.Yard, error) {
 			&yard.Description,
 			&yard.CreatedAt,
 			&yard.UpdatedAt,
+			&yard.Tenant,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning yard: %w", err)
@@ -77,3 +84,124 @@ func (r *YardRepository) GetAll() ([]model.Yard, error) {
 
 	return yards, nil
 }
+
+// GetPointsByYardID retrieves the points of interest of a yard
+func (r *YardRepository) GetPointsByYardID(ctx context.Context, yardID int) ([]model.YardPoint, error) {
+	query := `
+		SELECT id, yard_id, code, name, point_type, x, y, created_at
+		FROM yard_points
+		WHERE yard_id = $1
+		ORDER BY code
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, yardID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying yard points: %w", err)
+	}
+	defer rows.Close()
+
+	var points []model.YardPoint
+	for rows.Next() {
+		var point model.YardPoint
+		err := rows.Scan(
+			&point.ID,
+			&point.YardID,
+			&point.Code,
+			&point.Name,
+			&point.PointType,
+			&point.X,
+			&point.Y,
+			&point.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning yard point: %w", err)
+		}
+		points = append(points, point)
+	}
+
+	return points, nil
+}
+
+// CreatePoint inserts a new point of interest
+func (r *YardRepository) CreatePoint(ctx context.Context, point *model.YardPoint) error {
+	query := `
+		INSERT INTO yard_points (yard_id, code, name, point_type, x, y)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRowContext(ctx,
+		query,
+		point.YardID,
+		point.Code,
+		point.Name,
+		point.PointType,
+		point.X,
+		point.Y,
+	).Scan(&point.ID, &point.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("error creating yard point: %w", uniqueErr(err))
+	}
+
+	return nil
+}
+
+// GetOverflowRules retrieves the overflow rules of a yard ordered by priority.
+// Rules into yards of another tenant are never returned.
+func (r *YardRepository) GetOverflowRules(ctx context.Context, yardID int) ([]model.OverflowRule, error) {
+	query := `
+		SELECT r.id, r.yard_id, r.overflow_yard_id, y.code, r.priority, r.created_at
+		FROM yard_overflow_rules r
+		JOIN yards y ON y.id = r.overflow_yard_id
+		WHERE r.yard_id = $1 AND ($2 = '' OR y.tenant = $2)
+		ORDER BY r.priority, r.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, yardID, tenant.FromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("error querying overflow rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []model.OverflowRule
+	for rows.Next() {
+		var rule model.OverflowRule
+		err := rows.Scan(
+			&rule.ID,
+			&rule.YardID,
+			&rule.OverflowYardID,
+			&rule.OverflowYard,
+			&rule.Priority,
+			&rule.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning overflow rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// CreateOverflowRule inserts a new overflow rule
+func (r *YardRepository) CreateOverflowRule(ctx context.Context, rule *model.OverflowRule) error {
+	query := `
+		INSERT INTO yard_overflow_rules (yard_id, overflow_yard_id, priority)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRowContext(ctx,
+		query,
+		rule.YardID,
+		rule.OverflowYardID,
+		rule.Priority,
+	).Scan(&rule.ID, &rule.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("error creating overflow rule: %w", uniqueErr(err))
+	}
+
+	return nil
+}
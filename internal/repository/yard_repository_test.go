@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"testing"
 	"time"
@@ -10,6 +11,7 @@ import (
 )
 
 func TestYardRepository_GetByCode(t *testing.T) {
+	ctx := context.Background()
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
 	defer db.Close()
@@ -19,14 +21,14 @@ func TestYardRepository_GetByCode(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		now := time.Now()
 
-		rows := sqlmock.NewRows([]string{"id", "code", "name", "description", "created_at", "updated_at"}).
-			AddRow(1, "YRD1", "Yard 1", "Main yard", now, now)
+		rows := sqlmock.NewRows([]string{"id", "code", "name", "description", "created_at", "updated_at", "tenant"}).
+			AddRow(1, "YRD1", "Yard 1", "Main yard", now, now, "default")
 
-		mock.ExpectQuery("SELECT id, code, name, description, created_at, updated_at FROM yards WHERE code = \\$1").
-			WithArgs("YRD1").
+		mock.ExpectQuery("SELECT id, code, name, description, created_at, updated_at, tenant FROM yards WHERE code = \\$1").
+			WithArgs("YRD1", "default").
 			WillReturnRows(rows)
 
-		yard, err := repo.GetByCode("YRD1")
+		yard, err := repo.GetByCode(ctx, "YRD1")
 		assert.NoError(t, err)
 		assert.NotNil(t, yard)
 		assert.Equal(t, "YRD1", yard.Code)
@@ -34,19 +36,37 @@ func TestYardRepository_GetByCode(t *testing.T) {
 	})
 
 	t.Run("not found", func(t *testing.T) {
-		mock.ExpectQuery("SELECT id, code, name, description, created_at, updated_at FROM yards WHERE code = \\$1").
-			WithArgs("INVALID").
+		mock.ExpectQuery("SELECT id, code, name, description, created_at, updated_at, tenant FROM yards WHERE code = \\$1").
+			WithArgs("INVALID", "default").
 			WillReturnError(sql.ErrNoRows)
 
-		yard, err := repo.GetByCode("INVALID")
+		yard, err := repo.GetByCode(ctx, "INVALID")
 		assert.Error(t, err)
 		assert.Nil(t, yard)
 	})
 
+	t.Run("request timeout", func(t *testing.T) {
+		mock.ExpectQuery("SELECT id, code, name, description, created_at, updated_at, tenant FROM yards WHERE code = \\$1").
+			WithArgs("YRD1", "default").
+			WillDelayFor(time.Second).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		// The query is abandoned as soon as the deadline passes
+		start := time.Now()
+		yard, err := repo.GetByCode(ctx, "YRD1")
+		assert.Error(t, err)
+		assert.Nil(t, yard)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	})
+
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestYardRepository_GetAll(t *testing.T) {
+	ctx := context.Background()
 	time := time.Now()
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -54,14 +74,15 @@ func TestYardRepository_GetAll(t *testing.T) {
 
 	repo := NewYardRepository(db)
 
-	rows := sqlmock.NewRows([]string{"id", "code", "name", "description", "created_at", "updated_at"}).
-		AddRow(1, "YRD1", "Yard 1", "Main yard", time, time).
-		AddRow(2, "YRD2", "Yard 2", "Secondary yard", time, time)
+	rows := sqlmock.NewRows([]string{"id", "code", "name", "description", "created_at", "updated_at", "tenant"}).
+		AddRow(1, "YRD1", "Yard 1", "Main yard", time, time, "default").
+		AddRow(2, "YRD2", "Yard 2", "Secondary yard", time, time, "default")
 
-	mock.ExpectQuery("SELECT id, code, name, description, created_at, updated_at FROM yards ORDER BY code").
+	mock.ExpectQuery("SELECT id, code, name, description, created_at, updated_at, tenant FROM yards WHERE (.+) ORDER BY code").
+		WithArgs("default").
 		WillReturnRows(rows)
 
-	yards, err := repo.GetAll()
+	yards, err := repo.GetAll(ctx)
 	assert.NoError(t, err)
 	assert.Len(t, yards, 2)
 	assert.Equal(t, "YRD1", yards[0].Code)
@@ -0,0 +1,344 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/auth"
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+// BatchError is returned by PlaceBatch when any item of a batch can not be
+// placed. Nothing of the batch is written.
+type BatchError struct {
+	Items []BatchItemError
+}
+
+// BatchItemError explains why one item of a batch can not be placed
+type BatchItemError struct {
+	// Index is the position of the item in the batch
+	Index int
+	Err   error
+	// ConflictsWith lists the other items of the batch the item clashes with
+	ConflictsWith []int
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch rolled back: %d of its items cannot be placed", len(e.Items))
+}
+
+// batchItem is a placement of a batch being checked
+type batchItem struct {
+	index     int
+	req       model.PlacementRequest
+	yardID    int
+	block     *model.Block
+	container *model.Container
+	err       error
+	conflicts []int
+}
+
+// fail records the first reason an item can not be placed, and the items of
+// the batch it clashes with
+func (it *batchItem) fail(err error, conflicts ...int) {
+	if it.err == nil {
+		it.err = err
+	}
+	for _, c := range conflicts {
+		if !slices.Contains(it.conflicts, c) {
+			it.conflicts = append(it.conflicts, c)
+		}
+	}
+}
+
+// PlaceBatch places all containers of a batch in one transaction, or none of
+// them. Besides the checks of PlaceContainer, items of the batch must not
+// target the same cell, and an item may stack on another item of the batch.
+// When any item fails the returned error is a *BatchError.
+func (s *ContainerService) PlaceBatch(ctx context.Context, reqs []model.PlacementRequest) error {
+	if s.atomic == nil {
+		return apperror.New(apperror.CodeInternal, "batch placement needs transactions")
+	}
+
+	// Only one instance changes the yards of the batch at a time. Unknown
+	// yards fail their items in the transaction. A caller not allowed to place
+	// in every yard of the batch gets its items rejected without waiting for
+	// any lock, as a rejected batch writes nothing.
+	authorized := true
+	for _, req := range reqs {
+		if !auth.Allowed(ctx, auth.PermPlace, req.Yard) {
+			authorized = false
+			break
+		}
+	}
+	var yardIDs []int
+	if authorized {
+		for _, req := range reqs {
+			if yard, err := s.yardRepo.GetByCode(ctx, req.Yard); err == nil {
+				yardIDs = append(yardIDs, yard.ID)
+			}
+		}
+	}
+	ctx, unlock, err := s.lockYards(ctx, yardIDs...)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return s.atomic(ctx, func(tx repository.Stores) error {
+		if err := checkFences(ctx, tx.Fences); err != nil {
+			return err
+		}
+		return s.inTransaction(tx).placeBatch(ctx, reqs)
+	})
+}
+
+// inTransaction returns a copy of the service working on the stores of a
+// transaction
+func (s *ContainerService) inTransaction(tx repository.Stores) *ContainerService {
+	bound := *s
+	bound.yardRepo = tx.Yards
+	bound.blockRepo = tx.Blocks
+	bound.planRepo = tx.Plans
+	bound.containerRepo = tx.Containers
+	bound.closureRepo = tx.Closures
+	bound.equipmentRepo = tx.Equipment
+	bound.workOrderRepo = tx.WorkOrders
+	bound.atomic = tx.Atomic
+	// The index only catches up once the transaction has committed
+	bound.occupancy = nil
+	return &bound
+}
+
+func (s *ContainerService) placeBatch(ctx context.Context, reqs []model.PlacementRequest) error {
+	items := make([]*batchItem, len(reqs))
+	for i, req := range reqs {
+		items[i] = &batchItem{index: i, req: req}
+		if err := s.resolveBatchItem(ctx, items[i]); err != nil {
+			return err
+		}
+	}
+
+	checkBatchDuplicates(items)
+	checkBatchCells(items)
+
+	// Lower tiers are checked first, so an item knows whether the item it
+	// stacks on can be placed
+	ordered := make([]*batchItem, len(items))
+	copy(ordered, items)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].req.Tier < ordered[j].req.Tier
+	})
+
+	if err := s.checkBatchTargets(ctx, items, ordered); err != nil {
+		return err
+	}
+	if err := batchError(items); err != nil {
+		return err
+	}
+
+	for _, it := range ordered {
+		if err := s.place(ctx, it.container, it.block); err != nil {
+			if fatal(err) {
+				return err
+			}
+			it.fail(err)
+			return batchError(items)
+		}
+	}
+
+	return nil
+}
+
+// resolveBatchItem runs the checks of an item that do not depend on the rest
+// of the batch. Only errors that are not about the item are returned.
+func (s *ContainerService) resolveBatchItem(ctx context.Context, it *batchItem) error {
+	err := s.checkBatchItem(ctx, it)
+	if err != nil && fatal(err) {
+		return err
+	}
+	if err != nil {
+		it.fail(err)
+	}
+	return nil
+}
+
+func (s *ContainerService) checkBatchItem(ctx context.Context, it *batchItem) error {
+	req := it.req
+
+	// Check the role of the caller in the yard
+	if err := auth.Authorize(ctx, auth.PermPlace, req.Yard); err != nil {
+		return err
+	}
+
+	if req.ContainerNumber == "" {
+		return apperror.Required("container_number")
+	}
+
+	yard, err := s.yardRepo.GetByCode(ctx, req.Yard)
+	if err != nil {
+		return err
+	}
+	block, err := s.blockRepo.GetByYardAndCode(ctx, yard.ID, req.Block)
+	if err != nil {
+		return err
+	}
+	if err := s.validatePosition(block, req.Slot, req.Row, req.Tier); err != nil {
+		return err
+	}
+
+	it.yardID = yard.ID
+	it.block = block
+	it.container = newPlacement(req, yard.ID, block)
+
+	if err := s.checkNotInYard(ctx, req.ContainerNumber); err != nil {
+		return err
+	}
+	return s.checkNoPendingWorkOrder(ctx, req.ContainerNumber)
+}
+
+// checkBatchDuplicates fails items placing a container already placed by an
+// earlier item of the batch
+func checkBatchDuplicates(items []*batchItem) {
+	first := make(map[string]int)
+	for _, it := range items {
+		number := it.req.ContainerNumber
+		if number == "" {
+			continue
+		}
+		if j, ok := first[number]; ok {
+			it.fail(apperror.New(apperror.CodeConflict,
+				"container '%s' is already placed by containers[%d] of the batch", number, j), j)
+			continue
+		}
+		first[number] = it.index
+	}
+}
+
+// checkBatchCells fails every pair of items targeting the same cell
+func checkBatchCells(items []*batchItem) {
+	for i, a := range items {
+		for _, b := range items[i+1:] {
+			if !sameCell(a, b, 0) {
+				continue
+			}
+			a.fail(apperror.New(apperror.CodePositionOccupied,
+				"containers[%d] targets the same position", b.index), b.index)
+			b.fail(apperror.New(apperror.CodePositionOccupied,
+				"containers[%d] targets the same position", a.index), a.index)
+		}
+	}
+}
+
+// checkBatchTargets checks the position of every item against the yard, and
+// whether the tier below holds a container or an item of the batch
+func (s *ContainerService) checkBatchTargets(ctx context.Context, items, ordered []*batchItem) error {
+	type yardState struct {
+		closures     []model.BlockClosure
+		reservations []model.WorkOrder
+	}
+	yards := make(map[int]*yardState)
+
+	for _, it := range ordered {
+		if it.err != nil {
+			continue
+		}
+
+		state, ok := yards[it.yardID]
+		if !ok {
+			closures, err := s.closureRepo.GetActiveByYardID(ctx, it.yardID, time.Now())
+			if err != nil {
+				return err
+			}
+			reservations, err := s.reservations(ctx, it.yardID)
+			if err != nil {
+				return err
+			}
+			state = &yardState{closures: closures, reservations: reservations}
+			yards[it.yardID] = state
+		}
+
+		c := it.container
+		err := s.checkCell(ctx, state.closures, state.reservations, it.block, c.Slot, c.Row, c.Tier, c.ContainerSize)
+		if err == nil && c.Tier > 1 {
+			err = s.checkBatchSupport(ctx, items, it, state.reservations)
+		}
+		if err != nil && fatal(err) {
+			return err
+		}
+		if err != nil {
+			it.fail(err)
+		}
+	}
+
+	return nil
+}
+
+// checkBatchSupport accepts an item stacked on the yard or on another item
+// of the batch that can be placed
+func (s *ContainerService) checkBatchSupport(ctx context.Context, items []*batchItem, it *batchItem, reservations []model.WorkOrder) error {
+	c := it.container
+	supported, err := s.supported(ctx, reservations, it.block, c.Slot, c.Row, c.Tier, c.ContainerSize)
+	if err != nil || supported {
+		return err
+	}
+
+	for _, below := range items {
+		if below == it || !sameCell(below, it, 1) {
+			continue
+		}
+		if below.err != nil {
+			it.fail(apperror.New(apperror.CodeConflict,
+				"cannot place container at tier %d: it stacks on containers[%d], which cannot be placed",
+				c.Tier, below.index), below.index)
+			return nil
+		}
+		return nil
+	}
+
+	return errTierBelowEmpty(c.Tier)
+}
+
+// sameCell reports whether item b targets the cell the given number of tiers
+// above the cell of item a
+func sameCell(a, b *batchItem, tiersAbove int) bool {
+	if a.container == nil || b.container == nil || a.block.ID != b.block.ID {
+		return false
+	}
+	ca, cb := a.container, b.container
+	return ca.Row == cb.Row && ca.Tier+tiersAbove == cb.Tier &&
+		spansOverlap(ca.Slot, ca.ContainerSize, cb.Slot, cb.ContainerSize)
+}
+
+// batchError collects the failed items of a batch, in batch order
+func batchError(items []*batchItem) error {
+	var failed []BatchItemError
+	for _, it := range items {
+		if it.err == nil {
+			continue
+		}
+		conflicts := slices.Clone(it.conflicts)
+		slices.Sort(conflicts)
+		failed = append(failed, BatchItemError{Index: it.index, Err: it.err, ConflictsWith: conflicts})
+	}
+	if failed == nil {
+		return nil
+	}
+	return &BatchError{Items: failed}
+}
+
+// fatal reports whether an error is not caused by the item being checked,
+// in which case the whole batch fails with it
+func fatal(err error) bool {
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) {
+		return true
+	}
+	return appErr.Code == apperror.CodeInternal || appErr.Code == apperror.CodeTimeout
+}
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/occupancy"
+)
+
+// improvePasses bounds the rounds of swaps SuggestBatch makes to shorten the
+// total travel of a batch
+const improvePasses = 10
+
+// BatchSuggestion is the suggestion for one container of a batch, or the
+// reason none could be made
+type BatchSuggestion struct {
+	Suggestion *model.Suggestion
+	Err        error
+}
+
+// SuggestBatch suggests positions for all containers of a batch together.
+// Every container gets its own cell, and a container may be stacked on the
+// cell suggested to another container of the batch. Work the batch adds to a
+// machine counts towards its queue for the containers planned after it.
+//
+// Containers are planned one by one, 40ft before 20ft as they need two free
+// slots side by side, and then containers with the same specs trade cells
+// while that lowers the summed distance to their gates and berths. The result
+// only depends on the batch and the state of the yard. Errors about a single
+// container are returned in its BatchSuggestion.
+func (s *ContainerService) SuggestBatch(ctx context.Context, reqs []model.SuggestionRequest) ([]BatchSuggestion, error) {
+	p := &batchPlanner{
+		service:  s,
+		yards:    make(map[int]*plannedYard),
+		plans:    make(map[planKey]*model.YardPlan),
+		targets:  make(map[targetKey][]model.Point),
+		assigned: make([]*assignment, len(reqs)),
+	}
+
+	order := make([]int, len(reqs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return reqs[order[a]].ContainerSize > reqs[order[b]].ContainerSize
+	})
+
+	results := make([]BatchSuggestion, len(reqs))
+	for _, i := range order {
+		// Stop planning once the request is cancelled
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		suggestion, err := s.suggest(ctx, reqs[i], func(yard *model.Yard, req model.SuggestionRequest) (*model.Position, error) {
+			return p.assign(ctx, i, yard, req)
+		})
+		if err != nil && fatal(err) {
+			return nil, err
+		}
+		results[i] = BatchSuggestion{Suggestion: suggestion, Err: err}
+	}
+
+	p.improve(reqs, results)
+	return results, nil
+}
+
+// batchPlanner holds the state of a yard as a batch is planned into it
+type batchPlanner struct {
+	service  *ContainerService
+	yards    map[int]*plannedYard
+	plans    map[planKey]*model.YardPlan
+	targets  map[targetKey][]model.Point
+	assigned []*assignment
+}
+
+// plannedYard is a yard with the cells and work taken by the batch so far
+type plannedYard struct {
+	blocks    []model.Block
+	closures  []model.BlockClosure
+	equipment []model.Equipment
+	grids     map[int]*occupancy.Grid
+}
+
+type planKey struct {
+	blockID       int
+	size          int
+	height        float64
+	containerType string
+}
+
+type targetKey struct {
+	yardID   int
+	movement string
+	berth    string
+}
+
+// assignment is the cell planned for a container of the batch
+type assignment struct {
+	yardID  int
+	block   *model.Block
+	size    int
+	targets []model.Point
+}
+
+// assign picks the best free cell of a yard for a container and takes it
+func (p *batchPlanner) assign(ctx context.Context, i int, yard *model.Yard, req model.SuggestionRequest) (*model.Position, error) {
+	s := p.service
+	y, err := p.yard(ctx, yard)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := p.targetPoints(ctx, yard.ID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *candidate
+	var bestBlock *model.Block
+	for b := range y.blocks {
+		block := &y.blocks[b]
+		plan, err := p.plan(ctx, block.ID, req)
+		if err != nil || plan == nil {
+			continue // Try next block
+		}
+
+		responsible := responsibleEquipment(y.equipment, block.ID)
+		for _, position := range freePositions(y.grids[block.ID], *block, *plan, y.closures) {
+			c := candidate{
+				position:  position,
+				equipment: responsible,
+			}
+			if len(targets) > 0 {
+				center := block.CellCenter(position.Slot, position.Row, req.ContainerSize)
+				c.distance = nearestDistance(center, targets)
+			}
+			c.score = s.weights.scoreCandidate(c)
+			if best == nil || c.score < best.score {
+				best = &c
+				bestBlock = block
+			}
+		}
+	}
+
+	if best == nil {
+		return nil, errNoCapacity
+	}
+
+	// The cell is taken and the machine has one more job for the rest of the batch
+	y.grids[bestBlock.ID].Occupy(best.position.Slot, best.position.Row, best.position.Tier, req.ContainerSize)
+	if best.equipment != nil {
+		best.equipment.OutstandingOrders++
+	}
+	p.assigned[i] = &assignment{yardID: yard.ID, block: bestBlock, size: req.ContainerSize, targets: targets}
+
+	return &best.position, nil
+}
+
+// yard loads the blocks, closures, machines and occupied cells of a yard the
+// first time the batch needs it
+func (p *batchPlanner) yard(ctx context.Context, yard *model.Yard) (*plannedYard, error) {
+	if y, ok := p.yards[yard.ID]; ok {
+		return y, nil
+	}
+
+	s := p.service
+	blocks, err := s.blockRepo.GetByYardID(ctx, yard.ID)
+	if err != nil {
+		return nil, err
+	}
+	closures, err := s.closureRepo.GetActiveByYardID(ctx, yard.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	reservations, err := s.reservations(ctx, yard.ID)
+	if err != nil {
+		return nil, err
+	}
+	equipment, err := s.equipmentRepo.GetByYardID(ctx, yard.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	y := &plannedYard{
+		blocks:    blocks,
+		closures:  closures,
+		equipment: slices.Clone(equipment),
+		grids:     make(map[int]*occupancy.Grid, len(blocks)),
+	}
+	for _, block := range blocks {
+		// Plans of different container sizes can share a block, so the
+		// whole block is loaded
+		whole := model.YardPlan{SlotStart: 1, SlotEnd: block.MaxSlot, RowStart: 1, RowEnd: block.MaxRow}
+		grid, err := s.occupiedCells(ctx, block, whole)
+		if err != nil {
+			return nil, err
+		}
+		reserveCells(grid, block.ID, reservations)
+		y.grids[block.ID] = grid
+	}
+
+	p.yards[yard.ID] = y
+	return y, nil
+}
+
+// plan returns the yard plan of a block matching a container, or nil
+func (p *batchPlanner) plan(ctx context.Context, blockID int, req model.SuggestionRequest) (*model.YardPlan, error) {
+	key := planKey{blockID: blockID, size: req.ContainerSize, height: req.ContainerHeight, containerType: req.ContainerType}
+	if plan, ok := p.plans[key]; ok {
+		return plan, nil
+	}
+
+	plan, err := p.service.planRepo.FindMatchingPlan(ctx, blockID, req.ContainerSize, req.ContainerHeight, req.ContainerType)
+	if err != nil {
+		if fatal(err) {
+			return nil, err
+		}
+		plan = nil
+	}
+	p.plans[key] = plan
+	return plan, nil
+}
+
+// targetPoints returns the gates or berths a container is headed to
+func (p *batchPlanner) targetPoints(ctx context.Context, yardID int, req model.SuggestionRequest) ([]model.Point, error) {
+	key := targetKey{yardID: yardID, movement: req.Movement, berth: req.Berth}
+	if targets, ok := p.targets[key]; ok {
+		return targets, nil
+	}
+
+	targets, err := p.service.targetPoints(ctx, yardID, req)
+	if err != nil {
+		return nil, err
+	}
+	p.targets[key] = targets
+	return targets, nil
+}
+
+// improve swaps the cells of two containers with the same specs in the same
+// yard whenever that lowers their summed distance score. Both cells stay
+// valid, as the same plans accept both containers and the set of taken cells
+// and the work per machine do not change.
+func (p *batchPlanner) improve(reqs []model.SuggestionRequest, results []BatchSuggestion) {
+	for pass := 0; pass < improvePasses; pass++ {
+		swapped := false
+		for i := range reqs {
+			for j := i + 1; j < len(reqs); j++ {
+				a, b := p.assigned[i], p.assigned[j]
+				if a == nil || b == nil || a.yardID != b.yardID || !sameSpec(reqs[i], reqs[j]) {
+					continue
+				}
+				if len(a.targets) == 0 && len(b.targets) == 0 {
+					continue
+				}
+
+				posA, posB := &results[i].Suggestion.Position, &results[j].Suggestion.Position
+				before := p.distance(a, a.block, *posA) + p.distance(b, b.block, *posB)
+				after := p.distance(a, b.block, *posB) + p.distance(b, a.block, *posA)
+				if after >= before {
+					continue
+				}
+
+				*posA, *posB = *posB, *posA
+				a.block, b.block = b.block, a.block
+				swapped = true
+			}
+		}
+		if !swapped {
+			return
+		}
+	}
+}
+
+// distance returns the distance score of a container put at a position
+func (p *batchPlanner) distance(a *assignment, block *model.Block, position model.Position) float64 {
+	if len(a.targets) == 0 {
+		return 0
+	}
+	center := block.CellCenter(position.Slot, position.Row, a.size)
+	return p.service.weights.DistanceWeight * nearestDistance(center, a.targets)
+}
+
+// sameSpec reports whether two containers fit the same yard plans
+func sameSpec(a, b model.SuggestionRequest) bool {
+	return a.ContainerSize == b.ContainerSize && a.ContainerHeight == b.ContainerHeight && a.ContainerType == b.ContainerType
+}
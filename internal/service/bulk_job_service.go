@@ -0,0 +1,508 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/auth"
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/dwipurnomo515/yard-planning/pkg/cache"
+	"github.com/dwipurnomo515/yard-planning/pkg/worker"
+)
+
+const (
+	// bulkJobLockTTL is the lease of the lock of a running job; it is renewed
+	// while the job runs
+	bulkJobLockTTL = 30 * time.Second
+	// bulkJobQueueSize bounds the jobs waiting for a worker. Jobs that do not
+	// fit stay QUEUED and are picked up by a later Resume.
+	bulkJobQueueSize = 100
+)
+
+// jobPermissions is the permission a bulk job needs in the yard of each item
+var jobPermissions = map[string]auth.Permission{
+	model.BulkJobSuggestion: auth.PermSuggest,
+	model.BulkJobPlacement:  auth.PermPlace,
+	model.BulkJobMove:       auth.PermMove,
+}
+
+// BulkJobService runs bulk suggestions, placements and moves in the
+// background on a worker pool, oldest job first. The result of a placement or
+// move is stored in the transaction that changes the yard, so a job cut short
+// by a restart resumes with the items that have no result yet. The results of
+// a suggestion job are stored together once the batch is planned; a
+// suggestion job cut short before is planned again from scratch.
+type BulkJobService struct {
+	jobs       repository.BulkJobStore
+	roles      repository.RoleBindingStore
+	containers ContainerOperations
+	pool       *worker.Pool
+	queue      chan model.BulkJob
+	locker     cache.Locker
+	atomic     repository.Transactor
+
+	mu      sync.Mutex
+	running map[int]context.CancelFunc
+	// claimed holds the jobs queued or running on this instance
+	claimed map[int]bool
+}
+
+func NewBulkJobService(
+	jobs repository.BulkJobStore,
+	roles repository.RoleBindingStore,
+	containers ContainerOperations,
+) *BulkJobService {
+	return &BulkJobService{
+		jobs:       jobs,
+		roles:      roles,
+		containers: containers,
+		queue:      make(chan model.BulkJob, bulkJobQueueSize),
+		running:    make(map[int]context.CancelFunc),
+		claimed:    make(map[int]bool),
+	}
+}
+
+// SetLocker makes an instance claim a job with a cluster-wide lock before it
+// runs it, so two instances never run the same job. The progress of a job is
+// written in transactions that check the fencing token of its lock, so an
+// instance whose lock expired can not record results anymore.
+func (s *BulkJobService) SetLocker(locker cache.Locker, atomic repository.Transactor) {
+	s.locker = locker
+	s.atomic = atomic
+}
+
+// Start starts the workers. Jobs stop when ctx is done and are resumed by
+// the next Resume. The returned channel reports every job a worker ran and
+// must be drained.
+func (s *BulkJobService) Start(ctx context.Context, workers int) <-chan worker.Result {
+	s.pool = worker.NewPool(ctx, workers, s.run)
+	s.pool.Start()
+
+	// Jobs are handed to the workers one by one in the order they were queued
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case job := <-s.queue:
+				s.pool.Submit(worker.Job{ID: strconv.Itoa(job.ID), Payload: job})
+			}
+		}
+	}()
+
+	return s.pool.Results()
+}
+
+// Resume queues the unfinished jobs of every tenant that are not queued or
+// running on this instance, e.g. after a restart. Jobs another instance runs
+// are skipped when their turn comes, so Resume is called periodically to take
+// over the jobs of instances that stopped. It returns the number of jobs queued.
+func (s *BulkJobService) Resume(ctx context.Context) (int, error) {
+	jobs, err := s.jobs.GetUnfinished(tenant.WithAllTenants(ctx))
+	if err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for _, job := range jobs {
+		if s.enqueue(job) {
+			queued++
+		}
+	}
+	return queued, nil
+}
+
+// Create stores a bulk job and queues it. The items run later with the
+// permissions the caller has at that time.
+func (s *BulkJobService) Create(ctx context.Context, req model.BulkJobRequest) (*model.BulkJob, error) {
+	// Check the role of the caller in the yard of every item
+	if err := authorizeJob(ctx, req); err != nil {
+		return nil, err
+	}
+
+	request, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &model.BulkJob{Kind: req.Kind, Request: request, Total: req.Size()}
+	if _, ok := auth.FromContext(ctx); ok {
+		job.Subject = auth.Actor(ctx)
+	}
+	if err := s.jobs.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	s.enqueue(*job)
+	return job, nil
+}
+
+// Get returns a job with the results of its processed items
+func (s *BulkJobService) Get(ctx context.Context, id int) (*model.BulkJob, error) {
+	job, err := s.job(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.jobs.GetResults(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	job.Results = results
+	if job.Results == nil {
+		job.Results = []model.BulkJobResult{}
+	}
+
+	return job, nil
+}
+
+// Cancel stops a queued or running job. Items processed so far keep their
+// results, and placed or moved containers stay where they are.
+func (s *BulkJobService) Cancel(ctx context.Context, id int) (*model.BulkJob, error) {
+	job, err := s.job(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	cancelled, err := s.jobs.SetStatus(ctx, id, model.BulkJobCancelled, "cancelled by "+auth.Actor(ctx),
+		model.BulkJobQueued, model.BulkJobRunning)
+	if err != nil {
+		return nil, err
+	}
+	if !cancelled {
+		current, err := s.jobs.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, apperror.New(apperror.CodeConflict, "bulk job %d is already %s", id, current.Status)
+	}
+
+	s.mu.Lock()
+	if stop, ok := s.running[id]; ok {
+		stop()
+	}
+	s.mu.Unlock()
+
+	return s.Get(ctx, job.ID)
+}
+
+// job returns a job the caller may see: one of the caller's tenant whose
+// items are all in yards the caller has the job's permission in
+func (s *BulkJobService) job(ctx context.Context, id int) (*model.BulkJob, error) {
+	job, err := s.jobs.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var req model.BulkJobRequest
+	if err := json.Unmarshal(job.Request, &req); err != nil {
+		return nil, err
+	}
+	if err := authorizeJob(ctx, req); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// enqueue queues a job that is not queued or running on this instance yet,
+// without waiting. It reports whether the job was queued; a job that does not
+// fit in the queue is left for the next Resume.
+func (s *BulkJobService) enqueue(job model.BulkJob) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.claimed[job.ID] {
+		return false
+	}
+	select {
+	case s.queue <- job:
+		s.claimed[job.ID] = true
+		return true
+	default:
+		return false
+	}
+}
+
+// run processes the items of a job that have no result yet
+func (s *BulkJobService) run(ctx context.Context, wj worker.Job) (interface{}, error) {
+	job := wj.Payload.(model.BulkJob)
+	ctx = tenant.WithTenant(ctx, job.Tenant)
+	defer func() {
+		s.mu.Lock()
+		delete(s.claimed, job.ID)
+		s.mu.Unlock()
+	}()
+
+	// A job another instance runs is skipped
+	ctx, release, err := s.claim(ctx, job.ID)
+	if errors.Is(err, cache.ErrLockHeld) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// A job cancelled while it was queued is skipped
+	var started bool
+	err = s.write(ctx, func(jobs repository.BulkJobStore) (err error) {
+		started, err = jobs.SetStatus(ctx, job.ID, model.BulkJobRunning, "", model.BulkJobQueued, model.BulkJobRunning)
+		return err
+	})
+	if err != nil || !started {
+		return nil, err
+	}
+
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	s.mu.Lock()
+	s.running[job.ID] = stop
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, job.ID)
+		s.mu.Unlock()
+	}()
+
+	err = s.process(ctx, job)
+	if ctx.Err() != nil {
+		// Cancelled jobs already have their status. Jobs stopped by a
+		// shutdown stay RUNNING and are resumed.
+		return nil, ctx.Err()
+	}
+	// The final status is stored even when the job is stopped meanwhile
+	ctx = context.WithoutCancel(ctx)
+	if err != nil {
+		// Keep the original error; the job is rerun from its results if
+		// the status can not be stored
+		message := err.Error()
+		statusErr := s.write(ctx, func(jobs repository.BulkJobStore) error {
+			_, err := jobs.SetStatus(ctx, job.ID, model.BulkJobFailed, message, model.BulkJobRunning)
+			return err
+		})
+		if statusErr != nil {
+			return nil, fmt.Errorf("%w (error storing the failed status: %v)", err, statusErr)
+		}
+		return nil, err
+	}
+
+	err = s.write(ctx, func(jobs repository.BulkJobStore) error {
+		_, err := jobs.SetStatus(ctx, job.ID, model.BulkJobCompleted, "", model.BulkJobRunning)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job.ID, nil
+}
+
+// claim takes the lock of a job without waiting, or returns
+// cache.ErrLockHeld when another instance runs the job. Work done for the job
+// must use the returned context; release gives the lock up.
+func (s *BulkJobService) claim(ctx context.Context, id int) (context.Context, func(), error) {
+	if s.locker == nil {
+		return ctx, func() {}, nil
+	}
+
+	lock, err := s.locker.Acquire(ctx, "bulk-job:"+strconv.Itoa(id), bulkJobLockTTL)
+	if err != nil {
+		return ctx, nil, err
+	}
+
+	ctx, release := hold(ctx, lock, bulkJobLockTTL)
+	return ctx, release, nil
+}
+
+// write runs fn in a transaction that fails when the lock of the job was
+// taken over
+func (s *BulkJobService) write(ctx context.Context, fn func(jobs repository.BulkJobStore) error) error {
+	if s.atomic == nil {
+		return fn(s.jobs)
+	}
+	return s.atomic(ctx, func(tx repository.Stores) error {
+		if err := checkFences(ctx, tx.Fences); err != nil {
+			return err
+		}
+		return fn(tx.BulkJobs)
+	})
+}
+
+// process runs the items of a job with the current permissions of the
+// caller that created it
+func (s *BulkJobService) process(ctx context.Context, job model.BulkJob) error {
+	if job.Subject != "" {
+		bindings, err := s.roles.GetBySubject(ctx, job.Subject)
+		if err != nil {
+			return err
+		}
+		ctx = auth.WithGrants(ctx, auth.NewGrants(job.Subject, bindings))
+	}
+
+	var req model.BulkJobRequest
+	if err := json.Unmarshal(job.Request, &req); err != nil {
+		return err
+	}
+
+	results, err := s.jobs.GetResults(ctx, job.ID)
+	if err != nil {
+		return err
+	}
+	done := make(map[int]bool, len(results))
+	for _, r := range results {
+		done[r.Index] = true
+	}
+
+	// result completes the result of an item, with its target only on success
+	result := func(result model.BulkJobResult, err error) model.BulkJobResult {
+		result.Success = err == nil
+		if err != nil {
+			result.Yard, result.Position, result.Overflow = "", nil, false
+			result.Error = err.Error()
+			result.Code = string(apperror.CodeOf(err))
+		}
+		return result
+	}
+
+	// change runs the yard change of an item and stores its result. A
+	// successful change stores the result in its own transaction, so a job
+	// cut short never runs a change again that already happened.
+	change := func(item model.BulkJobResult, fn func(ctx context.Context) error) error {
+		recorded := false
+		changeErr := fn(withWrittenHook(ctx, func(tx repository.Stores) error {
+			recorded = true
+			return tx.BulkJobs.AddResult(ctx, job.ID, result(item, nil))
+		}))
+		if changeErr != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if changeErr == nil && recorded {
+			return nil
+		}
+		err := s.write(ctx, func(jobs repository.BulkJobStore) error {
+			return jobs.AddResult(ctx, job.ID, result(item, changeErr))
+		})
+		// A result stored before is kept
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil
+		}
+		return err
+	}
+
+	switch job.Kind {
+	case model.BulkJobSuggestion:
+		// Suggestions change nothing, so the whole batch is planned again
+		// when the job was cut short before its results were stored
+		if len(results) == len(req.Suggestions) {
+			return nil
+		}
+		planned, err := s.containers.SuggestBatch(ctx, req.Suggestions)
+		if err != nil {
+			return err
+		}
+		// The results of the batch are stored together, as its items only
+		// get distinct cells when planned together
+		return s.write(ctx, func(jobs repository.BulkJobStore) error {
+			for i, p := range planned {
+				if done[i] {
+					continue
+				}
+				item := model.BulkJobResult{Index: i, ContainerNumber: req.Suggestions[i].ContainerNumber}
+				if p.Err == nil {
+					item.Yard, item.Position, item.Overflow = p.Suggestion.Yard, &p.Suggestion.Position, p.Suggestion.Overflow
+				}
+				if err := jobs.AddResult(ctx, job.ID, result(item, p.Err)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	case model.BulkJobPlacement:
+		// Containers stacked on each other are placed bottom up
+		for _, stack := range PlacementStacks(req.Placements) {
+			for _, i := range stack {
+				if done[i] {
+					continue
+				}
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				c := req.Placements[i]
+				err := change(placedResult(i, c.ContainerNumber, c.Yard, c.Block, c.Slot, c.Row, c.Tier), func(ctx context.Context) error {
+					return s.containers.PlaceContainer(ctx, c)
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+	case model.BulkJobMove:
+		for i, c := range req.Moves {
+			if done[i] {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			err := change(placedResult(i, c.ContainerNumber, c.Yard, c.Block, c.Slot, c.Row, c.Tier), func(ctx context.Context) error {
+				return s.containers.MoveContainer(ctx, c)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+	default:
+		return apperror.New(apperror.CodeInternal, "unknown bulk job kind %s", job.Kind)
+	}
+
+	return nil
+}
+
+// placedResult is the result of an item that puts a container at a position
+func placedResult(i int, number, yard, block string, slot, row, tier int) model.BulkJobResult {
+	return model.BulkJobResult{
+		Index:           i,
+		ContainerNumber: number,
+		Yard:            yard,
+		Position:        &model.Position{Block: block, Slot: slot, Row: row, Tier: tier},
+	}
+}
+
+// authorizeJob checks the caller has the permission of the job's kind in the
+// yard of every item
+func authorizeJob(ctx context.Context, req model.BulkJobRequest) error {
+	perm := jobPermissions[req.Kind]
+	var yards []string
+	for _, c := range req.Suggestions {
+		yards = append(yards, c.Yard)
+	}
+	for _, c := range req.Placements {
+		yards = append(yards, c.Yard)
+	}
+	for _, c := range req.Moves {
+		yards = append(yards, c.Yard)
+	}
+
+	checked := make(map[string]bool)
+	for _, yard := range yards {
+		if checked[yard] {
+			continue
+		}
+		checked[yard] = true
+		if err := auth.Authorize(ctx, perm, yard); err != nil {
+			return err
+		}
+	}
+	return nil
+}
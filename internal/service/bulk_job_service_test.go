@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Resume queues every job once, and no more jobs than the queue holds
+func TestBulkJobService_ResumeQueuesOnce(t *testing.T) {
+	ctx := context.Background()
+	containers, stores := newMemoryService(t)
+	jobs := NewBulkJobService(stores.BulkJobs, stores.Roles, containers)
+
+	for i := 0; i < bulkJobQueueSize+1; i++ {
+		require.NoError(t, stores.BulkJobs.Create(ctx, &model.BulkJob{Kind: model.BulkJobMove, Request: []byte(`{}`)}))
+	}
+
+	// No worker takes jobs from the queue
+	resumed, err := jobs.Resume(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, bulkJobQueueSize, resumed)
+	resumed, err = jobs.Resume(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, resumed, "queued jobs were queued again")
+
+	// Jobs are queued oldest first; the newest is left for a later Resume
+	for i := 1; i <= bulkJobQueueSize; i++ {
+		assert.Equal(t, i, (<-jobs.queue).ID)
+	}
+	assert.False(t, jobs.claimed[bulkJobQueueSize+1])
+}
+
+// failingResults is a bulk job store whose results can not be stored
+type failingResults struct {
+	repository.BulkJobStore
+}
+
+func (failingResults) AddResult(ctx context.Context, id int, result model.BulkJobResult) error {
+	return errors.New("result insert failed")
+}
+
+// A placement whose result can not be stored is rolled back, so a resumed job
+// never finds a container it placed without a result
+func TestBulkJobService_ResultCommitsWithPlacement(t *testing.T) {
+	ctx := context.Background()
+	containers, stores := newMemoryService(t)
+	containers.SetTransactions(func(ctx context.Context, fn func(tx repository.Stores) error) error {
+		return stores.Atomic(ctx, func(tx repository.Stores) error {
+			tx.BulkJobs = failingResults{tx.BulkJobs}
+			return fn(tx)
+		})
+	})
+	jobs := NewBulkJobService(stores.BulkJobs, stores.Roles, containers)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := jobs.Start(runCtx, 1)
+
+	req := model.BulkJobRequest{Kind: model.BulkJobPlacement, Placements: []model.PlacementRequest{
+		{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 1, Row: 1, Tier: 1},
+	}}
+	request, err := json.Marshal(req)
+	require.NoError(t, err)
+	job := &model.BulkJob{Kind: req.Kind, Request: request, Total: req.Size()}
+	require.NoError(t, stores.BulkJobs.Create(ctx, job))
+
+	_, err = jobs.Resume(ctx)
+	require.NoError(t, err)
+	waitForResult(t, results)
+
+	_, err = stores.Containers.GetByNumber(ctx, "ABCU1234560")
+	assert.Error(t, err, "placement without result was kept")
+	found, err := stores.BulkJobs.GetByID(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.BulkJobCompleted, found.Status)
+	assert.Equal(t, 1, found.Failed)
+}
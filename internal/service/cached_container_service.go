@@ -1,25 +1,34 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"time"
 
+	"github.com/dwipurnomo515/yard-planning/internal/auth"
 	"github.com/dwipurnomo515/yard-planning/internal/model"
 	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/tenant"
 	"github.com/dwipurnomo515/yard-planning/pkg/cache"
 )
 
+// CachedContainerService caches suggestions and invalidates them when a yard changes
 type CachedContainerService struct {
 	ContainerService
-	cache *cache.RedisClient
+	cache cache.Cache
 }
 
+var _ ContainerOperations = (*CachedContainerService)(nil)
+
 func NewCachedContainerService(
-	yardRepo *repository.YardRepository,
-	blockRepo *repository.BlockRepository,
-	planRepo *repository.YardPlanRepository,
-	containerRepo *repository.ContainerRepository,
-	redisClient *cache.RedisClient,
+	yardRepo repository.YardStore,
+	blockRepo repository.BlockStore,
+	planRepo repository.YardPlanStore,
+	containerRepo repository.ContainerStore,
+	closureRepo repository.ClosureStore,
+	equipmentRepo repository.EquipmentStore,
+	workOrderRepo repository.WorkOrderStore,
+	suggestionCache cache.Cache,
 ) *CachedContainerService {
 	return &CachedContainerService{
 		ContainerService: ContainerService{
@@ -27,70 +36,135 @@ func NewCachedContainerService(
 			blockRepo:     blockRepo,
 			planRepo:      planRepo,
 			containerRepo: containerRepo,
+			closureRepo:   closureRepo,
+			equipmentRepo: equipmentRepo,
+			workOrderRepo: workOrderRepo,
+			weights:       DefaultSuggestionWeights(),
 		},
-		cache: redisClient,
+		cache: suggestionCache,
 	}
 }
 
 // GetSuggestion with caching
-func (s *CachedContainerService) GetSuggestion(req model.SuggestionRequest) (*model.Position, error) {
+func (s *CachedContainerService) GetSuggestion(ctx context.Context, req model.SuggestionRequest) (*model.Suggestion, error) {
+	// Cached suggestions are only served to callers allowed to ask for them
+	if err := auth.Authorize(ctx, auth.PermSuggest, req.Yard); err != nil {
+		return nil, err
+	}
+
 	// Validate input
 	if err := s.validateContainerSpec(req.ContainerSize, req.ContainerHeight, req.ContainerType); err != nil {
 		return nil, err
 	}
 
 	// Try to get from cache
-	cacheKey := fmt.Sprintf("suggestion:%s:%d:%.1f:%s",
-		req.Yard, req.ContainerSize, req.ContainerHeight, req.ContainerType)
+	cacheKey := fmt.Sprintf("suggestion:%s:%s:%d:%.1f:%s:%s:%s",
+		tenant.FromContext(ctx), req.Yard, req.ContainerSize, req.ContainerHeight, req.ContainerType, req.Movement, req.Berth)
 
-	var cachedPosition model.Position
-	if err := s.cache.Get(cacheKey, &cachedPosition); err == nil {
+	var cachedSuggestion model.Suggestion
+	err := s.cache.Get(ctx, cacheKey, &cachedSuggestion)
+	if err == nil && auth.Allowed(ctx, auth.PermSuggest, cachedSuggestion.Yard) {
 		// Verify position is still available
-		yard, _ := s.yardRepo.GetByCode(req.Yard)
-		if yard != nil {
-			block, _ := s.blockRepo.GetByYardAndCode(yard.ID, cachedPosition.Block)
-			if block != nil {
-				occupied, _ := s.containerRepo.IsPositionOccupied(
-					block.ID,
-					cachedPosition.Slot,
-					cachedPosition.Row,
-					cachedPosition.Tier,
-					req.ContainerSize,
-				)
-				if !occupied {
-					return &cachedPosition, nil
-				}
-			}
+		if s.stillSuggested(ctx, &cachedSuggestion, req) {
+			return &cachedSuggestion, nil
 		}
 		// Cache invalid, delete it
-		s.cache.Delete(cacheKey)
+		s.cache.Delete(ctx, cacheKey)
 	}
 
 	// Get fresh suggestion
-	position, err := s.ContainerService.GetSuggestion(req)
+	suggestion, err := s.ContainerService.GetSuggestion(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
 	// Cache the result for 5 minutes
-	s.cache.Set(cacheKey, position, 5*time.Minute)
+	s.cache.Set(ctx, cacheKey, suggestion, 5*time.Minute)
 
-	return position, nil
+	return suggestion, nil
+}
+
+// stillSuggested reports whether a cached suggestion would still be made.
+// Closures, reservations, plans and block sizes change without going through
+// this service, so the position is checked like a placement: it must be in a
+// plan for the container, open, free and supported.
+func (s *CachedContainerService) stillSuggested(ctx context.Context, suggestion *model.Suggestion, req model.SuggestionRequest) bool {
+	position := suggestion.Position
+
+	yard, err := s.yardRepo.GetByCode(ctx, suggestion.Yard)
+	if err != nil {
+		return false
+	}
+	block, err := s.blockRepo.GetByYardAndCode(ctx, yard.ID, position.Block)
+	if err != nil {
+		return false
+	}
+	if err := s.validatePosition(block, position.Slot, position.Row, position.Tier); err != nil {
+		return false
+	}
+
+	plan, err := s.planRepo.FindMatchingPlan(ctx, block.ID, req.ContainerSize, req.ContainerHeight, req.ContainerType)
+	if err != nil || !planCovers(plan, position.Slot, position.Row) {
+		return false
+	}
+
+	return s.checkTarget(ctx, yard.ID, block, position.Slot, position.Row, position.Tier, req.ContainerSize) == nil
+}
+
+// planCovers reports whether a container of the plan's size can start at a cell
+func planCovers(plan *model.YardPlan, slot, row int) bool {
+	end := slot + plan.ContainerSize/20 - 1
+	return slot >= plan.SlotStart && end <= plan.SlotEnd && row >= plan.RowStart && row <= plan.RowEnd
 }
 
 // PlaceContainer with cache invalidation
-func (s *CachedContainerService) PlaceContainer(req model.PlacementRequest) error {
-	err := s.ContainerService.PlaceContainer(req)
+func (s *CachedContainerService) PlaceContainer(ctx context.Context, req model.PlacementRequest) error {
+	err := s.ContainerService.PlaceContainer(ctx, req)
 	if err != nil {
 		return err
 	}
 
+	// The write is committed, so the caches are updated even if the request
+	// was cancelled in the meantime
+	ctx = context.WithoutCancel(ctx)
+
 	// Invalidate related caches
-	pattern := fmt.Sprintf("suggestion:%s:*", req.Yard)
-	s.cache.DeletePattern(pattern)
+	pattern := fmt.Sprintf("suggestion:%s:%s:*", tenant.FromContext(ctx), req.Yard)
+	s.cache.DeletePattern(ctx, pattern)
+
+	s.cachePosition(ctx, req)
+
+	return nil
+}
+
+// PlaceBatch with cache invalidation
+func (s *CachedContainerService) PlaceBatch(ctx context.Context, reqs []model.PlacementRequest) error {
+	err := s.ContainerService.PlaceBatch(ctx, reqs)
+	if err != nil {
+		return err
+	}
+
+	// The batch is committed, so the caches are updated even if the request
+	// was cancelled in the meantime
+	ctx = context.WithoutCancel(ctx)
+
+	// Invalidate the suggestions of every yard in the batch once
+	invalidated := make(map[string]bool)
+	for _, req := range reqs {
+		if !invalidated[req.Yard] {
+			pattern := fmt.Sprintf("suggestion:%s:%s:*", tenant.FromContext(ctx), req.Yard)
+			s.cache.DeletePattern(ctx, pattern)
+			invalidated[req.Yard] = true
+		}
+		s.cachePosition(ctx, req)
+	}
+
+	return nil
+}
 
-	// Cache the container position
-	cacheKey := fmt.Sprintf("container:%s", req.ContainerNumber)
+// cachePosition caches the position of a placed container
+func (s *CachedContainerService) cachePosition(ctx context.Context, req model.PlacementRequest) {
+	cacheKey := fmt.Sprintf("container:%s:%s", tenant.FromContext(ctx), req.ContainerNumber)
 	containerInfo := map[string]interface{}{
 		"yard":  req.Yard,
 		"block": req.Block,
@@ -98,25 +172,43 @@ func (s *CachedContainerService) PlaceContainer(req model.PlacementRequest) erro
 		"row":   req.Row,
 		"tier":  req.Tier,
 	}
-	s.cache.Set(cacheKey, containerInfo, 24*time.Hour)
+	s.cache.Set(ctx, cacheKey, containerInfo, 24*time.Hour)
+}
+
+// PickupContainer with cache invalidation
+func (s *CachedContainerService) PickupContainer(ctx context.Context, req model.PickupRequest) error {
+	err := s.ContainerService.PickupContainer(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	// Invalidate caches even if the request was cancelled after the write
+	ctx = context.WithoutCancel(ctx)
+	pattern := fmt.Sprintf("suggestion:%s:%s:*", tenant.FromContext(ctx), req.Yard)
+	s.cache.DeletePattern(ctx, pattern)
+
+	// Remove container cache
+	cacheKey := fmt.Sprintf("container:%s:%s", tenant.FromContext(ctx), req.ContainerNumber)
+	s.cache.Delete(ctx, cacheKey)
 
 	return nil
 }
 
-// PickupContainer with cache invalidation
-func (s *CachedContainerService) PickupContainer(req model.PickupRequest) error {
-	err := s.ContainerService.PickupContainer(req)
+// MoveContainer with cache invalidation
+func (s *CachedContainerService) MoveContainer(ctx context.Context, req model.MoveRequest) error {
+	err := s.ContainerService.MoveContainer(ctx, req)
 	if err != nil {
 		return err
 	}
 
-	// Invalidate caches
-	pattern := fmt.Sprintf("suggestion:%s:*", req.Yard)
-	s.cache.DeletePattern(pattern)
+	// Invalidate caches even if the request was cancelled after the write
+	ctx = context.WithoutCancel(ctx)
+	pattern := fmt.Sprintf("suggestion:%s:%s:*", tenant.FromContext(ctx), req.Yard)
+	s.cache.DeletePattern(ctx, pattern)
 
 	// Remove container cache
-	cacheKey := fmt.Sprintf("container:%s", req.ContainerNumber)
-	s.cache.Delete(cacheKey)
+	cacheKey := fmt.Sprintf("container:%s:%s", tenant.FromContext(ctx), req.ContainerNumber)
+	s.cache.Delete(ctx, cacheKey)
 
 	return nil
 }
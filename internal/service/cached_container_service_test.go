@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/repository/memory"
+	"github.com/dwipurnomo515/yard-planning/pkg/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCachedService returns a caching service on a seeded in-memory store
+func newCachedService(t *testing.T) (*CachedContainerService, repository.Stores) {
+	store := memory.NewStore()
+	require.NoError(t, store.Seed())
+	stores := store.Stores()
+
+	s := NewCachedContainerService(stores.Yards, stores.Blocks, stores.Plans, stores.Containers,
+		stores.Closures, stores.Equipment, stores.WorkOrders, cache.NewLocalCache(100, time.Hour))
+	s.SetTransactions(stores.Atomic)
+	return s, stores
+}
+
+func TestCachedContainerService_ClosureInvalidatesSuggestion(t *testing.T) {
+	ctx := context.Background()
+	s, stores := newCachedService(t)
+	req := model.SuggestionRequest{Yard: "YRD1", ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY"}
+
+	suggestion, err := s.GetSuggestion(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, "YRD1", suggestion.Yard)
+
+	// The yard is closed without going through the cached service
+	closeYard(t, stores, yardByCode(t, stores, "YRD1").ID)
+
+	suggestion, err = s.GetSuggestion(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "DEPOT1", suggestion.Yard, "a closed cell was suggested from the cache")
+	assert.True(t, suggestion.Overflow)
+}
+
+func TestCachedContainerService_ReservationInvalidatesSuggestion(t *testing.T) {
+	ctx := context.Background()
+	s, stores := newCachedService(t)
+	s.SetWorkOrderConfirmation(true)
+	req := model.SuggestionRequest{Yard: "YRD1", ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY"}
+
+	cached, err := s.GetSuggestion(ctx, req)
+	require.NoError(t, err)
+
+	// Another service reserves the suggested cell
+	block, err := stores.Blocks.GetByYardAndCode(ctx, yardByCode(t, stores, "YRD1").ID, cached.Position.Block)
+	require.NoError(t, err)
+	require.NoError(t, stores.WorkOrders.Create(ctx, &model.WorkOrder{
+		YardID:          block.YardID,
+		ContainerNumber: "ABCU1234560",
+		ContainerSize:   20,
+		ContainerHeight: 8.6,
+		ContainerType:   "DRY",
+		Operation:       model.WorkOrderPlacement,
+		Status:          model.WorkOrderStatusCreated,
+		To: &model.WorkOrderLocation{
+			BlockID: block.ID, Block: block.Code, Slot: cached.Position.Slot, Row: cached.Position.Row, Tier: cached.Position.Tier,
+		},
+	}))
+
+	suggestion, err := s.GetSuggestion(ctx, req)
+	require.NoError(t, err)
+	assert.NotEqual(t, cached.Position, suggestion.Position, "a reserved cell was suggested from the cache")
+}
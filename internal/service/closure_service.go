@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/auth"
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+type ClosureService struct {
+	yardRepo    repository.YardStore
+	blockRepo   repository.BlockStore
+	closureRepo repository.ClosureStore
+}
+
+func NewClosureService(
+	yardRepo repository.YardStore,
+	blockRepo repository.BlockStore,
+	closureRepo repository.ClosureStore,
+) *ClosureService {
+	return &ClosureService{
+		yardRepo:    yardRepo,
+		blockRepo:   blockRepo,
+		closureRepo: closureRepo,
+	}
+}
+
+// CreateClosure closes a yard, a block or a slot/row range of a block for a period of time
+func (s *ClosureService) CreateClosure(ctx context.Context, req model.ClosureRequest) (*model.BlockClosure, error) {
+	// Check the role of the caller in the yard
+	if err := auth.Authorize(ctx, auth.PermPlan, req.Yard); err != nil {
+		return nil, err
+	}
+
+	// Validate input
+	if req.StartsAt.IsZero() || req.EndsAt.IsZero() {
+		return nil, apperror.Required("starts_at", "ends_at")
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		return nil, apperror.Validation("ends_at", "must be after starts_at")
+	}
+	if (req.SlotStart == nil) != (req.SlotEnd == nil) || (req.RowStart == nil) != (req.RowEnd == nil) {
+		return nil, apperror.New(apperror.CodeValidationFailed, "slot and row ranges need both a start and an end")
+	}
+
+	// Get yard
+	yard, err := s.yardRepo.GetByCode(ctx, req.Yard)
+	if err != nil {
+		return nil, err
+	}
+
+	closure := &model.BlockClosure{
+		YardID:    yard.ID,
+		SlotStart: req.SlotStart,
+		SlotEnd:   req.SlotEnd,
+		RowStart:  req.RowStart,
+		RowEnd:    req.RowEnd,
+		Reason:    req.Reason,
+		StartsAt:  req.StartsAt,
+		EndsAt:    req.EndsAt,
+	}
+
+	if req.Block == "" {
+		if req.SlotStart != nil || req.RowStart != nil {
+			return nil, apperror.Required("block")
+		}
+	} else {
+		block, err := s.blockRepo.GetByYardAndCode(ctx, yard.ID, req.Block)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateRange("slot", req.SlotStart, req.SlotEnd, block.MaxSlot); err != nil {
+			return nil, err
+		}
+		if err := validateRange("row", req.RowStart, req.RowEnd, block.MaxRow); err != nil {
+			return nil, err
+		}
+		closure.BlockID = &block.ID
+		closure.BlockCode = block.Code
+	}
+
+	if err := s.closureRepo.Create(ctx, closure); err != nil {
+		return nil, err
+	}
+
+	return closure, nil
+}
+
+// ListClosures returns the active and upcoming closures of a yard
+func (s *ClosureService) ListClosures(ctx context.Context, yardCode string) (*model.ClosureListResponse, error) {
+	// Check the role of the caller in the yard
+	if err := auth.Authorize(ctx, auth.PermView, yardCode); err != nil {
+		return nil, err
+	}
+
+	// Get yard
+	yard, err := s.yardRepo.GetByCode(ctx, yardCode)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	closures, err := s.closureRepo.GetCurrentAndUpcomingByYardID(ctx, yard.ID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &model.ClosureListResponse{
+		Active:   []model.BlockClosure{},
+		Upcoming: []model.BlockClosure{},
+	}
+	for _, c := range closures {
+		if c.IsActiveAt(now) {
+			resp.Active = append(resp.Active, c)
+		} else {
+			resp.Upcoming = append(resp.Upcoming, c)
+		}
+	}
+
+	return resp, nil
+}
+
+func validateRange(name string, start, end *int, max int) error {
+	if start == nil {
+		return nil
+	}
+	if *start < 1 || *end > max || *end < *start {
+		return apperror.Validation(name, fmt.Sprintf("range must be within 1 and %d", max))
+	}
+	return nil
+}
@@ -1,56 +1,224 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/dwipurnomo515/yard-planning/internal/auth"
 	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/occupancy"
 	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/dwipurnomo515/yard-planning/pkg/cache"
 )
 
+// errNoCapacity is returned when no yard has a free position for a container
+var errNoCapacity = apperror.New(apperror.CodeNoCapacity, "no available position found for container")
+
+// ContainerOperations is the container API served by the HTTP handlers. It is
+// implemented by ContainerService and by the Redis backed CachedContainerService.
+type ContainerOperations interface {
+	GetSuggestion(ctx context.Context, req model.SuggestionRequest) (*model.Suggestion, error)
+	PlaceContainer(ctx context.Context, req model.PlacementRequest) error
+	PickupContainer(ctx context.Context, req model.PickupRequest) error
+	MoveContainer(ctx context.Context, req model.MoveRequest) error
+	PlaceBatch(ctx context.Context, reqs []model.PlacementRequest) error
+	SuggestBatch(ctx context.Context, reqs []model.SuggestionRequest) ([]BatchSuggestion, error)
+}
+
+var _ ContainerOperations = (*ContainerService)(nil)
+
 type ContainerService struct {
-	yardRepo      *repository.YardRepository
-	blockRepo     *repository.BlockRepository
-	planRepo      *repository.YardPlanRepository
-	containerRepo *repository.ContainerRepository
+	yardRepo      repository.YardStore
+	blockRepo     repository.BlockStore
+	planRepo      repository.YardPlanStore
+	containerRepo repository.ContainerStore
+	closureRepo   repository.ClosureStore
+	equipmentRepo repository.EquipmentStore
+	workOrderRepo repository.WorkOrderStore
+	weights       SuggestionWeights
+	confirmation  bool
+	occupancy     *occupancy.Index
+	atomic        repository.Transactor
+	locker        cache.Locker
 }
 
 func NewContainerService(
-	yardRepo *repository.YardRepository,
-	blockRepo *repository.BlockRepository,
-	planRepo *repository.YardPlanRepository,
-	containerRepo *repository.ContainerRepository,
+	yardRepo repository.YardStore,
+	blockRepo repository.BlockStore,
+	planRepo repository.YardPlanStore,
+	containerRepo repository.ContainerStore,
+	closureRepo repository.ClosureStore,
+	equipmentRepo repository.EquipmentStore,
+	workOrderRepo repository.WorkOrderStore,
 ) *ContainerService {
 	return &ContainerService{
 		yardRepo:      yardRepo,
 		blockRepo:     blockRepo,
 		planRepo:      planRepo,
 		containerRepo: containerRepo,
+		closureRepo:   closureRepo,
+		equipmentRepo: equipmentRepo,
+		workOrderRepo: workOrderRepo,
+		weights:       DefaultSuggestionWeights(),
 	}
 }
 
-// GetSuggestion suggests a position for a container based on yard plans
-func (s *ContainerService) GetSuggestion(req model.SuggestionRequest) (*model.Position, error) {
+// SetSuggestionWeights overrides the weights used to rank candidate blocks
+func (s *ContainerService) SetSuggestionWeights(weights SuggestionWeights) {
+	s.weights = weights
+}
+
+// SetWorkOrderConfirmation controls when placements, pickups and moves change the yard.
+// When enabled the container position only changes once the operator confirms the work order.
+func (s *ContainerService) SetWorkOrderConfirmation(enabled bool) {
+	s.confirmation = enabled
+}
+
+// SetOccupancyIndex makes suggestions read occupied cells from an in-memory index
+// instead of the database. Placements are still checked against the database.
+func (s *ContainerService) SetOccupancyIndex(index *occupancy.Index) {
+	s.occupancy = index
+}
+
+// SetTransactions makes placements, pickups and moves write the container and
+// its work order in one transaction, and lets PlaceBatch apply a batch of
+// placements in one transaction
+func (s *ContainerService) SetTransactions(atomic repository.Transactor) {
+	s.atomic = atomic
+}
+
+// GetSuggestion suggests a position for a container based on yard plans. When the
+// requested yard has no matching capacity, its overflow yards are tried in priority order.
+func (s *ContainerService) GetSuggestion(ctx context.Context, req model.SuggestionRequest) (*model.Suggestion, error) {
+	return s.suggest(ctx, req, func(yard *model.Yard, req model.SuggestionRequest) (*model.Position, error) {
+		return s.suggestInYard(ctx, yard, req)
+	})
+}
+
+// suggest checks a suggestion request and asks inYard for a position in the
+// requested yard, then in its overflow yards. inYard returns errNoCapacity
+// when a yard has no room for the container.
+func (s *ContainerService) suggest(
+	ctx context.Context,
+	req model.SuggestionRequest,
+	inYard func(yard *model.Yard, req model.SuggestionRequest) (*model.Position, error),
+) (*model.Suggestion, error) {
+	// Check the role of the caller in the yard
+	if err := auth.Authorize(ctx, auth.PermSuggest, req.Yard); err != nil {
+		return nil, err
+	}
+
 	// Validate input
 	if err := s.validateContainerSpec(req.ContainerSize, req.ContainerHeight, req.ContainerType); err != nil {
 		return nil, err
 	}
 
 	// Get yard
-	yard, err := s.yardRepo.GetByCode(req.Yard)
+	yard, err := s.yardRepo.GetByCode(ctx, req.Yard)
 	if err != nil {
 		return nil, err
 	}
 
+	position, err := inYard(yard, req)
+	if err == nil {
+		return &model.Suggestion{
+			Position: *position,
+			Yard:     yard.Code,
+			Reason:   fmt.Sprintf("capacity available in requested yard %s", yard.Code),
+		}, nil
+	}
+	if !errors.Is(err, errNoCapacity) {
+		return nil, err
+	}
+
+	// Route to overflow yards
+	rules, err := s.yardRepo.GetOverflowRules(ctx, yard.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	tried := []string{yard.Code}
+	for _, rule := range rules {
+		// Callers scoped to some yards only get suggestions in those
+		if !auth.Allowed(ctx, auth.PermSuggest, rule.OverflowYard) {
+			continue
+		}
+		overflowYard, err := s.yardRepo.GetByCode(ctx, rule.OverflowYard)
+		if err != nil {
+			return nil, err
+		}
+
+		// Berth codes belong to the requested yard
+		overflowReq := req
+		overflowReq.Berth = ""
+
+		position, err := inYard(overflowYard, overflowReq)
+		if errors.Is(err, errNoCapacity) {
+			tried = append(tried, overflowYard.Code)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return &model.Suggestion{
+			Position: *position,
+			Yard:     overflowYard.Code,
+			Overflow: true,
+			Reason: fmt.Sprintf("no matching capacity in %s; overflow rule priority %d routes to %s",
+				strings.Join(tried, ", "), rule.Priority, overflowYard.Code),
+		}, nil
+	}
+
+	return nil, errNoCapacity
+}
+
+// suggestInYard finds the best position for a container in a single yard
+func (s *ContainerService) suggestInYard(ctx context.Context, yard *model.Yard, req model.SuggestionRequest) (*model.Position, error) {
 	// Get blocks in yard
-	blocks, err := s.blockRepo.GetByYardID(yard.ID)
+	blocks, err := s.blockRepo.GetByYardID(ctx, yard.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Closed areas are never suggested
+	closures, err := s.closureRepo.GetActiveByYardID(ctx, yard.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// Cells promised to unconfirmed work orders are not suggested again
+	reservations, err := s.reservations(ctx, yard.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Try to find available position in each block
+	// Equipment queues are used to spread work across blocks
+	equipment, err := s.equipmentRepo.GetByYardID(ctx, yard.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Points of interest the container should stay close to
+	targets, err := s.targetPoints(ctx, yard.ID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the best available position over all blocks
+	var best *candidate
 	for _, block := range blocks {
+		// Stop scanning once the request is cancelled
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		// Find matching yard plan
-		plan, err := s.planRepo.FindMatchingPlan(
+		plan, err := s.planRepo.FindMatchingPlan(ctx,
 			block.ID,
 			req.ContainerSize,
 			req.ContainerHeight,
@@ -60,31 +228,60 @@ func (s *ContainerService) GetSuggestion(req model.SuggestionRequest) (*model.Po
 			continue // Try next block
 		}
 
-		// Find available position in this plan
-		position := s.findAvailablePosition(block, *plan)
-		if position != nil {
-			return position, nil
+		// Find available positions in this plan
+		positions := s.findAvailablePositions(ctx, block, *plan, closures, reservations)
+		responsible := responsibleEquipment(equipment, block.ID)
+
+		for _, position := range positions {
+			c := candidate{
+				position:  position,
+				equipment: responsible,
+			}
+			if len(targets) > 0 {
+				center := block.CellCenter(position.Slot, position.Row, req.ContainerSize)
+				c.distance = nearestDistance(center, targets)
+			}
+			c.score = s.weights.scoreCandidate(c)
+			if best == nil || c.score < best.score {
+				best = &c
+			}
 		}
 	}
 
-	return nil, fmt.Errorf("no available position found for container")
+	if best == nil {
+		return nil, errNoCapacity
+	}
+
+	return &best.position, nil
 }
 
 // PlaceContainer places a container at a specific position
-func (s *ContainerService) PlaceContainer(req model.PlacementRequest) error {
+func (s *ContainerService) PlaceContainer(ctx context.Context, req model.PlacementRequest) error {
+	// Check the role of the caller in the yard
+	if err := auth.Authorize(ctx, auth.PermPlace, req.Yard); err != nil {
+		return err
+	}
+
 	// Validate input
 	if req.ContainerNumber == "" {
-		return fmt.Errorf("container number is required")
+		return apperror.Required("container_number")
 	}
 
 	// Get yard
-	yard, err := s.yardRepo.GetByCode(req.Yard)
+	yard, err := s.yardRepo.GetByCode(ctx, req.Yard)
+	if err != nil {
+		return err
+	}
+
+	// Only one instance changes the yard at a time
+	ctx, unlock, err := s.lockYards(ctx, yard.ID)
 	if err != nil {
 		return err
 	}
+	defer unlock()
 
 	// Get block
-	block, err := s.blockRepo.GetByYardAndCode(yard.ID, req.Block)
+	block, err := s.blockRepo.GetByYardAndCode(ctx, yard.ID, req.Block)
 	if err != nil {
 		return err
 	}
@@ -94,75 +291,444 @@ func (s *ContainerService) PlaceContainer(req model.PlacementRequest) error {
 		return err
 	}
 
-	// Check if container already exists
-	existingContainer, _ := s.containerRepo.GetByNumber(req.ContainerNumber)
-	if existingContainer != nil {
-		return fmt.Errorf("container '%s' already placed in yard", req.ContainerNumber)
-	}
+	container := newPlacement(req, yard.ID, block)
+
+	// The yard is checked in the transaction that changes it, so two
+	// placements can not both take a cell
+	return s.write(ctx, func(s *ContainerService) error {
+		// Check if container already exists
+		if err := s.checkNotInYard(ctx, req.ContainerNumber); err != nil {
+			return err
+		}
+		if err := s.checkNoPendingWorkOrder(ctx, req.ContainerNumber); err != nil {
+			return err
+		}
+
+		// Check if target position is open, free and supported
+		if err := s.checkTarget(ctx, yard.ID, block, req.Slot, req.Row, req.Tier, container.ContainerSize); err != nil {
+			return err
+		}
 
+		return s.place(ctx, container, block)
+	})
+}
+
+// placementSize is the size of placed containers until placement requests
+// carry the container specs
+const placementSize = 20
+
+// newPlacement prepares the container of a placement request
+func newPlacement(req model.PlacementRequest, yardID int, block *model.Block) *model.Container {
 	// For now, we'll use default container specs (20ft, 8.6, DRY)
 	// In production, you'd want to pass these in the request
-	containerSize := 20
-	containerHeight := 8.6
-	containerType := "DRY"
+	return &model.Container{
+		ContainerNumber: req.ContainerNumber,
+		YardID:          yardID,
+		BlockID:         block.ID,
+		Slot:            req.Slot,
+		Row:             req.Row,
+		Tier:            req.Tier,
+		ContainerSize:   placementSize,
+		ContainerHeight: 8.6,
+		ContainerType:   "DRY",
+	}
+}
 
-	// Check if position is available
-	occupied, err := s.containerRepo.IsPositionOccupied(block.ID, req.Slot, req.Row, req.Tier, containerSize)
-	if err != nil {
-		return err
+// place stores a checked placement and creates its work order. It runs in the
+// transaction that checked the placement.
+func (s *ContainerService) place(ctx context.Context, container *model.Container, block *model.Block) error {
+	// Without confirmation the yard reflects the move right away
+	if !s.confirmation {
+		if err := s.containerRepo.Create(ctx, container); err != nil {
+			return err
+		}
 	}
-	if occupied {
-		return fmt.Errorf("position is already occupied")
+
+	// Hand the move to the machine serving the block
+	order := newWorkOrder(container, model.WorkOrderPlacement, !s.confirmation)
+	order.To = &model.WorkOrderLocation{
+		BlockID: block.ID,
+		Block:   block.Code,
+		Slot:    container.Slot,
+		Row:     container.Row,
+		Tier:    container.Tier,
 	}
+	return s.createWorkOrder(ctx, order)
+}
 
-	// Check if tier > 1, ensure tier below is occupied
-	if req.Tier > 1 {
-		occupied, err := s.containerRepo.IsPositionOccupied(block.ID, req.Slot, req.Row, req.Tier-1, containerSize)
+// write runs the yard change of a request and its work order in one
+// transaction, so a container never moves without a work order. The
+// transaction fails when a yard lock of ctx was taken over. Once the change
+// succeeded, the function set by withWrittenHook runs in the same transaction.
+func (s *ContainerService) write(ctx context.Context, fn func(s *ContainerService) error) error {
+	if s.atomic == nil {
+		return fn(s)
+	}
+	return s.atomic(ctx, func(tx repository.Stores) error {
+		if err := checkFences(ctx, tx.Fences); err != nil {
+			return err
+		}
+		if err := fn(s.inTransaction(tx)); err != nil {
+			return err
+		}
+		if written, ok := ctx.Value(writtenHookKey{}).(func(tx repository.Stores) error); ok {
+			return written(tx)
+		}
+		return nil
+	})
+}
+
+// writtenHookKey is the context key of the function run in the transaction
+// of a yard change
+type writtenHookKey struct{}
+
+// withWrittenHook makes the yard change of a request run fn in its
+// transaction, so whatever fn records commits together with the change. fn is
+// not run when the service has no transactions.
+func withWrittenHook(ctx context.Context, fn func(tx repository.Stores) error) context.Context {
+	return context.WithValue(ctx, writtenHookKey{}, fn)
+}
+
+// applyWorkOrder performs the yard change of a confirmed work order. The yard
+// may have changed since the order was created, so the change is checked again
+// like a new request. The order must no longer be pending.
+func (s *ContainerService) applyWorkOrder(ctx context.Context, order *model.WorkOrder) error {
+	switch order.Operation {
+	case model.WorkOrderPlacement:
+		if err := s.checkNotInYard(ctx, order.ContainerNumber); err != nil {
+			return err
+		}
+		block, err := s.blockRepo.GetByID(ctx, order.To.BlockID)
 		if err != nil {
 			return err
 		}
-		if !occupied {
-			return fmt.Errorf("cannot place container at tier %d: tier below is empty", req.Tier)
+		if err := s.checkTarget(ctx, order.YardID, block, order.To.Slot, order.To.Row, order.To.Tier, order.ContainerSize); err != nil {
+			return err
 		}
-	}
+		return s.containerRepo.Create(ctx, &model.Container{
+			ContainerNumber: order.ContainerNumber,
+			YardID:          order.YardID,
+			BlockID:         block.ID,
+			Slot:            order.To.Slot,
+			Row:             order.To.Row,
+			Tier:            order.To.Tier,
+			ContainerSize:   order.ContainerSize,
+			ContainerHeight: order.ContainerHeight,
+			ContainerType:   order.ContainerType,
+		})
+
+	case model.WorkOrderPickup:
+		container, err := s.containerRepo.GetByNumber(ctx, order.ContainerNumber)
+		if err != nil {
+			return err
+		}
+		if err := s.checkNotBlocked(ctx, container); err != nil {
+			return err
+		}
+		return s.containerRepo.Delete(ctx, order.ContainerNumber)
 
-	// Create container
-	container := &model.Container{
-		ContainerNumber: req.ContainerNumber,
-		YardID:          yard.ID,
-		BlockID:         block.ID,
-		Slot:            req.Slot,
-		Row:             req.Row,
-		Tier:            req.Tier,
-		ContainerSize:   containerSize,
-		ContainerHeight: containerHeight,
-		ContainerType:   containerType,
+	case model.WorkOrderMove, model.WorkOrderRehandle:
+		container, err := s.containerRepo.GetByNumber(ctx, order.ContainerNumber)
+		if err != nil {
+			return err
+		}
+		if err := s.checkNotBlocked(ctx, container); err != nil {
+			return err
+		}
+		block, err := s.blockRepo.GetByID(ctx, order.To.BlockID)
+		if err != nil {
+			return err
+		}
+		if err := s.checkTarget(ctx, order.YardID, block, order.To.Slot, order.To.Row, order.To.Tier, order.ContainerSize); err != nil {
+			return err
+		}
+		return s.containerRepo.UpdatePosition(ctx,
+			order.ContainerNumber, block.ID, order.To.Slot, order.To.Row, order.To.Tier,
+		)
 	}
 
-	return s.containerRepo.Create(container)
+	return fmt.Errorf("unknown work order operation %s", order.Operation)
+}
+
+// checkNotInYard rejects placing a container that is already in the yard
+func (s *ContainerService) checkNotInYard(ctx context.Context, containerNumber string) error {
+	existingContainer, _ := s.containerRepo.GetByNumber(ctx, containerNumber)
+	if existingContainer != nil {
+		return apperror.New(apperror.CodeConflict, "container '%s' already placed in yard", containerNumber)
+	}
+	return nil
 }
 
 // PickupContainer removes a container from the yard
-func (s *ContainerService) PickupContainer(req model.PickupRequest) error {
+func (s *ContainerService) PickupContainer(ctx context.Context, req model.PickupRequest) error {
+	// Check the role of the caller in the yard
+	if err := auth.Authorize(ctx, auth.PermPickup, req.Yard); err != nil {
+		return err
+	}
+
+	// Validate input
+	if req.ContainerNumber == "" {
+		return apperror.Required("container_number")
+	}
+
+	// Get yard
+	yard, err := s.yardRepo.GetByCode(ctx, req.Yard)
+	if err != nil {
+		return err
+	}
+
+	// Only one instance changes the yard at a time
+	ctx, unlock, err := s.lockYards(ctx, yard.ID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// The yard is checked in the transaction that changes it
+	return s.write(ctx, func(s *ContainerService) error {
+		// Get container; it must be in the yard the caller is allowed to work in
+		container, err := s.containerRepo.GetByNumber(ctx, req.ContainerNumber)
+		if err != nil {
+			return err
+		}
+		if container.YardID != yard.ID {
+			return apperror.New(apperror.CodeContainerNotFound, "container '%s' not found in yard '%s'", req.ContainerNumber, req.Yard)
+		}
+		if err := s.checkNoPendingWorkOrder(ctx, req.ContainerNumber); err != nil {
+			return err
+		}
+
+		// A held container only leaves the yard when a supervisor overrides the hold
+		if container.Hold != "" {
+			if !req.OverrideHold {
+				return apperror.New(apperror.CodeContainerHeld, "container '%s' is on hold: %s", req.ContainerNumber, container.Hold)
+			}
+			if err := auth.Authorize(ctx, auth.PermOverrideHold, req.Yard); err != nil {
+				return err
+			}
+		}
+
+		// Check if container is blocked (has containers on top)
+		if err := s.checkNotBlocked(ctx, container); err != nil {
+			return err
+		}
+
+		// Without confirmation the yard reflects the move right away
+		if !s.confirmation {
+			if err := s.containerRepo.Delete(ctx, req.ContainerNumber); err != nil {
+				return err
+			}
+		}
+
+		order := newWorkOrder(container, model.WorkOrderPickup, !s.confirmation)
+		order.From = containerLocation(container)
+		order.Destination = req.Destination
+		return s.createWorkOrder(ctx, order)
+	})
+}
+
+// MoveContainer moves a container to another position in the same yard
+func (s *ContainerService) MoveContainer(ctx context.Context, req model.MoveRequest) error {
+	// Check the role of the caller in the yard
+	if err := auth.Authorize(ctx, auth.PermMove, req.Yard); err != nil {
+		return err
+	}
+
 	// Validate input
 	if req.ContainerNumber == "" {
-		return fmt.Errorf("container number is required")
+		return apperror.Required("container_number")
 	}
 
 	// Get yard
-	_, err := s.yardRepo.GetByCode(req.Yard)
+	yard, err := s.yardRepo.GetByCode(ctx, req.Yard)
 	if err != nil {
 		return err
 	}
 
-	// Get container
-	container, err := s.containerRepo.GetByNumber(req.ContainerNumber)
+	// Only one instance changes the yard at a time
+	ctx, unlock, err := s.lockYards(ctx, yard.ID)
 	if err != nil {
 		return err
 	}
+	defer unlock()
 
-	// Check if container is blocked (has containers on top)
-	blocked, err := s.containerRepo.IsContainerBlocked(
+	// Get target block
+	block, err := s.blockRepo.GetByYardAndCode(ctx, yard.ID, req.Block)
+	if err != nil {
+		return err
+	}
+
+	// Validate position
+	if err := s.validatePosition(block, req.Slot, req.Row, req.Tier); err != nil {
+		return err
+	}
+
+	// The yard is checked in the transaction that changes it, so two moves
+	// can not both take a cell
+	return s.write(ctx, func(s *ContainerService) error {
+		// Get container
+		container, err := s.containerRepo.GetByNumber(ctx, req.ContainerNumber)
+		if err != nil {
+			return err
+		}
+		if container.YardID != yard.ID {
+			return apperror.New(apperror.CodeContainerNotFound, "container '%s' not found in yard '%s'", req.ContainerNumber, req.Yard)
+		}
+		if err := s.checkNoPendingWorkOrder(ctx, req.ContainerNumber); err != nil {
+			return err
+		}
+		if block.ID == container.BlockID && req.Slot == container.Slot && req.Row == container.Row {
+			return apperror.New(apperror.CodeConflict, "container '%s' is already in this stack", req.ContainerNumber)
+		}
+
+		// Only the top container of a stack can be lifted
+		if err := s.checkNotBlocked(ctx, container); err != nil {
+			return err
+		}
+
+		// Check if target position is open, free and supported
+		if err := s.checkTarget(ctx, yard.ID, block, req.Slot, req.Row, req.Tier, container.ContainerSize); err != nil {
+			return err
+		}
+
+		from := containerLocation(container)
+
+		// Without confirmation the yard reflects the move right away
+		if !s.confirmation {
+			err := s.containerRepo.UpdatePosition(ctx, container.ContainerNumber, block.ID, req.Slot, req.Row, req.Tier)
+			if err != nil {
+				return err
+			}
+		}
+
+		operation := model.WorkOrderMove
+		if req.Rehandle {
+			operation = model.WorkOrderRehandle
+		}
+		order := newWorkOrder(container, operation, !s.confirmation)
+		order.From = from
+		order.To = &model.WorkOrderLocation{
+			BlockID: block.ID,
+			Block:   block.Code,
+			Slot:    req.Slot,
+			Row:     req.Row,
+			Tier:    req.Tier,
+		}
+		return s.createWorkOrder(ctx, order)
+	})
+}
+
+// Helper methods
+
+// targetPoints returns the points of interest a container should be stored close to:
+// the gates for imports and the loading berth for exports
+func (s *ContainerService) targetPoints(ctx context.Context, yardID int, req model.SuggestionRequest) ([]model.Point, error) {
+	var pointType string
+	switch req.Movement {
+	case "":
+		return nil, nil
+	case model.MovementImport:
+		pointType = model.PointTypeGate
+	case model.MovementExport:
+		pointType = model.PointTypeBerth
+	default:
+		return nil, apperror.Validation("movement", "must be IMPORT or EXPORT")
+	}
+
+	points, err := s.yardRepo.GetPointsByYardID(ctx, yardID)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []model.Point
+	for _, p := range points {
+		if p.PointType != pointType {
+			continue
+		}
+		if pointType == model.PointTypeBerth && req.Berth != "" && p.Code != req.Berth {
+			continue
+		}
+		targets = append(targets, p.Location())
+	}
+
+	if req.Berth != "" && len(targets) == 0 {
+		return nil, apperror.New(apperror.CodeNotFound, "berth '%s' not found in yard", req.Berth)
+	}
+
+	return targets, nil
+}
+
+// checkTarget verifies a container of the given size can be put at a position:
+// the cell must not be closed, occupied or reserved, and must be supported from below
+func (s *ContainerService) checkTarget(ctx context.Context, yardID int, block *model.Block, slot, row, tier, containerSize int) error {
+	// Reject placements into closed areas
+	closures, err := s.closureRepo.GetActiveByYardID(ctx, yardID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// Cells targeted by unconfirmed work orders are reserved
+	reservations, err := s.reservations(ctx, yardID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkCell(ctx, closures, reservations, block, slot, row, tier, containerSize); err != nil {
+		return err
+	}
+
+	// Check if tier > 1, ensure tier below is occupied
+	if tier > 1 {
+		supported, err := s.supported(ctx, reservations, block, slot, row, tier, containerSize)
+		if err != nil {
+			return err
+		}
+		if !supported {
+			return errTierBelowEmpty(tier)
+		}
+	}
+
+	return nil
+}
+
+// checkCell verifies a position is not closed, occupied or reserved
+func (s *ContainerService) checkCell(ctx context.Context, closures []model.BlockClosure, reservations []model.WorkOrder, block *model.Block, slot, row, tier, containerSize int) error {
+	if closure := findClosure(closures, block.ID, slot, row, containerSize); closure != nil {
+		return apperror.New(apperror.CodePositionClosed, "position is closed until %s: %s", closure.EndsAt.Format(time.RFC3339), closure.Reason)
+	}
+
+	// Check if position is available
+	occupied, err := s.containerRepo.IsPositionOccupied(ctx, block.ID, slot, row, tier, containerSize)
+	if err != nil {
+		return err
+	}
+	if occupied {
+		return apperror.New(apperror.CodePositionOccupied, "position is already occupied")
+	}
+	if findReservation(reservations, block.ID, slot, row, tier, containerSize) != nil {
+		return apperror.New(apperror.CodePositionReserved, "position is reserved by a pending work order")
+	}
+
+	return nil
+}
+
+// supported reports whether the tier below a position holds, or is about to
+// hold, a container
+func (s *ContainerService) supported(ctx context.Context, reservations []model.WorkOrder, block *model.Block, slot, row, tier, containerSize int) (bool, error) {
+	occupied, err := s.containerRepo.IsPositionOccupied(ctx, block.ID, slot, row, tier-1, containerSize)
+	if err != nil {
+		return false, err
+	}
+	return occupied || findReservation(reservations, block.ID, slot, row, tier-1, containerSize) != nil, nil
+}
+
+func errTierBelowEmpty(tier int) error {
+	return apperror.New(apperror.CodeConflict, "cannot place container at tier %d: tier below is empty", tier)
+}
+
+// checkNotBlocked verifies nothing is stacked, or about to be stacked, on a container
+func (s *ContainerService) checkNotBlocked(ctx context.Context, container *model.Container) error {
+	blocked, err := s.containerRepo.IsContainerBlocked(ctx,
 		container.BlockID,
 		container.Slot,
 		container.Row,
@@ -171,122 +737,264 @@ func (s *ContainerService) PickupContainer(req model.PickupRequest) error {
 	if err != nil {
 		return err
 	}
+
+	if !blocked {
+		reservations, err := s.reservations(ctx, container.YardID)
+		if err != nil {
+			return err
+		}
+		blocked = findReservation(reservations, container.BlockID, container.Slot, container.Row,
+			container.Tier+1, container.ContainerSize) != nil
+	}
+
 	if blocked {
-		return fmt.Errorf("cannot pickup container: there are containers on top")
+		return apperror.New(apperror.CodeContainerBlocked, "cannot pickup container: there are containers on top")
 	}
 
-	// Delete container
-	return s.containerRepo.Delete(req.ContainerNumber)
+	return nil
 }
 
-// Helper methods
+// checkNoPendingWorkOrder rejects requests for a container that is already being moved
+func (s *ContainerService) checkNoPendingWorkOrder(ctx context.Context, containerNumber string) error {
+	if !s.confirmation {
+		return nil
+	}
+
+	pending, err := s.workOrderRepo.HasPendingForContainer(ctx, containerNumber)
+	if err != nil {
+		return err
+	}
+	if pending {
+		return apperror.New(apperror.CodeWorkOrderPending, "container '%s' already has a pending work order", containerNumber)
+	}
+	return nil
+}
+
+// reservations returns the unconfirmed work orders of a yard that will put a
+// container into a cell
+func (s *ContainerService) reservations(ctx context.Context, yardID int) ([]model.WorkOrder, error) {
+	if !s.confirmation {
+		return nil, nil
+	}
+
+	orders, err := s.workOrderRepo.GetPendingByYardID(ctx, yardID)
+	if err != nil {
+		return nil, err
+	}
+
+	var reserved []model.WorkOrder
+	for _, o := range orders {
+		if !o.PositionApplied && o.To != nil {
+			reserved = append(reserved, o)
+		}
+	}
+	return reserved, nil
+}
+
+// createWorkOrder records a move for the least loaded machine serving the block it happens in
+func (s *ContainerService) createWorkOrder(ctx context.Context, order *model.WorkOrder) error {
+	equipment, err := s.equipmentRepo.GetByYardID(ctx, order.YardID)
+	if err != nil {
+		return err
+	}
+
+	workBlock := order.To
+	if workBlock == nil {
+		workBlock = order.From
+	}
+	if e := responsibleEquipment(equipment, workBlock.BlockID); e != nil {
+		order.EquipmentID = &e.ID
+		order.EquipmentCode = e.Code
+	}
+
+	return s.workOrderRepo.Create(ctx, order)
+}
+
+// newWorkOrder prepares a work order for a container
+func newWorkOrder(container *model.Container, operation string, applied bool) *model.WorkOrder {
+	return &model.WorkOrder{
+		YardID:          container.YardID,
+		ContainerNumber: container.ContainerNumber,
+		ContainerSize:   container.ContainerSize,
+		ContainerHeight: container.ContainerHeight,
+		ContainerType:   container.ContainerType,
+		Operation:       operation,
+		PositionApplied: applied,
+		Status:          model.WorkOrderStatusCreated,
+	}
+}
+
+// containerLocation returns the current cell of a container
+func containerLocation(container *model.Container) *model.WorkOrderLocation {
+	return &model.WorkOrderLocation{
+		BlockID: container.BlockID,
+		Slot:    container.Slot,
+		Row:     container.Row,
+		Tier:    container.Tier,
+	}
+}
+
+// findReservation returns the work order reserving any slot a container of the given
+// size would use at the position
+func findReservation(orders []model.WorkOrder, blockID, slot, row, tier, containerSize int) *model.WorkOrder {
+	for i := range orders {
+		to := orders[i].To
+		if to == nil || to.BlockID != blockID || to.Row != row || to.Tier != tier {
+			continue
+		}
+		if spansOverlap(to.Slot, orders[i].ContainerSize, slot, containerSize) {
+			return &orders[i]
+		}
+	}
+	return nil
+}
+
+// spansOverlap reports whether two containers starting at the given slots share a slot
+func spansOverlap(slotA, sizeA, slotB, sizeB int) bool {
+	endA := slotA + sizeA/20 - 1
+	endB := slotB + sizeB/20 - 1
+	return slotA <= endB && slotB <= endA
+}
 
 func (s *ContainerService) validateContainerSpec(size int, height float64, containerType string) error {
 	if size != 20 && size != 40 {
-		return fmt.Errorf("invalid container size: must be 20 or 40")
+		return apperror.Validation("container_size", "must be 20 or 40")
 	}
 	if height != 8.6 && height != 9.6 {
-		return fmt.Errorf("invalid container height: must be 8.6 or 9.6")
+		return apperror.Validation("container_height", "must be 8.6 or 9.6")
 	}
 	validTypes := map[string]bool{"DRY": true, "REEFER": true, "OPEN_TOP": true}
 	if !validTypes[containerType] {
-		return fmt.Errorf("invalid container type: must be DRY, REEFER, or OPEN_TOP")
+		return apperror.Validation("container_type", "must be DRY, REEFER, or OPEN_TOP")
 	}
 	return nil
 }
 
 func (s *ContainerService) validatePosition(block *model.Block, slot, row, tier int) error {
 	if slot < 1 || slot > block.MaxSlot {
-		return fmt.Errorf("invalid slot: must be between 1 and %d", block.MaxSlot)
+		return apperror.Validation("slot", fmt.Sprintf("must be between 1 and %d", block.MaxSlot))
 	}
 	if row < 1 || row > block.MaxRow {
-		return fmt.Errorf("invalid row: must be between 1 and %d", block.MaxRow)
+		return apperror.Validation("row", fmt.Sprintf("must be between 1 and %d", block.MaxRow))
 	}
 	if tier < 1 || tier > block.MaxTier {
-		return fmt.Errorf("invalid tier: must be between 1 and %d", block.MaxTier)
+		return apperror.Validation("tier", fmt.Sprintf("must be between 1 and %d", block.MaxTier))
 	}
 	return nil
 }
 
-func (s *ContainerService) findAvailablePosition(block model.Block, plan model.YardPlan) *model.Position {
-	// Get all occupied positions in this plan's area
-	occupied, err := s.containerRepo.GetOccupiedPositionsInArea(
-		block.ID,
-		plan.SlotStart,
-		plan.SlotEnd,
-		plan.RowStart,
-		plan.RowEnd,
-	)
+// findClosure returns the closure covering any slot used by a container at the given position
+func findClosure(closures []model.BlockClosure, blockID, slot, row, containerSize int) *model.BlockClosure {
+	slotsNeeded := 1
+	if containerSize == 40 {
+		slotsNeeded = 2
+	}
+	for i := range closures {
+		for s := 0; s < slotsNeeded; s++ {
+			if closures[i].Covers(blockID, slot+s, row) {
+				return &closures[i]
+			}
+		}
+	}
+	return nil
+}
+
+// findAvailablePositions returns the free positions of a plan area on the lowest tier
+// that still has room (tier 1 first, then stack up)
+func (s *ContainerService) findAvailablePositions(
+	ctx context.Context,
+	block model.Block,
+	plan model.YardPlan,
+	closures []model.BlockClosure,
+	reservations []model.WorkOrder,
+) []model.Position {
+	grid, err := s.occupiedCells(ctx, block, plan)
 	if err != nil {
 		return nil
 	}
+	reserveCells(grid, block.ID, reservations)
 
-	// Create map of occupied positions
-	occupiedMap := make(map[string]bool)
-	for _, c := range occupied {
-		key := fmt.Sprintf("%d-%d-%d", c.Slot, c.Row, c.Tier)
-		occupiedMap[key] = true
+	return freePositions(grid, block, plan, closures)
+}
 
-		// For 40ft containers, mark second slot as occupied too
-		if c.ContainerSize == 40 {
-			key2 := fmt.Sprintf("%d-%d-%d", c.Slot+1, c.Row, c.Tier)
-			occupiedMap[key2] = true
+// reserveCells marks the cells of a block reserved by work orders as occupied
+func reserveCells(grid *occupancy.Grid, blockID int, reservations []model.WorkOrder) {
+	for _, o := range reservations {
+		if o.To.BlockID == blockID {
+			grid.Occupy(o.To.Slot, o.To.Row, o.To.Tier, o.ContainerSize)
 		}
 	}
+}
 
-	// Find first available position (tier 1 first, then stack up)
+// freePositions returns the free and supported cells of a plan on the
+// lowest tier that still has room
+func freePositions(grid *occupancy.Grid, block model.Block, plan model.YardPlan, closures []model.BlockClosure) []model.Position {
+	// Find available positions (tier 1 first, then stack up)
 	for tier := 1; tier <= block.MaxTier; tier++ {
+		var positions []model.Position
 		for slot := plan.SlotStart; slot <= plan.SlotEnd; slot++ {
 			for row := plan.RowStart; row <= plan.RowEnd; row++ {
-				// For 40ft, need to check if both slots are available
-				slotsNeeded := 1
-				if plan.ContainerSize == 40 {
-					slotsNeeded = 2
-					// Make sure we don't exceed slot range
-					if slot+1 > plan.SlotEnd {
-						continue
-					}
+				// A 40ft container must fit in the plan's slot range
+				if plan.ContainerSize == 40 && slot+1 > plan.SlotEnd {
+					continue
 				}
 
 				// Check if position is available
-				available := true
-				for s := 0; s < slotsNeeded; s++ {
-					key := fmt.Sprintf("%d-%d-%d", slot+s, row, tier)
-					if occupiedMap[key] {
-						available = false
-						break
-					}
+				if !grid.Free(slot, row, tier, plan.ContainerSize) {
+					continue
 				}
 
-				if !available {
+				// Skip cells inside an active closure
+				if findClosure(closures, block.ID, slot, row, plan.ContainerSize) != nil {
 					continue
 				}
 
 				// For tier > 1, check if tier below is occupied
-				if tier > 1 {
-					tierBelowOccupied := true
-					for s := 0; s < slotsNeeded; s++ {
-						keyBelow := fmt.Sprintf("%d-%d-%d", slot+s, row, tier-1)
-						if !occupiedMap[keyBelow] {
-							tierBelowOccupied = false
-							break
-						}
-					}
-					if !tierBelowOccupied {
-						continue
-					}
+				if !grid.Supported(slot, row, tier, plan.ContainerSize) {
+					continue
 				}
 
 				// Found available position
-				return &model.Position{
+				positions = append(positions, model.Position{
 					Block: block.Code,
 					Slot:  slot,
 					Row:   row,
 					Tier:  tier,
-				}
+				})
 			}
 		}
+
+		if len(positions) > 0 {
+			return positions
+		}
 	}
 
 	return nil
 }
+
+// occupiedCells returns the occupancy of a block, from the index when it is
+// enabled and otherwise from the containers stored in the plan's area
+func (s *ContainerService) occupiedCells(ctx context.Context, block model.Block, plan model.YardPlan) (*occupancy.Grid, error) {
+	if s.occupancy != nil {
+		if grid, ok := s.occupancy.Snapshot(block.ID); ok {
+			return grid, nil
+		}
+	}
+
+	occupied, err := s.containerRepo.GetOccupiedPositionsInArea(ctx,
+		block.ID,
+		plan.SlotStart,
+		plan.SlotEnd,
+		plan.RowStart,
+		plan.RowEnd,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	grid := occupancy.NewGrid(block.MaxSlot, block.MaxRow, block.MaxTier)
+	for _, c := range occupied {
+		grid.Occupy(c.Slot, c.Row, c.Tier, c.ContainerSize)
+	}
+	return grid, nil
+}
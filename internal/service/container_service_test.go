@@ -1,11 +1,46 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/internal/repository/memory"
+	"github.com/dwipurnomo515/yard-planning/internal/repository/repotest"
+	"github.com/dwipurnomo515/yard-planning/migrations"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/dwipurnomo515/yard-planning/pkg/database"
+	"github.com/dwipurnomo515/yard-planning/pkg/migrate"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// newMemoryService returns a service on a seeded in-memory store
+func newMemoryService(t *testing.T) (*ContainerService, repository.Stores) {
+	store := memory.NewStore()
+	require.NoError(t, store.Seed())
+	stores := store.Stores()
+
+	s := NewContainerService(stores.Yards, stores.Blocks, stores.Plans, stores.Containers,
+		stores.Closures, stores.Equipment, stores.WorkOrders)
+	s.SetTransactions(stores.Atomic)
+	return s, stores
+}
+
+// failingWorkOrders is a work order store whose inserts fail
+type failingWorkOrders struct {
+	repository.WorkOrderStore
+}
+
+func (failingWorkOrders) Create(ctx context.Context, order *model.WorkOrder) error {
+	return errors.New("work order insert failed")
+}
+
 func TestContainerService_ValidateContainerSpec(t *testing.T) {
 	service := &ContainerService{}
 
@@ -64,3 +99,369 @@ func TestContainerService_ValidateContainerSpec(t *testing.T) {
 		})
 	}
 }
+
+func TestFindClosure(t *testing.T) {
+	blockID := 1
+	slotStart, slotEnd := 3, 4
+	closures := []model.BlockClosure{
+		{ID: 1, BlockID: &blockID, SlotStart: &slotStart, SlotEnd: &slotEnd, Reason: "crane repair"},
+	}
+
+	tests := []struct {
+		name          string
+		blockID       int
+		slot          int
+		containerSize int
+		want          bool
+	}{
+		{name: "20ft inside closed range", blockID: 1, slot: 3, containerSize: 20, want: true},
+		{name: "20ft outside closed range", blockID: 1, slot: 2, containerSize: 20, want: false},
+		{name: "40ft spanning into closed range", blockID: 1, slot: 2, containerSize: 40, want: true},
+		{name: "other block", blockID: 2, slot: 3, containerSize: 20, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findClosure(closures, tt.blockID, tt.slot, 1, tt.containerSize)
+			assert.Equal(t, tt.want, got != nil)
+		})
+	}
+
+	t.Run("whole yard closure covers every block", func(t *testing.T) {
+		yardClosure := []model.BlockClosure{{ID: 2}}
+		assert.NotNil(t, findClosure(yardClosure, 7, 1, 1, 20))
+	})
+}
+
+func TestFindReservation(t *testing.T) {
+	reservations := []model.WorkOrder{
+		{ID: 1, ContainerSize: 40, To: &model.WorkOrderLocation{BlockID: 1, Slot: 4, Row: 2, Tier: 1}},
+		{ID: 2, ContainerSize: 20, To: &model.WorkOrderLocation{BlockID: 1, Slot: 1, Row: 1, Tier: 1}},
+	}
+
+	tests := []struct {
+		name          string
+		slot, row     int
+		tier          int
+		containerSize int
+		wantID        int
+	}{
+		{name: "same cell", slot: 1, row: 1, tier: 1, containerSize: 20, wantID: 2},
+		{name: "second slot of reserved 40ft", slot: 5, row: 2, tier: 1, containerSize: 20, wantID: 1},
+		{name: "40ft overlapping reserved 40ft", slot: 3, row: 2, tier: 1, containerSize: 40, wantID: 1},
+		{name: "next to reserved 40ft", slot: 6, row: 2, tier: 1, containerSize: 20, wantID: 0},
+		{name: "other tier", slot: 1, row: 1, tier: 2, containerSize: 20, wantID: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findReservation(reservations, 1, tt.slot, tt.row, tt.tier, tt.containerSize)
+			if tt.wantID == 0 {
+				assert.Nil(t, got)
+			} else {
+				assert.NotNil(t, got)
+				assert.Equal(t, tt.wantID, got.ID)
+			}
+		})
+	}
+}
+
+func TestPlacementStacks(t *testing.T) {
+	reqs := []model.PlacementRequest{
+		{Yard: "YRD1", Block: "LC01", Slot: 1, Row: 1, Tier: 3},
+		{Yard: "YRD1", Block: "LC01", Slot: 2, Row: 1, Tier: 1},
+		{Yard: "YRD1", Block: "LC01", Slot: 1, Row: 1, Tier: 1},
+		{Yard: "YRD1", Block: "LC01", Slot: 1, Row: 1, Tier: 2},
+		{Yard: "YRD1", Block: "LC01", Slot: 1, Row: 2, Tier: 1},
+		{Yard: "YRD1", Block: "LC02", Slot: 1, Row: 1, Tier: 2},
+		{Yard: "YRD1", Block: "LC01", Slot: 2, Row: 1, Tier: 1},
+	}
+
+	assert.Equal(t, [][]int{
+		// Tier 3 on tier 2 on tier 1 of the same cell
+		{2, 3, 0},
+		// Two items aiming at the same cell, first one first
+		{1, 6},
+		{4},
+		{5},
+	}, PlacementStacks(reqs))
+}
+
+func TestBatchPlanner_ImproveSwapsTowardsTargets(t *testing.T) {
+	block := &model.Block{ID: 1, Code: "LC01", BlockGeometry: model.BlockGeometry{SlotPitch: 6, RowPitch: 3}}
+	gate := []model.Point{{X: 0, Y: 0}}
+	berth := []model.Point{{X: 60, Y: 0}}
+
+	reqs := []model.SuggestionRequest{
+		{ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY", Movement: model.MovementExport},
+		{ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY", Movement: model.MovementImport},
+		{ContainerSize: 20, ContainerHeight: 9.6, ContainerType: "DRY", Movement: model.MovementImport},
+	}
+	results := []BatchSuggestion{
+		{Suggestion: &model.Suggestion{Position: model.Position{Block: "LC01", Slot: 1, Row: 1, Tier: 1}}},
+		{Suggestion: &model.Suggestion{Position: model.Position{Block: "LC01", Slot: 10, Row: 1, Tier: 1}}},
+		{Suggestion: &model.Suggestion{Position: model.Position{Block: "LC01", Slot: 9, Row: 1, Tier: 1}}},
+	}
+
+	p := &batchPlanner{
+		service: &ContainerService{weights: DefaultSuggestionWeights()},
+		assigned: []*assignment{
+			{yardID: 1, block: block, size: 20, targets: berth},
+			{yardID: 1, block: block, size: 20, targets: gate},
+			{yardID: 1, block: block, size: 20, targets: gate},
+		},
+	}
+	p.improve(reqs, results)
+
+	// The export and import containers trade cells, the high cube can not
+	assert.Equal(t, 10, results[0].Suggestion.Position.Slot)
+	assert.Equal(t, 1, results[1].Suggestion.Position.Slot)
+	assert.Equal(t, 9, results[2].Suggestion.Position.Slot)
+}
+
+func TestCanTransitionWorkOrder(t *testing.T) {
+	assert.True(t, model.CanTransitionWorkOrder(model.WorkOrderStatusCreated, model.WorkOrderStatusDispatched))
+	assert.True(t, model.CanTransitionWorkOrder(model.WorkOrderStatusDispatched, model.WorkOrderStatusCompleted))
+	assert.True(t, model.CanTransitionWorkOrder(model.WorkOrderStatusInProgress, model.WorkOrderStatusFailed))
+	assert.False(t, model.CanTransitionWorkOrder(model.WorkOrderStatusCreated, model.WorkOrderStatusCompleted))
+	assert.False(t, model.CanTransitionWorkOrder(model.WorkOrderStatusCompleted, model.WorkOrderStatusFailed))
+}
+
+func TestContainerService_WorkOrderFailureKeepsContainer(t *testing.T) {
+	ctx := context.Background()
+	s, stores := newMemoryService(t)
+
+	place := model.PlacementRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 1, Row: 1, Tier: 1}
+	require.NoError(t, s.PlaceContainer(ctx, place))
+
+	// Work orders can not be written from now on
+	s.SetTransactions(func(ctx context.Context, fn func(tx repository.Stores) error) error {
+		return stores.Atomic(ctx, func(tx repository.Stores) error {
+			tx.WorkOrders = failingWorkOrders{tx.WorkOrders}
+			return fn(tx)
+		})
+	})
+
+	err := s.PlaceContainer(ctx, model.PlacementRequest{Yard: "YRD1", ContainerNumber: "ABCU7654323", Block: "LC01", Slot: 2, Row: 1, Tier: 1})
+	require.Error(t, err)
+	_, err = stores.Containers.GetByNumber(ctx, "ABCU7654323")
+	assert.Error(t, err, "placement without work order was kept")
+
+	move := model.MoveRequest{Yard: "YRD1", ContainerNumber: place.ContainerNumber, Block: "LC01", Slot: 3, Row: 1, Tier: 1}
+	require.Error(t, s.MoveContainer(ctx, move))
+	require.Error(t, s.PickupContainer(ctx, model.PickupRequest{Yard: "YRD1", ContainerNumber: place.ContainerNumber}))
+
+	container, err := stores.Containers.GetByNumber(ctx, place.ContainerNumber)
+	require.NoError(t, err)
+	assert.Equal(t, 1, container.Slot, "move without work order was kept")
+}
+
+// newSQLiteService returns a service on a migrated SQLite database loaded with
+// the repository test fixture
+func newSQLiteService(t *testing.T) (*ContainerService, repository.Stores) {
+	db, err := database.NewSQLiteDB(database.SQLiteConfig{Path: t.TempDir() + "/yard.db"})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrate.New(db, migrate.DialectSQLite, migrations.SQLite())
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+	_, err = db.Exec(repotest.FixtureSQL)
+	require.NoError(t, err)
+
+	stores := repository.NewSQLStores(db)
+	s := NewContainerService(stores.Yards, stores.Blocks, stores.Plans, stores.Containers,
+		stores.Closures, stores.Equipment, stores.WorkOrders)
+	s.SetTransactions(stores.Atomic)
+	return s, stores
+}
+
+func TestContainerService_ConcurrentPlacementsTakeCellOnce(t *testing.T) {
+	backends := []struct {
+		name string
+		open func(t *testing.T) (*ContainerService, repository.Stores)
+	}{
+		{"memory", newMemoryService},
+		{"sqlite", newSQLiteService},
+	}
+
+	for _, backend := range backends {
+		for _, confirmation := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/confirmation=%t", backend.name, confirmation), func(t *testing.T) {
+				ctx := context.Background()
+				// No yard lock: the transaction alone keeps the cell for one placement
+				s, stores := backend.open(t)
+				s.SetWorkOrderConfirmation(confirmation)
+				yard := yardByCode(t, stores, "YRD1")
+
+				const placements = 10
+				errs := make(chan error, placements)
+				var wg sync.WaitGroup
+				for i := 0; i < placements; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						errs <- s.PlaceContainer(ctx, model.PlacementRequest{
+							Yard: "YRD1", ContainerNumber: fmt.Sprintf("ABCU%07d", i), Block: "LC01", Slot: 1, Row: 1, Tier: 1,
+						})
+					}(i)
+				}
+				wg.Wait()
+				close(errs)
+
+				placed := 0
+				for err := range errs {
+					if err == nil {
+						placed++
+						continue
+					}
+					assert.Contains(t, []apperror.Code{apperror.CodePositionOccupied, apperror.CodePositionReserved},
+						apperror.CodeOf(err), "%v", err)
+				}
+				assert.Equal(t, 1, placed)
+
+				orders, err := stores.WorkOrders.GetPendingByYardID(ctx, yard.ID)
+				require.NoError(t, err)
+				assert.Len(t, orders, 1)
+			})
+		}
+	}
+}
+
+// closeYard closes every block of a yard for the next hour
+func closeYard(t *testing.T, stores repository.Stores, yardID int) {
+	require.NoError(t, stores.Closures.Create(context.Background(), &model.BlockClosure{
+		YardID: yardID, Reason: "full", StartsAt: time.Now().Add(-time.Hour), EndsAt: time.Now().Add(time.Hour),
+	}))
+}
+
+// addYard adds a yard with one block for 20ft DRY containers
+func addYard(t *testing.T, stores repository.Stores, code string) *model.Yard {
+	ctx := context.Background()
+	yard := &model.Yard{Code: code, Name: code}
+	require.NoError(t, stores.Yards.(*memory.YardRepository).Create(ctx, yard))
+	block := &model.Block{YardID: yard.ID, Code: "B01", Name: "B01", MaxSlot: 4, MaxRow: 2, MaxTier: 2}
+	require.NoError(t, stores.Blocks.(*memory.BlockRepository).Create(ctx, block))
+	require.NoError(t, stores.Plans.Create(ctx, &model.YardPlan{
+		BlockID: block.ID, SlotStart: 1, SlotEnd: 4, RowStart: 1, RowEnd: 2,
+		ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY",
+	}))
+	return yard
+}
+
+// addOverflowRule routes a yard to another yard
+func addOverflowRule(t *testing.T, stores repository.Stores, from, to *model.Yard, priority int) {
+	require.NoError(t, stores.Yards.CreateOverflowRule(context.Background(), &model.OverflowRule{
+		YardID: from.ID, OverflowYardID: to.ID, Priority: priority,
+	}))
+}
+
+func yardByCode(t *testing.T, stores repository.Stores, code string) *model.Yard {
+	yard, err := stores.Yards.GetByCode(context.Background(), code)
+	require.NoError(t, err)
+	return yard
+}
+
+var dry20 = model.SuggestionRequest{ContainerNumber: "ABCU1234560", ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY"}
+
+func TestContainerService_OverflowWhenYardFull(t *testing.T) {
+	ctx := context.Background()
+	s, stores := newMemoryService(t)
+	req := dry20
+	req.Yard = "YRD1"
+
+	suggestion, err := s.GetSuggestion(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "YRD1", suggestion.Yard)
+	assert.False(t, suggestion.Overflow)
+
+	// Fill every cell of the 20ft plan of LC01
+	n := 0
+	for slot := 1; slot <= 3; slot++ {
+		for row := 1; row <= 5; row++ {
+			for tier := 1; tier <= 5; tier++ {
+				n++
+				require.NoError(t, stores.Containers.Create(ctx, &model.Container{
+					ContainerNumber: fmt.Sprintf("FULU%07d", n), YardID: 1, BlockID: 1,
+					Slot: slot, Row: row, Tier: tier, ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY",
+				}))
+			}
+		}
+	}
+
+	suggestion, err = s.GetSuggestion(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "DEPOT1", suggestion.Yard)
+	assert.True(t, suggestion.Overflow)
+	assert.Equal(t, "OD01", suggestion.Position.Block)
+	assert.Contains(t, suggestion.Reason, "no matching capacity in YRD1")
+}
+
+func TestContainerService_OverflowPriority(t *testing.T) {
+	ctx := context.Background()
+	s, stores := newMemoryService(t)
+	yrd1 := yardByCode(t, stores, "YRD1")
+	depot1 := yardByCode(t, stores, "DEPOT1")
+
+	// YRD1 routes to DEPOT1 (priority 1), then DEPOT3 (2), then DEPOT2 (3)
+	depot2 := addYard(t, stores, "DEPOT2")
+	depot3 := addYard(t, stores, "DEPOT3")
+	addOverflowRule(t, stores, yrd1, depot2, 3)
+	addOverflowRule(t, stores, yrd1, depot3, 2)
+
+	closeYard(t, stores, yrd1.ID)
+	closeYard(t, stores, depot1.ID)
+
+	req := dry20
+	req.Yard = "YRD1"
+	suggestion, err := s.GetSuggestion(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "DEPOT3", suggestion.Yard)
+	assert.True(t, suggestion.Overflow)
+	assert.Equal(t, "no matching capacity in YRD1, DEPOT1; overflow rule priority 2 routes to DEPOT3", suggestion.Reason)
+
+	closeYard(t, stores, depot3.ID)
+	suggestion, err = s.GetSuggestion(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "DEPOT2", suggestion.Yard)
+}
+
+func TestContainerService_OverflowWithoutRule(t *testing.T) {
+	ctx := context.Background()
+	s, stores := newMemoryService(t)
+
+	// DEPOT1 has no overflow rule of its own
+	closeYard(t, stores, yardByCode(t, stores, "DEPOT1").ID)
+	req := dry20
+	req.Yard = "DEPOT1"
+	_, err := s.GetSuggestion(ctx, req)
+	assert.Equal(t, apperror.CodeNoCapacity, apperror.CodeOf(err))
+}
+
+func TestContainerService_OverflowCycle(t *testing.T) {
+	ctx := context.Background()
+	s, stores := newMemoryService(t)
+	yrd1 := yardByCode(t, stores, "YRD1")
+	depot1 := yardByCode(t, stores, "DEPOT1")
+
+	// YRD1 and DEPOT1 overflow into each other
+	addOverflowRule(t, stores, depot1, yrd1, 1)
+
+	closeYard(t, stores, depot1.ID)
+	req := dry20
+	req.Yard = "DEPOT1"
+	suggestion, err := s.GetSuggestion(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "YRD1", suggestion.Yard)
+	assert.True(t, suggestion.Overflow)
+
+	// Only the rules of the requested yard are followed, so a cycle ends
+	// after one hop
+	closeYard(t, stores, yrd1.ID)
+	_, err = s.GetSuggestion(ctx, req)
+	assert.Equal(t, apperror.CodeNoCapacity, apperror.CodeOf(err))
+	req.Yard = "YRD1"
+	_, err = s.GetSuggestion(ctx, req)
+	assert.Equal(t, apperror.CodeNoCapacity, apperror.CodeOf(err))
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+
+	"github.com/dwipurnomo515/yard-planning/internal/auth"
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+type EquipmentService struct {
+	yardRepo      repository.YardStore
+	blockRepo     repository.BlockStore
+	equipmentRepo repository.EquipmentStore
+}
+
+func NewEquipmentService(
+	yardRepo repository.YardStore,
+	blockRepo repository.BlockStore,
+	equipmentRepo repository.EquipmentStore,
+) *EquipmentService {
+	return &EquipmentService{
+		yardRepo:      yardRepo,
+		blockRepo:     blockRepo,
+		equipmentRepo: equipmentRepo,
+	}
+}
+
+// ListEquipment returns the equipment of a yard with its current workload
+func (s *EquipmentService) ListEquipment(ctx context.Context, yardCode string) ([]model.Equipment, error) {
+	// Check the role of the caller in the yard
+	if err := auth.Authorize(ctx, auth.PermView, yardCode); err != nil {
+		return nil, err
+	}
+
+	// Get yard
+	yard, err := s.yardRepo.GetByCode(ctx, yardCode)
+	if err != nil {
+		return nil, err
+	}
+
+	equipment, err := s.equipmentRepo.GetByYardID(ctx, yard.ID)
+	if err != nil {
+		return nil, err
+	}
+	if equipment == nil {
+		equipment = []model.Equipment{}
+	}
+
+	return equipment, nil
+}
+
+// CreateEquipment registers a machine and the blocks it serves
+func (s *EquipmentService) CreateEquipment(ctx context.Context, req model.EquipmentRequest) (*model.Equipment, error) {
+	// Check the role of the caller in the yard
+	if err := auth.Authorize(ctx, auth.PermPlan, req.Yard); err != nil {
+		return nil, err
+	}
+
+	// Validate input
+	if req.Code == "" {
+		return nil, apperror.Required("code")
+	}
+	validTypes := map[string]bool{
+		model.EquipmentTypeRTG:          true,
+		model.EquipmentTypeRMG:          true,
+		model.EquipmentTypeReachStacker: true,
+	}
+	if !validTypes[req.EquipmentType] {
+		return nil, apperror.Validation("equipment_type", "must be RTG, RMG, or REACH_STACKER")
+	}
+
+	// Get yard
+	yard, err := s.yardRepo.GetByCode(ctx, req.Yard)
+	if err != nil {
+		return nil, err
+	}
+
+	equipment := &model.Equipment{
+		YardID:        yard.ID,
+		Code:          req.Code,
+		EquipmentType: req.EquipmentType,
+		Active:        true,
+		Blocks:        []string{},
+	}
+
+	// Resolve served blocks
+	for _, code := range req.Blocks {
+		block, err := s.blockRepo.GetByYardAndCode(ctx, yard.ID, code)
+		if err != nil {
+			return nil, err
+		}
+		equipment.BlockIDs = append(equipment.BlockIDs, block.ID)
+		equipment.Blocks = append(equipment.Blocks, block.Code)
+	}
+
+	if err := s.equipmentRepo.Create(ctx, equipment); err != nil {
+		return nil, err
+	}
+
+	return equipment, nil
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockGeometry_CellCenter(t *testing.T) {
+	g := model.BlockGeometry{OriginX: 100, OriginY: 50, SlotPitch: 6, RowPitch: 3}
+
+	t.Run("20ft along x axis", func(t *testing.T) {
+		p := g.CellCenter(1, 1, 20)
+		assert.InDelta(t, 103.0, p.X, 1e-9)
+		assert.InDelta(t, 51.5, p.Y, 1e-9)
+	})
+
+	t.Run("40ft spans two slots", func(t *testing.T) {
+		p := g.CellCenter(2, 2, 40)
+		assert.InDelta(t, 112.0, p.X, 1e-9)
+		assert.InDelta(t, 54.5, p.Y, 1e-9)
+	})
+
+	t.Run("rotated block", func(t *testing.T) {
+		rotated := g
+		rotated.Orientation = 90
+		p := rotated.CellCenter(1, 1, 20)
+		assert.InDelta(t, 98.5, p.X, 1e-9)
+		assert.InDelta(t, 53.0, p.Y, 1e-9)
+	})
+}
+
+func TestNearestDistance(t *testing.T) {
+	targets := []model.Point{{X: 0, Y: 0}, {X: 100, Y: 100}}
+
+	assert.Equal(t, 30.0, nearestDistance(model.Point{X: 10, Y: 20}, targets))
+	assert.Equal(t, 15.0, nearestDistance(model.Point{X: 95, Y: 110}, targets))
+}
+
+func TestSuggestionWeights_PrefersCloserCell(t *testing.T) {
+	w := SuggestionWeights{WorkloadPenalty: 1, DistanceWeight: 0.05}
+	queue := &model.Equipment{OutstandingOrders: 2}
+
+	near := w.scoreCandidate(candidate{equipment: queue, distance: 40})
+	far := w.scoreCandidate(candidate{equipment: queue, distance: 400})
+	assert.Less(t, near, far)
+
+	// A long queue outweighs a short walk
+	busyNear := w.scoreCandidate(candidate{equipment: &model.Equipment{OutstandingOrders: 30}, distance: 40})
+	assert.Less(t, far, busyNear)
+}
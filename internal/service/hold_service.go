@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+
+	"github.com/dwipurnomo515/yard-planning/internal/auth"
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+// HoldService puts containers on hold and releases them. Planners place
+// holds; only supervisors release them or pick up a held container.
+type HoldService struct {
+	yardRepo      repository.YardStore
+	containerRepo repository.ContainerStore
+}
+
+func NewHoldService(yardRepo repository.YardStore, containerRepo repository.ContainerStore) *HoldService {
+	return &HoldService{
+		yardRepo:      yardRepo,
+		containerRepo: containerRepo,
+	}
+}
+
+// HoldContainer puts a container on hold for the given reason
+func (s *HoldService) HoldContainer(ctx context.Context, req model.HoldRequest) error {
+	// Check the role of the caller in the yard
+	if err := auth.Authorize(ctx, auth.PermPlan, req.Yard); err != nil {
+		return err
+	}
+
+	// Validate input
+	if req.ContainerNumber == "" || req.Reason == "" {
+		return apperror.Required("container_number", "reason")
+	}
+
+	if _, err := s.container(ctx, req.Yard, req.ContainerNumber); err != nil {
+		return err
+	}
+
+	return s.containerRepo.SetHold(ctx, req.ContainerNumber, req.Reason)
+}
+
+// ReleaseHold overrides the hold of a container
+func (s *HoldService) ReleaseHold(ctx context.Context, req model.HoldRequest) error {
+	// Check the role of the caller in the yard
+	if err := auth.Authorize(ctx, auth.PermOverrideHold, req.Yard); err != nil {
+		return err
+	}
+
+	// Validate input
+	if req.ContainerNumber == "" {
+		return apperror.Required("container_number")
+	}
+
+	container, err := s.container(ctx, req.Yard, req.ContainerNumber)
+	if err != nil {
+		return err
+	}
+	if container.Hold == "" {
+		return apperror.New(apperror.CodeConflict, "container '%s' is not on hold", req.ContainerNumber)
+	}
+
+	return s.containerRepo.SetHold(ctx, req.ContainerNumber, "")
+}
+
+// container returns a container stored in the yard
+func (s *HoldService) container(ctx context.Context, yardCode, containerNumber string) (*model.Container, error) {
+	yard, err := s.yardRepo.GetByCode(ctx, yardCode)
+	if err != nil {
+		return nil, err
+	}
+
+	container, err := s.containerRepo.GetByNumber(ctx, containerNumber)
+	if err != nil {
+		return nil, err
+	}
+	if container.YardID != yard.ID {
+		return nil, apperror.New(apperror.CodeContainerNotFound, "container '%s' not found in yard '%s'", containerNumber, yardCode)
+	}
+
+	return container, nil
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+
+	"github.com/dwipurnomo515/yard-planning/internal/auth"
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+type LayoutService struct {
+	yardRepo  repository.YardStore
+	blockRepo repository.BlockStore
+}
+
+func NewLayoutService(
+	yardRepo repository.YardStore,
+	blockRepo repository.BlockStore,
+) *LayoutService {
+	return &LayoutService{
+		yardRepo:  yardRepo,
+		blockRepo: blockRepo,
+	}
+}
+
+// GetLayout returns the block geometry and points of interest of a yard
+func (s *LayoutService) GetLayout(ctx context.Context, yardCode string) (*model.YardLayout, error) {
+	// Check the role of the caller in the yard
+	if err := auth.Authorize(ctx, auth.PermView, yardCode); err != nil {
+		return nil, err
+	}
+
+	// Get yard
+	yard, err := s.yardRepo.GetByCode(ctx, yardCode)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, err := s.blockRepo.GetByYardID(ctx, yard.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	points, err := s.yardRepo.GetPointsByYardID(ctx, yard.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := &model.YardLayout{
+		Yard:   yard.Code,
+		Blocks: blocks,
+		Points: points,
+	}
+	if layout.Blocks == nil {
+		layout.Blocks = []model.Block{}
+	}
+	if layout.Points == nil {
+		layout.Points = []model.YardPoint{}
+	}
+
+	return layout, nil
+}
+
+// CreatePoint adds a gate, berth or rail point to a yard
+func (s *LayoutService) CreatePoint(ctx context.Context, req model.YardPointRequest) (*model.YardPoint, error) {
+	// Check the role of the caller in the yard
+	if err := auth.Authorize(ctx, auth.PermPlan, req.Yard); err != nil {
+		return nil, err
+	}
+
+	// Validate input
+	if req.Code == "" {
+		return nil, apperror.Required("code")
+	}
+	validTypes := map[string]bool{
+		model.PointTypeGate:  true,
+		model.PointTypeBerth: true,
+		model.PointTypeRail:  true,
+	}
+	if !validTypes[req.PointType] {
+		return nil, apperror.Validation("point_type", "must be GATE, BERTH, or RAIL")
+	}
+
+	// Get yard
+	yard, err := s.yardRepo.GetByCode(ctx, req.Yard)
+	if err != nil {
+		return nil, err
+	}
+
+	point := &model.YardPoint{
+		YardID:    yard.ID,
+		Code:      req.Code,
+		Name:      req.Name,
+		PointType: req.PointType,
+		X:         req.X,
+		Y:         req.Y,
+	}
+	if point.Name == "" {
+		point.Name = req.Code
+	}
+
+	if err := s.yardRepo.CreatePoint(ctx, point); err != nil {
+		return nil, err
+	}
+
+	return point, nil
+}
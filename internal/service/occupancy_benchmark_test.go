@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/occupancy"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/migrations"
+	"github.com/dwipurnomo515/yard-planning/pkg/database"
+	"github.com/dwipurnomo515/yard-planning/pkg/migrate"
+)
+
+// BenchmarkGetSuggestion compares reading occupied cells from SQLite with the
+// in-memory occupancy index, on a yard whose 20ft area is filled up to tier 4
+func BenchmarkGetSuggestion(b *testing.B) {
+	ctx := context.Background()
+	stores := benchmarkStores(b)
+	req := model.SuggestionRequest{
+		Yard: "YRD1", ContainerNumber: "BENCH0000001",
+		ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY",
+	}
+
+	b.Run("database", func(b *testing.B) {
+		s := NewContainerService(stores.Yards, stores.Blocks, stores.Plans, stores.Containers,
+			stores.Closures, stores.Equipment, stores.WorkOrders)
+		runSuggestions(b, s, req)
+	})
+
+	b.Run("index", func(b *testing.B) {
+		index := occupancy.NewIndex()
+		if err := index.Load(ctx, stores); err != nil {
+			b.Fatal(err)
+		}
+		s := NewContainerService(stores.Yards, stores.Blocks, stores.Plans, stores.Containers,
+			stores.Closures, stores.Equipment, stores.WorkOrders)
+		s.SetOccupancyIndex(index)
+		runSuggestions(b, s, req)
+	})
+}
+
+func runSuggestions(b *testing.B, s *ContainerService, req model.SuggestionRequest) {
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		suggestion, err := s.GetSuggestion(ctx, req)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if suggestion.Position.Tier != 4 {
+			b.Fatalf("expected tier 4, got %d", suggestion.Position.Tier)
+		}
+	}
+}
+
+func benchmarkStores(b *testing.B) repository.Stores {
+	ctx := context.Background()
+	db, err := database.NewSQLiteDB(database.SQLiteConfig{Path: b.TempDir() + "/yard.db"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+
+	migrator, err := migrate.New(db, migrate.DialectSQLite, migrations.SQLite())
+	if err != nil {
+		b.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		b.Fatal(err)
+	}
+	if err := migrator.Seed(context.Background(), migrations.SQLiteSeed); err != nil {
+		b.Fatal(err)
+	}
+
+	stores := repository.NewSQLStores(db)
+	for tier := 1; tier <= 3; tier++ {
+		for slot := 1; slot <= 3; slot++ {
+			for row := 1; row <= 5; row++ {
+				err := stores.Containers.Create(ctx, &model.Container{
+					ContainerNumber: fmt.Sprintf("BENC%d%d%d", slot, row, tier),
+					YardID:          1, BlockID: 1, Slot: slot, Row: row, Tier: tier,
+					ContainerSize: 20, ContainerHeight: 8.6, ContainerType: "DRY",
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+	return stores
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"sort"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+)
+
+// PlacementStacks orders the items of a bulk placement by their dependencies.
+// An item depends on the items of the batch it is stacked on: the tier below
+// in the same block and row, on any slot its container uses, so a 40ft
+// container supports the 20ft containers on both of its slots. Items aiming
+// at the same cell are linked too, so the first of them in the batch wins.
+//
+// Linked items end up in the same stack, listed lowest tier first, which
+// places every item after the items it depends on. Stacks do not depend on
+// each other and can be placed in parallel. The result holds the indexes of
+// the items, with the stacks ordered by their first item in the batch.
+func PlacementStacks(reqs []model.PlacementRequest) [][]int {
+	parent := make([]int, len(reqs))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	// Only items in the same row of a block can depend on each other
+	type rowKey struct {
+		yard, block string
+		row         int
+	}
+	rows := make(map[rowKey][]int)
+	for i, req := range reqs {
+		key := rowKey{yard: req.Yard, block: req.Block, row: req.Row}
+		rows[key] = append(rows[key], i)
+	}
+
+	for _, items := range rows {
+		for x, i := range items {
+			for _, j := range items[x+1:] {
+				if dependent(reqs[i], reqs[j]) {
+					a, b := find(i), find(j)
+					// The stack is named after its first item
+					if b < a {
+						a, b = b, a
+					}
+					parent[b] = a
+				}
+			}
+		}
+	}
+
+	byRoot := make(map[int][]int)
+	var roots []int
+	for i := range reqs {
+		root := find(i)
+		if _, ok := byRoot[root]; !ok {
+			roots = append(roots, root)
+		}
+		byRoot[root] = append(byRoot[root], i)
+	}
+
+	stacks := make([][]int, 0, len(roots))
+	for _, root := range roots {
+		stack := byRoot[root]
+		sort.SliceStable(stack, func(a, b int) bool {
+			return reqs[stack[a]].Tier < reqs[stack[b]].Tier
+		})
+		stacks = append(stacks, stack)
+	}
+	return stacks
+}
+
+// dependent reports whether one of two items in the same row stacks on the
+// other or both aim at the same cell
+func dependent(a, b model.PlacementRequest) bool {
+	tiers := a.Tier - b.Tier
+	if tiers < -1 || tiers > 1 {
+		return false
+	}
+	return spansOverlap(a.Slot, placementSize, b.Slot, placementSize)
+}
@@ -0,0 +1,351 @@
+package service
+
+import (
+	"context"
+
+	"github.com/dwipurnomo515/yard-planning/internal/auth"
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/occupancy"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/dwipurnomo515/yard-planning/pkg/cache"
+)
+
+// PlanningService edits blocks and their yard plans. Every write names the
+// version it was based on, so concurrent edits by two planners are detected
+// instead of overwriting each other.
+type PlanningService struct {
+	yardRepo      repository.YardStore
+	blockRepo     repository.BlockStore
+	planRepo      repository.YardPlanStore
+	containerRepo repository.ContainerStore
+	occupancy     *occupancy.Index
+	atomic        repository.Transactor
+	locker        cache.Locker
+}
+
+func NewPlanningService(
+	yardRepo repository.YardStore,
+	blockRepo repository.BlockStore,
+	planRepo repository.YardPlanStore,
+	containerRepo repository.ContainerStore,
+) *PlanningService {
+	return &PlanningService{
+		yardRepo:      yardRepo,
+		blockRepo:     blockRepo,
+		planRepo:      planRepo,
+		containerRepo: containerRepo,
+	}
+}
+
+// SetOccupancyIndex makes block resizes update the in-memory occupancy index
+func (s *PlanningService) SetOccupancyIndex(index *occupancy.Index) {
+	s.occupancy = index
+}
+
+// SetTransactions makes a block resize check the containers and plans of the
+// block in the transaction that saves it
+func (s *PlanningService) SetTransactions(atomic repository.Transactor) {
+	s.atomic = atomic
+}
+
+// SetLocker makes a block resize take the lock of its yard that placements,
+// pickups and moves take, so a container is never put outside a block that
+// shrinks meanwhile. The lock must be the one given to ContainerService.
+func (s *PlanningService) SetLocker(locker cache.Locker) {
+	s.locker = locker
+}
+
+// GetBlock returns a block of a yard
+func (s *PlanningService) GetBlock(ctx context.Context, yardCode, blockCode string) (*model.Block, error) {
+	// Check the role of the caller in the yard
+	if err := auth.Authorize(ctx, auth.PermView, yardCode); err != nil {
+		return nil, err
+	}
+
+	yard, err := s.yardRepo.GetByCode(ctx, yardCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.blockRepo.GetByYardAndCode(ctx, yard.ID, blockCode)
+}
+
+// UpdateBlock replaces the name, dimensions and geometry of a block that is
+// still at the given version. A block can not shrink below the containers
+// stacked in it or the yard plans drawn on it.
+func (s *PlanningService) UpdateBlock(ctx context.Context, yardCode, blockCode string, req model.BlockRequest, version int) (*model.Block, error) {
+	// Check the role of the caller in the yard
+	if err := auth.Authorize(ctx, auth.PermPlan, yardCode); err != nil {
+		return nil, err
+	}
+
+	yard, err := s.yardRepo.GetByCode(ctx, yardCode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Placements check the block dimensions under the yard lock
+	ctx, unlock, err := lockYards(ctx, s.locker, yard.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	var resized model.Block
+	err = s.write(ctx, func(s *PlanningService) error {
+		block, err := s.blockRepo.GetByYardAndCode(ctx, yard.ID, blockCode)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(version, block.Version); err != nil {
+			return err
+		}
+
+		resized = *block
+		resized.Name = req.Name
+		resized.MaxSlot = req.MaxSlot
+		resized.MaxRow = req.MaxRow
+		resized.MaxTier = req.MaxTier
+		resized.BlockGeometry = req.BlockGeometry
+
+		if resized.MaxSlot < block.MaxSlot || resized.MaxRow < block.MaxRow || resized.MaxTier < block.MaxTier {
+			if err := s.checkFits(ctx, &resized); err != nil {
+				return err
+			}
+		}
+
+		return s.blockRepo.Update(ctx, &resized, version)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.occupancy != nil {
+		s.occupancy.Resize(resized)
+	}
+
+	return &resized, nil
+}
+
+// write runs fn in a transaction that fails when a yard lock of ctx was taken
+// over
+func (s *PlanningService) write(ctx context.Context, fn func(s *PlanningService) error) error {
+	if s.atomic == nil {
+		return fn(s)
+	}
+	return s.atomic(ctx, func(tx repository.Stores) error {
+		if err := checkFences(ctx, tx.Fences); err != nil {
+			return err
+		}
+		bound := *s
+		bound.yardRepo = tx.Yards
+		bound.blockRepo = tx.Blocks
+		bound.planRepo = tx.Plans
+		bound.containerRepo = tx.Containers
+		bound.atomic = tx.Atomic
+		return fn(&bound)
+	})
+}
+
+// checkFits returns a CONFLICT error when a container or a yard plan is
+// outside the new dimensions of a block
+func (s *PlanningService) checkFits(ctx context.Context, block *model.Block) error {
+	containers, err := s.containerRepo.GetByBlock(ctx, block.ID)
+	if err != nil {
+		return err
+	}
+	for _, c := range containers {
+		lastSlot := c.Slot + c.ContainerSize/20 - 1
+		if lastSlot > block.MaxSlot || c.Row > block.MaxRow || c.Tier > block.MaxTier {
+			return apperror.New(apperror.CodeConflict,
+				"container '%s' at slot %d, row %d, tier %d is outside the new block dimensions",
+				c.ContainerNumber, c.Slot, c.Row, c.Tier)
+		}
+	}
+
+	plans, err := s.planRepo.GetByBlockID(ctx, block.ID)
+	if err != nil {
+		return err
+	}
+	for _, p := range plans {
+		if p.SlotEnd > block.MaxSlot || p.RowEnd > block.MaxRow {
+			return apperror.New(apperror.CodeConflict,
+				"yard plan %d covers slots %d-%d and rows %d-%d, outside the new block dimensions",
+				p.ID, p.SlotStart, p.SlotEnd, p.RowStart, p.RowEnd)
+		}
+	}
+
+	return nil
+}
+
+// ListPlans returns the yard plans of a block
+func (s *PlanningService) ListPlans(ctx context.Context, yardCode, blockCode string) ([]model.YardPlan, error) {
+	block, err := s.GetBlock(ctx, yardCode, blockCode)
+	if err != nil {
+		return nil, err
+	}
+
+	plans, err := s.planRepo.GetByBlockID(ctx, block.ID)
+	if err != nil {
+		return nil, err
+	}
+	if plans == nil {
+		plans = []model.YardPlan{}
+	}
+
+	return plans, nil
+}
+
+// GetPlan returns a yard plan by ID
+func (s *PlanningService) GetPlan(ctx context.Context, id int) (*model.YardPlan, error) {
+	plan, yard, err := s.plan(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the role of the caller in the yard
+	if err := auth.Authorize(ctx, auth.PermView, yard.Code); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// CreatePlan adds a yard plan to a block
+func (s *PlanningService) CreatePlan(ctx context.Context, req model.YardPlanRequest) (*model.YardPlan, error) {
+	// Check the role of the caller in the yard
+	if err := auth.Authorize(ctx, auth.PermPlan, req.Yard); err != nil {
+		return nil, err
+	}
+
+	plan := &model.YardPlan{}
+	if err := s.applyPlan(ctx, plan, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.planRepo.Create(ctx, plan); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// UpdatePlan replaces a yard plan that is still at the given version. The plan
+// can be moved to another block the caller may plan.
+func (s *PlanningService) UpdatePlan(ctx context.Context, id int, req model.YardPlanRequest, version int) (*model.YardPlan, error) {
+	current, yard, err := s.plan(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the role of the caller in both yards
+	if err := auth.Authorize(ctx, auth.PermPlan, yard.Code); err != nil {
+		return nil, err
+	}
+	if err := auth.Authorize(ctx, auth.PermPlan, req.Yard); err != nil {
+		return nil, err
+	}
+	if err := checkVersion(version, current.Version); err != nil {
+		return nil, err
+	}
+
+	plan := &model.YardPlan{ID: current.ID}
+	if err := s.applyPlan(ctx, plan, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.planRepo.Update(ctx, plan, version); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// DeletePlan removes a yard plan that is still at the given version
+func (s *PlanningService) DeletePlan(ctx context.Context, id, version int) error {
+	current, yard, err := s.plan(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// Check the role of the caller in the yard
+	if err := auth.Authorize(ctx, auth.PermPlan, yard.Code); err != nil {
+		return err
+	}
+	if err := checkVersion(version, current.Version); err != nil {
+		return err
+	}
+
+	return s.planRepo.Delete(ctx, id, version)
+}
+
+// checkVersion turns away a write based on an old version before it is
+// checked further. The store checks the version again when writing, so a
+// change in between is still detected. A version of 0 matches any.
+func checkVersion(version, current int) error {
+	if version != 0 && version != current {
+		return apperror.New(apperror.CodePreconditionFailed,
+			"version %d does not match the current version %d", version, current)
+	}
+	return nil
+}
+
+// plan returns a yard plan with the yard it belongs to. Plans in yards of
+// other tenants are not found.
+func (s *PlanningService) plan(ctx context.Context, id int) (*model.YardPlan, *model.Yard, error) {
+	plan, err := s.planRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, err := s.blockRepo.GetByID(ctx, plan.BlockID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	yards, err := s.yardRepo.GetAll(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range yards {
+		if yards[i].ID == block.YardID {
+			return plan, &yards[i], nil
+		}
+	}
+
+	return nil, nil, apperror.New(apperror.CodeNotFound, "yard plan %d not found", id)
+}
+
+// applyPlan resolves the block of a request and copies the request to plan
+func (s *PlanningService) applyPlan(ctx context.Context, plan *model.YardPlan, req model.YardPlanRequest) error {
+	yard, err := s.yardRepo.GetByCode(ctx, req.Yard)
+	if err != nil {
+		return err
+	}
+	block, err := s.blockRepo.GetByYardAndCode(ctx, yard.ID, req.Block)
+	if err != nil {
+		return err
+	}
+
+	if err := validateRange("slot", &req.SlotStart, &req.SlotEnd, block.MaxSlot); err != nil {
+		return err
+	}
+	if err := validateRange("row", &req.RowStart, &req.RowEnd, block.MaxRow); err != nil {
+		return err
+	}
+	if req.StackingPriority == "" {
+		req.StackingPriority = "LEFT_TO_RIGHT"
+	}
+
+	plan.BlockID = block.ID
+	plan.SlotStart = req.SlotStart
+	plan.SlotEnd = req.SlotEnd
+	plan.RowStart = req.RowStart
+	plan.RowEnd = req.RowEnd
+	plan.ContainerSize = req.ContainerSize
+	plan.ContainerHeight = req.ContainerHeight
+	plan.ContainerType = req.ContainerType
+	plan.StackingPriority = req.StackingPriority
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+
+	"github.com/dwipurnomo515/yard-planning/internal/auth"
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+)
+
+// RoleBindingService manages the roles granted to callers. Every method
+// requires the admin permission.
+type RoleBindingService struct {
+	yardRepo repository.YardStore
+	roleRepo repository.RoleBindingStore
+}
+
+func NewRoleBindingService(yardRepo repository.YardStore, roleRepo repository.RoleBindingStore) *RoleBindingService {
+	return &RoleBindingService{yardRepo: yardRepo, roleRepo: roleRepo}
+}
+
+// ListBindings returns the role bindings of a subject, or all of them when
+// subject is empty
+func (s *RoleBindingService) ListBindings(ctx context.Context, subject string) ([]model.RoleBinding, error) {
+	if err := auth.Authorize(ctx, auth.PermAdmin, model.AllYards); err != nil {
+		return nil, err
+	}
+
+	var (
+		bindings []model.RoleBinding
+		err      error
+	)
+	if subject == "" {
+		bindings, err = s.roleRepo.GetAll(ctx)
+	} else {
+		bindings, err = s.roleRepo.GetBySubject(ctx, subject)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bindings == nil {
+		bindings = []model.RoleBinding{}
+	}
+
+	return bindings, nil
+}
+
+// CreateBinding grants a role to a subject in a yard, or in all yards when
+// no yard is given
+func (s *RoleBindingService) CreateBinding(ctx context.Context, req model.RoleBindingRequest) (*model.RoleBinding, error) {
+	if err := auth.Authorize(ctx, auth.PermAdmin, model.AllYards); err != nil {
+		return nil, err
+	}
+	if err := model.Validate(req); err != nil {
+		return nil, err
+	}
+
+	binding := &model.RoleBinding{Subject: req.Subject, Role: req.Role, Yard: req.Yard}
+	if binding.Yard == "" {
+		binding.Yard = model.AllYards
+	}
+	if binding.Yard != model.AllYards {
+		if _, err := s.yardRepo.GetByCode(ctx, binding.Yard); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.roleRepo.Create(ctx, binding); err != nil {
+		return nil, err
+	}
+
+	return binding, nil
+}
+
+// DeleteBinding revokes a role binding
+func (s *RoleBindingService) DeleteBinding(ctx context.Context, id int) error {
+	if err := auth.Authorize(ctx, auth.PermAdmin, model.AllYards); err != nil {
+		return err
+	}
+	return s.roleRepo.Delete(ctx, id)
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"math"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+)
+
+// SuggestionWeights controls how candidate positions from different blocks are ranked.
+// The candidate with the lowest score wins; ties keep block order.
+type SuggestionWeights struct {
+	// WorkloadPenalty is added for every outstanding work order queued on the
+	// equipment serving the candidate block. Zero disables load balancing.
+	WorkloadPenalty float64
+
+	// DistanceWeight is added per meter of travel between the candidate cell and
+	// the gate (imports) or berth (exports) the container is headed to.
+	DistanceWeight float64
+}
+
+// DefaultSuggestionWeights returns the weights used when none are configured
+func DefaultSuggestionWeights() SuggestionWeights {
+	return SuggestionWeights{
+		WorkloadPenalty: 1,
+		DistanceWeight:  0.05,
+	}
+}
+
+// candidate is a free position found in one block during suggestion
+type candidate struct {
+	position  model.Position
+	equipment *model.Equipment
+	distance  float64
+	score     float64
+}
+
+// scoreCandidate computes the ranking score of a candidate position
+func (w SuggestionWeights) scoreCandidate(c candidate) float64 {
+	score := w.DistanceWeight * c.distance
+	if c.equipment != nil {
+		score += w.WorkloadPenalty * float64(c.equipment.OutstandingOrders)
+	}
+	return score
+}
+
+// responsibleEquipment returns the active machine serving a block with the
+// shortest queue, or nil when no machine is assigned to the block
+func responsibleEquipment(equipment []model.Equipment, blockID int) *model.Equipment {
+	var best *model.Equipment
+	for i := range equipment {
+		e := &equipment[i]
+		if !e.Active || !e.Serves(blockID) {
+			continue
+		}
+		if best == nil || e.OutstandingOrders < best.OutstandingOrders {
+			best = e
+		}
+	}
+	return best
+}
+
+// nearestDistance returns the travel distance from a point to the closest target
+func nearestDistance(from model.Point, targets []model.Point) float64 {
+	nearest := math.Inf(1)
+	for _, t := range targets {
+		if d := model.Distance(from, t); d < nearest {
+			nearest = d
+		}
+	}
+	return nearest
+}
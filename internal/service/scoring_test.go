@@ -0,0 +1,45 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponsibleEquipment(t *testing.T) {
+	equipment := []model.Equipment{
+		{ID: 1, Code: "RTG01", Active: true, BlockIDs: []int{1, 2}, OutstandingOrders: 4},
+		{ID: 2, Code: "RTG02", Active: true, BlockIDs: []int{2}, OutstandingOrders: 1},
+		{ID: 3, Code: "RTG03", Active: false, BlockIDs: []int{2}, OutstandingOrders: 0},
+	}
+
+	t.Run("single machine", func(t *testing.T) {
+		e := responsibleEquipment(equipment, 1)
+		assert.NotNil(t, e)
+		assert.Equal(t, "RTG01", e.Code)
+	})
+
+	t.Run("shortest queue wins and inactive machines are ignored", func(t *testing.T) {
+		e := responsibleEquipment(equipment, 2)
+		assert.NotNil(t, e)
+		assert.Equal(t, "RTG02", e.Code)
+	})
+
+	t.Run("unserved block", func(t *testing.T) {
+		assert.Nil(t, responsibleEquipment(equipment, 3))
+	})
+}
+
+func TestSuggestionWeights_ScoreCandidate(t *testing.T) {
+	busy := &model.Equipment{OutstandingOrders: 5}
+	idle := &model.Equipment{OutstandingOrders: 1}
+
+	w := SuggestionWeights{WorkloadPenalty: 2}
+	assert.Equal(t, 10.0, w.scoreCandidate(candidate{equipment: busy}))
+	assert.Equal(t, 2.0, w.scoreCandidate(candidate{equipment: idle}))
+	assert.Equal(t, 0.0, w.scoreCandidate(candidate{}))
+
+	disabled := SuggestionWeights{}
+	assert.Equal(t, 0.0, disabled.scoreCandidate(candidate{equipment: busy}))
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dwipurnomo515/yard-planning/internal/auth"
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+type WorkOrderService struct {
+	yardRepo      repository.YardStore
+	equipmentRepo repository.EquipmentStore
+	workOrderRepo repository.WorkOrderStore
+	// containers checks and applies the yard change of confirmed jobs
+	containers *ContainerService
+}
+
+func NewWorkOrderService(
+	yardRepo repository.YardStore,
+	equipmentRepo repository.EquipmentStore,
+	workOrderRepo repository.WorkOrderStore,
+	containers *ContainerService,
+) *WorkOrderService {
+	return &WorkOrderService{
+		yardRepo:      yardRepo,
+		equipmentRepo: equipmentRepo,
+		workOrderRepo: workOrderRepo,
+		containers:    containers,
+	}
+}
+
+// ListPendingJobs returns the unfinished work orders of a yard, oldest first
+func (s *WorkOrderService) ListPendingJobs(ctx context.Context, yardCode string) ([]model.WorkOrder, error) {
+	// Check the role of the caller in the yard
+	if err := auth.Authorize(ctx, auth.PermView, yardCode); err != nil {
+		return nil, err
+	}
+
+	// Get yard
+	yard, err := s.yardRepo.GetByCode(ctx, yardCode)
+	if err != nil {
+		return nil, err
+	}
+
+	orders, err := s.workOrderRepo.GetPendingByYardID(ctx, yard.ID)
+	if err != nil {
+		return nil, err
+	}
+	if orders == nil {
+		orders = []model.WorkOrder{}
+	}
+
+	return orders, nil
+}
+
+// NextJob returns the job an operator should work on. A job that is already
+// dispatched to the machine is returned again; otherwise the oldest waiting job
+// is dispatched. It returns nil when the queue is empty.
+func (s *WorkOrderService) NextJob(ctx context.Context, yardCode, equipmentCode string) (*model.WorkOrder, error) {
+	// Check the role of the caller in the yard
+	if err := auth.Authorize(ctx, auth.PermOperate, yardCode); err != nil {
+		return nil, err
+	}
+
+	// Get yard
+	yard, err := s.yardRepo.GetByCode(ctx, yardCode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get equipment
+	equipment, err := s.equipmentRepo.GetByYardAndCode(ctx, yard.ID, equipmentCode)
+	if err != nil {
+		return nil, err
+	}
+	if !equipment.Active {
+		return nil, apperror.New(apperror.CodeEquipmentInactive, "equipment '%s' is not active", equipmentCode)
+	}
+
+	active, err := s.workOrderRepo.GetActiveForEquipment(ctx, equipment.ID)
+	if err != nil {
+		return nil, err
+	}
+	if active != nil {
+		return active, nil
+	}
+
+	return s.workOrderRepo.DispatchNext(ctx, equipment.ID)
+}
+
+// StartJob marks a dispatched job as in progress
+func (s *WorkOrderService) StartJob(ctx context.Context, id int) (*model.WorkOrder, error) {
+	return s.transition(ctx, id, model.WorkOrderStatusInProgress, "")
+}
+
+// ConfirmJob completes a job. If the job was requested with confirmation, this is
+// the moment the container position changes in the yard: the job completes and
+// the container moves together, or neither happens.
+func (s *WorkOrderService) ConfirmJob(ctx context.Context, id int) (*model.WorkOrder, error) {
+	order, err := s.getOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !model.CanTransitionWorkOrder(order.Status, model.WorkOrderStatusCompleted) {
+		return nil, apperror.New(apperror.CodeConflict, "cannot complete work order %d: it is %s", id, order.Status)
+	}
+	if order.PositionApplied {
+		return s.transition(ctx, id, model.WorkOrderStatusCompleted, "")
+	}
+
+	// Only one instance changes the yard at a time
+	ctx, unlock, err := s.containers.lockYards(ctx, order.YardID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	err = s.containers.write(ctx, func(containers *ContainerService) error {
+		// The job is completed first, so its own reservation does not count
+		// against its target
+		err := containers.workOrderRepo.UpdateStatus(ctx, id, order.Status, model.WorkOrderStatusCompleted, "")
+		if err != nil {
+			return err
+		}
+		if err := containers.applyWorkOrder(ctx, order); err != nil {
+			return fmt.Errorf("cannot complete work order %d: %w", id, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.workOrderRepo.GetByID(ctx, id)
+}
+
+// FailJob marks a job as failed. The container position is left unchanged.
+func (s *WorkOrderService) FailJob(ctx context.Context, id int, reason string) (*model.WorkOrder, error) {
+	if reason == "" {
+		return nil, apperror.Required("reason")
+	}
+	return s.transition(ctx, id, model.WorkOrderStatusFailed, reason)
+}
+
+// getOrder returns a work order the caller may operate on
+func (s *WorkOrderService) getOrder(ctx context.Context, id int) (*model.WorkOrder, error) {
+	order, err := s.workOrderRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Work orders only know their yard id
+	yards, err := s.yardRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, yard := range yards {
+		if yard.ID == order.YardID {
+			if err := auth.Authorize(ctx, auth.PermOperate, yard.Code); err != nil {
+				return nil, err
+			}
+			return order, nil
+		}
+	}
+
+	return nil, apperror.New(apperror.CodeYardNotFound, "yard %d of work order %d not found", order.YardID, id)
+}
+
+func (s *WorkOrderService) transition(ctx context.Context, id int, to, reason string) (*model.WorkOrder, error) {
+	order, err := s.getOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !model.CanTransitionWorkOrder(order.Status, to) {
+		return nil, apperror.New(apperror.CodeConflict, "cannot move work order %d from %s to %s", id, order.Status, to)
+	}
+
+	if err := s.workOrderRepo.UpdateStatus(ctx, id, order.Status, to, reason); err != nil {
+		return nil, err
+	}
+
+	return s.workOrderRepo.GetByID(ctx, id)
+}
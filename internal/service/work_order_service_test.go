@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newConfirmingServices returns services that only change the yard once a
+// job is confirmed
+func newConfirmingServices(t *testing.T) (*ContainerService, *WorkOrderService, repository.Stores) {
+	containers, stores := newMemoryService(t)
+	containers.SetWorkOrderConfirmation(true)
+	jobs := NewWorkOrderService(stores.Yards, stores.Equipment, stores.WorkOrders, containers)
+	return containers, jobs, stores
+}
+
+// nextJob dispatches the next job of RTG01 and checks it is for a container
+func nextJob(t *testing.T, jobs *WorkOrderService, containerNumber string) *model.WorkOrder {
+	order, err := jobs.NextJob(context.Background(), "YRD1", "RTG01")
+	require.NoError(t, err)
+	require.NotNil(t, order)
+	require.Equal(t, containerNumber, order.ContainerNumber)
+	return order
+}
+
+func TestWorkOrderService_ConfirmAppliesPosition(t *testing.T) {
+	ctx := context.Background()
+	containers, jobs, stores := newConfirmingServices(t)
+
+	place := model.PlacementRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 1, Row: 1, Tier: 1}
+	require.NoError(t, containers.PlaceContainer(ctx, place))
+	_, err := stores.Containers.GetByNumber(ctx, place.ContainerNumber)
+	require.Error(t, err, "placed before the job was confirmed")
+
+	order := nextJob(t, jobs, place.ContainerNumber)
+	confirmed, err := jobs.ConfirmJob(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.WorkOrderStatusCompleted, confirmed.Status)
+
+	container, err := stores.Containers.GetByNumber(ctx, place.ContainerNumber)
+	require.NoError(t, err)
+	assert.Equal(t, 1, container.Slot)
+
+	// Confirming twice is refused and changes nothing
+	_, err = jobs.ConfirmJob(ctx, order.ID)
+	assert.Equal(t, apperror.CodeConflict, apperror.CodeOf(err))
+}
+
+func TestWorkOrderService_ConfirmChecksTargetAgain(t *testing.T) {
+	ctx := context.Background()
+	containers, jobs, stores := newConfirmingServices(t)
+
+	// A stack of two pending placements; the upper one rests on the lower
+	// one's reservation
+	lower := model.PlacementRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 1, Row: 1, Tier: 1}
+	upper := model.PlacementRequest{Yard: "YRD1", ContainerNumber: "ABCU7654323", Block: "LC01", Slot: 1, Row: 1, Tier: 2}
+	require.NoError(t, containers.PlaceContainer(ctx, lower))
+	require.NoError(t, containers.PlaceContainer(ctx, upper))
+
+	// The lower job fails, so the upper container would float
+	lowerOrder := nextJob(t, jobs, lower.ContainerNumber)
+	_, err := jobs.FailJob(ctx, lowerOrder.ID, "spreader fault")
+	require.NoError(t, err)
+	upperOrder := nextJob(t, jobs, upper.ContainerNumber)
+	_, err = jobs.ConfirmJob(ctx, upperOrder.ID)
+	assert.Equal(t, apperror.CodeConflict, apperror.CodeOf(err))
+
+	// The job stays open and nothing moved
+	order, err := stores.WorkOrders.GetByID(ctx, upperOrder.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.WorkOrderStatusDispatched, order.Status)
+	_, err = stores.Containers.GetByNumber(ctx, upper.ContainerNumber)
+	assert.Error(t, err)
+	_, err = jobs.FailJob(ctx, upperOrder.ID, "nothing below")
+	require.NoError(t, err)
+
+	// A closure put on the cell after the job was created is respected too
+	place := model.PlacementRequest{Yard: "YRD1", ContainerNumber: "ABCU1234508", Block: "LC01", Slot: 2, Row: 1, Tier: 1}
+	require.NoError(t, containers.PlaceContainer(ctx, place))
+	placeOrder := nextJob(t, jobs, place.ContainerNumber)
+	blockID := 1
+	require.NoError(t, stores.Closures.Create(ctx, &model.BlockClosure{
+		YardID: 1, BlockID: &blockID, Reason: "resurfacing",
+		StartsAt: time.Now().Add(-time.Hour), EndsAt: time.Now().Add(time.Hour),
+	}))
+	_, err = jobs.ConfirmJob(ctx, placeOrder.ID)
+	assert.Equal(t, apperror.CodePositionClosed, apperror.CodeOf(err))
+	_, err = stores.Containers.GetByNumber(ctx, place.ContainerNumber)
+	assert.Error(t, err)
+}
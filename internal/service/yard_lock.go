@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/dwipurnomo515/yard-planning/pkg/cache"
+)
+
+const (
+	// yardLockTTL is the lease of a yard lock; it is renewed while the request
+	// runs
+	yardLockTTL = 15 * time.Second
+	// yardLockWait is how long a request waits for a yard another instance is
+	// writing to
+	yardLockWait = 10 * time.Second
+	// yardLockRetry is the pause between two attempts to take a yard lock
+	yardLockRetry = 20 * time.Millisecond
+)
+
+// heldLocksKey is the context key of the locks held by a request
+type heldLocksKey struct{}
+
+// SetLocker makes placements, pickups, moves and confirmed jobs take a
+// cluster-wide lock on their yard, so the checks of a request and its write
+// are not interleaved with another instance writing to the same yard. Each
+// write checks the fencing token of the lock in its transaction, which needs
+// SetTransactions.
+func (s *ContainerService) SetLocker(locker cache.Locker) {
+	s.locker = locker
+}
+
+// lockYards takes the locks of the yards, waiting while another owner holds
+// them. Work done under the locks must use the returned context; unlock
+// releases the locks. Yards already locked by ctx are not locked again.
+func (s *ContainerService) lockYards(ctx context.Context, yardIDs ...int) (context.Context, func(), error) {
+	return lockYards(ctx, s.locker, yardIDs...)
+}
+
+// lockYards takes the yard locks of locker for any service writing to a yard
+func lockYards(ctx context.Context, locker cache.Locker, yardIDs ...int) (context.Context, func(), error) {
+	if locker == nil {
+		return ctx, func() {}, nil
+	}
+
+	// Locks are always taken in the same order, so two batches can not wait
+	// for each other
+	yardIDs = slices.Clone(yardIDs)
+	slices.Sort(yardIDs)
+	yardIDs = slices.Compact(yardIDs)
+
+	var unlocks []func()
+	unlock := func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+
+	for _, id := range yardIDs {
+		key := fmt.Sprintf("yard:%d", id)
+		if slices.ContainsFunc(heldLocks(ctx), func(l cache.Lock) bool { return l.Key() == key }) {
+			continue
+		}
+
+		lock, err := acquire(ctx, locker, key)
+		if err != nil {
+			unlock()
+			return ctx, nil, err
+		}
+
+		var release func()
+		ctx, release = hold(ctx, lock, yardLockTTL)
+		unlocks = append(unlocks, release)
+	}
+
+	return ctx, unlock, nil
+}
+
+// hold keeps a lock alive and adds it to the locks held by ctx. The returned
+// context is cancelled when the lock is lost; release gives the lock up.
+func hold(ctx context.Context, lock cache.Lock, ttl time.Duration) (context.Context, func()) {
+	// The lock is released even when the request was cancelled
+	releaseCtx := context.WithoutCancel(ctx)
+	ctx, cancel := cache.KeepAlive(ctx, lock, ttl)
+	ctx = context.WithValue(ctx, heldLocksKey{}, append(slices.Clip(heldLocks(ctx)), lock))
+
+	return ctx, func() {
+		cancel()
+		_ = lock.Release(releaseCtx)
+	}
+}
+
+// acquire takes a lock, retrying while another owner holds it
+func acquire(ctx context.Context, locker cache.Locker, key string) (cache.Lock, error) {
+	ctx, cancel := context.WithTimeout(ctx, yardLockWait)
+	defer cancel()
+
+	ticker := time.NewTicker(yardLockRetry)
+	defer ticker.Stop()
+
+	for {
+		lock, err := locker.Acquire(ctx, key, yardLockTTL)
+		if err == nil {
+			return lock, nil
+		}
+		if !errors.Is(err, cache.ErrLockHeld) && ctx.Err() == nil {
+			return nil, fmt.Errorf("error taking lock %s: %w", key, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, apperror.New(apperror.CodeTimeout, "timed out waiting for lock %s", key)
+		case <-ticker.C:
+		}
+	}
+}
+
+// heldLocks returns the locks held by ctx
+func heldLocks(ctx context.Context) []cache.Lock {
+	locks, _ := ctx.Value(heldLocksKey{}).([]cache.Lock)
+	return locks
+}
+
+// checkFences records the fencing tokens of the locks held by ctx in a
+// transaction. It fails when a newer owner of a lock has written, so an owner
+// whose lease expired unnoticed can not change the yard anymore.
+func checkFences(ctx context.Context, fences repository.FenceStore) error {
+	for _, lock := range heldLocks(ctx) {
+		if err := fences.Check(ctx, lock.Key(), lock.Token()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dwipurnomo515/yard-planning/internal/auth"
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/dwipurnomo515/yard-planning/pkg/cache"
+	"github.com/dwipurnomo515/yard-planning/pkg/worker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestLocker returns a locker on a fresh in-memory Redis
+func newTestLocker(t *testing.T) cache.Locker {
+	redisServer := miniredis.RunT(t)
+	redisClient, err := cache.NewRedisClient(cache.RedisConfig{
+		Host: redisServer.Host(),
+		Port: redisServer.Port(),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { redisClient.Close() })
+
+	return cache.NewRedisLocker(redisClient)
+}
+
+func TestContainerService_YardLockWaitsForOtherOwner(t *testing.T) {
+	ctx := context.Background()
+	s, stores := newMemoryService(t)
+	locker := newTestLocker(t)
+	s.SetLocker(locker)
+	yard := yardByCode(t, stores, "YRD1")
+
+	// Another instance is writing to the yard
+	other, err := locker.Acquire(ctx, fmt.Sprintf("yard:%d", yard.ID), time.Minute)
+	require.NoError(t, err)
+
+	placed := make(chan error, 1)
+	go func() {
+		placed <- s.PlaceContainer(ctx, model.PlacementRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 1, Row: 1, Tier: 1})
+	}()
+
+	select {
+	case err := <-placed:
+		t.Fatalf("placement did not wait for the yard lock: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, other.Release(ctx))
+	select {
+	case err := <-placed:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("placement did not continue after the yard lock was released")
+	}
+	_, err = stores.Containers.GetByNumber(ctx, "ABCU1234560")
+	assert.NoError(t, err)
+}
+
+func TestContainerService_YardLockTimeout(t *testing.T) {
+	ctx := context.Background()
+	s, stores := newMemoryService(t)
+	locker := newTestLocker(t)
+	s.SetLocker(locker)
+	yard := yardByCode(t, stores, "YRD1")
+
+	_, err := locker.Acquire(ctx, fmt.Sprintf("yard:%d", yard.ID), time.Minute)
+	require.NoError(t, err)
+
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	err = s.PlaceContainer(waitCtx, model.PlacementRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 1, Row: 1, Tier: 1})
+	assert.Equal(t, apperror.CodeTimeout, apperror.CodeOf(err), "%v", err)
+	_, err = stores.Containers.GetByNumber(ctx, "ABCU1234560")
+	assert.Error(t, err)
+}
+
+func TestContainerService_YardLockFencesStaleOwner(t *testing.T) {
+	ctx := context.Background()
+	s, stores := newMemoryService(t)
+	s.SetLocker(newTestLocker(t))
+	yard := yardByCode(t, stores, "YRD1")
+
+	place := model.PlacementRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 1, Row: 1, Tier: 1}
+	require.NoError(t, s.PlaceContainer(ctx, place))
+
+	// This request holds the yard lock while a newer owner has already written
+	lockedCtx, unlock, err := s.lockYards(ctx, yard.ID)
+	require.NoError(t, err)
+	defer unlock()
+	lock := heldLocks(lockedCtx)[0]
+	require.NoError(t, stores.Fences.Check(ctx, lock.Key(), lock.Token()+1))
+
+	err = s.PlaceContainer(lockedCtx, model.PlacementRequest{Yard: "YRD1", ContainerNumber: "ABCU7654323", Block: "LC01", Slot: 2, Row: 1, Tier: 1})
+	assert.True(t, errors.Is(err, repository.ErrFenced), "%v", err)
+	_, err = stores.Containers.GetByNumber(ctx, "ABCU7654323")
+	assert.Error(t, err, "placement of a stale owner was kept")
+
+	err = s.MoveContainer(lockedCtx, model.MoveRequest{Yard: "YRD1", ContainerNumber: place.ContainerNumber, Block: "LC01", Slot: 3, Row: 1, Tier: 1})
+	assert.True(t, errors.Is(err, repository.ErrFenced), "%v", err)
+	err = s.PickupContainer(lockedCtx, model.PickupRequest{Yard: "YRD1", ContainerNumber: place.ContainerNumber})
+	assert.True(t, errors.Is(err, repository.ErrFenced), "%v", err)
+	err = s.PlaceBatch(lockedCtx, []model.PlacementRequest{{Yard: "YRD1", ContainerNumber: "ABCU7654323", Block: "LC01", Slot: 2, Row: 1, Tier: 1}})
+	assert.True(t, errors.Is(err, repository.ErrFenced), "%v", err)
+
+	container, err := stores.Containers.GetByNumber(ctx, place.ContainerNumber)
+	require.NoError(t, err)
+	assert.Equal(t, 1, container.Slot)
+}
+
+// A batch the caller may not place is rejected without waiting for the locks
+// of its yards
+func TestContainerService_PlaceBatchAuthorizesBeforeLocking(t *testing.T) {
+	ctx := context.Background()
+	s, stores := newMemoryService(t)
+	locker := newTestLocker(t)
+	s.SetLocker(locker)
+	yard := yardByCode(t, stores, "YRD1")
+
+	// Another instance is writing to the yard
+	_, err := locker.Acquire(ctx, fmt.Sprintf("yard:%d", yard.ID), time.Minute)
+	require.NoError(t, err)
+
+	ctx = auth.WithGrants(ctx, auth.NewGrants("jwt:clerk", []model.RoleBinding{{Role: model.RoleGateClerk, Yard: "DEPOT1"}}))
+	start := time.Now()
+	err = s.PlaceBatch(ctx, []model.PlacementRequest{
+		{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 1, Row: 1, Tier: 1},
+	})
+	var batchErr *BatchError
+	require.True(t, errors.As(err, &batchErr), "%v", err)
+	assert.Equal(t, apperror.CodeForbidden, apperror.CodeOf(batchErr.Items[0].Err))
+	assert.Less(t, time.Since(start), time.Second, "batch waited for the yard lock")
+}
+
+// A block is not resized while a placement holds the lock of its yard
+func TestPlanningService_ResizeWaitsForYardLock(t *testing.T) {
+	ctx := context.Background()
+	containers, stores := newMemoryService(t)
+	locker := cache.NewLocalLocker()
+	containers.SetLocker(locker)
+	planning := NewPlanningService(stores.Yards, stores.Blocks, stores.Plans, stores.Containers)
+	planning.SetTransactions(stores.Atomic)
+	planning.SetLocker(locker)
+	yard := yardByCode(t, stores, "YRD1")
+
+	block, err := stores.Blocks.GetByYardAndCode(ctx, yard.ID, "LC01")
+	require.NoError(t, err)
+	require.Greater(t, block.MaxSlot, 8)
+
+	// A placement checked slot 9 against the current block and is writing
+	placeCtx, unlock, err := containers.lockYards(ctx, yard.ID)
+	require.NoError(t, err)
+
+	resized := make(chan error, 1)
+	go func() {
+		_, err := planning.UpdateBlock(ctx, "YRD1", "LC01", model.BlockRequest{
+			Name: block.Name, MaxSlot: 8, MaxRow: block.MaxRow, MaxTier: block.MaxTier, BlockGeometry: block.BlockGeometry,
+		}, block.Version)
+		resized <- err
+	}()
+
+	select {
+	case err := <-resized:
+		t.Fatalf("resize did not wait for the yard lock: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, containers.PlaceContainer(placeCtx, model.PlacementRequest{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 9, Row: 1, Tier: 1}))
+	unlock()
+
+	// The resize now sees the container
+	select {
+	case err := <-resized:
+		assert.Equal(t, apperror.CodeConflict, apperror.CodeOf(err), "%v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("resize did not continue after the yard lock was released")
+	}
+	current, err := stores.Blocks.GetByID(ctx, block.ID)
+	require.NoError(t, err)
+	assert.Equal(t, block.MaxSlot, current.MaxSlot)
+}
+
+func TestBulkJobService_SkipsJobClaimedElsewhere(t *testing.T) {
+	ctx := context.Background()
+	containers, stores := newMemoryService(t)
+	locker := newTestLocker(t)
+	containers.SetLocker(locker)
+	jobs := NewBulkJobService(stores.BulkJobs, stores.Roles, containers)
+	jobs.SetLocker(locker, stores.Atomic)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := jobs.Start(runCtx, 1)
+
+	req := model.BulkJobRequest{Kind: model.BulkJobPlacement, Placements: []model.PlacementRequest{
+		{Yard: "YRD1", ContainerNumber: "ABCU1234560", Block: "LC01", Slot: 1, Row: 1, Tier: 1},
+	}}
+	request, err := json.Marshal(req)
+	require.NoError(t, err)
+	job := &model.BulkJob{Kind: req.Kind, Request: request, Total: req.Size()}
+	require.NoError(t, stores.BulkJobs.Create(ctx, job))
+
+	// Another instance runs the job
+	other, err := locker.Acquire(ctx, "bulk-job:"+strconv.Itoa(job.ID), time.Minute)
+	require.NoError(t, err)
+
+	_, err = jobs.Resume(ctx)
+	require.NoError(t, err)
+	waitForResult(t, results)
+	found, err := stores.BulkJobs.GetByID(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.BulkJobQueued, found.Status)
+	_, err = stores.Containers.GetByNumber(ctx, "ABCU1234560")
+	assert.Error(t, err, "a job claimed by another instance ran here")
+
+	// Once the other instance gives the job up it runs here
+	require.NoError(t, other.Release(ctx))
+	_, err = jobs.Resume(ctx)
+	require.NoError(t, err)
+	waitForResult(t, results)
+	found, err = stores.BulkJobs.GetByID(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.BulkJobCompleted, found.Status)
+	assert.Equal(t, 1, found.Succeeded)
+}
+
+// waitForResult waits until a worker has run a job
+func waitForResult(t *testing.T, results <-chan worker.Result) worker.Result {
+	select {
+	case result := <-results:
+		return result
+	case <-time.After(5 * time.Second):
+		t.Fatal("no bulk job was run")
+		return worker.Result{}
+	}
+}
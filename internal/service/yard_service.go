@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+
+	"github.com/dwipurnomo515/yard-planning/internal/auth"
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/internal/repository"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+)
+
+type YardService struct {
+	yardRepo repository.YardStore
+}
+
+func NewYardService(yardRepo repository.YardStore) *YardService {
+	return &YardService{yardRepo: yardRepo}
+}
+
+// ListOverflowRules returns the overflow yards of a yard in the order they are tried
+func (s *YardService) ListOverflowRules(ctx context.Context, yardCode string) ([]model.OverflowRule, error) {
+	// Check the role of the caller in the yard
+	if err := auth.Authorize(ctx, auth.PermView, yardCode); err != nil {
+		return nil, err
+	}
+
+	// Get yard
+	yard, err := s.yardRepo.GetByCode(ctx, yardCode)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := s.yardRepo.GetOverflowRules(ctx, yard.ID)
+	if err != nil {
+		return nil, err
+	}
+	if rules == nil {
+		rules = []model.OverflowRule{}
+	}
+
+	return rules, nil
+}
+
+// CreateOverflowRule routes suggestions of a full yard to another yard
+func (s *YardService) CreateOverflowRule(ctx context.Context, req model.OverflowRuleRequest) (*model.OverflowRule, error) {
+	// Check the role of the caller in both yards
+	if err := auth.Authorize(ctx, auth.PermPlan, req.Yard); err != nil {
+		return nil, err
+	}
+	if err := auth.Authorize(ctx, auth.PermPlan, req.OverflowYard); err != nil {
+		return nil, err
+	}
+
+	// Validate input
+	if req.Yard == req.OverflowYard {
+		return nil, apperror.Validation("overflow_yard", "a yard cannot overflow into itself")
+	}
+	if req.Priority < 1 {
+		return nil, apperror.Validation("priority", "must be at least 1")
+	}
+
+	// Get yards
+	yard, err := s.yardRepo.GetByCode(ctx, req.Yard)
+	if err != nil {
+		return nil, err
+	}
+	overflowYard, err := s.yardRepo.GetByCode(ctx, req.OverflowYard)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := &model.OverflowRule{
+		YardID:         yard.ID,
+		OverflowYardID: overflowYard.ID,
+		OverflowYard:   overflowYard.Code,
+		Priority:       req.Priority,
+	}
+
+	if err := s.yardRepo.CreateOverflowRule(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dwipurnomo515/yard-planning/internal/model"
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestYardService_CreateOverflowRule(t *testing.T) {
+	ctx := context.Background()
+	_, stores := newMemoryService(t)
+	s := NewYardService(stores.Yards)
+
+	tests := []struct {
+		name  string
+		req   model.OverflowRuleRequest
+		code  apperror.Code
+		field string
+	}{
+		{"self reference", model.OverflowRuleRequest{Yard: "YRD1", OverflowYard: "YRD1", Priority: 2}, apperror.CodeValidationFailed, "overflow_yard"},
+		{"zero priority", model.OverflowRuleRequest{Yard: "DEPOT1", OverflowYard: "YRD1", Priority: 0}, apperror.CodeValidationFailed, "priority"},
+		{"unknown yard", model.OverflowRuleRequest{Yard: "DEPOT1", OverflowYard: "NOPE", Priority: 1}, apperror.CodeYardNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.CreateOverflowRule(ctx, tt.req)
+			require.Error(t, err)
+			appErr := apperror.From(err)
+			assert.Equal(t, tt.code, appErr.Code)
+			if tt.field != "" {
+				require.Len(t, appErr.Details, 1)
+				assert.Equal(t, tt.field, appErr.Details[0].Field)
+			}
+		})
+	}
+
+	// A rule back to the yard that overflows into it is allowed
+	rule, err := s.CreateOverflowRule(ctx, model.OverflowRuleRequest{Yard: "DEPOT1", OverflowYard: "YRD1", Priority: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "YRD1", rule.OverflowYard)
+
+	rules, err := s.ListOverflowRules(ctx, "DEPOT1")
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, 1, rules[0].Priority)
+}
@@ -0,0 +1,49 @@
+// Package tenant carries the terminal operator a request belongs to. The
+// repositories read it from the context and only return and change rows of
+// that tenant.
+package tenant
+
+import "context"
+
+// Default is the tenant of requests without one, e.g. when authentication
+// is disabled, and of data created before tenants existed
+const Default = "default"
+
+type tenantKey struct{}
+
+// allTenants marks background work that must see the data of every tenant
+const allTenants = "\x00all"
+
+// WithTenant returns a copy of ctx scoped to the tenant
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	if tenant == "" {
+		tenant = Default
+	}
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// WithAllTenants returns a copy of ctx that is not scoped to a tenant. It is
+// only meant for system work such as loading the occupancy index.
+func WithAllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tenantKey{}, allTenants)
+}
+
+// FromContext returns the tenant of ctx, Default when none is set, or ""
+// for a context created by WithAllTenants
+func FromContext(ctx context.Context) string {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	switch {
+	case !ok:
+		return Default
+	case tenant == allTenants:
+		return ""
+	default:
+		return tenant
+	}
+}
+
+// Matches reports whether a row of the given tenant is visible in ctx
+func Matches(ctx context.Context, tenant string) bool {
+	scope := FromContext(ctx)
+	return scope == "" || scope == tenant
+}
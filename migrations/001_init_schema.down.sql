@@ -0,0 +1,6 @@
+-- migrations/001_init_schema.down.sql
+
+DROP TABLE IF EXISTS containers;
+DROP TABLE IF EXISTS yard_plans;
+DROP TABLE IF EXISTS blocks;
+DROP TABLE IF EXISTS yards;
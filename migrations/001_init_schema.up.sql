@@ -1,4 +1,4 @@
--- migrations/001_init_schema.sql
+-- migrations/001_init_schema.up.sql
 
 -- Table: yards
 CREATE TABLE IF NOT EXISTS yards (
@@ -42,7 +42,7 @@ CREATE TABLE IF NOT EXISTS yard_plans (
 );
 
 -- Index untuk checking overlap
-CREATE INDEX idx_yard_plans_block ON yard_plans(block_id);
+CREATE INDEX IF NOT EXISTS idx_yard_plans_block ON yard_plans(block_id);
 
 -- Table: containers
 -- Menyimpan container yang ada di yard
@@ -67,23 +67,7 @@ CREATE TABLE IF NOT EXISTS containers (
 );
 
 -- Index untuk performance
-CREATE INDEX idx_containers_yard ON containers(yard_id);
-CREATE INDEX idx_containers_block ON containers(block_id);
-CREATE INDEX idx_containers_position ON containers(block_id, slot, row, tier);
-CREATE INDEX idx_containers_number ON containers(container_number);
-
--- Seed data untuk testing
-INSERT INTO yards (code, name, description) VALUES
-('YRD1', 'Yard 1', 'Main container yard');
-
-INSERT INTO blocks (yard_id, code, name, max_slot, max_row, max_tier) VALUES
-(1, 'LC01', 'Loading Container Block 01', 10, 5, 5);
-
--- Yard Plans sesuai studi kasus
--- Plan untuk 20ft containers di slot 1-3, row 1-5
-INSERT INTO yard_plans (block_id, slot_start, slot_end, row_start, row_end, container_size, container_height, container_type) VALUES
-(1, 1, 3, 1, 5, 20, 8.6, 'DRY');
-
--- Plan untuk 40ft containers di slot 4-7, row 1-5
-INSERT INTO yard_plans (block_id, slot_start, slot_end, row_start, row_end, container_size, container_height, container_type) VALUES
-(1, 4, 7, 1, 5, 40, 8.6, 'DRY');
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_containers_yard ON containers(yard_id);
+CREATE INDEX IF NOT EXISTS idx_containers_block ON containers(block_id);
+CREATE INDEX IF NOT EXISTS idx_containers_position ON containers(block_id, slot, row, tier);
+CREATE INDEX IF NOT EXISTS idx_containers_number ON containers(container_number);
@@ -0,0 +1,3 @@
+-- migrations/002_block_closures.down.sql
+
+DROP TABLE IF EXISTS block_closures;
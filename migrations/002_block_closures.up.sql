@@ -0,0 +1,24 @@
+-- migrations/002_block_closures.up.sql
+
+-- Table: block_closures
+-- Menyimpan penutupan area yard (perbaikan crane, pekerjaan perkerasan, dll)
+-- block_id NULL berarti seluruh yard ditutup, slot/row NULL berarti seluruh block
+CREATE TABLE IF NOT EXISTS block_closures (
+    id SERIAL PRIMARY KEY,
+    yard_id INTEGER NOT NULL REFERENCES yards(id) ON DELETE CASCADE,
+    block_id INTEGER REFERENCES blocks(id) ON DELETE CASCADE,
+    slot_start INTEGER CHECK (slot_start > 0),
+    slot_end INTEGER,
+    row_start INTEGER CHECK (row_start > 0),
+    row_end INTEGER,
+    reason TEXT NOT NULL DEFAULT '',
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ends_at > starts_at),
+    CHECK ((slot_start IS NULL) = (slot_end IS NULL) AND (slot_end IS NULL OR slot_end >= slot_start)),
+    CHECK ((row_start IS NULL) = (row_end IS NULL) AND (row_end IS NULL OR row_end >= row_start)),
+    CHECK (block_id IS NOT NULL OR (slot_start IS NULL AND row_start IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_block_closures_yard_period ON block_closures(yard_id, ends_at);
@@ -0,0 +1,5 @@
+-- migrations/003_equipment.down.sql
+
+DROP TABLE IF EXISTS work_orders;
+DROP TABLE IF EXISTS equipment_blocks;
+DROP TABLE IF EXISTS equipment;
@@ -0,0 +1,41 @@
+-- migrations/003_equipment.up.sql
+
+-- Table: equipment
+-- Alat yard (RTG, RMG, reach stacker) yang melakukan setiap pergerakan container
+CREATE TABLE IF NOT EXISTS equipment (
+    id SERIAL PRIMARY KEY,
+    yard_id INTEGER NOT NULL REFERENCES yards(id) ON DELETE CASCADE,
+    code VARCHAR(50) NOT NULL,
+    equipment_type VARCHAR(20) NOT NULL CHECK (equipment_type IN ('RTG', 'RMG', 'REACH_STACKER')),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(yard_id, code)
+);
+
+-- Table: equipment_blocks
+-- Block yang dilayani oleh setiap alat
+CREATE TABLE IF NOT EXISTS equipment_blocks (
+    equipment_id INTEGER NOT NULL REFERENCES equipment(id) ON DELETE CASCADE,
+    block_id INTEGER NOT NULL REFERENCES blocks(id) ON DELETE CASCADE,
+    PRIMARY KEY (equipment_id, block_id)
+);
+
+-- Table: work_orders
+-- Pekerjaan yang harus dilakukan oleh alat yard
+CREATE TABLE IF NOT EXISTS work_orders (
+    id SERIAL PRIMARY KEY,
+    yard_id INTEGER NOT NULL REFERENCES yards(id) ON DELETE CASCADE,
+    equipment_id INTEGER REFERENCES equipment(id) ON DELETE SET NULL,
+    container_number VARCHAR(50) NOT NULL,
+    operation VARCHAR(20) NOT NULL,
+    block_id INTEGER NOT NULL REFERENCES blocks(id) ON DELETE CASCADE,
+    slot INTEGER NOT NULL,
+    row INTEGER NOT NULL,
+    tier INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'CREATED',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_work_orders_equipment_status ON work_orders(equipment_id, status);
@@ -0,0 +1,26 @@
+-- migrations/004_work_order_lifecycle.down.sql
+
+DROP INDEX IF EXISTS idx_work_orders_container;
+
+ALTER TABLE work_orders DROP CONSTRAINT IF EXISTS work_orders_status_check;
+ALTER TABLE work_orders DROP CONSTRAINT IF EXISTS work_orders_operation_check;
+
+ALTER TABLE work_orders DROP COLUMN IF EXISTS started_at;
+ALTER TABLE work_orders DROP COLUMN IF EXISTS dispatched_at;
+ALTER TABLE work_orders DROP COLUMN IF EXISTS failure_reason;
+ALTER TABLE work_orders DROP COLUMN IF EXISTS position_applied;
+ALTER TABLE work_orders DROP COLUMN IF EXISTS container_type;
+ALTER TABLE work_orders DROP COLUMN IF EXISTS container_height;
+ALTER TABLE work_orders DROP COLUMN IF EXISTS container_size;
+ALTER TABLE work_orders DROP COLUMN IF EXISTS destination;
+ALTER TABLE work_orders DROP COLUMN IF EXISTS from_tier;
+ALTER TABLE work_orders DROP COLUMN IF EXISTS from_row;
+ALTER TABLE work_orders DROP COLUMN IF EXISTS from_slot;
+ALTER TABLE work_orders DROP COLUMN IF EXISTS from_block_id;
+
+-- Work order tanpa posisi tujuan (pickup) tidak bisa disimpan di skema lama
+DELETE FROM work_orders WHERE block_id IS NULL OR slot IS NULL OR row IS NULL OR tier IS NULL;
+ALTER TABLE work_orders ALTER COLUMN block_id SET NOT NULL;
+ALTER TABLE work_orders ALTER COLUMN slot SET NOT NULL;
+ALTER TABLE work_orders ALTER COLUMN row SET NOT NULL;
+ALTER TABLE work_orders ALTER COLUMN tier SET NOT NULL;
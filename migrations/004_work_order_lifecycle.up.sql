@@ -0,0 +1,32 @@
+-- migrations/004_work_order_lifecycle.up.sql
+
+-- Work order menjadi job list untuk operator alat:
+-- CREATED -> DISPATCHED -> IN_PROGRESS -> COMPLETED/FAILED
+-- from_* = posisi asal (pickup, move, rehandle), block_id/slot/row/tier = posisi tujuan
+ALTER TABLE work_orders ALTER COLUMN block_id DROP NOT NULL;
+ALTER TABLE work_orders ALTER COLUMN slot DROP NOT NULL;
+ALTER TABLE work_orders ALTER COLUMN row DROP NOT NULL;
+ALTER TABLE work_orders ALTER COLUMN tier DROP NOT NULL;
+
+ALTER TABLE work_orders ADD COLUMN IF NOT EXISTS from_block_id INTEGER REFERENCES blocks(id) ON DELETE CASCADE;
+ALTER TABLE work_orders ADD COLUMN IF NOT EXISTS from_slot INTEGER;
+ALTER TABLE work_orders ADD COLUMN IF NOT EXISTS from_row INTEGER;
+ALTER TABLE work_orders ADD COLUMN IF NOT EXISTS from_tier INTEGER;
+ALTER TABLE work_orders ADD COLUMN IF NOT EXISTS destination VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE work_orders ADD COLUMN IF NOT EXISTS container_size INTEGER NOT NULL DEFAULT 20;
+ALTER TABLE work_orders ADD COLUMN IF NOT EXISTS container_height DECIMAL(3,1) NOT NULL DEFAULT 8.6;
+ALTER TABLE work_orders ADD COLUMN IF NOT EXISTS container_type VARCHAR(20) NOT NULL DEFAULT 'DRY';
+ALTER TABLE work_orders ADD COLUMN IF NOT EXISTS position_applied BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE work_orders ADD COLUMN IF NOT EXISTS failure_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE work_orders ADD COLUMN IF NOT EXISTS dispatched_at TIMESTAMP;
+ALTER TABLE work_orders ADD COLUMN IF NOT EXISTS started_at TIMESTAMP;
+
+ALTER TABLE work_orders DROP CONSTRAINT IF EXISTS work_orders_operation_check;
+ALTER TABLE work_orders ADD CONSTRAINT work_orders_operation_check
+    CHECK (operation IN ('PLACEMENT', 'PICKUP', 'MOVE', 'REHANDLE'));
+
+ALTER TABLE work_orders DROP CONSTRAINT IF EXISTS work_orders_status_check;
+ALTER TABLE work_orders ADD CONSTRAINT work_orders_status_check
+    CHECK (status IN ('CREATED', 'DISPATCHED', 'IN_PROGRESS', 'COMPLETED', 'FAILED'));
+
+CREATE INDEX IF NOT EXISTS idx_work_orders_container ON work_orders(container_number, status);
@@ -0,0 +1,9 @@
+-- migrations/005_yard_geometry.down.sql
+
+DROP TABLE IF EXISTS yard_points;
+
+ALTER TABLE blocks DROP COLUMN IF EXISTS row_pitch;
+ALTER TABLE blocks DROP COLUMN IF EXISTS slot_pitch;
+ALTER TABLE blocks DROP COLUMN IF EXISTS orientation;
+ALTER TABLE blocks DROP COLUMN IF EXISTS origin_y;
+ALTER TABLE blocks DROP COLUMN IF EXISTS origin_x;
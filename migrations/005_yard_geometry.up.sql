@@ -0,0 +1,23 @@
+-- migrations/005_yard_geometry.up.sql
+
+-- Geometri block: titik origin (pojok slot 1/row 1) dalam meter, orientasi sumbu slot
+-- dalam derajat, dan jarak antar slot/row
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS origin_x DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS origin_y DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS orientation DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS slot_pitch DOUBLE PRECISION NOT NULL DEFAULT 6.5 CHECK (slot_pitch > 0);
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS row_pitch DOUBLE PRECISION NOT NULL DEFAULT 2.8 CHECK (row_pitch > 0);
+
+-- Table: yard_points
+-- Titik penting di yard (gate, berth, rail) untuk menghitung jarak tempuh
+CREATE TABLE IF NOT EXISTS yard_points (
+    id SERIAL PRIMARY KEY,
+    yard_id INTEGER NOT NULL REFERENCES yards(id) ON DELETE CASCADE,
+    code VARCHAR(50) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    point_type VARCHAR(20) NOT NULL CHECK (point_type IN ('GATE', 'BERTH', 'RAIL')),
+    x DOUBLE PRECISION NOT NULL,
+    y DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(yard_id, code)
+);
@@ -0,0 +1,3 @@
+-- migrations/006_yard_overflow.down.sql
+
+DROP TABLE IF EXISTS yard_overflow_rules;
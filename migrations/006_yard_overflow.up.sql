@@ -0,0 +1,13 @@
+-- migrations/006_yard_overflow.up.sql
+
+-- Table: yard_overflow_rules
+-- Yard cadangan yang dicoba (urut priority) ketika yard utama tidak punya kapasitas
+CREATE TABLE IF NOT EXISTS yard_overflow_rules (
+    id SERIAL PRIMARY KEY,
+    yard_id INTEGER NOT NULL REFERENCES yards(id) ON DELETE CASCADE,
+    overflow_yard_id INTEGER NOT NULL REFERENCES yards(id) ON DELETE CASCADE,
+    priority INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(yard_id, overflow_yard_id),
+    CHECK (yard_id <> overflow_yard_id)
+);
@@ -0,0 +1,3 @@
+-- migrations/007_lock_fencing.down.sql
+
+DROP SEQUENCE IF EXISTS lock_fencing_seq;
@@ -0,0 +1,5 @@
+-- migrations/007_lock_fencing.up.sql
+
+-- Sequence: lock_fencing_seq
+-- Fencing token untuk advisory lock PostgreSQL (pkg/cache.PostgresLocker)
+CREATE SEQUENCE IF NOT EXISTS lock_fencing_seq;
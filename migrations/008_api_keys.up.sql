@@ -0,0 +1,13 @@
+-- migrations/008_api_keys.up.sql
+
+-- Table: api_keys
+-- API key statis; hanya hash SHA-256 yang disimpan. Rotasi: buat key baru lalu
+-- set expires_at key lama, sehingga keduanya berlaku selama masa transisi.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL,
+    key_hash CHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,15 @@
+-- migrations/009_role_bindings.up.sql
+
+-- Table: role_bindings
+-- Role per pemanggil (subject = "api_key:<nama>" atau "jwt:<sub>") dan per yard.
+-- yard_code '*' berarti role berlaku di semua yard.
+CREATE TABLE IF NOT EXISTS role_bindings (
+    id SERIAL PRIMARY KEY,
+    subject VARCHAR(200) NOT NULL,
+    role VARCHAR(30) NOT NULL,
+    yard_code VARCHAR(20) NOT NULL DEFAULT '*',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(subject, role, yard_code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_role_bindings_subject ON role_bindings(subject);
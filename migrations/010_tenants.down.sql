@@ -0,0 +1,14 @@
+-- migrations/010_tenants.down.sql
+
+ALTER TABLE role_bindings DROP CONSTRAINT IF EXISTS role_bindings_tenant_key;
+ALTER TABLE role_bindings ADD CONSTRAINT role_bindings_subject_role_yard_code_key UNIQUE (subject, role, yard_code);
+ALTER TABLE role_bindings DROP COLUMN IF EXISTS tenant;
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS tenant;
+
+ALTER TABLE containers DROP CONSTRAINT IF EXISTS containers_tenant_number_key;
+ALTER TABLE containers ADD CONSTRAINT containers_container_number_key UNIQUE (container_number);
+ALTER TABLE containers DROP COLUMN IF EXISTS tenant;
+
+DROP INDEX IF EXISTS idx_yards_tenant;
+ALTER TABLE yards DROP COLUMN IF EXISTS tenant;
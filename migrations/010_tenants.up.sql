@@ -0,0 +1,17 @@
+-- migrations/010_tenants.up.sql
+
+-- Tenant = operator terminal. Data yang sudah ada masuk ke tenant 'default'.
+-- Kode yard tetap unik secara global; nomor container unik per tenant.
+ALTER TABLE yards ADD COLUMN IF NOT EXISTS tenant VARCHAR(50) NOT NULL DEFAULT 'default';
+CREATE INDEX IF NOT EXISTS idx_yards_tenant ON yards(tenant);
+
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS tenant VARCHAR(50) NOT NULL DEFAULT 'default';
+ALTER TABLE containers DROP CONSTRAINT IF EXISTS containers_container_number_key;
+ALTER TABLE containers ADD CONSTRAINT containers_tenant_number_key UNIQUE (tenant, container_number);
+
+-- API key dan role binding selalu milik satu tenant
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant VARCHAR(50) NOT NULL DEFAULT 'default';
+
+ALTER TABLE role_bindings ADD COLUMN IF NOT EXISTS tenant VARCHAR(50) NOT NULL DEFAULT 'default';
+ALTER TABLE role_bindings DROP CONSTRAINT IF EXISTS role_bindings_subject_role_yard_code_key;
+ALTER TABLE role_bindings ADD CONSTRAINT role_bindings_tenant_key UNIQUE (tenant, subject, role, yard_code);
@@ -0,0 +1,19 @@
+-- migrations/011_idempotency_keys.up.sql
+
+-- Table: idempotency_keys
+-- Respons request yang dikirim dengan header Idempotency-Key, per tenant dan pemanggil.
+-- status_code 0 berarti request masih diproses. request_hash adalah SHA-256 dari
+-- method, path dan body, sehingga key yang dipakai ulang untuk request lain ditolak.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id SERIAL PRIMARY KEY,
+    tenant VARCHAR(50) NOT NULL DEFAULT 'default',
+    subject VARCHAR(200) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    response_body TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant, subject, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created ON idempotency_keys(created_at);
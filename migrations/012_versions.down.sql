@@ -0,0 +1,4 @@
+-- migrations/012_versions.down.sql
+
+ALTER TABLE yard_plans DROP COLUMN IF EXISTS version;
+ALTER TABLE blocks DROP COLUMN IF EXISTS version;
@@ -0,0 +1,6 @@
+-- migrations/012_versions.up.sql
+
+-- Versi baris untuk optimistic concurrency. Setiap perubahan menaikkan versi;
+-- API mengirimnya sebagai ETag dan meminta If-Match saat update/delete.
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE yard_plans ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,4 @@
+-- migrations/013_bulk_jobs.down.sql
+
+DROP TABLE IF EXISTS bulk_job_results;
+DROP TABLE IF EXISTS bulk_jobs;
@@ -0,0 +1,32 @@
+-- migrations/013_bulk_jobs.up.sql
+
+-- Table: bulk_jobs
+-- Bulk suggestion, placement atau move yang diproses di background. request berisi
+-- body JSON asli; subject adalah pemanggil yang hak aksesnya dipakai saat job berjalan.
+-- Job QUEUED/RUNNING dilanjutkan lagi saat service start.
+CREATE TABLE IF NOT EXISTS bulk_jobs (
+    id SERIAL PRIMARY KEY,
+    tenant VARCHAR(50) NOT NULL DEFAULT 'default',
+    subject VARCHAR(200) NOT NULL DEFAULT '',
+    kind VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'QUEUED',
+    request TEXT NOT NULL,
+    total INTEGER NOT NULL,
+    succeeded INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_bulk_jobs_status ON bulk_jobs(status);
+
+-- Table: bulk_job_results
+-- Hasil per item (JSON), disimpan segera setelah item diproses sehingga job
+-- yang dilanjutkan tidak memproses item yang sama dua kali.
+CREATE TABLE IF NOT EXISTS bulk_job_results (
+    job_id INTEGER NOT NULL REFERENCES bulk_jobs(id) ON DELETE CASCADE,
+    item_index INTEGER NOT NULL,
+    result TEXT NOT NULL,
+    PRIMARY KEY (job_id, item_index)
+);
@@ -0,0 +1,3 @@
+-- migrations/014_container_holds.down.sql
+
+ALTER TABLE containers DROP COLUMN IF EXISTS hold;
@@ -0,0 +1,5 @@
+-- migrations/014_container_holds.up.sql
+
+-- Hold pada container (bea cukai, kerusakan, sengketa). Container yang di-hold
+-- hanya bisa di-pickup bila supervisor meng-override hold tersebut.
+ALTER TABLE containers ADD COLUMN IF NOT EXISTS hold VARCHAR(255) NOT NULL DEFAULT '';
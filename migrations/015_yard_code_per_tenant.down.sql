@@ -0,0 +1,4 @@
+-- migrations/015_yard_code_per_tenant.down.sql
+
+ALTER TABLE yards DROP CONSTRAINT IF EXISTS yards_tenant_code_key;
+ALTER TABLE yards ADD CONSTRAINT yards_code_key UNIQUE (code);
@@ -0,0 +1,6 @@
+-- migrations/015_yard_code_per_tenant.up.sql
+
+-- Kode yard unik per tenant, sehingga dua operator terminal boleh memakai kode
+-- yang sama. Yard selalu dicari berdasarkan kode di dalam tenant pemanggil.
+ALTER TABLE yards DROP CONSTRAINT IF EXISTS yards_code_key;
+ALTER TABLE yards ADD CONSTRAINT yards_tenant_code_key UNIQUE (tenant, code);
@@ -0,0 +1,3 @@
+-- migrations/016_lock_fences.down.sql
+
+DROP TABLE IF EXISTS lock_fences;
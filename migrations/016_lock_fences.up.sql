@@ -0,0 +1,12 @@
+-- migrations/016_lock_fences.up.sql
+
+-- Table: lock_fences
+-- Fencing token terbaru per lock (pkg/cache.Locker). Setiap penulisan di bawah
+-- lock mencatat tokennya di transaksi yang sama dan ditolak bila pemilik lock
+-- yang lebih baru sudah menulis, sehingga instance yang lease-nya habis tanpa
+-- disadari tidak bisa lagi mengubah yard.
+CREATE TABLE IF NOT EXISTS lock_fences (
+    lock_key VARCHAR(200) PRIMARY KEY,
+    token BIGINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
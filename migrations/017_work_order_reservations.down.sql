@@ -0,0 +1,3 @@
+-- migrations/017_work_order_reservations.down.sql
+
+DROP INDEX IF EXISTS idx_work_orders_reserved_cell;
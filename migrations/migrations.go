@@ -0,0 +1,41 @@
+// Package migrations embeds the versioned database migrations and the optional
+// seed fixtures so the binary can apply them itself.
+//
+// Migrations are named NNN_name.up.sql and NNN_name.down.sql. The PostgreSQL
+// migrations live in this directory and the SQLite ones in sqlite/.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed *.sql
+var postgres embed.FS
+
+//go:embed sqlite/*.sql
+var sqlite embed.FS
+
+// PostgresSeed loads the sample yards, blocks, plans and equipment into PostgreSQL
+//
+//go:embed seed/postgres.sql
+var PostgresSeed string
+
+// SQLiteSeed loads the sample yards, blocks, plans and equipment into SQLite
+//
+//go:embed seed/sqlite.sql
+var SQLiteSeed string
+
+// Postgres returns the PostgreSQL migrations
+func Postgres() fs.FS {
+	return postgres
+}
+
+// SQLite returns the SQLite migrations
+func SQLite() fs.FS {
+	sub, err := fs.Sub(sqlite, "sqlite")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
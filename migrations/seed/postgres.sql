@@ -0,0 +1,61 @@
+-- migrations/seed/postgres.sql
+
+-- Seed data untuk testing: YRD1 dengan block LC01, RTG01, gate/berth,
+-- dan depot off-dock DEPOT1 sebagai overflow YRD1.
+-- Aman dijalankan berulang kali. Semua data masuk ke tenant 'default'.
+INSERT INTO yards (code, name, description) VALUES
+('YRD1', 'Yard 1', 'Main container yard'),
+('DEPOT1', 'Off-dock Depot 1', 'Off-dock overflow depot')
+ON CONFLICT (tenant, code) DO NOTHING;
+
+INSERT INTO blocks (yard_id, code, name, max_slot, max_row, max_tier, origin_x, origin_y)
+SELECT y.id, b.code, b.name, b.max_slot, b.max_row, b.max_tier, b.origin_x, b.origin_y
+FROM (VALUES
+    ('YRD1', 'LC01', 'Loading Container Block 01', 10, 5, 5, 120.0, 60.0),
+    ('DEPOT1', 'OD01', 'Off-dock Block 01', 20, 6, 4, 0.0, 0.0)
+) AS b(yard, code, name, max_slot, max_row, max_tier, origin_x, origin_y)
+JOIN yards y ON y.code = b.yard AND y.tenant = 'default'
+ON CONFLICT (yard_id, code) DO NOTHING;
+
+-- Yard Plans sesuai studi kasus
+-- LC01: 20ft di slot 1-3, 40ft di slot 4-7; OD01: 20ft di slot 1-10, 40ft di slot 11-20
+INSERT INTO yard_plans (block_id, slot_start, slot_end, row_start, row_end, container_size, container_height, container_type)
+SELECT b.id, p.slot_start, p.slot_end, p.row_start, p.row_end, p.container_size, p.container_height, p.container_type
+FROM (VALUES
+    ('YRD1', 'LC01', 1, 3, 1, 5, 20, 8.6, 'DRY'),
+    ('YRD1', 'LC01', 4, 7, 1, 5, 40, 8.6, 'DRY'),
+    ('DEPOT1', 'OD01', 1, 10, 1, 6, 20, 8.6, 'DRY'),
+    ('DEPOT1', 'OD01', 11, 20, 1, 6, 40, 8.6, 'DRY')
+) AS p(yard, block, slot_start, slot_end, row_start, row_end, container_size, container_height, container_type)
+JOIN yards y ON y.code = p.yard AND y.tenant = 'default'
+JOIN blocks b ON b.yard_id = y.id AND b.code = p.block
+WHERE NOT EXISTS (
+    SELECT 1 FROM yard_plans e
+    WHERE e.block_id = b.id AND e.slot_start = p.slot_start AND e.row_start = p.row_start
+);
+
+INSERT INTO equipment (yard_id, code, equipment_type)
+SELECT id, 'RTG01', 'RTG' FROM yards WHERE code = 'YRD1' AND tenant = 'default'
+ON CONFLICT (yard_id, code) DO NOTHING;
+
+INSERT INTO equipment_blocks (equipment_id, block_id)
+SELECT e.id, b.id
+FROM equipment e
+JOIN yards y ON y.id = e.yard_id
+JOIN blocks b ON b.yard_id = y.id
+WHERE y.code = 'YRD1' AND y.tenant = 'default' AND e.code = 'RTG01' AND b.code = 'LC01'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO yard_points (yard_id, code, name, point_type, x, y)
+SELECT y.id, p.code, p.name, p.point_type, p.x, p.y
+FROM (VALUES
+    ('GATE1', 'Main Gate', 'GATE', 0.0, 0.0),
+    ('BERTH1', 'Berth 1', 'BERTH', 250.0, 200.0)
+) AS p(code, name, point_type, x, y)
+JOIN yards y ON y.code = 'YRD1' AND y.tenant = 'default'
+ON CONFLICT (yard_id, code) DO NOTHING;
+
+INSERT INTO yard_overflow_rules (yard_id, overflow_yard_id, priority)
+SELECT y.id, o.id, 1 FROM yards y, yards o
+WHERE y.code = 'YRD1' AND y.tenant = 'default' AND o.code = 'DEPOT1' AND o.tenant = 'default'
+ON CONFLICT (yard_id, overflow_yard_id) DO NOTHING;
@@ -0,0 +1,30 @@
+-- migrations/seed/sqlite.sql
+
+-- Seed data untuk testing, sama dengan seed di migrations PostgreSQL.
+-- INSERT OR IGNORE dengan id tetap agar aman dijalankan berulang kali.
+INSERT OR IGNORE INTO yards (id, code, name, description) VALUES
+(1, 'YRD1', 'Yard 1', 'Main container yard'),
+(2, 'DEPOT1', 'Off-dock Depot 1', 'Off-dock overflow depot');
+
+INSERT OR IGNORE INTO blocks (id, yard_id, code, name, max_slot, max_row, max_tier, origin_x, origin_y) VALUES
+(1, 1, 'LC01', 'Loading Container Block 01', 10, 5, 5, 120, 60),
+(2, 2, 'OD01', 'Off-dock Block 01', 20, 6, 4, 0, 0);
+
+INSERT OR IGNORE INTO yard_plans (id, block_id, slot_start, slot_end, row_start, row_end, container_size, container_height, container_type) VALUES
+(1, 1, 1, 3, 1, 5, 20, 8.6, 'DRY'),
+(2, 1, 4, 7, 1, 5, 40, 8.6, 'DRY'),
+(3, 2, 1, 10, 1, 6, 20, 8.6, 'DRY'),
+(4, 2, 11, 20, 1, 6, 40, 8.6, 'DRY');
+
+INSERT OR IGNORE INTO equipment (id, yard_id, code, equipment_type) VALUES
+(1, 1, 'RTG01', 'RTG');
+
+INSERT OR IGNORE INTO equipment_blocks (equipment_id, block_id) VALUES
+(1, 1);
+
+INSERT OR IGNORE INTO yard_points (id, yard_id, code, name, point_type, x, y) VALUES
+(1, 1, 'GATE1', 'Main Gate', 'GATE', 0, 0),
+(2, 1, 'BERTH1', 'Berth 1', 'BERTH', 250, 200);
+
+INSERT OR IGNORE INTO yard_overflow_rules (id, yard_id, overflow_yard_id, priority) VALUES
+(1, 1, 2, 1);
@@ -0,0 +1,12 @@
+-- migrations/sqlite/001_init_schema.down.sql
+
+DROP TABLE IF EXISTS yard_overflow_rules;
+DROP TABLE IF EXISTS yard_points;
+DROP TABLE IF EXISTS work_orders;
+DROP TABLE IF EXISTS equipment_blocks;
+DROP TABLE IF EXISTS equipment;
+DROP TABLE IF EXISTS block_closures;
+DROP TABLE IF EXISTS containers;
+DROP TABLE IF EXISTS yard_plans;
+DROP TABLE IF EXISTS blocks;
+DROP TABLE IF EXISTS yards;
@@ -0,0 +1,169 @@
+-- migrations/sqlite/001_init_schema.up.sql
+
+-- Skema SQLite yang setara dengan migrations/001-006 PostgreSQL,
+-- untuk deployment depot kecil / edge tanpa PostgreSQL.
+-- Waktu disimpan sebagai teks UTC sehingga bisa dibandingkan langsung.
+
+-- Table: yards
+CREATE TABLE IF NOT EXISTS yards (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    code VARCHAR(50) UNIQUE NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Table: blocks
+CREATE TABLE IF NOT EXISTS blocks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    yard_id INTEGER NOT NULL REFERENCES yards(id) ON DELETE CASCADE,
+    code VARCHAR(50) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    max_slot INTEGER NOT NULL CHECK (max_slot > 0),
+    max_row INTEGER NOT NULL CHECK (max_row > 0),
+    max_tier INTEGER NOT NULL CHECK (max_tier > 0),
+    origin_x DOUBLE PRECISION NOT NULL DEFAULT 0,
+    origin_y DOUBLE PRECISION NOT NULL DEFAULT 0,
+    orientation DOUBLE PRECISION NOT NULL DEFAULT 0,
+    slot_pitch DOUBLE PRECISION NOT NULL DEFAULT 6.5 CHECK (slot_pitch > 0),
+    row_pitch DOUBLE PRECISION NOT NULL DEFAULT 2.8 CHECK (row_pitch > 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(yard_id, code)
+);
+
+-- Table: yard_plans
+CREATE TABLE IF NOT EXISTS yard_plans (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    block_id INTEGER NOT NULL REFERENCES blocks(id) ON DELETE CASCADE,
+    slot_start INTEGER NOT NULL CHECK (slot_start > 0),
+    slot_end INTEGER NOT NULL CHECK (slot_end >= slot_start),
+    row_start INTEGER NOT NULL CHECK (row_start > 0),
+    row_end INTEGER NOT NULL CHECK (row_end >= row_start),
+    container_size INTEGER NOT NULL CHECK (container_size IN (20, 40)),
+    container_height DECIMAL(3,1) NOT NULL CHECK (container_height IN (8.6, 9.6)),
+    container_type VARCHAR(20) NOT NULL CHECK (container_type IN ('DRY', 'REEFER', 'OPEN_TOP')),
+    stacking_priority VARCHAR(20) DEFAULT 'LEFT_TO_RIGHT',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_yard_plans_block ON yard_plans(block_id);
+
+-- Table: containers
+-- UNIQUE(block_id, slot, row, tier) menjamin satu cell hanya dipakai satu container
+CREATE TABLE IF NOT EXISTS containers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    container_number VARCHAR(50) UNIQUE NOT NULL,
+    yard_id INTEGER NOT NULL REFERENCES yards(id),
+    block_id INTEGER NOT NULL REFERENCES blocks(id),
+    slot INTEGER NOT NULL,
+    row INTEGER NOT NULL,
+    tier INTEGER NOT NULL,
+    container_size INTEGER NOT NULL CHECK (container_size IN (20, 40)),
+    container_height DECIMAL(3,1) NOT NULL CHECK (container_height IN (8.6, 9.6)),
+    container_type VARCHAR(20) NOT NULL CHECK (container_type IN ('DRY', 'REEFER', 'OPEN_TOP')),
+    placed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(block_id, slot, row, tier)
+);
+
+CREATE INDEX IF NOT EXISTS idx_containers_yard ON containers(yard_id);
+CREATE INDEX IF NOT EXISTS idx_containers_block ON containers(block_id);
+
+-- Table: block_closures
+CREATE TABLE IF NOT EXISTS block_closures (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    yard_id INTEGER NOT NULL REFERENCES yards(id) ON DELETE CASCADE,
+    block_id INTEGER REFERENCES blocks(id) ON DELETE CASCADE,
+    slot_start INTEGER CHECK (slot_start > 0),
+    slot_end INTEGER,
+    row_start INTEGER CHECK (row_start > 0),
+    row_end INTEGER,
+    reason TEXT NOT NULL DEFAULT '',
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ends_at > starts_at),
+    CHECK ((slot_start IS NULL) = (slot_end IS NULL) AND (slot_end IS NULL OR slot_end >= slot_start)),
+    CHECK ((row_start IS NULL) = (row_end IS NULL) AND (row_end IS NULL OR row_end >= row_start)),
+    CHECK (block_id IS NOT NULL OR (slot_start IS NULL AND row_start IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_block_closures_yard_period ON block_closures(yard_id, ends_at);
+
+-- Table: equipment
+CREATE TABLE IF NOT EXISTS equipment (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    yard_id INTEGER NOT NULL REFERENCES yards(id) ON DELETE CASCADE,
+    code VARCHAR(50) NOT NULL,
+    equipment_type VARCHAR(20) NOT NULL CHECK (equipment_type IN ('RTG', 'RMG', 'REACH_STACKER')),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(yard_id, code)
+);
+
+-- Table: equipment_blocks
+CREATE TABLE IF NOT EXISTS equipment_blocks (
+    equipment_id INTEGER NOT NULL REFERENCES equipment(id) ON DELETE CASCADE,
+    block_id INTEGER NOT NULL REFERENCES blocks(id) ON DELETE CASCADE,
+    PRIMARY KEY (equipment_id, block_id)
+);
+
+-- Table: work_orders
+-- from_* = posisi asal (pickup, move, rehandle), block_id/slot/row/tier = posisi tujuan
+CREATE TABLE IF NOT EXISTS work_orders (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    yard_id INTEGER NOT NULL REFERENCES yards(id) ON DELETE CASCADE,
+    equipment_id INTEGER REFERENCES equipment(id) ON DELETE SET NULL,
+    container_number VARCHAR(50) NOT NULL,
+    container_size INTEGER NOT NULL DEFAULT 20,
+    container_height DECIMAL(3,1) NOT NULL DEFAULT 8.6,
+    container_type VARCHAR(20) NOT NULL DEFAULT 'DRY',
+    operation VARCHAR(20) NOT NULL CHECK (operation IN ('PLACEMENT', 'PICKUP', 'MOVE', 'REHANDLE')),
+    from_block_id INTEGER REFERENCES blocks(id) ON DELETE CASCADE,
+    from_slot INTEGER,
+    from_row INTEGER,
+    from_tier INTEGER,
+    block_id INTEGER REFERENCES blocks(id) ON DELETE CASCADE,
+    slot INTEGER,
+    row INTEGER,
+    tier INTEGER,
+    destination VARCHAR(100) NOT NULL DEFAULT '',
+    position_applied BOOLEAN NOT NULL DEFAULT TRUE,
+    status VARCHAR(20) NOT NULL DEFAULT 'CREATED'
+        CHECK (status IN ('CREATED', 'DISPATCHED', 'IN_PROGRESS', 'COMPLETED', 'FAILED')),
+    failure_reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    dispatched_at TIMESTAMP,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_work_orders_equipment_status ON work_orders(equipment_id, status);
+CREATE INDEX IF NOT EXISTS idx_work_orders_container ON work_orders(container_number, status);
+
+-- Table: yard_points
+CREATE TABLE IF NOT EXISTS yard_points (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    yard_id INTEGER NOT NULL REFERENCES yards(id) ON DELETE CASCADE,
+    code VARCHAR(50) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    point_type VARCHAR(20) NOT NULL CHECK (point_type IN ('GATE', 'BERTH', 'RAIL')),
+    x DOUBLE PRECISION NOT NULL,
+    y DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(yard_id, code)
+);
+
+-- Table: yard_overflow_rules
+CREATE TABLE IF NOT EXISTS yard_overflow_rules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    yard_id INTEGER NOT NULL REFERENCES yards(id) ON DELETE CASCADE,
+    overflow_yard_id INTEGER NOT NULL REFERENCES yards(id) ON DELETE CASCADE,
+    priority INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(yard_id, overflow_yard_id),
+    CHECK (yard_id <> overflow_yard_id)
+);
@@ -0,0 +1,3 @@
+-- migrations/sqlite/002_api_keys.down.sql
+
+DROP TABLE IF EXISTS api_keys;
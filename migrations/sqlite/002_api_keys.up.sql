@@ -0,0 +1,11 @@
+-- migrations/sqlite/002_api_keys.up.sql
+
+-- Table: api_keys (lihat migrations/008_api_keys.up.sql)
+CREATE TABLE IF NOT EXISTS api_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL,
+    key_hash CHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+-- migrations/sqlite/003_role_bindings.down.sql
+
+DROP TABLE IF EXISTS role_bindings;
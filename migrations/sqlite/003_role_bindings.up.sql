@@ -0,0 +1,13 @@
+-- migrations/sqlite/003_role_bindings.up.sql
+
+-- Table: role_bindings (lihat migrations/009_role_bindings.up.sql)
+CREATE TABLE IF NOT EXISTS role_bindings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    subject VARCHAR(200) NOT NULL,
+    role VARCHAR(30) NOT NULL,
+    yard_code VARCHAR(20) NOT NULL DEFAULT '*',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(subject, role, yard_code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_role_bindings_subject ON role_bindings(subject);
@@ -0,0 +1,44 @@
+-- migrations/sqlite/004_tenants.down.sql
+
+CREATE TABLE role_bindings_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    subject VARCHAR(200) NOT NULL,
+    role VARCHAR(30) NOT NULL,
+    yard_code VARCHAR(20) NOT NULL DEFAULT '*',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(subject, role, yard_code)
+);
+INSERT OR IGNORE INTO role_bindings_old (id, subject, role, yard_code, created_at)
+SELECT id, subject, role, yard_code, created_at FROM role_bindings;
+DROP TABLE role_bindings;
+ALTER TABLE role_bindings_old RENAME TO role_bindings;
+CREATE INDEX IF NOT EXISTS idx_role_bindings_subject ON role_bindings(subject);
+
+ALTER TABLE api_keys DROP COLUMN tenant;
+
+CREATE TABLE containers_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    container_number VARCHAR(50) UNIQUE NOT NULL,
+    yard_id INTEGER NOT NULL REFERENCES yards(id),
+    block_id INTEGER NOT NULL REFERENCES blocks(id),
+    slot INTEGER NOT NULL,
+    row INTEGER NOT NULL,
+    tier INTEGER NOT NULL,
+    container_size INTEGER NOT NULL CHECK (container_size IN (20, 40)),
+    container_height DECIMAL(3,1) NOT NULL CHECK (container_height IN (8.6, 9.6)),
+    container_type VARCHAR(20) NOT NULL CHECK (container_type IN ('DRY', 'REEFER', 'OPEN_TOP')),
+    placed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(block_id, slot, row, tier)
+);
+INSERT INTO containers_old (id, container_number, yard_id, block_id, slot, row, tier,
+                            container_size, container_height, container_type, placed_at)
+SELECT id, container_number, yard_id, block_id, slot, row, tier,
+       container_size, container_height, container_type, placed_at
+FROM containers;
+DROP TABLE containers;
+ALTER TABLE containers_old RENAME TO containers;
+CREATE INDEX IF NOT EXISTS idx_containers_yard ON containers(yard_id);
+CREATE INDEX IF NOT EXISTS idx_containers_block ON containers(block_id);
+
+DROP INDEX IF EXISTS idx_yards_tenant;
+ALTER TABLE yards DROP COLUMN tenant;
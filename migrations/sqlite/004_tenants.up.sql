@@ -0,0 +1,49 @@
+-- migrations/sqlite/004_tenants.up.sql
+
+-- Lihat migrations/010_tenants.up.sql. SQLite tidak bisa menghapus constraint
+-- UNIQUE, jadi containers dan role_bindings dibuat ulang.
+ALTER TABLE yards ADD COLUMN tenant VARCHAR(50) NOT NULL DEFAULT 'default';
+CREATE INDEX IF NOT EXISTS idx_yards_tenant ON yards(tenant);
+
+CREATE TABLE containers_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant VARCHAR(50) NOT NULL DEFAULT 'default',
+    container_number VARCHAR(50) NOT NULL,
+    yard_id INTEGER NOT NULL REFERENCES yards(id),
+    block_id INTEGER NOT NULL REFERENCES blocks(id),
+    slot INTEGER NOT NULL,
+    row INTEGER NOT NULL,
+    tier INTEGER NOT NULL,
+    container_size INTEGER NOT NULL CHECK (container_size IN (20, 40)),
+    container_height DECIMAL(3,1) NOT NULL CHECK (container_height IN (8.6, 9.6)),
+    container_type VARCHAR(20) NOT NULL CHECK (container_type IN ('DRY', 'REEFER', 'OPEN_TOP')),
+    placed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant, container_number),
+    UNIQUE(block_id, slot, row, tier)
+);
+INSERT INTO containers_new (id, container_number, yard_id, block_id, slot, row, tier,
+                            container_size, container_height, container_type, placed_at)
+SELECT id, container_number, yard_id, block_id, slot, row, tier,
+       container_size, container_height, container_type, placed_at
+FROM containers;
+DROP TABLE containers;
+ALTER TABLE containers_new RENAME TO containers;
+CREATE INDEX IF NOT EXISTS idx_containers_yard ON containers(yard_id);
+CREATE INDEX IF NOT EXISTS idx_containers_block ON containers(block_id);
+
+ALTER TABLE api_keys ADD COLUMN tenant VARCHAR(50) NOT NULL DEFAULT 'default';
+
+CREATE TABLE role_bindings_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant VARCHAR(50) NOT NULL DEFAULT 'default',
+    subject VARCHAR(200) NOT NULL,
+    role VARCHAR(30) NOT NULL,
+    yard_code VARCHAR(20) NOT NULL DEFAULT '*',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant, subject, role, yard_code)
+);
+INSERT INTO role_bindings_new (id, subject, role, yard_code, created_at)
+SELECT id, subject, role, yard_code, created_at FROM role_bindings;
+DROP TABLE role_bindings;
+ALTER TABLE role_bindings_new RENAME TO role_bindings;
+CREATE INDEX IF NOT EXISTS idx_role_bindings_subject ON role_bindings(subject);
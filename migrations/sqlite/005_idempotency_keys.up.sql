@@ -0,0 +1,16 @@
+-- migrations/sqlite/005_idempotency_keys.up.sql
+
+-- Table: idempotency_keys (lihat migrations/011_idempotency_keys.up.sql)
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant VARCHAR(50) NOT NULL DEFAULT 'default',
+    subject VARCHAR(200) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    response_body TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant, subject, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created ON idempotency_keys(created_at);
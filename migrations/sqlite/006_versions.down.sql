@@ -0,0 +1,4 @@
+-- migrations/sqlite/006_versions.down.sql
+
+ALTER TABLE yard_plans DROP COLUMN version;
+ALTER TABLE blocks DROP COLUMN version;
@@ -0,0 +1,5 @@
+-- migrations/sqlite/006_versions.up.sql
+
+-- Lihat migrations/012_versions.up.sql
+ALTER TABLE blocks ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE yard_plans ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+-- migrations/sqlite/008_container_holds.down.sql
+
+ALTER TABLE containers DROP COLUMN hold;
@@ -0,0 +1,4 @@
+-- migrations/sqlite/008_container_holds.up.sql
+
+-- Lihat migrations/014_container_holds.up.sql
+ALTER TABLE containers ADD COLUMN hold VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,16 @@
+-- migrations/sqlite/009_yard_code_per_tenant.down.sql
+
+CREATE TABLE yards_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    code VARCHAR(50) UNIQUE NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    tenant VARCHAR(50) NOT NULL DEFAULT 'default'
+);
+INSERT INTO yards_old (id, code, name, description, created_at, updated_at, tenant)
+SELECT id, code, name, description, created_at, updated_at, tenant FROM yards;
+DROP TABLE yards;
+ALTER TABLE yards_old RENAME TO yards;
+CREATE INDEX IF NOT EXISTS idx_yards_tenant ON yards(tenant);
@@ -0,0 +1,21 @@
+-- migrations/sqlite/009_yard_code_per_tenant.up.sql
+
+-- Lihat migrations/015_yard_code_per_tenant.up.sql. SQLite tidak bisa menghapus
+-- constraint UNIQUE, jadi yards dibuat ulang. Migrator mematikan foreign key
+-- selama migrasi, sehingga DROP TABLE tidak menghapus block dan data lain
+-- milik yard secara cascade.
+CREATE TABLE yards_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    code VARCHAR(50) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    tenant VARCHAR(50) NOT NULL DEFAULT 'default',
+    UNIQUE(tenant, code)
+);
+INSERT INTO yards_new (id, code, name, description, created_at, updated_at, tenant)
+SELECT id, code, name, description, created_at, updated_at, tenant FROM yards;
+DROP TABLE yards;
+ALTER TABLE yards_new RENAME TO yards;
+CREATE INDEX IF NOT EXISTS idx_yards_tenant ON yards(tenant);
@@ -0,0 +1,3 @@
+-- migrations/sqlite/010_lock_fences.down.sql
+
+DROP TABLE IF EXISTS lock_fences;
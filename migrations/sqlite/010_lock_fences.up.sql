@@ -0,0 +1,8 @@
+-- migrations/sqlite/010_lock_fences.up.sql
+
+-- Lihat migrations/016_lock_fences.up.sql
+CREATE TABLE IF NOT EXISTS lock_fences (
+    lock_key VARCHAR(200) PRIMARY KEY,
+    token BIGINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
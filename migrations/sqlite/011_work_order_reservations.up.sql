@@ -0,0 +1,23 @@
+-- migrations/sqlite/011_work_order_reservations.up.sql
+
+-- Lihat migrations/017_work_order_reservations.up.sql
+UPDATE work_orders
+SET status = 'FAILED', failure_reason = 'cell already reserved by work order ' || (
+        SELECT MIN(o.id) FROM work_orders o
+        WHERE o.block_id = work_orders.block_id AND o.slot = work_orders.slot
+          AND o.row = work_orders.row AND o.tier = work_orders.tier
+          AND NOT o.position_applied AND o.status NOT IN ('COMPLETED', 'FAILED')
+    )
+WHERE NOT position_applied
+  AND block_id IS NOT NULL
+  AND status NOT IN ('COMPLETED', 'FAILED')
+  AND EXISTS (
+        SELECT 1 FROM work_orders o
+        WHERE o.block_id = work_orders.block_id AND o.slot = work_orders.slot
+          AND o.row = work_orders.row AND o.tier = work_orders.tier
+          AND NOT o.position_applied AND o.status NOT IN ('COMPLETED', 'FAILED')
+          AND o.id < work_orders.id
+    );
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_work_orders_reserved_cell ON work_orders(block_id, slot, row, tier)
+    WHERE NOT position_applied AND block_id IS NOT NULL AND status NOT IN ('COMPLETED', 'FAILED');
@@ -0,0 +1,155 @@
+// Package apperror defines the domain errors returned by services and
+// repositories, each with a machine-readable code that maps to an HTTP status.
+package apperror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Code identifies the kind of a domain error
+type Code string
+
+const (
+	CodeInvalidRequest       Code = "INVALID_REQUEST"
+	CodeValidationFailed     Code = "VALIDATION_FAILED"
+	CodeMethodNotAllowed     Code = "METHOD_NOT_ALLOWED"
+	CodeUnsupportedMedia     Code = "UNSUPPORTED_MEDIA_TYPE"
+	CodeUnauthorized         Code = "UNAUTHORIZED"
+	CodeForbidden            Code = "FORBIDDEN"
+	CodeYardNotFound         Code = "YARD_NOT_FOUND"
+	CodeBlockNotFound        Code = "BLOCK_NOT_FOUND"
+	CodeContainerNotFound    Code = "CONTAINER_NOT_FOUND"
+	CodeEquipmentNotFound    Code = "EQUIPMENT_NOT_FOUND"
+	CodeWorkOrderNotFound    Code = "WORK_ORDER_NOT_FOUND"
+	CodeNotFound             Code = "NOT_FOUND"
+	CodePositionOccupied     Code = "POSITION_OCCUPIED"
+	CodePositionClosed       Code = "POSITION_CLOSED"
+	CodePositionReserved     Code = "POSITION_RESERVED"
+	CodeContainerBlocked     Code = "CONTAINER_BLOCKED"
+	CodeContainerHeld        Code = "CONTAINER_HELD"
+	CodeWorkOrderPending     Code = "WORK_ORDER_PENDING"
+	CodeEquipmentInactive    Code = "EQUIPMENT_INACTIVE"
+	CodeConflict             Code = "CONFLICT"
+	CodeIdempotencyReused    Code = "IDEMPOTENCY_KEY_REUSED"
+	CodePreconditionFailed   Code = "PRECONDITION_FAILED"
+	CodePreconditionRequired Code = "PRECONDITION_REQUIRED"
+	CodeNoCapacity           Code = "NO_CAPACITY"
+	CodeTimeout              Code = "TIMEOUT"
+	CodeInternal             Code = "INTERNAL"
+)
+
+// statuses maps every code to its HTTP status
+var statuses = map[Code]int{
+	CodeInvalidRequest:       http.StatusBadRequest,
+	CodeValidationFailed:     http.StatusUnprocessableEntity,
+	CodeMethodNotAllowed:     http.StatusMethodNotAllowed,
+	CodeUnsupportedMedia:     http.StatusUnsupportedMediaType,
+	CodeUnauthorized:         http.StatusUnauthorized,
+	CodeForbidden:            http.StatusForbidden,
+	CodeYardNotFound:         http.StatusNotFound,
+	CodeBlockNotFound:        http.StatusNotFound,
+	CodeContainerNotFound:    http.StatusNotFound,
+	CodeEquipmentNotFound:    http.StatusNotFound,
+	CodeWorkOrderNotFound:    http.StatusNotFound,
+	CodeNotFound:             http.StatusNotFound,
+	CodePositionOccupied:     http.StatusConflict,
+	CodePositionClosed:       http.StatusConflict,
+	CodePositionReserved:     http.StatusConflict,
+	CodeContainerBlocked:     http.StatusConflict,
+	CodeContainerHeld:        http.StatusConflict,
+	CodeWorkOrderPending:     http.StatusConflict,
+	CodeEquipmentInactive:    http.StatusConflict,
+	CodeConflict:             http.StatusConflict,
+	CodeIdempotencyReused:    http.StatusConflict,
+	CodePreconditionFailed:   http.StatusPreconditionFailed,
+	CodePreconditionRequired: http.StatusPreconditionRequired,
+	CodeNoCapacity:           http.StatusServiceUnavailable,
+	CodeTimeout:              http.StatusServiceUnavailable,
+	CodeInternal:             http.StatusInternalServerError,
+}
+
+// FieldError describes why a single request field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is a domain error with a code, a message for humans and, for
+// validation failures, the offending fields
+type Error struct {
+	Code    Code
+	Message string
+	Details []FieldError
+	Err     error
+}
+
+// New creates an error with a formatted message
+func New(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap creates an error that keeps err as its cause
+func Wrap(code Code, err error, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+// Validation creates a VALIDATION_FAILED error for one field
+func Validation(field, message string) *Error {
+	return &Error{
+		Code:    CodeValidationFailed,
+		Message: fmt.Sprintf("%s: %s", field, message),
+		Details: []FieldError{{Field: field, Message: message}},
+	}
+}
+
+// Required creates a VALIDATION_FAILED error for missing fields
+func Required(fields ...string) *Error {
+	err := &Error{Code: CodeValidationFailed, Message: "missing required fields"}
+	for _, field := range fields {
+		err.Details = append(err.Details, FieldError{Field: field, Message: "is required"})
+	}
+	if len(fields) == 1 {
+		err.Message = fmt.Sprintf("%s is required", fields[0])
+	}
+	return err
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// From returns the domain error in err's chain. Cancelled and timed out
+// requests become TIMEOUT, anything else INTERNAL.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return Wrap(CodeTimeout, err, "request was cancelled before it completed")
+	}
+	return Wrap(CodeInternal, err, "internal error")
+}
+
+// CodeOf returns the code of err
+func CodeOf(err error) Code {
+	return From(err).Code
+}
+
+// Status returns the HTTP status for err
+func Status(err error) int {
+	if status, ok := statuses[From(err).Code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,47 @@
+// Package cache provides the caches used by the API: Redis shared by all
+// instances, an in-process LRU, and a two-tier combination of both.
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by Get when a key is missing or expired
+var ErrNotFound = errors.New("key not found")
+
+// Cache stores JSON encoded values with an expiration
+type Cache interface {
+	// Get decodes the value of key into dest, or returns ErrNotFound
+	Get(ctx context.Context, key string, dest interface{}) error
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	Delete(ctx context.Context, key string) error
+	// DeletePattern deletes the keys matching a Redis glob pattern (* and ?)
+	DeletePattern(ctx context.Context, pattern string) error
+}
+
+// matchPattern reports whether key matches a glob pattern with * and ?
+func matchPattern(pattern, key string) bool {
+	star, starKey := -1, 0
+	p, k := 0, 0
+	for k < len(key) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == key[k]):
+			p++
+			k++
+		case p < len(pattern) && pattern[p] == '*':
+			star, starKey = p, k
+			p++
+		case star >= 0:
+			starKey++
+			p, k = star+1, starKey
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
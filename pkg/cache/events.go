@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// yardEventsChannel is the pub/sub channel shared by all API instances
+const yardEventsChannel = "yard-events"
+
+// YardEvent announces that the containers of a block changed
+type YardEvent struct {
+	Source  string `json:"source"`
+	YardID  int    `json:"yard_id"`
+	BlockID int    `json:"block_id"`
+}
+
+// YardEvents publishes yard changes to the other API instances and receives theirs
+type YardEvents struct {
+	client *RedisClient
+	source string
+}
+
+// NewYardEvents creates the event channel of this instance
+func NewYardEvents(client *RedisClient) *YardEvents {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return &YardEvents{client: client, source: hex.EncodeToString(id)}
+}
+
+// Publish tells the other instances that a block changed
+func (e *YardEvents) Publish(ctx context.Context, yardID, blockID int) error {
+	payload, err := json.Marshal(YardEvent{Source: e.source, YardID: yardID, BlockID: blockID})
+	if err != nil {
+		return fmt.Errorf("error marshaling yard event: %w", err)
+	}
+	if err := e.client.client.Publish(ctx, yardEventsChannel, payload).Err(); err != nil {
+		return fmt.Errorf("error publishing yard event: %w", err)
+	}
+	return nil
+}
+
+// Subscribe calls handle for every event published by another instance until
+// ctx is cancelled. Events sent while the connection is down are lost, so
+// resync is called every time the subscription is (re)established.
+func (e *YardEvents) Subscribe(ctx context.Context, handle func(YardEvent), resync func()) {
+	pubsub := e.client.client.Subscribe(ctx, yardEventsChannel)
+	defer pubsub.Close()
+
+	backoff := 100 * time.Millisecond
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			// The next Receive reconnects and subscribes again
+			log.Printf("Yard events subscription lost: %v", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, 5*time.Second)
+			continue
+		}
+
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			if msg.Kind == "subscribe" {
+				backoff = 100 * time.Millisecond
+				resync()
+			}
+		case *redis.Message:
+			var event YardEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				log.Printf("Ignoring malformed yard event: %v", err)
+				continue
+			}
+			if event.Source != e.source {
+				handle(event)
+			}
+		}
+	}
+}
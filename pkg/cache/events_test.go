@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, server *miniredis.Miniredis) *RedisClient {
+	client, err := NewRedisClient(RedisConfig{Host: server.Host(), Port: server.Port()})
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestYardEvents_PublishSubscribeResync(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	publisher := NewYardEvents(newTestClient(t, server))
+	subscriber := NewYardEvents(newTestClient(t, server))
+
+	received := make(chan YardEvent, 10)
+	resyncs := make(chan struct{}, 10)
+	subscribe := func(e *YardEvents, events chan YardEvent) {
+		go e.Subscribe(ctx,
+			func(event YardEvent) { events <- event },
+			func() { resyncs <- struct{}{} },
+		)
+	}
+	subscribe(subscriber, received)
+	own := make(chan YardEvent, 10)
+	subscribe(publisher, own)
+
+	// Both instances resync once subscribed
+	for i := 0; i < 2; i++ {
+		waitFor(t, resyncs)
+	}
+
+	require.NoError(t, publisher.Publish(ctx, 1, 2))
+	event := waitFor(t, received)
+	assert.Equal(t, 1, event.YardID)
+	assert.Equal(t, 2, event.BlockID)
+	assert.Equal(t, publisher.source, event.Source)
+
+	// An instance ignores its own events
+	select {
+	case event := <-own:
+		t.Fatalf("publisher received its own event %+v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// A lost connection triggers a resync of every instance once it is back
+	server.Close()
+	require.NoError(t, server.Restart())
+	for i := 0; i < 2; i++ {
+		waitFor(t, resyncs)
+	}
+
+	require.NoError(t, publisher.Publish(ctx, 1, 3))
+	assert.Equal(t, 3, waitFor(t, received).BlockID)
+}
+
+func waitFor[T any](t *testing.T, ch chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for yard event")
+	}
+	var zero T
+	return zero
+}
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// LocalCache is an in-process LRU cache with per-key expiration. Values are
+// stored JSON encoded, so callers never share memory with the cache.
+type LocalCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxTTL     time.Duration
+	entries    map[string]*list.Element
+	order      *list.List // front is most recently used
+	now        func() time.Time
+}
+
+type localEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewLocalCache creates a cache holding at most maxEntries keys. A positive
+// maxTTL caps the expiration of every key.
+func NewLocalCache(maxEntries int, maxTTL time.Duration) *LocalCache {
+	return &LocalCache{
+		maxEntries: maxEntries,
+		maxTTL:     maxTTL,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+var _ Cache = (*LocalCache)(nil)
+
+// Set stores a value, evicting the least recently used key when full
+func (c *LocalCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("error marshaling value: %w", err)
+	}
+	if c.maxTTL > 0 && (expiration <= 0 || expiration > c.maxTTL) {
+		expiration = c.maxTTL
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &localEntry{key: key, value: data}
+	if expiration > 0 {
+		entry.expiresAt = c.now().Add(expiration)
+	}
+
+	if el, ok := c.entries[key]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+// Get retrieves a value
+func (c *LocalCache) Get(ctx context.Context, key string, dest interface{}) error {
+	c.mu.Lock()
+	el, ok := c.entries[key]
+	if !ok {
+		c.mu.Unlock()
+		return ErrNotFound
+	}
+	entry := el.Value.(*localEntry)
+	if !entry.expiresAt.IsZero() && !c.now().Before(entry.expiresAt) {
+		c.remove(el)
+		c.mu.Unlock()
+		return ErrNotFound
+	}
+	c.order.MoveToFront(el)
+	c.mu.Unlock()
+
+	if err := json.Unmarshal(entry.value, dest); err != nil {
+		return fmt.Errorf("error unmarshaling value: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a key
+func (c *LocalCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	return nil
+}
+
+// DeletePattern removes all keys matching a pattern
+func (c *LocalCache) DeletePattern(ctx context.Context, pattern string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, el := range c.entries {
+		if matchPattern(pattern, key) {
+			c.remove(el)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of cached keys, including expired ones not yet evicted
+func (c *LocalCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LocalCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*localEntry).key)
+}
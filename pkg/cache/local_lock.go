@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LocalLocker implements Locker inside the process, for storage that serves a
+// single API instance (memory and SQLite). Fencing tokens start at the time
+// the locker was created, so they stay above the tokens an earlier run of the
+// process recorded in persistent storage.
+type LocalLocker struct {
+	mu    sync.Mutex
+	locks map[string]*localLock
+	token int64
+	now   func() time.Time
+}
+
+// NewLocalLocker creates an in-process locker
+func NewLocalLocker() *LocalLocker {
+	return &LocalLocker{
+		locks: make(map[string]*localLock),
+		token: time.Now().UnixNano(),
+		now:   time.Now,
+	}
+}
+
+var _ Locker = (*LocalLocker)(nil)
+
+// Acquire takes the lock on key for ttl
+func (l *LocalLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if held, ok := l.locks[key]; ok && l.now().Before(held.expiresAt) {
+		return nil, ErrLockHeld
+	}
+
+	l.token++
+	lock := &localLock{locker: l, key: key, token: l.token, expiresAt: l.now().Add(ttl)}
+	l.locks[key] = lock
+	return lock, nil
+}
+
+type localLock struct {
+	locker    *LocalLocker
+	key       string
+	token     int64
+	expiresAt time.Time // guarded by locker.mu
+}
+
+func (l *localLock) Key() string  { return l.key }
+func (l *localLock) Token() int64 { return l.token }
+
+func (l *localLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	locker := l.locker
+	locker.mu.Lock()
+	defer locker.mu.Unlock()
+
+	if !l.heldLocked() {
+		return ErrLockLost
+	}
+	l.expiresAt = locker.now().Add(ttl)
+	return nil
+}
+
+func (l *localLock) Release(ctx context.Context) error {
+	locker := l.locker
+	locker.mu.Lock()
+	defer locker.mu.Unlock()
+
+	if !l.heldLocked() {
+		return ErrLockLost
+	}
+	delete(locker.locks, l.key)
+	return nil
+}
+
+// heldLocked reports whether the lease of the lock still runs. The caller
+// holds locker.mu.
+func (l *localLock) heldLocked() bool {
+	return l.locker.locks[l.key] == l && l.locker.now().Before(l.expiresAt)
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalCache_LRUAndTTL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := NewLocalCache(2, time.Minute)
+	c.now = func() time.Time { return now }
+
+	require.NoError(t, c.Set(ctx, "a", 1, 0))
+	require.NoError(t, c.Set(ctx, "b", 2, time.Hour))
+
+	// Reading a makes b the least recently used key
+	var v int
+	require.NoError(t, c.Get(ctx, "a", &v))
+	assert.Equal(t, 1, v)
+	require.NoError(t, c.Set(ctx, "c", 3, time.Second))
+	assert.ErrorIs(t, c.Get(ctx, "b", &v), ErrNotFound)
+	assert.Equal(t, 2, c.Len())
+
+	// c expires after its own TTL, a after the cache's maximum TTL
+	now = now.Add(2 * time.Second)
+	assert.ErrorIs(t, c.Get(ctx, "c", &v), ErrNotFound)
+	require.NoError(t, c.Get(ctx, "a", &v))
+	now = now.Add(time.Minute)
+	assert.ErrorIs(t, c.Get(ctx, "a", &v), ErrNotFound)
+}
+
+func TestLocalCache_DeletePattern(t *testing.T) {
+	ctx := context.Background()
+	c := NewLocalCache(10, 0)
+	for _, key := range []string{"suggestion:YRD1:20", "suggestion:YRD2:20", "container:ABCU1234560"} {
+		require.NoError(t, c.Set(ctx, key, key, time.Minute))
+	}
+
+	require.NoError(t, c.DeletePattern(ctx, "suggestion:YRD1:*"))
+	var v string
+	assert.ErrorIs(t, c.Get(ctx, "suggestion:YRD1:20", &v), ErrNotFound)
+	assert.NoError(t, c.Get(ctx, "suggestion:YRD2:20", &v))
+
+	require.NoError(t, c.DeletePattern(ctx, "*"))
+	assert.Zero(t, c.Len())
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern, key string
+		want         bool
+	}{
+		{"suggestion:YRD1:*", "suggestion:YRD1:20:8.6:DRY::", true},
+		{"suggestion:YRD1:*", "suggestion:YRD10:20", false},
+		{"*:YRD1:*", "suggestion:YRD1:20", true},
+		{"container:?BCU*", "container:ABCU1234560", true},
+		{"a*b*c", "aXbYbZc", true},
+		{"a*b*c", "aXbYbZ", false},
+		{"exact", "exact", true},
+		{"exact", "exactly", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, matchPattern(tt.pattern, tt.key), "%s ~ %s", tt.pattern, tt.key)
+	}
+}
@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrLockHeld is returned by Acquire when another owner holds the lock
+	ErrLockHeld = errors.New("lock is held by another owner")
+	// ErrLockLost is returned when a lock expired or was taken over
+	ErrLockLost = errors.New("lock is no longer held")
+)
+
+// Locker hands out cluster-wide locks, so that only one API instance runs an
+// operation such as a rehandle sequence at a time
+type Locker interface {
+	// Acquire takes the lock on key for ttl without waiting, or returns ErrLockHeld
+	Acquire(ctx context.Context, key string, ttl time.Duration) (Lock, error)
+}
+
+// Lock is a lock held by this instance
+type Lock interface {
+	Key() string
+	// Token is a fencing token that grows every time a lock is acquired. Storage
+	// guarded by the lock can reject writes carrying a lower token than the last
+	// one it has seen, which stops an owner whose lease silently expired.
+	Token() int64
+	// Refresh extends the lease to ttl, or returns ErrLockLost
+	Refresh(ctx context.Context, ttl time.Duration) error
+	// Release gives the lock up. Releasing a lock that was lost returns ErrLockLost.
+	Release(ctx context.Context) error
+}
+
+// KeepAlive refreshes a lock every third of ttl until the returned cancel
+// function is called. The returned context is cancelled as well when the lease
+// can not be renewed, so work done under the lock should use it.
+func KeepAlive(ctx context.Context, lock Lock, ttl time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	go func() {
+		defer cancel()
+
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := lock.Refresh(ctx, ttl); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	return ctx, cancel
+}
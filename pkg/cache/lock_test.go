@@ -0,0 +1,274 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dwipurnomo515/yard-planning/migrations"
+	"github.com/dwipurnomo515/yard-planning/pkg/migrate"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testLocker checks the behaviour shared by every Locker
+func testLocker(t *testing.T, locker Locker) {
+	ctx := context.Background()
+
+	lock, err := locker.Acquire(ctx, "rehandle:YRD1", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "rehandle:YRD1", lock.Key())
+
+	_, err = locker.Acquire(ctx, "rehandle:YRD1", time.Minute)
+	assert.ErrorIs(t, err, ErrLockHeld)
+
+	// Other keys are independent
+	other, err := locker.Acquire(ctx, "housekeeping", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, other.Release(ctx))
+
+	require.NoError(t, lock.Refresh(ctx, time.Minute))
+	require.NoError(t, lock.Release(ctx))
+	assert.ErrorIs(t, lock.Release(ctx), ErrLockLost)
+
+	// Every new owner gets a higher fencing token
+	next, err := locker.Acquire(ctx, "rehandle:YRD1", time.Minute)
+	require.NoError(t, err)
+	assert.Greater(t, next.Token(), lock.Token())
+	require.NoError(t, next.Release(ctx))
+}
+
+func TestLocalLocker(t *testing.T) {
+	testLocker(t, NewLocalLocker())
+}
+
+func TestLocalLocker_ExpiredLease(t *testing.T) {
+	ctx := context.Background()
+	locker := NewLocalLocker()
+	now := time.Now()
+	locker.now = func() time.Time { return now }
+
+	lock, err := locker.Acquire(ctx, "rehandle:YRD1", time.Second)
+	require.NoError(t, err)
+
+	// The lease runs out and another owner takes over
+	now = now.Add(2 * time.Second)
+	takeover, err := locker.Acquire(ctx, "rehandle:YRD1", time.Minute)
+	require.NoError(t, err)
+	assert.Greater(t, takeover.Token(), lock.Token())
+
+	// The first owner can neither renew nor release the new owner's lock
+	assert.ErrorIs(t, lock.Refresh(ctx, time.Second), ErrLockLost)
+	assert.ErrorIs(t, lock.Release(ctx), ErrLockLost)
+	_, err = locker.Acquire(ctx, "rehandle:YRD1", time.Minute)
+	assert.ErrorIs(t, err, ErrLockHeld)
+	require.NoError(t, takeover.Release(ctx))
+}
+
+// Tokens of a new locker are above those of a locker created before, as after
+// a restart
+func TestLocalLocker_TokensGrowAcrossLockers(t *testing.T) {
+	ctx := context.Background()
+	lock, err := NewLocalLocker().Acquire(ctx, "yard:1", time.Minute)
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	next, err := NewLocalLocker().Acquire(ctx, "yard:1", time.Minute)
+	require.NoError(t, err)
+	assert.Greater(t, next.Token(), lock.Token())
+}
+
+func TestRedisLocker(t *testing.T) {
+	server := miniredis.RunT(t)
+	testLocker(t, NewRedisLocker(newTestClient(t, server)))
+}
+
+func TestRedisLocker_ExpiredLease(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	locker := NewRedisLocker(newTestClient(t, server))
+
+	lock, err := locker.Acquire(ctx, "rehandle:YRD1", time.Second)
+	require.NoError(t, err)
+
+	// The lease runs out and another instance takes over
+	server.FastForward(2 * time.Second)
+	takeover, err := locker.Acquire(ctx, "rehandle:YRD1", time.Minute)
+	require.NoError(t, err)
+
+	// The first owner can neither renew nor release the new owner's lock
+	assert.ErrorIs(t, lock.Refresh(ctx, time.Second), ErrLockLost)
+	assert.ErrorIs(t, lock.Release(ctx), ErrLockLost)
+	assert.True(t, server.Exists("lock:rehandle:YRD1"))
+	require.NoError(t, takeover.Release(ctx))
+}
+
+func TestKeepAlive(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	locker := NewRedisLocker(newTestClient(t, server))
+
+	lock, err := locker.Acquire(ctx, "rehandle:YRD1", 300*time.Millisecond)
+	require.NoError(t, err)
+
+	held, cancel := KeepAlive(ctx, lock, 300*time.Millisecond)
+	defer cancel()
+
+	// Renewals keep the lease at its full length
+	time.Sleep(150 * time.Millisecond)
+	assert.NoError(t, held.Err())
+	assert.Greater(t, server.TTL("lock:rehandle:YRD1"), 200*time.Millisecond)
+
+	// Losing the lock cancels the work done under it
+	server.Del("lock:rehandle:YRD1")
+	select {
+	case <-held.Done():
+	case <-time.After(time.Second):
+		t.Fatal("keep-alive context not cancelled after the lock was lost")
+	}
+}
+
+// openTestPostgres opens the database in TEST_DATABASE_URL and applies the
+// pending migrations, or skips the test
+func openTestPostgres(t *testing.T) *sql.DB {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrate.New(db, migrate.DialectPostgres, migrations.Postgres())
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	return db
+}
+
+// TestPostgresLocker runs against the database in TEST_DATABASE_URL
+func TestPostgresLocker(t *testing.T) {
+	testLocker(t, NewPostgresLocker(openTestPostgres(t)))
+}
+
+// Two instances with their own connection pools exclude each other
+func TestPostgresLocker_Instances(t *testing.T) {
+	ctx := context.Background()
+	first := NewPostgresLocker(openTestPostgres(t))
+	second := NewPostgresLocker(openTestPostgres(t))
+
+	lock, err := first.Acquire(ctx, "yard:instances", time.Minute)
+	require.NoError(t, err)
+	_, err = second.Acquire(ctx, "yard:instances", time.Minute)
+	assert.ErrorIs(t, err, ErrLockHeld)
+
+	require.NoError(t, lock.Release(ctx))
+	taken, err := second.Acquire(ctx, "yard:instances", time.Minute)
+	require.NoError(t, err)
+	defer taken.Release(ctx)
+	assert.Greater(t, taken.Token(), lock.Token())
+}
+
+// A lock is lost with the connection holding it, and the next owner gets a
+// higher fencing token
+func TestPostgresLocker_LostConnection(t *testing.T) {
+	ctx := context.Background()
+	db := openTestPostgres(t)
+	locker := NewPostgresLocker(db)
+
+	lock, err := locker.Acquire(ctx, "yard:lost", time.Minute)
+	require.NoError(t, err)
+	lockCtx, cancel := KeepAlive(ctx, lock, 300*time.Millisecond)
+	defer cancel()
+
+	// Terminate the backend holding the advisory lock
+	id := advisoryLockID("yard:lost")
+	var terminated bool
+	err = db.QueryRowContext(ctx, `
+		SELECT pg_terminate_backend(pid) FROM pg_locks
+		WHERE locktype = 'advisory' AND classid = $1 AND objid = $2 AND objsubid = 1
+	`, int64(uint32(id>>32)), int64(uint32(id))).Scan(&terminated)
+	require.NoError(t, err)
+	require.True(t, terminated)
+
+	assert.ErrorIs(t, lock.Refresh(ctx, time.Minute), ErrLockLost)
+	select {
+	case <-lockCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("KeepAlive did not notice the lost lock")
+	}
+
+	taken, err := locker.Acquire(ctx, "yard:lost", time.Minute)
+	require.NoError(t, err)
+	defer taken.Release(ctx)
+	assert.Greater(t, taken.Token(), lock.Token())
+	assert.ErrorIs(t, lock.Release(ctx), ErrLockLost)
+}
+
+// unlockFailsConnector opens connections on which every query succeeds except
+// pg_advisory_unlock
+type unlockFailsConnector struct{}
+
+func (c unlockFailsConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return unlockFailsConn{}, nil
+}
+
+func (c unlockFailsConnector) Driver() driver.Driver { return nil }
+
+type unlockFailsConn struct{}
+
+func (unlockFailsConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+func (unlockFailsConn) Close() error              { return nil }
+func (unlockFailsConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+func (unlockFailsConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	switch {
+	case strings.Contains(query, "pg_advisory_unlock"):
+		return nil, errors.New("connection reset by peer")
+	case strings.Contains(query, "nextval"):
+		return &oneValueRows{value: int64(1)}, nil
+	default:
+		return &oneValueRows{value: true}, nil
+	}
+}
+
+type oneValueRows struct {
+	value driver.Value
+	done  bool
+}
+
+func (r *oneValueRows) Columns() []string { return []string{"value"} }
+func (r *oneValueRows) Close() error      { return nil }
+
+func (r *oneValueRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+	return nil
+}
+
+// A connection whose unlock failed may still hold the lock, so it does not go
+// back to the pool
+func TestPostgresLocker_FailedUnlockDiscardsConnection(t *testing.T) {
+	ctx := context.Background()
+	db := sql.OpenDB(unlockFailsConnector{})
+	defer db.Close()
+
+	lock, err := NewPostgresLocker(db).Acquire(ctx, "yard:unlock", time.Minute)
+	require.NoError(t, err)
+	assert.ErrorIs(t, lock.Release(ctx), ErrLockLost)
+	assert.Zero(t, db.Stats().OpenConnections, "connection that may hold the lock was kept")
+}
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+// PostgresLocker implements Locker with PostgreSQL session advisory locks, for
+// deployments without Redis. A lock keeps its own database connection until it
+// is released and is lost when that connection breaks, so the ttl is not used.
+// Fencing tokens come from the lock_fencing_seq sequence (migration 007).
+type PostgresLocker struct {
+	db *sql.DB
+}
+
+// NewPostgresLocker creates a locker on a PostgreSQL database
+func NewPostgresLocker(db *sql.DB) *PostgresLocker {
+	return &PostgresLocker{db: db}
+}
+
+var _ Locker = (*PostgresLocker)(nil)
+
+// Acquire takes the advisory lock of key
+func (l *PostgresLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting connection: %w", err)
+	}
+
+	lock := &postgresLock{conn: conn, key: key, id: advisoryLockID(key)}
+
+	var acquired bool
+	err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, lock.id).Scan(&acquired)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error acquiring lock %s: %w", key, err)
+	}
+	if !acquired {
+		conn.Close()
+		return nil, ErrLockHeld
+	}
+
+	err = conn.QueryRowContext(ctx, `SELECT nextval('lock_fencing_seq')`).Scan(&lock.token)
+	if err != nil {
+		lock.Release(context.Background())
+		return nil, fmt.Errorf("error getting fencing token for lock %s: %w", key, err)
+	}
+
+	return lock, nil
+}
+
+// advisoryLockID maps a lock name to the 64 bit key of an advisory lock
+func advisoryLockID(key string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("yard-planning:" + key))
+	return int64(h.Sum64())
+}
+
+type postgresLock struct {
+	conn  *sql.Conn
+	key   string
+	id    int64
+	token int64
+}
+
+func (l *postgresLock) Key() string  { return l.key }
+func (l *postgresLock) Token() int64 { return l.token }
+
+// Refresh checks that the connection holding the lock is still alive
+func (l *postgresLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	if err := l.conn.PingContext(ctx); err != nil {
+		return fmt.Errorf("%w: %v", ErrLockLost, err)
+	}
+	return nil
+}
+
+// Release unlocks the advisory lock and returns the connection to the pool. A
+// connection that may still hold the lock is closed instead, which ends its
+// session and so releases the lock.
+func (l *postgresLock) Release(ctx context.Context) error {
+	defer l.conn.Close()
+
+	var released bool
+	if err := l.conn.QueryRowContext(ctx, `SELECT pg_advisory_unlock($1)`, l.id).Scan(&released); err != nil {
+		l.discard()
+		return fmt.Errorf("%w: %v", ErrLockLost, err)
+	}
+	if !released {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// discard removes the connection of the lock from the pool
+func (l *postgresLock) discard() {
+	_ = l.conn.Raw(func(driverConn any) error {
+		return driver.ErrBadConn
+	})
+}
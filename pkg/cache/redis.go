@@ -9,9 +9,9 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// RedisClient is a Cache shared by all API instances
 type RedisClient struct {
 	client *redis.Client
-	ctx    context.Context
 }
 
 type RedisConfig struct {
@@ -29,34 +29,29 @@ func NewRedisClient(cfg RedisConfig) (*RedisClient, error) {
 		DB:       cfg.DB,
 	})
 
-	ctx := context.Background()
-
 	// Test connection
-	if err := client.Ping(ctx).Err(); err != nil {
+	if err := client.Ping(context.Background()).Err(); err != nil {
 		return nil, fmt.Errorf("error connecting to redis: %w", err)
 	}
 
-	return &RedisClient{
-		client: client,
-		ctx:    ctx,
-	}, nil
+	return &RedisClient{client: client}, nil
 }
 
 // Set stores a value in Redis with expiration
-func (r *RedisClient) Set(key string, value interface{}, expiration time.Duration) error {
+func (r *RedisClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
 	json, err := json.Marshal(value)
 	if err != nil {
 		return fmt.Errorf("error marshaling value: %w", err)
 	}
 
-	return r.client.Set(r.ctx, key, json, expiration).Err()
+	return r.client.Set(ctx, key, json, expiration).Err()
 }
 
 // Get retrieves a value from Redis
-func (r *RedisClient) Get(key string, dest interface{}) error {
-	val, err := r.client.Get(r.ctx, key).Result()
+func (r *RedisClient) Get(ctx context.Context, key string, dest interface{}) error {
+	val, err := r.client.Get(ctx, key).Result()
 	if err == redis.Nil {
-		return fmt.Errorf("key not found")
+		return ErrNotFound
 	}
 	if err != nil {
 		return fmt.Errorf("error getting value: %w", err)
@@ -70,15 +65,15 @@ func (r *RedisClient) Get(key string, dest interface{}) error {
 }
 
 // Delete removes a key from Redis
-func (r *RedisClient) Delete(key string) error {
-	return r.client.Del(r.ctx, key).Err()
+func (r *RedisClient) Delete(ctx context.Context, key string) error {
+	return r.client.Del(ctx, key).Err()
 }
 
 // DeletePattern deletes all keys matching a pattern
-func (r *RedisClient) DeletePattern(pattern string) error {
-	iter := r.client.Scan(r.ctx, 0, pattern, 0).Iterator()
-	for iter.Next(r.ctx) {
-		if err := r.client.Del(r.ctx, iter.Val()).Err(); err != nil {
+func (r *RedisClient) DeletePattern(ctx context.Context, pattern string) error {
+	iter := r.client.Scan(ctx, 0, pattern, 0).Iterator()
+	for iter.Next(ctx) {
+		if err := r.client.Del(ctx, iter.Val()).Err(); err != nil {
 			return fmt.Errorf("error deleting key: %w", err)
 		}
 	}
@@ -86,8 +81,8 @@ func (r *RedisClient) DeletePattern(pattern string) error {
 }
 
 // Exists checks if a key exists
-func (r *RedisClient) Exists(key string) (bool, error) {
-	result, err := r.client.Exists(r.ctx, key).Result()
+func (r *RedisClient) Exists(ctx context.Context, key string) (bool, error) {
+	result, err := r.client.Exists(ctx, key).Result()
 	if err != nil {
 		return false, err
 	}
@@ -99,6 +94,8 @@ func (r *RedisClient) Close() error {
 	return r.client.Close()
 }
 
+var _ Cache = (*RedisClient)(nil)
+
 // GetClient returns the underlying Redis client
 func (r *RedisClient) GetClient() *redis.Client {
 	return r.client
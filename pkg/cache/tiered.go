@@ -0,0 +1,188 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// TieredCache keeps recently used keys in a local cache in front of a shared
+// one. When the shared cache fails it is skipped for a while and the local
+// tier keeps serving; deletes missed during the outage are replayed once it is
+// reachable again. Errors of cancelled or timed out requests do not count as
+// failures of the shared cache.
+type TieredCache struct {
+	local      *LocalCache
+	remote     Cache
+	retryAfter time.Duration
+
+	mu              sync.Mutex
+	downUntil       time.Time
+	replaying       bool
+	pendingKeys     map[string]bool
+	pendingPatterns map[string]bool
+}
+
+// NewTieredCache puts local in front of remote. Keys read from remote are kept
+// locally for the local cache's maximum TTL.
+func NewTieredCache(local *LocalCache, remote Cache) *TieredCache {
+	return &TieredCache{
+		local:           local,
+		remote:          remote,
+		retryAfter:      5 * time.Second,
+		pendingKeys:     make(map[string]bool),
+		pendingPatterns: make(map[string]bool),
+	}
+}
+
+var _ Cache = (*TieredCache)(nil)
+
+// Get reads the local tier first, then the shared one
+func (c *TieredCache) Get(ctx context.Context, key string, dest interface{}) error {
+	if err := c.local.Get(ctx, key, dest); err == nil {
+		return nil
+	}
+	if !c.available(ctx) {
+		return ErrNotFound
+	}
+
+	err := c.remote.Get(ctx, key, dest)
+	if errors.Is(err, ErrNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		c.fail(ctx, err)
+		return ErrNotFound
+	}
+
+	c.local.Set(ctx, key, dest, c.local.maxTTL)
+	return nil
+}
+
+// Set writes both tiers
+func (c *TieredCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	if err := c.local.Set(ctx, key, value, expiration); err != nil {
+		return err
+	}
+	if c.available(ctx) {
+		if err := c.remote.Set(ctx, key, value, expiration); err != nil {
+			c.fail(ctx, err)
+		}
+	}
+	return nil
+}
+
+// Delete removes a key from both tiers
+func (c *TieredCache) Delete(ctx context.Context, key string) error {
+	c.local.Delete(ctx, key)
+	if c.available(ctx) {
+		err := c.remote.Delete(ctx, key)
+		if err == nil {
+			return nil
+		}
+		c.fail(ctx, err)
+	}
+
+	c.mu.Lock()
+	c.pendingKeys[key] = true
+	c.mu.Unlock()
+	return nil
+}
+
+// DeletePattern removes the matching keys from both tiers
+func (c *TieredCache) DeletePattern(ctx context.Context, pattern string) error {
+	c.local.DeletePattern(ctx, pattern)
+	if c.available(ctx) {
+		err := c.remote.DeletePattern(ctx, pattern)
+		if err == nil {
+			return nil
+		}
+		c.fail(ctx, err)
+	}
+
+	c.mu.Lock()
+	c.pendingPatterns[pattern] = true
+	c.mu.Unlock()
+	return nil
+}
+
+// available reports whether the shared tier should be used. After an outage
+// the deletes it missed are replayed first. The replay runs without the lock,
+// and other requests skip the shared tier until it is done.
+func (c *TieredCache) available(ctx context.Context) bool {
+	for {
+		c.mu.Lock()
+		if c.replaying || time.Now().Before(c.downUntil) {
+			c.mu.Unlock()
+			return false
+		}
+		if len(c.pendingKeys) == 0 && len(c.pendingPatterns) == 0 {
+			recovered := !c.downUntil.IsZero()
+			c.downUntil = time.Time{}
+			c.mu.Unlock()
+			if recovered {
+				log.Println("Shared cache is reachable again")
+			}
+			return true
+		}
+
+		keys, patterns := c.pendingKeys, c.pendingPatterns
+		c.pendingKeys, c.pendingPatterns = make(map[string]bool), make(map[string]bool)
+		c.replaying = true
+		c.mu.Unlock()
+
+		err := c.replay(ctx, keys, patterns)
+
+		// Deletes that were not replayed are tried again later
+		c.mu.Lock()
+		c.replaying = false
+		for key := range keys {
+			c.pendingKeys[key] = true
+		}
+		for pattern := range patterns {
+			c.pendingPatterns[pattern] = true
+		}
+		c.mu.Unlock()
+
+		if err != nil {
+			c.fail(ctx, err)
+			return false
+		}
+	}
+}
+
+// replay sends missed deletes to the shared tier and removes the ones that
+// succeeded from keys and patterns
+func (c *TieredCache) replay(ctx context.Context, keys, patterns map[string]bool) error {
+	for pattern := range patterns {
+		if err := c.remote.DeletePattern(ctx, pattern); err != nil {
+			return err
+		}
+		delete(patterns, pattern)
+	}
+	for key := range keys {
+		if err := c.remote.Delete(ctx, key); err != nil {
+			return err
+		}
+		delete(keys, key)
+	}
+	return nil
+}
+
+// fail switches to the local tier only for a while. An error of a request
+// that was cancelled or timed out says nothing about the shared cache.
+func (c *TieredCache) fail(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.downUntil.IsZero() {
+		log.Printf("Shared cache failed, using local cache only: %v", err)
+	}
+	c.downUntil = time.Now().Add(c.retryAfter)
+}
@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTieredCache_ReadsThroughToRedis(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	remote := newTestClient(t, server)
+
+	require.NoError(t, remote.Set(ctx, "suggestion:YRD1:a", "from redis", time.Minute))
+
+	c := NewTieredCache(NewLocalCache(10, time.Minute), remote)
+	var v string
+	require.NoError(t, c.Get(ctx, "suggestion:YRD1:a", &v))
+	assert.Equal(t, "from redis", v)
+	assert.Equal(t, 1, c.local.Len())
+
+	require.NoError(t, c.Set(ctx, "suggestion:YRD1:b", "both", time.Minute))
+	assert.True(t, server.Exists("suggestion:YRD1:b"))
+
+	require.NoError(t, c.DeletePattern(ctx, "suggestion:YRD1:*"))
+	assert.Empty(t, server.Keys())
+	assert.ErrorIs(t, c.Get(ctx, "suggestion:YRD1:a", &v), ErrNotFound)
+}
+
+func TestTieredCache_RedisOutage(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	remote := newTestClient(t, server)
+
+	c := NewTieredCache(NewLocalCache(10, time.Minute), remote)
+	c.retryAfter = 50 * time.Millisecond
+	require.NoError(t, c.Set(ctx, "suggestion:YRD1:a", "cached", time.Minute))
+
+	// Requests keep working on the local tier while Redis is down
+	server.Close()
+	var v string
+	require.NoError(t, c.Get(ctx, "suggestion:YRD1:a", &v))
+	require.NoError(t, c.Set(ctx, "suggestion:YRD1:b", "local only", time.Minute))
+	require.NoError(t, c.Get(ctx, "suggestion:YRD1:b", &v))
+	assert.Equal(t, "local only", v)
+	require.NoError(t, c.DeletePattern(ctx, "suggestion:YRD1:*"))
+	assert.ErrorIs(t, c.Get(ctx, "suggestion:YRD1:a", &v), ErrNotFound)
+
+	// The delete missed by Redis is replayed once it is back
+	require.NoError(t, server.Restart())
+	assert.True(t, server.Exists("suggestion:YRD1:a"))
+	time.Sleep(60 * time.Millisecond)
+	assert.ErrorIs(t, c.Get(ctx, "suggestion:YRD1:a", &v), ErrNotFound)
+	assert.False(t, server.Exists("suggestion:YRD1:a"))
+}
+
+func TestTieredCache_CancelledRequestKeepsRedis(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	remote := newTestClient(t, server)
+	require.NoError(t, remote.Set(ctx, "suggestion:YRD1:a", "from redis", time.Minute))
+
+	c := NewTieredCache(NewLocalCache(10, time.Minute), remote)
+
+	// A client that went away before its read reached Redis
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	var v string
+	assert.ErrorIs(t, c.Get(cancelled, "suggestion:YRD1:a", &v), ErrNotFound)
+	require.NoError(t, c.Delete(cancelled, "suggestion:YRD1:b"))
+
+	// Other requests still use Redis, and the missed delete is replayed
+	require.NoError(t, server.Set("suggestion:YRD1:b", "stale"))
+	require.NoError(t, c.Get(ctx, "suggestion:YRD1:a", &v))
+	assert.Equal(t, "from redis", v)
+	assert.False(t, server.Exists("suggestion:YRD1:b"))
+}
+
+// blockingCache holds DeletePattern calls until release is closed
+type blockingCache struct {
+	Cache
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingCache) DeletePattern(ctx context.Context, pattern string) error {
+	close(c.started)
+	<-c.release
+	return c.Cache.DeletePattern(ctx, pattern)
+}
+
+func TestTieredCache_ReplayDoesNotBlockReads(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	remote := &blockingCache{
+		Cache:   newTestClient(t, server),
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+
+	c := NewTieredCache(NewLocalCache(10, time.Minute), remote)
+	require.NoError(t, c.Set(ctx, "suggestion:YRD1:a", "local", time.Minute))
+	c.pendingPatterns["suggestion:YRD1:*"] = true
+
+	// The first request replays the missed delete and waits for Redis
+	replayed := make(chan error)
+	go func() {
+		var v string
+		replayed <- c.Get(ctx, "suggestion:YRD1:b", &v)
+	}()
+	<-remote.started
+
+	// Meanwhile reads are served by the local tier without waiting
+	read := make(chan error)
+	go func() {
+		var v string
+		read <- c.Get(ctx, "suggestion:YRD1:a", &v)
+		read <- c.Get(ctx, "suggestion:YRD1:b", &v)
+	}()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-read:
+			if i == 0 {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrNotFound)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("read waited for the replay")
+		}
+	}
+
+	close(remote.release)
+	assert.ErrorIs(t, <-replayed, ErrNotFound)
+	assert.Empty(t, c.pendingPatterns)
+}
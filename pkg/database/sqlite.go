@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+type SQLiteConfig struct {
+	// Path is the database file, or ":memory:" for a private in-memory database
+	Path string
+	// BusyTimeout is how long a writer waits for the database lock
+	BusyTimeout time.Duration
+}
+
+func init() {
+	sql.Register("sqlite-utc", &utcDriver{})
+}
+
+// NewSQLiteDB creates a new SQLite database connection. Foreign keys are
+// enforced, the database runs in WAL mode and transactions take the write
+// lock up front, so a transaction sees every write committed before it and no
+// other transaction writes until it ends. Checks made inside a transaction
+// therefore still hold when it commits; checks made before it do not.
+func NewSQLiteDB(cfg SQLiteConfig) (*sql.DB, error) {
+	busyTimeout := cfg.BusyTimeout
+	if busyTimeout == 0 {
+		busyTimeout = 5 * time.Second
+	}
+
+	dsn := fmt.Sprintf(
+		"file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)&_txlock=immediate&_time_format=sqlite",
+		cfg.Path, busyTimeout.Milliseconds(),
+	)
+
+	db, err := sql.Open("sqlite-utc", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+
+	// Test connection
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("error connecting to database: %w", err)
+	}
+
+	// Every connection to ":memory:" is a separate database
+	if cfg.Path == ":memory:" {
+		db.SetMaxOpenConns(1)
+	} else {
+		db.SetMaxOpenConns(10)
+		db.SetMaxIdleConns(5)
+	}
+
+	log.Println("Database connection established successfully")
+	return db, nil
+}
+
+// utcDriver wraps the SQLite driver and converts every time argument to UTC.
+// SQLite compares timestamps as text, which is only correct when they all
+// use the same offset.
+type utcDriver struct {
+	sqlite.Driver
+}
+
+func (d *utcDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &utcConn{Conn: c}, nil
+}
+
+type utcConn struct {
+	driver.Conn
+}
+
+// CheckNamedValue converts time arguments to UTC and leaves the rest to the
+// default conversion
+func (c *utcConn) CheckNamedValue(nv *driver.NamedValue) error {
+	switch v := nv.Value.(type) {
+	case time.Time:
+		nv.Value = v.UTC()
+		return nil
+	case *time.Time:
+		if v != nil {
+			nv.Value = v.UTC()
+			return nil
+		}
+	}
+	return driver.ErrSkip
+}
+
+func (c *utcConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *utcConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+}
+
+func (c *utcConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *utcConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func (c *utcConn) Ping(ctx context.Context) error {
+	return c.Conn.(driver.Pinger).Ping(ctx)
+}
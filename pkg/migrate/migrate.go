@@ -0,0 +1,364 @@
+// Package migrate applies versioned SQL migrations and records them in the
+// schema_migrations table.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite"
+)
+
+// lockID is the PostgreSQL advisory lock taken while migrating, so replicas
+// starting at the same time do not apply the same migration twice
+const lockID = 7_413_002
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one numbered schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status reports whether a migration has been applied
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// Load reads the NNN_name.up.sql and NNN_name.down.sql files of a directory.
+// Every version needs both files.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("error reading migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %03d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Migrator applies and rolls back migrations on a database
+type Migrator struct {
+	db         *sql.DB
+	dialect    string
+	migrations []Migration
+}
+
+// New creates a migrator for the migrations in fsys
+func New(db *sql.DB, dialect string, fsys fs.FS) (*Migrator, error) {
+	if dialect != DialectPostgres && dialect != DialectSQLite {
+		return nil, fmt.Errorf("unsupported dialect %q", dialect)
+	}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// Latest returns the highest known migration version
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Status lists every known migration and whether it has been applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withConn(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := Status{Version: migration.Version, Name: migration.Name}
+			if at, ok := applied[migration.Version]; ok {
+				status.Applied = true
+				status.AppliedAt = &at
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+
+	return statuses, err
+}
+
+// Version returns the highest applied migration version, or 0
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	version := 0
+	err := m.withConn(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		for v := range applied {
+			if v > version {
+				version = v
+			}
+		}
+		return err
+	})
+
+	return version, err
+}
+
+// Up applies every pending migration and returns how many were applied
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	return m.To(ctx, m.Latest())
+}
+
+// Down rolls back the most recently applied migration
+func (m *Migrator) Down(ctx context.Context) error {
+	version, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if version == 0 {
+		return fmt.Errorf("no migration to roll back")
+	}
+
+	target := 0
+	for _, migration := range m.migrations {
+		if migration.Version < version {
+			target = migration.Version
+		}
+	}
+
+	_, err = m.To(ctx, target)
+	return err
+}
+
+// To applies or rolls back migrations until version is the latest applied one,
+// and returns how many migrations were applied or rolled back. Version 0 rolls
+// back everything.
+func (m *Migrator) To(ctx context.Context, version int) (int, error) {
+	if version != 0 && m.find(version) == nil {
+		return 0, fmt.Errorf("unknown migration version %d", version)
+	}
+
+	count := 0
+	err := m.withConn(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok || migration.Version > version {
+				continue
+			}
+			if err := m.run(ctx, conn, migration, true); err != nil {
+				return err
+			}
+			count++
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok || migration.Version <= version {
+				continue
+			}
+			if err := m.run(ctx, conn, migration, false); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+
+	return count, err
+}
+
+// Seed runs a fixture script. Fixtures must be safe to run more than once.
+func (m *Migrator) Seed(ctx context.Context, fixture string) error {
+	if _, err := m.db.ExecContext(ctx, fixture); err != nil {
+		return fmt.Errorf("error loading seed data: %w", err)
+	}
+	return nil
+}
+
+func (m *Migrator) find(version int) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// withConn runs fn on a single connection that holds the migration lock
+func (m *Migrator) withConn(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting connection: %w", err)
+	}
+	defer conn.Close()
+
+	if m.dialect == DialectPostgres {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+			return fmt.Errorf("error taking migration lock: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+	}
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var (
+			version   int
+			appliedAt time.Time
+		)
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("error scanning schema_migrations: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// run applies or rolls back one migration in its own transaction
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, migration Migration, up bool) error {
+	// SQLite changes most constraints by rebuilding a table. Foreign keys are
+	// switched off meanwhile, so dropping the old table does not cascade to
+	// its children, and checked before the migration is committed.
+	if m.dialect == DialectSQLite {
+		if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+			return fmt.Errorf("error disabling foreign keys: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), `PRAGMA foreign_keys = ON`)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if up {
+		if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+			return fmt.Errorf("error applying migration %03d_%s: %w", migration.Version, migration.Name, err)
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`,
+			migration.Version, migration.Name,
+		)
+	} else {
+		if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+			return fmt.Errorf("error rolling back migration %03d_%s: %w", migration.Version, migration.Name, err)
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("error recording migration %03d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	if m.dialect == DialectSQLite {
+		if err := checkForeignKeys(ctx, tx); err != nil {
+			return fmt.Errorf("error checking migration %03d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing migration %03d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	return nil
+}
+
+// checkForeignKeys returns an error when a SQLite row references a missing row
+func checkForeignKeys(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `PRAGMA foreign_key_check`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var (
+			table, parent string
+			rowID         sql.NullInt64
+			fk            int
+		)
+		if err := rows.Scan(&table, &rowID, &parent, &fk); err != nil {
+			return err
+		}
+		return fmt.Errorf("row %d of %s references a missing row of %s", rowID.Int64, table, parent)
+	}
+
+	return rows.Err()
+}
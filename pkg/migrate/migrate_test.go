@@ -0,0 +1,264 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dwipurnomo515/yard-planning/migrations"
+	"github.com/dwipurnomo515/yard-planning/pkg/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMigrations = fstest.MapFS{
+	"001_yards.up.sql":     {Data: []byte(`CREATE TABLE yards (id INTEGER PRIMARY KEY, code TEXT NOT NULL UNIQUE);`)},
+	"001_yards.down.sql":   {Data: []byte(`DROP TABLE yards;`)},
+	"002_blocks.up.sql":    {Data: []byte(`CREATE TABLE blocks (id INTEGER PRIMARY KEY, yard_id INTEGER NOT NULL);`)},
+	"002_blocks.down.sql":  {Data: []byte(`DROP TABLE blocks;`)},
+	"003_indexes.up.sql":   {Data: []byte(`CREATE INDEX idx_blocks_yard ON blocks(yard_id);`)},
+	"003_indexes.down.sql": {Data: []byte(`DROP INDEX idx_blocks_yard;`)},
+	"README.md":            {Data: []byte(`not a migration`)},
+}
+
+func newTestMigrator(t *testing.T) *Migrator {
+	db, err := database.NewSQLiteDB(database.SQLiteConfig{Path: t.TempDir() + "/migrate.db"})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := New(db, DialectSQLite, testMigrations)
+	require.NoError(t, err)
+	return migrator
+}
+
+func appliedVersions(t *testing.T, m *Migrator) []int {
+	statuses, err := m.Status(context.Background())
+	require.NoError(t, err)
+
+	var versions []int
+	for _, s := range statuses {
+		if s.Applied {
+			versions = append(versions, s.Version)
+		}
+	}
+	return versions
+}
+
+func TestLoad(t *testing.T) {
+	loaded, err := Load(testMigrations)
+	require.NoError(t, err)
+	require.Len(t, loaded, 3)
+	assert.Equal(t, 1, loaded[0].Version)
+	assert.Equal(t, "yards", loaded[0].Name)
+	assert.Equal(t, "indexes", loaded[2].Name)
+
+	_, err = Load(fstest.MapFS{"001_yards.up.sql": {Data: []byte(`SELECT 1;`)}})
+	assert.Error(t, err, "missing down file")
+
+	_, err = Load(fstest.MapFS{
+		"001_yards.up.sql":    {Data: []byte(`SELECT 1;`)},
+		"001_blocks.down.sql": {Data: []byte(`SELECT 1;`)},
+	})
+	assert.Error(t, err, "two names for one version")
+}
+
+func TestLoad_EmbeddedMigrations(t *testing.T) {
+	postgres, err := Load(migrations.Postgres())
+	require.NoError(t, err)
+	assert.Equal(t, 17, len(postgres))
+
+	sqlite, err := Load(migrations.SQLite())
+	require.NoError(t, err)
+	assert.NotEmpty(t, sqlite)
+}
+
+func TestMigrator_UpDownTo(t *testing.T) {
+	ctx := context.Background()
+	m := newTestMigrator(t)
+
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, applied)
+	assert.Equal(t, []int{1, 2, 3}, appliedVersions(t, m))
+
+	// Running again is a no-op
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, applied)
+
+	require.NoError(t, m.Down(ctx))
+	assert.Equal(t, []int{1, 2}, appliedVersions(t, m))
+
+	changed, err := m.To(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, changed)
+	assert.Equal(t, []int{1}, appliedVersions(t, m))
+
+	changed, err = m.To(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, 2, changed)
+
+	version, err := m.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, version)
+
+	_, err = m.To(ctx, 9)
+	assert.Error(t, err)
+
+	_, err = m.To(ctx, 0)
+	require.NoError(t, err)
+	assert.Empty(t, appliedVersions(t, m))
+	assert.Error(t, m.Down(ctx))
+}
+
+func TestMigrator_FailedMigrationRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.NewSQLiteDB(database.SQLiteConfig{Path: t.TempDir() + "/migrate.db"})
+	require.NoError(t, err)
+	defer db.Close()
+
+	broken := fstest.MapFS{
+		"001_yards.up.sql":    testMigrations["001_yards.up.sql"],
+		"001_yards.down.sql":  testMigrations["001_yards.down.sql"],
+		"002_broken.up.sql":   {Data: []byte(`CREATE TABLE half (id INTEGER); SELECT * FROM missing_table;`)},
+		"002_broken.down.sql": {Data: []byte(`DROP TABLE half;`)},
+	}
+	m, err := New(db, DialectSQLite, broken)
+	require.NoError(t, err)
+
+	_, err = m.Up(ctx)
+	assert.Error(t, err)
+
+	version, err := m.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	var tables int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'half'`).Scan(&tables))
+	assert.Equal(t, 0, tables)
+}
+
+func TestMigrator_SQLiteSchemaAndSeed(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.NewSQLiteDB(database.SQLiteConfig{Path: t.TempDir() + "/yard.db"})
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := New(db, DialectSQLite, migrations.SQLite())
+	require.NoError(t, err)
+
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+
+	// The fixture is safe to load more than once
+	require.NoError(t, m.Seed(ctx, migrations.SQLiteSeed))
+	require.NoError(t, m.Seed(ctx, migrations.SQLiteSeed))
+
+	var yards int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM yards`).Scan(&yards))
+	assert.Equal(t, 2, yards)
+
+	_, err = m.To(ctx, 0)
+	require.NoError(t, err)
+
+	var tables int
+	require.NoError(t, db.QueryRow(
+		`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name NOT IN ('schema_migrations', 'sqlite_sequence')`,
+	).Scan(&tables))
+	assert.Equal(t, 0, tables)
+}
+
+// Rebuilding a parent table, as 009_yard_code_per_tenant does, keeps the rows
+// that reference it
+func TestMigrator_SQLiteRebuildKeepsChildren(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.NewSQLiteDB(database.SQLiteConfig{Path: t.TempDir() + "/yard.db"})
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := New(db, DialectSQLite, migrations.SQLite())
+	require.NoError(t, err)
+	_, err = m.To(ctx, 8)
+	require.NoError(t, err)
+	require.NoError(t, m.Seed(ctx, migrations.SQLiteSeed))
+
+	count := func(table string) int {
+		var n int
+		require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM `+table).Scan(&n))
+		return n
+	}
+	blocks, plans, rules := count("blocks"), count("yard_plans"), count("yard_overflow_rules")
+	require.NotZero(t, blocks)
+
+	_, err = m.To(ctx, 9)
+	require.NoError(t, err)
+	assert.Equal(t, blocks, count("blocks"))
+	assert.Equal(t, plans, count("yard_plans"))
+	assert.Equal(t, rules, count("yard_overflow_rules"))
+
+	// Codes are unique per tenant now
+	_, err = db.Exec(`INSERT INTO yards (code, name, tenant) VALUES ('YRD1', 'Yard 1', 'acme')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO yards (code, name, tenant) VALUES ('YRD1', 'Yard 1', 'acme')`)
+	assert.Error(t, err)
+	_, err = db.Exec(`DELETE FROM yards WHERE tenant = 'acme'`)
+	require.NoError(t, err)
+
+	require.NoError(t, m.Down(ctx))
+	assert.Equal(t, blocks, count("blocks"))
+
+	// Foreign keys are enforced again after migrating
+	_, err = db.Exec(`INSERT INTO blocks (yard_id, code, name, max_slot, max_row, max_tier) VALUES (99, 'X', 'X', 1, 1, 1)`)
+	assert.Error(t, err)
+}
+
+func TestNew_UnsupportedDialect(t *testing.T) {
+	_, err := New(nil, "mysql", testMigrations)
+	assert.Error(t, err)
+}
+
+// 011_work_order_reservations fails the reservations of a cell made after the
+// first one before making reservations unique
+func TestMigrator_SQLiteFailsDuplicateReservations(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.NewSQLiteDB(database.SQLiteConfig{Path: t.TempDir() + "/yard.db"})
+	require.NoError(t, err)
+	defer db.Close()
+
+	m, err := New(db, DialectSQLite, migrations.SQLite())
+	require.NoError(t, err)
+	_, err = m.To(ctx, 10)
+	require.NoError(t, err)
+	require.NoError(t, m.Seed(ctx, migrations.SQLiteSeed))
+
+	for _, number := range []string{"ABCU0000001", "ABCU0000002", "ABCU0000003"} {
+		_, err = db.Exec(`
+			INSERT INTO work_orders (yard_id, container_number, operation, block_id, slot, row, tier, position_applied)
+			VALUES (1, ?, 'PLACEMENT', 1, 1, 1, 1, FALSE)
+		`, number)
+		require.NoError(t, err)
+	}
+
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+
+	rows, err := db.Query(`SELECT container_number, status, failure_reason FROM work_orders ORDER BY id`)
+	require.NoError(t, err)
+	defer rows.Close()
+	var statuses, reasons []string
+	for rows.Next() {
+		var number, status, reason string
+		require.NoError(t, rows.Scan(&number, &status, &reason))
+		statuses = append(statuses, status)
+		reasons = append(reasons, reason)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"CREATED", "FAILED", "FAILED"}, statuses)
+	assert.Equal(t, "cell already reserved by work order 1", reasons[2])
+
+	_, err = db.Exec(`
+		INSERT INTO work_orders (yard_id, container_number, operation, block_id, slot, row, tier, position_applied)
+		VALUES (1, 'ABCU0000004', 'PLACEMENT', 1, 1, 1, 1, FALSE)
+	`)
+	assert.Error(t, err)
+}
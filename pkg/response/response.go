@@ -3,11 +3,17 @@ package response
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/dwipurnomo515/yard-planning/pkg/apperror"
 )
 
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message,omitempty"`
+	Error   string                `json:"error"`
+	Code    apperror.Code         `json:"code,omitempty"`
+	Message string                `json:"message,omitempty"`
+	Details []apperror.FieldError `json:"details,omitempty"`
+	// Current is the current state of a resource a write could not change
+	Current interface{} `json:"current,omitempty"`
 }
 
 // JSON writes a JSON response
@@ -20,14 +26,36 @@ func JSON(w http.ResponseWriter, status int, data interface{}) {
 	}
 }
 
-// Error writes an error response
+// Error writes an error response with the given status
 func Error(w http.ResponseWriter, status int, err error) {
+	appErr := apperror.From(err)
 	JSON(w, status, ErrorResponse{
 		Error:   http.StatusText(status),
+		Code:    appErr.Code,
 		Message: err.Error(),
+		Details: appErr.Details,
 	})
 }
 
+// FailWithCurrent writes an error response with the current state of the
+// resource, e.g. for a write that expected an older version
+func FailWithCurrent(w http.ResponseWriter, err error, current interface{}) {
+	status := apperror.Status(err)
+	appErr := apperror.From(err)
+	JSON(w, status, ErrorResponse{
+		Error:   http.StatusText(status),
+		Code:    appErr.Code,
+		Message: err.Error(),
+		Details: appErr.Details,
+		Current: current,
+	})
+}
+
+// Fail writes an error response with the status of the error's code
+func Fail(w http.ResponseWriter, err error) {
+	Error(w, apperror.Status(err), err)
+}
+
 // Success writes a success response
 func Success(w http.ResponseWriter, data interface{}) {
 	JSON(w, http.StatusOK, data)
@@ -37,3 +65,8 @@ func Success(w http.ResponseWriter, data interface{}) {
 func Created(w http.ResponseWriter, data interface{}) {
 	JSON(w, http.StatusCreated, data)
 }
+
+// Accepted writes a response for work that continues in the background
+func Accepted(w http.ResponseWriter, data interface{}) {
+	JSON(w, http.StatusAccepted, data)
+}
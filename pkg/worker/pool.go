@@ -32,9 +32,10 @@ type Pool struct {
 	cancel     context.CancelFunc
 }
 
-// NewPool creates a new worker pool
-func NewPool(workers int, workerFunc WorkerFunc) *Pool {
-	ctx, cancel := context.WithCancel(context.Background())
+// NewPool creates a new worker pool whose jobs run with a context derived
+// from ctx, usually the context of the request that submitted them
+func NewPool(ctx context.Context, workers int, workerFunc WorkerFunc) *Pool {
+	ctx, cancel := context.WithCancel(ctx)
 	return &Pool{
 		workers:    workers,
 		jobs:       make(chan Job, workers*2),
@@ -53,25 +54,23 @@ func (p *Pool) Start() {
 	}
 }
 
-// worker is the worker goroutine
+// worker is the worker goroutine. Once the context is cancelled the remaining
+// jobs are not run but still produce a result carrying the context error, so
+// Submit never blocks and every job is accounted for.
 func (p *Pool) worker(id int) {
 	defer p.wg.Done()
 
-	for {
-		select {
-		case <-p.ctx.Done():
-			return
-		case job, ok := <-p.jobs:
-			if !ok {
-				return
-			}
+	for job := range p.jobs {
+		if err := p.ctx.Err(); err != nil {
+			p.results <- Result{Job: job, Err: err}
+			continue
+		}
 
-			value, err := p.workerFunc(p.ctx, job)
-			p.results <- Result{
-				Job:   job,
-				Value: value,
-				Err:   err,
-			}
+		value, err := p.workerFunc(p.ctx, job)
+		p.results <- Result{
+			Job:   job,
+			Value: value,
+			Err:   err,
 		}
 	}
 }
@@ -86,10 +85,11 @@ func (p *Pool) Results() <-chan Result {
 	return p.results
 }
 
-// Stop stops the worker pool
+// Stop waits for the submitted jobs and closes the results channel
 func (p *Pool) Stop() {
 	close(p.jobs)
 	p.wg.Wait()
+	p.cancel()
 	close(p.results)
 }
 
@@ -0,0 +1,44 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPool_CancelSkipsRemainingJobs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan struct{})
+	pool := NewPool(ctx, 1, func(ctx context.Context, job Job) (interface{}, error) {
+		if job.ID == "0" {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return job.ID, nil
+	})
+	pool.Start()
+
+	// Submitting more jobs than the buffer holds must not block after cancel
+	go func() {
+		for i := 0; i < 10; i++ {
+			pool.Submit(Job{ID: fmt.Sprint(i)})
+		}
+		pool.Stop()
+	}()
+
+	<-started
+	cancel()
+
+	results := 0
+	for result := range pool.Results() {
+		results++
+		assert.ErrorIs(t, result.Err, context.Canceled, "job %s", result.Job.ID)
+	}
+	require.Equal(t, 10, results)
+}
@@ -1,6 +1,9 @@
 #!/bin/bash
 
 # Test script untuk Yard Planning API
+# Jalankan API dengan WORK_ORDER_CONFIRMATION=false agar placement dan pickup langsung
+# mengubah yard; secara default posisi baru berubah setelah job dikonfirmasi.
+# Script ini tidak mengirim kredensial: jalankan dengan AUTH_ENABLED=false ALLOW_INSECURE=true.
 
 BASE_URL="http://localhost:8080"
 